@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// defaultAnswerTemplate is the wrapper put around the user's question
+const defaultAnswerTemplate = `the user is asking: 
+<question>
+{{.Question}}
+</question>
+please think step by step and give your answer
+{{if .ReplyLanguage}}please answer in {{.ReplyLanguage}}
+{{end}}{{if .ToolCalls}}tool calls used so far in this session: {{.ToolCalls}}
+{{end}}----
+{{.Profile}}`
+
+// answerTemplateData is the data available to answer templates
+type answerTemplateData struct {
+	Question string
+	// Profile is the rendered selected profile, or the built-in guidelines
+	Profile       string
+	ProfileName   string
+	WorkingDir    string
+	ReplyLanguage string
+	// ToolCalls is the tool-call count of the session, see handleCount, 0 if unused
+	ToolCalls int
+}
+
+// getAnswerTemplate returns the answer template: the file named by the
+// answerTemplateFile front matter of the profile (relative to the config dir),
+// then Config.AnswerTemplate, then the default
+func getAnswerTemplate(config *Config, profileContent string) string {
+	if file := sections.ProfileFrontMatter(profileContent)["answertemplatefile"]; file != "" {
+		configDir, err := getConfigDir(false)
+		if err == nil {
+			content, err := os.ReadFile(resolveIncludePath(file, configDir))
+			if err == nil {
+				return sections.Normalize(string(content))
+			}
+			Errorf("read answer template %s: %v", file, err)
+		}
+	}
+	if config != nil && config.AnswerTemplate != "" {
+		return config.AnswerTemplate
+	}
+	return defaultAnswerTemplate
+}
+
+// renderAnswer executes text with data, falling back to the default
+// template if text cannot be parsed or executed
+func renderAnswer(text string, data *answerTemplateData) string {
+	result, err := executeAnswerTemplate(text, data)
+	if err != nil {
+		Errorf("answer template: %v", err)
+		result, _ = executeAnswerTemplate(defaultAnswerTemplate, data)
+	}
+	return result
+}
+
+// appendWaitTime appends how long the user took to reply to answer
+// if Config.WaitTimeMetadata is set
+func appendWaitTime(config *Config, answer string, wait time.Duration) string {
+	if config == nil || !config.WaitTimeMetadata {
+		return answer
+	}
+	return strings.TrimRight(answer, "\n") + "\n\n(user replied after " + formatWaitTime(wait) + ")"
+}
+
+// formatWaitTime formats d like "12s", "4m 12s" or "1h 4m"
+func formatWaitTime(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func executeAnswerTemplate(text string, data *answerTemplateData) (string, error) {
+	tmpl, err := template.New("answer").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
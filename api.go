@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/whats_next/pkg/protocol"
+)
+
+// apiPrefix is the root of the JSON API of the server for editor extensions.
+// Breaking changes go to a new version.
+const apiPrefix = protocol.APIPrefix
+
+// defaultAPIAllowOrigins lets VS Code webviews call the API when
+// apiAllowOrigins is not set. Neovim and extension hosts send no Origin.
+var defaultAPIAllowOrigins = []string{"vscode-webview://*"}
+
+// apiSession is a client waiting for input
+type apiSession = protocol.Session
+
+type apiSessions = protocol.Sessions
+
+// apiMessage is the body of POST /api/v1/messages
+type apiMessage = protocol.Message
+
+type apiProfile = protocol.Profile
+
+type apiError = protocol.Error
+
+// errAPINotFound is returned by API routes for unknown resources
+var errAPINotFound = errors.New("not found")
+
+// handleAPIRequest serves the JSON API under apiPrefix:
+//
+//	GET  /api/v1/sessions          agents waiting for input
+//	POST /api/v1/messages          {"text": "...", "workingDir": "..."} queues a reply for the next agent
+//	GET  /api/v1/profiles          profile names, with the selected one
+//	GET  /api/v1/profiles/NAME     a profile with its content
+//	GET  /api/v1/config?workingDir=DIR  the effective config, secrets redacted
+func handleAPIRequest(h *serveHandler, w http.ResponseWriter, r *http.Request) {
+	if !allowAPIOrigin(w, r, readServerConfig().APIAllowOrigins) {
+		writeAPIError(w, http.StatusForbidden, fmt.Errorf("origin not allowed: %s, see apiAllowOrigins", r.Header.Get("Origin")))
+		return
+	}
+	if r.Method == http.MethodOptions {
+		// CORS preflight
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	route := strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/")
+	resource, name, _ := strings.Cut(route, "/")
+
+	var result interface{}
+	var err error
+	status := http.StatusOK
+	switch {
+	case resource == "sessions" && name == "" && r.Method == http.MethodGet:
+		result = getAPISessions(h)
+	case resource == "messages" && name == "" && r.Method == http.MethodPost:
+		result, err = postAPIMessage(h, r)
+		status = http.StatusAccepted
+	case resource == "profiles" && name == "" && r.Method == http.MethodGet:
+		result, err = listAPIProfiles()
+	case resource == "profiles" && name != "" && r.Method == http.MethodGet:
+		result, err = getAPIProfile(name)
+	case resource == "config" && name == "" && r.Method == http.MethodGet:
+		result, err = getAPIConfig(r.URL.Query().Get("workingDir"))
+	case resource == "sessions" || resource == "messages" || resource == "profiles" || resource == "config":
+		writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s not allowed on %s", r.Method, r.URL.Path))
+		return
+	default:
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("unknown API: %s", r.URL.Path))
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errAPINotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, errInputClosed):
+			status = http.StatusServiceUnavailable
+		default:
+			status = http.StatusBadRequest
+		}
+		writeAPIError(w, status, err)
+		return
+	}
+	writeAPIJSON(w, status, result)
+}
+
+// allowAPIOrigin sets the CORS headers for the Origin of r if it matches allowed,
+// or defaultAPIAllowOrigins if nil. Requests without Origin are not from browsers and always allowed.
+func allowAPIOrigin(w http.ResponseWriter, r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if allowed == nil {
+		allowed = defaultAPIAllowOrigins
+	}
+	for _, pattern := range allowed {
+		prefix, wildcard := strings.CutSuffix(pattern, "*")
+		if origin == pattern || (wildcard && strings.HasPrefix(origin, prefix)) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+tokenHeader)
+			w.Header().Add("Vary", "Origin")
+			return true
+		}
+	}
+	return false
+}
+
+func getAPISessions(h *serveHandler) *apiSessions {
+	now := time.Now()
+	result := &apiSessions{Sessions: []apiSession{}, Typing: h.hasInputContent()}
+	for _, session := range h.getWaitingSessions() {
+		s := apiSession{
+			ID:         session.ID,
+			WorkingDir: session.WorkingDir,
+			Since:      session.Since,
+			Wait:       now.Sub(session.Since).Round(time.Second).Seconds(),
+		}
+		if session.WorkingDir != "" {
+			s.Project = filepath.Base(session.WorkingDir)
+		}
+		result.Sessions = append(result.Sessions, s)
+	}
+	return result
+}
+
+// errInputClosed is returned when a reply cannot be queued
+var errInputClosed = errors.New("input queue is closed or full")
+
+func postAPIMessage(h *serveHandler, r *http.Request) (interface{}, error) {
+	var msg apiMessage
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	if strings.TrimSpace(msg.Text) == "" {
+		return nil, fmt.Errorf("requires text")
+	}
+	if !h.enqueueInput(InputMessage{Content: msg.Text, WorkingDir: msg.WorkingDir}) {
+		return nil, errInputClosed
+	}
+	Logf("API message enqueued from %s", r.RemoteAddr)
+	return map[string]bool{"queued": true}, nil
+}
+
+func listAPIProfiles() (interface{}, error) {
+	groupDir, err := getGroupConfigPath(false)
+	if err != nil {
+		return nil, err
+	}
+	names, err := getGroupNames(groupDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	config, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+	profiles := make([]apiProfile, 0, len(names))
+	for _, name := range names {
+		profiles = append(profiles, apiProfile{Name: name, Selected: name == config.SelectedProfile})
+	}
+	return map[string][]apiProfile{"profiles": profiles}, nil
+}
+
+func getAPIProfile(name string) (interface{}, error) {
+	name = strings.TrimSuffix(name, ".md")
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid profile name: %s", name)
+	}
+	groupDir, err := getGroupConfigPath(false)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(groupDir, addMDSuffix(name)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("profile %s: %w", name, errAPINotFound)
+		}
+		return nil, err
+	}
+	config, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &apiProfile{Name: name, Selected: name == config.SelectedProfile, Content: string(content)}, nil
+}
+
+func getAPIConfig(workingDir string) (interface{}, error) {
+	config, err := readEffectiveConfig(workingDir)
+	if err != nil {
+		return nil, err
+	}
+	return redactConfig(config), nil
+}
+
+// redactedValue replaces the secrets of the config served by the API
+const redactedValue = "<redacted>"
+
+// redactConfig returns a copy of config without the values that may be
+// literal secrets. Secret references like "keychain:NAME" are kept.
+func redactConfig(config *Config) *Config {
+	redact := func(value string) string {
+		if value == "" || strings.HasPrefix(value, secretRefPrefix) {
+			return value
+		}
+		return redactedValue
+	}
+	c := *config
+	c.GistToken = redact(c.GistToken)
+	c.ServerToken = redact(c.ServerToken)
+	if c.Slack != nil {
+		slack := *c.Slack
+		slack.Token = redact(slack.Token)
+		c.Slack = &slack
+	}
+	if c.Digest != nil {
+		digest := *c.Digest
+		digest.Password = redact(digest.Password)
+		c.Digest = &digest
+	}
+	if c.Webhooks != nil {
+		c.Webhooks = make([]Webhook, len(config.Webhooks))
+		for i, hook := range config.Webhooks {
+			// URLs like ntfy topics are secrets themselves
+			hook.URL = redact(hook.URL)
+			if hook.Headers != nil {
+				headers := make(map[string]string, len(hook.Headers))
+				for k, v := range hook.Headers {
+					headers[k] = redact(v)
+				}
+				hook.Headers = headers
+			}
+			c.Webhooks[i] = hook
+		}
+	}
+	// hosts overrides are already applied
+	c.Hosts = nil
+	return &c
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, &apiError{Error: err.Error()})
+}
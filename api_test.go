@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newAPITestServer(t *testing.T, h *serveHandler) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleAPIRequest(h, w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAPISessionsAndMessages(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	h := &serveHandler{inputChan: make(chan InputMessage, 1)}
+	h.addWaiting("/src/api")
+	server := newAPITestServer(t, h)
+
+	resp, err := http.Get(server.URL + "/api/v1/sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sessions apiSessions
+	json.NewDecoder(resp.Body).Decode(&sessions)
+	resp.Body.Close()
+	if len(sessions.Sessions) != 1 || sessions.Sessions[0].Project != "api" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+
+	resp, err = http.Post(server.URL+"/api/v1/messages", "application/json", strings.NewReader(`{"text":"run the tests","workingDir":"/src/api"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expect 202, got %s", resp.Status)
+	}
+	if msg := <-h.inputChan; msg.Content != "run the tests" || msg.WorkingDir != "/src/api" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+
+	resp, err = http.Post(server.URL+"/api/v1/messages", "application/json", strings.NewReader(`{"text":""}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expect 400 for an empty message, got %s", resp.Status)
+	}
+
+	resp, err = http.Get(server.URL + "/api/v1/messages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expect 405, got %s", resp.Status)
+	}
+}
+
+func TestAPIProfilesAndConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "group"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "group", "work.md"), []byte("# Work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfig(&Config{
+		SelectedProfile: "work",
+		GistToken:       "ghp_secret",
+		Slack:           &SlackConfig{Token: "keychain:slack", Channel: "C1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	server := newAPITestServer(t, &serveHandler{})
+
+	get := func(path string, v interface{}) int {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		json.NewDecoder(resp.Body).Decode(v)
+		return resp.StatusCode
+	}
+
+	var profiles map[string][]apiProfile
+	get("/api/v1/profiles", &profiles)
+	if len(profiles["profiles"]) != 1 || !profiles["profiles"][0].Selected {
+		t.Errorf("unexpected profiles: %+v", profiles)
+	}
+	var profile apiProfile
+	if status := get("/api/v1/profiles/work", &profile); status != http.StatusOK || profile.Content != "# Work\n" {
+		t.Errorf("unexpected profile: %d %+v", status, profile)
+	}
+	if status := get("/api/v1/profiles/missing", &apiError{}); status != http.StatusNotFound {
+		t.Errorf("expect 404 for a missing profile, got %d", status)
+	}
+
+	var config Config
+	get("/api/v1/config", &config)
+	if config.GistToken != redactedValue {
+		t.Errorf("expect gistToken redacted, got %q", config.GistToken)
+	}
+	if config.Slack == nil || config.Slack.Token != "keychain:slack" {
+		t.Errorf("expect secret references kept, got %+v", config.Slack)
+	}
+}
+
+func TestAPICORS(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	server := newAPITestServer(t, &serveHandler{})
+
+	request := func(origin string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodOptions, server.URL+"/api/v1/sessions", nil)
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	resp := request("vscode-webview://abc")
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Access-Control-Allow-Origin") != "vscode-webview://abc" {
+		t.Errorf("expect preflight allowed, got %s %v", resp.Status, resp.Header)
+	}
+	if resp := request("https://evil.example"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expect other origins forbidden, got %s", resp.Status)
+	}
+}
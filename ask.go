@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/protocol"
+	"golang.org/x/term"
+)
+
+const askHelp = `
+Usage:
+  whats_next ask QUESTION... [--port PORT]
+
+For agents: asks the user a yes/no question and prints the answer as an
+explicit approval or denial, with the comment of the user if any.
+
+The user answers with a single key, y or n, or types an answer like
+"ok, but keep the last one" or any other text, then Enter. In server mode,
+the question shows in the server input.
+
+Options:
+  --port PORT  The server port (default: serverPort or 7654)
+`
+
+// askParam passes the question of ask to the server
+const askParam = protocol.ParamAsk
+
+// askVerdict is how the user answered an ask question
+type askVerdict string
+
+const (
+	askApproved askVerdict = "approved"
+	askDenied   askVerdict = "denied"
+	// askAnswered is an answer that is neither a yes nor a no
+	askAnswered askVerdict = "answered"
+)
+
+var askYesWords = []string{"y", "yes", "ok", "approve", "approved", "lgtm"}
+var askNoWords = []string{"n", "no", "deny", "denied", "nope"}
+
+func handleAsk(args []string) error {
+	var port int
+	args, err := flags.Int("--port", &port).
+		Help("-h,--help", askHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	question := strings.TrimSpace(strings.Join(args, " "))
+	if question == "" {
+		return fmt.Errorf("requires QUESTION")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	config, err := readEffectiveConfig(wd)
+	if err != nil {
+		return err
+	}
+
+	var answer string
+	if config.Mode == ModeServer {
+		addr := getServerAddr(config.ServerBind, resolveServerPort(port, config))
+		answer, err = askServer(addr, question, wd)
+		if err != nil {
+			return err
+		}
+		// the server formats the answer
+		fmt.Println(answer)
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		answer = string(data)
+	} else {
+		answer, err = askInTerminal(question)
+		if err != nil {
+			return err
+		}
+	}
+	formatted := formatAskAnswer(question, answer)
+	fmt.Println(formatted)
+	recordTranscript(ModeNative, WebhookEventInput, wd, time.Now(), answer, formatted)
+	return nil
+}
+
+// parseAskAnswer splits answer into its verdict and comment:
+// "y", "n, not yet" or "keep them for a week" answered without a yes or no
+func parseAskAnswer(answer string) (askVerdict, string) {
+	answer = strings.TrimSpace(answer)
+	word, rest, _ := strings.Cut(answer, " ")
+	word = strings.ToLower(strings.TrimRight(word, ",.:;!"))
+	rest = strings.TrimSpace(rest)
+	switch {
+	case containsFold(askYesWords, word):
+		return askApproved, rest
+	case containsFold(askNoWords, word):
+		return askDenied, rest
+	}
+	return askAnswered, answer
+}
+
+// formatAskAnswer renders the answer of the user to question for the agent
+func formatAskAnswer(question string, answer string) string {
+	verdict, comment := parseAskAnswer(answer)
+	var b strings.Builder
+	switch verdict {
+	case askApproved:
+		fmt.Fprintf(&b, "APPROVED: the user approved %q.", question)
+	case askDenied:
+		fmt.Fprintf(&b, "DENIED: the user denied %q. Do not do it.", question)
+	default:
+		if comment == "" {
+			fmt.Fprintf(&b, "NO ANSWER: the user dismissed %q, treat it as denied.", question)
+			return b.String()
+		}
+		fmt.Fprintf(&b, "NEITHER APPROVED NOR DENIED: the user answered %q with:", question)
+		b.WriteString("\n" + comment)
+		return b.String()
+	}
+	if comment != "" {
+		b.WriteString("\nComment of the user: " + comment)
+	}
+	return b.String()
+}
+
+// askServer waits for the answer of the user on the server to question
+func askServer(addr string, question string, workingDir string) (string, error) {
+	client := protocol.NewClient(addr)
+	client.Token, _ = getServerToken(readServerConfig())
+	answer, err := client.Ask(context.Background(), workingDir, question, os.Getenv(toolCountSessionEnv))
+	if err != nil && !isAddrReachable(addr) {
+		return "", fmt.Errorf("server %s is not running, start it with: %s serve", addr, GetProgramName())
+	}
+	return answer, err
+}
+
+// renderAskQuestions renders the questions of the waiting ask clients below the server prompt
+func renderAskQuestions(questions []string) string {
+	var b strings.Builder
+	for _, question := range questions {
+		fmt.Fprintf(&b, "\nagent asks: %s (y to approve • n to deny • or type an answer)", question)
+	}
+	return b.String()
+}
@@ -0,0 +1,20 @@
+//go:build headless
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// askInTerminal reads the answer as a line in a headless build
+func askInTerminal(question string) (string, error) {
+	fmt.Fprintf(os.Stdout, "agent asks: %s\ny to approve • n to deny • or type an answer and Enter\n> ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAskAnswer(t *testing.T) {
+	tests := []struct {
+		answer  string
+		verdict askVerdict
+		comment string
+	}{
+		{"y", askApproved, ""},
+		{"Yes", askApproved, ""},
+		{"ok, but keep the last one", askApproved, "but keep the last one"},
+		{"n", askDenied, ""},
+		{"no. not yet", askDenied, "not yet"},
+		{"keep them for a week", askAnswered, "keep them for a week"},
+		{"  ", askAnswered, ""},
+	}
+	for _, tt := range tests {
+		verdict, comment := parseAskAnswer(tt.answer)
+		if verdict != tt.verdict || comment != tt.comment {
+			t.Errorf("parseAskAnswer(%q) = %q, %q, want %q, %q", tt.answer, verdict, comment, tt.verdict, tt.comment)
+		}
+	}
+}
+
+func TestFormatAskAnswer(t *testing.T) {
+	question := "Delete the old migration files?"
+	tests := []struct {
+		answer string
+		want   []string
+	}{
+		{"y", []string{"APPROVED:", question}},
+		{"n, not yet", []string{"DENIED:", "Do not do it.", "Comment of the user: not yet"}},
+		{"keep them for a week", []string{"NEITHER APPROVED NOR DENIED:", "\nkeep them for a week"}},
+		{"", []string{"NO ANSWER:", "treat it as denied"}},
+	}
+	for _, tt := range tests {
+		got := formatAskAnswer(question, tt.answer)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("formatAskAnswer(%q) = %q, want it to contain %q", tt.answer, got, want)
+			}
+		}
+	}
+}
+
+func TestWaitingQuestions(t *testing.T) {
+	h := &serveHandler{}
+	first := h.addWaiting("/src/web")
+	h.addWaiting("/src/api")
+	second := h.addWaiting("/src/api")
+	h.setWaitingQuestion(second, "Push to main?")
+	h.setWaitingQuestion(first, "Delete the old migration files?")
+
+	questions := h.getWaitingQuestions()
+	if len(questions) != 2 || questions[0] != "Delete the old migration files?" || questions[1] != "Push to main?" {
+		t.Errorf("Unexpected questions: %v", questions)
+	}
+	if got := renderAskQuestions(questions[:1]); got != "\nagent asks: Delete the old migration files? (y to approve • n to deny • or type an answer)" {
+		t.Errorf("Unexpected render: %q", got)
+	}
+}
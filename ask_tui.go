@@ -0,0 +1,68 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// askModel asks a question answered by y or n, or a typed answer
+type askModel struct {
+	question string
+	input    textinput.Model
+	answer   string
+	done     bool
+}
+
+func (m askModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m askModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			m.done = true
+			return m, tea.Quit
+		case tea.KeyEnter:
+			m.answer = m.input.Value()
+			m.done = true
+			return m, tea.Quit
+		case tea.KeyRunes:
+			// single-key answers while nothing is typed
+			if m.input.Value() == "" && len(msg.Runes) == 1 {
+				switch msg.Runes[0] {
+				case 'y', 'Y', 'n', 'N':
+					m.answer = strings.ToLower(string(msg.Runes))
+					m.done = true
+					return m, tea.Quit
+				}
+			}
+		}
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m askModel) View() string {
+	if m.done {
+		return ""
+	}
+	return fmt.Sprintf("agent asks: %s\n%s\n\ny to approve • n to deny • or type an answer and Enter • esc to dismiss\n", m.question, m.input.View())
+}
+
+func askInTerminal(question string) (string, error) {
+	input := textinput.New()
+	input.Placeholder = "y / n / or type an answer"
+	input.Focus()
+	finalModel, err := tea.NewProgram(askModel{question: question, input: input}).Run()
+	if err != nil {
+		return "", err
+	}
+	return finalModel.(askModel).answer, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// backupSuffix names the copy of the previous version kept by writeFileAtomic
+const backupSuffix = ".bak"
+
+// writeFileAtomic writes data to file through a temporary file in the same
+// directory renamed over it, so that a crash mid-write leaves either the old
+// or the new content, never a truncated file.
+// The previous content, if any, is kept in file.bak.
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpFile := tmp.Name()
+	// no-op once renamed
+	defer os.Remove(tmpFile)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpFile, perm); err != nil {
+		return err
+	}
+
+	previous, err := os.ReadFile(file)
+	if err == nil {
+		if err := os.WriteFile(file+backupSuffix, previous, perm); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(tmpFile, file)
+}
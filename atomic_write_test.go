@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+
+	if err := writeFileAtomic(file, []byte(`{"mode":"native"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file + backupSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup of a new file, got %v", err)
+	}
+
+	if err := writeFileAtomic(file, []byte(`{"mode":"server"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(file); string(content) != `{"mode":"server"}` {
+		t.Errorf("Unexpected content: %q", content)
+	}
+	if content, _ := os.ReadFile(file + backupSuffix); string(content) != `{"mode":"native"}` {
+		t.Errorf("Expected the previous version in the backup, got %q", content)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected no temporary file left, got %d entries", len(entries))
+	}
+}
+
+func TestGroupNamesSkipBackups(t *testing.T) {
+	groupDir := t.TempDir()
+	os.WriteFile(filepath.Join(groupDir, "work.md"), []byte("# Work"), 0644)
+	os.WriteFile(filepath.Join(groupDir, "work.md"+backupSuffix), []byte("# Old"), 0644)
+
+	names, err := getGroupNames(groupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "work" {
+		t.Errorf("Expected only work, got %v", names)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// auditFile is the file under the config dir recording the changes
+// of profiles and custom.md, one JSON entry per line
+const auditFile = "audit.jsonl"
+
+// auditCustomName is the name of custom.md in the audit log
+const auditCustomName = "custom.md"
+
+// Audit actions
+const (
+	auditActionCreate  = "create"
+	auditActionEdit    = "edit"
+	auditActionAdd     = "add"
+	auditActionRename  = "rename"
+	auditActionRemove  = "remove"
+	auditActionRestore = "restore"
+)
+
+// auditEntry is a change of a profile or custom.md
+type auditEntry struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Profile string    `json:"profile"`
+	// From is the previous name of a renamed profile
+	From string `json:"from,omitempty"`
+	// Command is the command making the change, without its arguments
+	Command string `json:"command"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	// Sections are the headings added or removed
+	Sections []string `json:"sections,omitempty"`
+}
+
+// recordAudit appends a change of profile from before to after to the audit log,
+// unless nothing changed. Failures are only reported on stderr.
+func recordAudit(action string, profile string, command string, before string, after string) {
+	if before == after {
+		return
+	}
+	entry := &auditEntry{
+		Time:    time.Now(),
+		Action:  action,
+		Profile: strings.TrimSuffix(profile, ".md"),
+		Command: command,
+	}
+	if profile == auditCustomName {
+		entry.Profile = auditCustomName
+	}
+	entry.Added, entry.Removed, entry.Sections = diffSummary(before, after)
+	if before == "" || after == "" {
+		// the whole file is new or gone, listing all its headings says little
+		entry.Sections = nil
+	}
+	if err := appendAudit(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: record audit: %v\n", err)
+	}
+}
+
+func appendAudit(entry *auditEntry) error {
+	file, err := getConfigPath(true, auditFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readAudit returns the audit entries of profile, following renames, or all if empty
+func readAudit(profile string) ([]*auditEntry, error) {
+	file, err := getConfigPath(false, auditFile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []*auditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		return entries, nil
+	}
+
+	// walk back from the latest name so that the history before renames is kept
+	names := map[string]bool{strings.TrimSuffix(profile, ".md"): true}
+	if profile == auditCustomName {
+		names = map[string]bool{auditCustomName: true}
+	}
+	var filtered []*auditEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if !names[entry.Profile] {
+			continue
+		}
+		filtered = append(filtered, entry)
+		if entry.Action == auditActionRename && entry.From != "" {
+			names[entry.From] = true
+		}
+	}
+	for i, j := 0, len(filtered)-1; i < j; i, j = i+1, j-1 {
+		filtered[i], filtered[j] = filtered[j], filtered[i]
+	}
+	return filtered, nil
+}
+
+func printAudit(w io.Writer, entries []*auditEntry) {
+	for _, entry := range entries {
+		change := fmt.Sprintf("+%d -%d", entry.Added, entry.Removed)
+		if entry.Action == auditActionRename {
+			change = "from " + entry.From
+		}
+		fmt.Fprintf(w, "%s  %-7s  %s  %s  (%s)\n", entry.Time.Local().Format("2006-01-02 15:04"), entry.Action, entry.Profile, change, entry.Command)
+		for _, section := range entry.Sections {
+			fmt.Fprintf(w, "    %s\n", section)
+		}
+	}
+}
+
+// maxDiffLines bounds the files diffed line by line, larger ones are compared as sets of lines
+const maxDiffLines = 2000
+
+// diffSummary counts the lines added and removed from before to after,
+// and lists the headings among them prefixed with + or -
+func diffSummary(before string, after string) (added int, removed int, sections []string) {
+	a, b := splitLines(before), splitLines(after)
+	var removedLines, addedLines []string
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		removedLines, addedLines = diffLineSets(a, b)
+	} else {
+		removedLines, addedLines = diffLinesLCS(a, b)
+	}
+	for _, line := range removedLines {
+		if strings.HasPrefix(line, "#") {
+			sections = append(sections, "- "+line)
+		}
+	}
+	for _, line := range addedLines {
+		if strings.HasPrefix(line, "#") {
+			sections = append(sections, "+ "+line)
+		}
+	}
+	return len(addedLines), len(removedLines), sections
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLinesLCS returns the lines of a not in b and of b not in a,
+// along their longest common subsequence
+func diffLinesLCS(a []string, b []string) (removed []string, added []string) {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, a[i])
+			i++
+		default:
+			added = append(added, b[j])
+			j++
+		}
+	}
+	removed = append(removed, a[i:]...)
+	added = append(added, b[j:]...)
+	return removed, added
+}
+
+// diffLineSets compares a and b as multisets of lines
+func diffLineSets(a []string, b []string) (removed []string, added []string) {
+	counts := make(map[string]int, len(a))
+	for _, line := range a {
+		counts[line]++
+	}
+	for _, line := range b {
+		if counts[line] > 0 {
+			counts[line]--
+			continue
+		}
+		added = append(added, line)
+	}
+	for _, line := range a {
+		if counts[line] > 0 {
+			counts[line]--
+			removed = append(removed, line)
+		}
+	}
+	return removed, added
+}
+
+// recordRenameAudit records the rename of profile oldName to newName
+func recordRenameAudit(oldName string, newName string, command string) {
+	entry := &auditEntry{
+		Time:    time.Now(),
+		Action:  auditActionRename,
+		Profile: strings.TrimSuffix(newName, ".md"),
+		From:    strings.TrimSuffix(oldName, ".md"),
+		Command: command,
+	}
+	if err := appendAudit(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: record audit: %v\n", err)
+	}
+}
+
+// readAuditSnapshot returns the content of file, empty if it cannot be read
+func readAuditSnapshot(file string) string {
+	data, _ := os.ReadFile(file)
+	return string(data)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffSummary(t *testing.T) {
+	added, removed, sections := diffSummary("# A\na\nb\nc\n", "# A\na\nc\n# B\nd\n")
+	if added != 2 || removed != 1 {
+		t.Errorf("diffSummary() = +%d -%d, want +2 -1", added, removed)
+	}
+	if want := []string{"+ # B"}; !reflect.DeepEqual(sections, want) {
+		t.Errorf("sections = %q, want %q", sections, want)
+	}
+
+	added, removed, _ = diffSummary("", "a\nb\n")
+	if added != 2 || removed != 0 {
+		t.Errorf("diffSummary() of new file = +%d -%d, want +2 -0", added, removed)
+	}
+}
+
+func TestReadAuditFollowsRenames(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+
+	recordAudit(auditActionCreate, "old.md", "group edit", "", "a\n")
+	recordAudit(auditActionEdit, "other.md", "group edit", "", "x\n")
+	recordAudit(auditActionEdit, "old.md", "group edit", "a\n", "a\n")
+	recordRenameAudit("old.md", "new.md", "group rename")
+	recordAudit(auditActionEdit, "new", "group edit", "a\n", "a\nb\n")
+	recordAudit(auditActionAdd, auditCustomName, "add", "", "c\n")
+
+	entries, err := readAudit("new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Action+" "+entry.Profile)
+	}
+	// the unchanged edit is not recorded
+	want := []string{"create old", "rename new", "edit new"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readAudit(new) = %q, want %q", got, want)
+	}
+
+	all, err := readAudit("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Errorf("readAudit() returned %d entries, want 5", len(all))
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// builtinTexts are the hardcoded texts, in the order `show` prints them.
+// A file with the same name in the builtins/ dir of the config dir overrides the text.
+var builtinTexts = []struct {
+	file string
+	text func() string
+}{
+	{"general.md", defaultGeneralGuideline},
+	{"tool_call_awareness.md", constText(toolCallAwareness)},
+	{"running_command.md", constText(runningCommand)},
+	{"no_test.md", constText(noTest)},
+	{"dont_ignore_lint.md", constText(dontIgnoreLint)},
+	{"server_implementation.md", constText(serverImplementation)},
+	{"ignore_lint.md", constText(ignoreLint)},
+	{"verify.md", constText(verify)},
+	{"pattern.md", constText(pattern)},
+	{"recover.md", constText(recover)},
+	{"go_compile_instruction.md", constText(goCompileInstruction)},
+	{"dump_prompt.md", constText(dumpPrompt)},
+}
+
+func constText(text string) func() string {
+	return func() string {
+		return text
+	}
+}
+
+// getBuiltinText returns the text of a builtin, read from builtins/<file>
+// if it exists, otherwise the hardcoded one
+func getBuiltinText(file string) string {
+	if builtinsDir, err := getConfigPath(false, "builtins"); err == nil {
+		if content, err := os.ReadFile(filepath.Join(builtinsDir, file)); err == nil {
+			// match the layout of the hardcoded texts: surrounded by newlines
+			return "\n" + strings.Trim(sections.Normalize(string(content)), "\n") + "\n"
+		}
+	}
+	for _, builtin := range builtinTexts {
+		if builtin.file == file {
+			return builtin.text()
+		}
+	}
+	return ""
+}
+
+// builtinGuidelineNames are the names accepted by Config.BuiltinGuidelines,
+// in the order the guidelines are emitted
+var builtinGuidelineNames = []string{"general", "toolCalls", "runningCommand"}
+
+func getBuiltinGuideline(name string) string {
+	switch name {
+	case "general":
+		return getGeneralGuideline()
+	case "toolCalls":
+		return getBuiltinText("tool_call_awareness.md")
+	case "runningCommand":
+		return getBuiltinText("running_command.md")
+	}
+	return ""
+}
+
+// isBuiltinGuidelineEnabled reports whether the named guideline should be emitted,
+// all guidelines are enabled unless config lists them explicitly
+func isBuiltinGuidelineEnabled(config *Config, name string) bool {
+	if config == nil || config.BuiltinGuidelines == nil {
+		return true
+	}
+	return containsFold(*config.BuiltinGuidelines, name)
+}
+
+// writeBuiltinGuidelines writes the enabled built-in guidelines to w
+func writeBuiltinGuidelines(w io.Writer, config *Config) {
+	for _, name := range builtinGuidelineNames {
+		if isBuiltinGuidelineEnabled(config, name) {
+			io.WriteString(w, getBuiltinGuideline(name))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteBuiltinGuidelines(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+
+	var all strings.Builder
+	writeBuiltinGuidelines(&all, &Config{})
+	if all.String() != getGeneralGuideline()+toolCallAwareness+runningCommand {
+		t.Errorf("Expected all guidelines by default, got:\n%s", all.String())
+	}
+
+	var some strings.Builder
+	writeBuiltinGuidelines(&some, &Config{BuiltinGuidelines: &[]string{"general", "runningCommand"}})
+	if some.String() != getGeneralGuideline()+runningCommand {
+		t.Errorf("Expected general and runningCommand, got:\n%s", some.String())
+	}
+
+	var none strings.Builder
+	writeBuiltinGuidelines(&none, &Config{BuiltinGuidelines: &[]string{}})
+	if none.String() != "" {
+		t.Errorf("Expected no guidelines, got:\n%s", none.String())
+	}
+}
+
+func TestSetBuiltinGuidelines(t *testing.T) {
+	config := &Config{}
+	if err := setConfigValue(config, "builtinGuidelines", "general, toolCalls"); err != nil {
+		t.Fatal(err)
+	}
+	if config.BuiltinGuidelines == nil || strings.Join(*config.BuiltinGuidelines, ",") != "general,toolCalls" {
+		t.Errorf("Expected general,toolCalls, got %v", config.BuiltinGuidelines)
+	}
+	if err := setConfigValue(config, "builtinGuidelines", ""); err != nil || config.BuiltinGuidelines == nil || len(*config.BuiltinGuidelines) != 0 {
+		t.Errorf("Expected an empty list, got %v, err: %v", config.BuiltinGuidelines, err)
+	}
+	if err := setConfigValue(config, "builtinGuidelines", "general,unknown"); err == nil {
+		t.Errorf("Expected error for unknown guideline")
+	}
+
+	problems, err := validateConfigJSON([]byte(`{"builtinGuidelines": ["general", "tools"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "invalid builtinGuidelines[1]: tools, expect one of general, toolCalls, runningCommand"
+	if strings.Join(problems, "\n") != expected {
+		t.Errorf("Expected %q, got %q", expected, problems)
+	}
+}
+
+func TestBuiltinTextOverride(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	builtinsDir := filepath.Join(configDir, "builtins")
+	if err := os.MkdirAll(builtinsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(builtinsDir, "general.md"), []byte("# My loop\r\nAlways run the command.\r\n\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "\n# My loop\nAlways run the command.\n"
+	if general := getGeneralGuideline(); general != expected {
+		t.Errorf("Expected override %q, got %q", expected, general)
+	}
+	if text := getBuiltinText("no_test.md"); text != noTest {
+		t.Errorf("Expected hardcoded text without override, got %q", text)
+	}
+
+	var b strings.Builder
+	if err := showW(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), "# My loop\n") {
+		t.Errorf("Expected show to use the override, got:\n%s", b.String())
+	}
+}
@@ -1,16 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"math/rand"
-	"net/http"
 	"net/url"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/protocol"
 )
 
 type ReplyStyle string
@@ -32,13 +32,13 @@ func handleClient(args []string) error {
 	}
 
 	var port int
-	args, err = flags.Int("--port", &port).Parse(args)
+	var copy bool
+	args, err = flags.Int("--port", &port).Bool("--copy", &copy).Parse(args)
 	if err != nil {
 		return err
 	}
-	if port == 0 {
-		port = SERVER_PORT
-	}
+	config := readServerConfig()
+	port = resolveServerPort(port, config)
 
 	wd, _ := os.Getwd()
 	pid := os.Getpid()
@@ -58,7 +58,7 @@ func handleClient(args []string) error {
 	}
 
 	startTime := time.Now()
-	addr := getServerAddrWithPort(port)
+	addr := getServerAddr(config.ServerBind, port)
 	if !isAddrReachable(addr) {
 		for i := 0; i < 10; i++ {
 			logf("waiting for server to be ready...")
@@ -75,40 +75,34 @@ func handleClient(args []string) error {
 		logfNoTime: logfNoTime,
 		done: done,
 	})
-	params := make(url.Values)
-	params.Set("workingDir", wd)
-	params.Set("programName", GetProgramName())
-	resp, err := http.Get(fmt.Sprintf("http://%s/?%s", addr, params.Encode()))
-	close(done)
+	token, err := getServerToken(config)
 	if err != nil {
-		errMsg := ""
-		// if is connection refused, ask the client to retry again in 10 seconds, this retry could be repeated up to 10 times
-		// check if the server is running
-		if !isAddrReachable(addr) {
-			// this is a way to tell LLM that it should wait user to spin the server up
-			errMsg = "the server is busy, please `sleep 10` and try again. This retry could be repeated up to 10 times"
-		} else {
-			errMsg = fmt.Sprintf("failed to connect to server (is it running?): %v\nStart server with: %s serve", err, GetProgramName())
-		}
-		if logger != nil {
-			logger.LogStderr(errMsg)
-		}
-		return fmt.Errorf("%s", errMsg)
+		Errorf("server token: %v", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		errMsg := fmt.Sprintf("server returned status: %d", resp.StatusCode)
-		if logger != nil {
-			logger.LogStderr(errMsg)
-		}
-		return fmt.Errorf("%s", errMsg)
+	client := &protocol.Client{Addr: addr, Token: token, OnVersion: warnServerVersion}
+	reply, err := client.Wait(context.Background(), wd, protocol.WaitOptions{
+		ProgramName: GetProgramName(),
+		Version:     getVersion(),
+		Session:     os.Getenv(toolCountSessionEnv),
+	})
+	close(done)
+	if errors.Is(err, protocol.ErrUserExit) {
+		// the user quit the server, tell the agent to stop
+		reply, err = protocol.ExitResponse, nil
 	}
-
-	// Read and display the response from server
-	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		errMsg := fmt.Sprintf("failed to read response: %v", err)
+		errMsg := err.Error()
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			// if is connection refused, ask the client to retry again in 10 seconds, this retry could be repeated up to 10 times
+			// check if the server is running
+			if !isAddrReachable(addr) {
+				// this is a way to tell LLM that it should wait user to spin the server up
+				errMsg = "the server is busy, please `sleep 10` and try again. This retry could be repeated up to 10 times"
+			} else {
+				errMsg = fmt.Sprintf("failed to connect to server (is it running?): %v\nStart server with: %s serve", err, GetProgramName())
+			}
+		}
 		if logger != nil {
 			logger.LogStderr(errMsg)
 		}
@@ -116,7 +110,6 @@ func handleClient(args []string) error {
 	}
 
 	waitTime := time.Since(startTime)
-	reply := string(body)
 
 	// Log response
 	if logger != nil {
@@ -130,14 +123,13 @@ func handleClient(args []string) error {
 
 	reply = replaceWhatsNextWithProgramName(reply)
 
-	fmt.Print(reply)
+	fmt.Println(reply)
+	if copy {
+		copyReply(reply)
+	}
 	return nil
 }
 
-func replaceWhatsNextWithProgramName(reply string) string {
-	return strings.ReplaceAll(reply, "`whats_next`", "`"+GetProgramName()+"`")
-}
-
 type options struct {
 	logf func(format string, args ...interface{})
 	logfNoTime func(format string, args ...interface{})
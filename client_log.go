@@ -11,16 +11,24 @@ import (
 
 
 type clientLogger struct {
-	file *os.File
+	file *rotatingFile
 }
 
-func newClientLogger() (*clientLogger, error) {
+// getClientLogPath returns the log file of the client, ~/.whats_next.log
+func getClientLogPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home dir: %v", err)
+		return "", fmt.Errorf("failed to get home dir: %v", err)
+	}
+	return filepath.Join(homeDir, ".whats_next.log"), nil
+}
+
+func newClientLogger() (*clientLogger, error) {
+	logPath, err := getClientLogPath()
+	if err != nil {
+		return nil, err
 	}
-	logPath := filepath.Join(homeDir, ".whats_next.log")
-	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f, err := openLogFile(logPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open log file: %v", err)
 	}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// copyToClipboard puts text on the system clipboard with pbcopy on macOS,
+// Set-Clipboard on Windows and wl-copy, xclip or xsel on Linux.
+// Without any of them, e.g. over ssh, it asks the terminal through an OSC 52 sequence.
+func copyToClipboard(text string) error {
+	for _, command := range clipboardCommands() {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %v %s", command[0], err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+	// stdout may be read by the agent
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		return fmt.Errorf("no clipboard command found, install xclip or wl-clipboard")
+	}
+	fmt.Fprintf(os.Stderr, "\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	return nil
+}
+
+// clipboardCommands lists the commands reading the clipboard content from stdin, in order of preference
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"powershell", "-NoProfile", "-NonInteractive", "-Command", "Set-Clipboard -Value ([Console]::In.ReadToEnd())"}}
+	}
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-copy"})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		commands = append(commands, []string{"xclip", "-selection", "clipboard"}, []string{"xsel", "--clipboard", "--input"})
+	}
+	return commands
+}
+
+// copyReply copies a reply for --copy, failures are only reported on stderr
+// since stdout is read by the agent
+func copyReply(reply string) {
+	if err := copyToClipboard(reply); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: copy to clipboard: %v\n", err)
+		Errorf("copy to clipboard: %v", err)
+	}
+}
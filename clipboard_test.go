@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCopyToClipboard(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses a fake xclip")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "clipboard")
+	if err := os.WriteFile(filepath.Join(dir, "xclip"), []byte("#!/bin/sh\ncat > "+out+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("DISPLAY", ":0")
+
+	if err := copyToClipboard("the user is asking"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "the user is asking" {
+		t.Errorf("Expected clipboard content, got %q", content)
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -15,20 +17,54 @@ func TestWhere(t *testing.T) {
 }
 
 func TestWhereWithArgs(t *testing.T) {
-	// Test that where command rejects arguments
+	// Test that where command rejects unknown targets
 	err := where([]string{"some-arg"})
 	if err == nil {
-		t.Error("where() with args should return error")
+		t.Fatal("where() with an unknown target should return error")
 	}
 
-	expectedError := "where command does not accept arguments"
-	if err.Error() != expectedError {
+	expectedError := "unrecognized target: some-arg"
+	if !strings.HasPrefix(err.Error(), expectedError) {
 		t.Errorf("Expected error %q, got %q", expectedError, err.Error())
 	}
+
+	if err := where([]string{"config", "extra"}); err == nil {
+		t.Error("where() with extra args should return error")
+	}
+}
+
+func TestWhereTargets(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, dir},
+		{[]string{"config"}, filepath.Join(dir, "config.json")},
+		{[]string{"custom"}, filepath.Join(dir, "custom.md")},
+		{[]string{"group"}, filepath.Join(dir, "group")},
+		{[]string{"group", "backend"}, filepath.Join(dir, "group", "backend.md")},
+		{[]string{"prompt", "review"}, filepath.Join(dir, "prompts", "review.md")},
+		{[]string{"logs"}, filepath.Join(dir, "logs")},
+		{[]string{"history"}, filepath.Join(dir, "transcripts")},
+	}
+	for _, tt := range tests {
+		got, err := resolveWherePath(tt.args)
+		if err != nil {
+			t.Errorf("resolveWherePath(%q): %v", tt.args, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveWherePath(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
 }
 
 func TestWhereOutputsCorrectPath(t *testing.T) {
 	// Test that the path returned by getConfigDir matches what where would print
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", "")
 	configDir, err := getConfigDir(false)
 	if err != nil {
 		t.Fatalf("getConfigDir failed: %v", err)
@@ -45,3 +81,284 @@ func TestWhereOutputsCorrectPath(t *testing.T) {
 		t.Errorf("Expected config dir %q, got %q", expectedPath, configDir)
 	}
 }
+
+func TestConfigDirEnvOverride(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+
+	configDir, err := getConfigDir(false)
+	if err != nil {
+		t.Fatalf("getConfigDir failed: %v", err)
+	}
+	if configDir != dir {
+		t.Errorf("Expected config dir %q, got %q", dir, configDir)
+	}
+}
+
+func TestConfigDirFlag(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", filepath.Join(dir, "from-env"))
+	defer func() { configDirFlag = "" }()
+
+	args, err := extractGlobalFlags([]string{"group", "--config-dir", dir, "list", "--", "--config-dir=x"})
+	if err != nil {
+		t.Fatalf("extractGlobalFlags failed: %v", err)
+	}
+	expectedArgs := []string{"group", "list", "--", "--config-dir=x"}
+	if strings.Join(args, " ") != strings.Join(expectedArgs, " ") {
+		t.Errorf("Expected args %v, got %v", expectedArgs, args)
+	}
+
+	configDir, err := getConfigDir(false)
+	if err != nil {
+		t.Fatalf("getConfigDir failed: %v", err)
+	}
+	if configDir != dir {
+		t.Errorf("Expected flag to win over env, got %q", configDir)
+	}
+
+	if _, err := extractGlobalFlags([]string{"--config-dir"}); err == nil {
+		t.Errorf("Expected error for missing --config-dir value")
+	}
+}
+
+func TestAddFileAndGroup(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+
+	notes := filepath.Join(t.TempDir(), "notes.md")
+	if err := os.WriteFile(notes, []byte("\nUse `make check`.\nNo vendoring.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := add([]string{"--file", notes, "--group", "backend", "--title", "Build"}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "group", "backend.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "# Build\nUse `make check`.\nNo vendoring.\n" {
+		t.Errorf("Unexpected profile content: %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "custom.md")); !os.IsNotExist(err) {
+		t.Errorf("Expected custom.md untouched, got %v", err)
+	}
+
+	if err := add([]string{"-", "--file", notes}); err == nil {
+		t.Errorf("Expected - and --file to conflict")
+	}
+	if err := add(nil); err == nil {
+		t.Errorf("Expected missing content to fail")
+	}
+}
+
+func TestComposeAddContentWithEditor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the editor")
+	}
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\nprintf 'Keep commits small.\\n' >> \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content, err := composeAddContent(editor, formatAddTitle("Git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "# Git\nKeep commits small.\n" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+
+	// an editor leaving only the heading adds nothing
+	content, err = composeAddContent("true", formatAddTitle("Git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "" {
+		t.Errorf("Expected no content, got %q", content)
+	}
+}
+
+func TestShowPreview(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	project := t.TempDir()
+
+	profile := "# Everywhere\nalways\n\n# Claude only (agents: claude)\nclaude rule\n\n# Project only (project: " + project + ")\nproject rule\n"
+	if err := os.MkdirAll(filepath.Join(dir, "group"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "group", "team.md"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := showPreview(&b, "team", project, "cursor", false); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !strings.Contains(out, "always") || !strings.Contains(out, "project rule") || strings.Contains(out, "claude rule") {
+		t.Errorf("Unexpected preview for cursor in project:\n%s", out)
+	}
+
+	b.Reset()
+	if err := showPreview(&b, "team", t.TempDir(), "claude", false); err != nil {
+		t.Fatal(err)
+	}
+	out = b.String()
+	if !strings.Contains(out, "claude rule") || strings.Contains(out, "project rule") {
+		t.Errorf("Unexpected preview for claude elsewhere:\n%s", out)
+	}
+
+	if err := showPreview(&b, "team", project, "vim", false); err == nil {
+		t.Errorf("Expected unknown agent to fail")
+	}
+	if err := showPreview(&b, "missing", project, "", false); err == nil {
+		t.Errorf("Expected unknown profile to fail")
+	}
+}
+
+func TestShowExplain(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	project := t.TempDir()
+	sub := filepath.Join(project, "api")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := "# Everywhere\nalways\n\n# Repo (project: " + project + ")\nrepo rule\n\n# API (project: " + sub + ")\napi rule\n\n# Claude only (agents: claude)\nclaude rule\n"
+	if err := os.MkdirAll(filepath.Join(dir, "group"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "group", "team.md"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := showPreview(&b, "team", sub, "cursor", true); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# Everywhere\n<!-- explain: included: no-project -->\n",
+		"<!-- explain: dropped, a more specific project matches: path, specificity",
+		"<!-- explain: included: path, specificity",
+		"# Claude only (agents: claude)\n<!-- explain: excluded -->\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in explanation:\n%s", want, out)
+		}
+	}
+}
+
+func TestUseDryRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "group"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	profile := "# Everywhere\nalways\n\n# Elsewhere (project: " + t.TempDir() + ")\nother\n"
+	if err := os.WriteFile(filepath.Join(dir, "group", "team.md"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := useDryRun(&b, []string{"team"}); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{"  + # Everywhere\n", "  - # Elsewhere (project: ", `selectedProfile "" -> "team"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in dry run output:\n%s", want, out)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected config.json not written, got %v", err)
+	}
+	if err := group([]string{"use", "missing", "--dry-run"}); err == nil {
+		t.Errorf("Expected unknown profile to fail")
+	}
+}
+
+func TestRmCustomSection(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	custom := "# Keep\nkept\n\n# Stale one-off\ngone\n## Detail\nalso gone\n\n# Last\nlast\n"
+	customFile := filepath.Join(dir, "custom.md")
+	if err := os.WriteFile(customFile, []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := listCustomTitles(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "Keep\nStale one-off\nDetail\nLast\n" {
+		t.Errorf("Unexpected titles: %q", b.String())
+	}
+
+	if err := handleRm([]string{"stale ONE-OFF"}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(customFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "# Keep\nkept\n\n# Last\nlast\n" {
+		t.Errorf("Unexpected custom.md: %q", content)
+	}
+	if err := handleRm([]string{"Missing"}); err == nil {
+		t.Errorf("Expected missing title to fail")
+	}
+}
+
+func TestExpandCommandAlias(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	config := &Config{CommandAliases: map[string]string{
+		"s":    "group show --use",
+		"show": "version",
+		"bad":  "nope",
+	}}
+	if err := writeConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := expandCommandAlias([]string{"s", "work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(args, " ") != "group show --use work" {
+		t.Errorf("Unexpected expansion: %q", args)
+	}
+	// built-in commands win over aliases
+	if args, _ := expandCommandAlias([]string{"show"}); strings.Join(args, " ") != "show" {
+		t.Errorf("Expected show not expanded, got %q", args)
+	}
+	if args, _ := expandCommandAlias([]string{"other"}); strings.Join(args, " ") != "other" {
+		t.Errorf("Expected unknown name kept, got %q", args)
+	}
+	if _, err := expandCommandAlias([]string{"bad"}); err == nil {
+		t.Errorf("Expected alias to an unknown command to fail")
+	}
+}
+
+func TestSuggestCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"stauts", "status"},
+		{"sevre", "serve"},
+		{"hist", "list"},
+		{"ad", "add"},
+		{"standup", ""},
+		{"xyz", ""},
+	}
+	for _, tt := range tests {
+		got, ok := suggestCommand(tt.name)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("suggestCommand(%q) = %q, %v, want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
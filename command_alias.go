@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expandCommandAlias replaces a first argument naming one of the commandAliases
+// of the config by its command line, keeping the remaining arguments.
+// Aliases are expanded once, so an alias cannot refer to another alias.
+func expandCommandAlias(args []string) ([]string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") || isBuiltinCommand(args[0]) {
+		return args, nil
+	}
+	wd, _ := os.Getwd()
+	config, err := readEffectiveConfig(wd)
+	if err != nil {
+		// a broken config must not prevent `config` or `doctor` from fixing it
+		return args, nil
+	}
+	line, ok := config.CommandAliases[args[0]]
+	if !ok {
+		return args, nil
+	}
+	expanded := strings.Fields(line)
+	if len(expanded) == 0 {
+		return nil, fmt.Errorf("command alias %s is empty", args[0])
+	}
+	if !strings.HasPrefix(expanded[0], "-") && !isBuiltinCommand(expanded[0]) {
+		return nil, fmt.Errorf("command alias %s: %s is not a command", args[0], expanded[0])
+	}
+	Debugf("expand command alias %s: %s", args[0], line)
+	return append(expanded, args[1:]...), nil
+}
+
+// suggestCommand returns the command closest to name by edit distance,
+// if close enough to be a typo: 1 edit for short names, 2 for the others
+func suggestCommand(name string) (string, bool) {
+	maxDistance := 2
+	if len(name) <= 3 {
+		maxDistance = 1
+	}
+	var best string
+	bestDistance := maxDistance + 1
+	for _, cmd := range completionCommands {
+		if d := editDistance(name, cmd.name); d < bestDistance {
+			best, bestDistance = cmd.name, d
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// isBuiltinCommand tells whether name is a command of the program
+func isBuiltinCommand(name string) bool {
+	for _, cmd := range completionCommands {
+		if cmd.name == name {
+			return true
+		}
+	}
+	return name == "--help"
+}
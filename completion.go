@@ -0,0 +1,330 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+const completionHelp = `
+Usage:
+  whats_next completion bash|zsh|fish
+
+Prints a shell completion script covering commands, flags and group names.
+
+Load it in the current shell:
+  source <(whats_next completion bash)
+  source <(whats_next completion zsh)
+  whats_next completion fish | source
+
+Or add that line to ~/.bashrc, ~/.zshrc or ~/.config/fish/config.fish.
+`
+
+// completionCommand describes a command for completion scripts
+type completionCommand struct {
+	name  string
+	flags []string
+	// groupArgs completes group names as the first argument
+	groupArgs   bool
+	subCommands []completionCommand
+}
+
+// completionGlobalFlags are accepted before any command
+var completionGlobalFlags = []string{"--port", "--editor", "--config-dir", "--copy", "--verbose", "--debug"}
+
+// completionValueFlags take a value as the next argument,
+// which must not be taken as a command
+var completionValueFlags = []string{"--port", "--editor", "--config-dir", "--dir", "--bind", "--title", "--goto", "--token", "--id", "--date", "--out", "--project", "--log-dir", "--since", "-o", "-n", "--backup", "--file", "--group", "--agent", "--inc", "--session"}
+
+var completionCommands = []completionCommand{
+	{name: "show", flags: []string{"--use", "--dir", "--agent", "--explain"}, groupArgs: true},
+	{name: "edit", flags: []string{"--editor", "--goto"}, groupArgs: true},
+	{name: "add", flags: []string{"--title", "--file", "--group", "--editor"}},
+	{name: "where", flags: []string{"--open"}, subCommands: []completionCommand{
+		{name: "config"}, {name: "custom"}, {name: "group", groupArgs: true}, {name: "prompt"},
+		{name: "builtins"}, {name: "logs"}, {name: "history"}, {name: "project"},
+	}},
+	{name: "rm"},
+	{name: "list", flags: []string{"--custom"}},
+	{name: "use", flags: []string{"--dry-run"}, groupArgs: true},
+	{name: "init", flags: []string{"--yes"}},
+	{name: "install", subCommands: []completionCommand{
+		{name: "cursor", flags: []string{"--dir"}},
+		{name: "claude", flags: []string{"--global", "--dir"}},
+		{name: "windsurf", flags: []string{"--global", "--dir"}},
+	}},
+	{name: "uninstall", flags: []string{"--yes", "--backup"}},
+	{name: "prompt", subCommands: []completionCommand{
+		{name: "save", flags: []string{"--editor"}},
+		{name: "list"},
+		{name: "use", flags: []string{"--send", "--copy", "--port"}},
+		{name: "rm"},
+	}},
+	{name: "recover", flags: []string{"--id", "--last", "--send", "--copy", "--editor", "--port"}},
+	{name: "config", flags: []string{"--editor"}, subCommands: []completionCommand{
+		{name: "list"}, {name: "get"}, {name: "set"}, {name: "unset"}, {name: "schema"},
+		{name: "secret"},
+	}},
+	{name: "doctor", flags: []string{"--port"}},
+	{name: "export", subCommands: []completionCommand{
+		{name: "cursor", flags: []string{"--dir"}, groupArgs: true},
+	}},
+	{name: "serve", flags: []string{"--log", "--log-dir", "--kill", "--port", "--bind", "--slack", "--tmux"}},
+	{name: "group", subCommands: []completionCommand{
+		{name: "list"},
+		{name: "show", flags: []string{"--use", "--dir", "--agent", "--explain"}, groupArgs: true},
+		{name: "edit", flags: []string{"--editor", "--goto"}, groupArgs: true},
+		{name: "use", flags: []string{"--dry-run"}, groupArgs: true},
+		{name: "rm", groupArgs: true},
+		{name: "remove", groupArgs: true},
+		{name: "mv", groupArgs: true},
+		{name: "rename", groupArgs: true},
+		{name: "lint", groupArgs: true},
+		{name: "audit", groupArgs: true},
+	}},
+	{name: "completion", subCommands: []completionCommand{
+		{name: "bash"}, {name: "zsh"}, {name: "fish"},
+	}},
+	{name: "url", subCommands: []completionCommand{
+		{name: "register"}, {name: "unregister"}, {name: "open"}, {name: "endpoint"},
+	}},
+	{name: "backup", subCommands: []completionCommand{
+		{name: "gist", flags: []string{"--token"}},
+	}},
+	{name: "restore", subCommands: []completionCommand{
+		{name: "gist", flags: []string{"--token", "--id", "--force"}},
+	}},
+	{name: "status", flags: []string{"--short", "--port"}},
+	{name: "history", flags: []string{"--today", "--project", "--full"}, subCommands: []completionCommand{
+		{name: "search", flags: []string{"--today", "--project", "--full"}},
+		{name: "replay", flags: []string{"--print", "--port"}},
+		{name: "export", flags: []string{"--since", "--project", "--full", "-o", "--out"}},
+	}},
+	{name: "digest", flags: []string{"--date", "--out", "--email"}},
+	{name: "logs", subCommands: []completionCommand{
+		{name: "tail", flags: []string{"--client", "--server", "-n", "--no-follow", "--log-dir"}},
+		{name: "path", flags: []string{"--client", "--server", "--log-dir"}},
+	}},
+	{name: "telemetry", subCommands: []completionCommand{
+		{name: "on"}, {name: "off"}, {name: "status"},
+	}},
+	{name: "count", flags: []string{"--inc", "--reset", "--show", "--session"}},
+	{name: "remind", flags: []string{"--dir"}, subCommands: []completionCommand{
+		{name: "list"}, {name: "clear"},
+	}},
+	{name: "todo", subCommands: []completionCommand{
+		{name: "add", flags: []string{"--dir", "--global"}},
+		{name: "list", flags: []string{"--all"}},
+		{name: "done"},
+	}},
+	{name: "plan", subCommands: []completionCommand{
+		{name: "set", flags: []string{"--dir"}}, {name: "show"}, {name: "clear"},
+	}},
+	{name: "push", flags: []string{"--port"}},
+	{name: "reply", flags: []string{"--copy", "--port"}},
+	{name: "ask", flags: []string{"--port"}},
+	{name: "pause", flags: []string{"--port"}},
+	{name: "resume", flags: []string{"--port"}},
+	{name: "version", flags: []string{"--json"}},
+	{name: "help"},
+}
+
+// completionCase is the candidates after the words "cmd sub"
+type completionCase struct {
+	// key is "cmd/sub", "/" before any command
+	key    string
+	words  []string
+	groups bool
+}
+
+func handleCompletion(args []string) error {
+	args, err := flags.Help("-h,--help", completionHelp).Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("requires shell: bash, zsh or fish")
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion(GetProgramName()))
+	case "zsh":
+		fmt.Print(zshCompletion(GetProgramName()))
+	case "fish":
+		fmt.Print(fishCompletion(GetProgramName()))
+	case "groups":
+		// used by the scripts to complete group names
+		groupDir, err := getGroupConfigPath(false)
+		if err != nil {
+			return err
+		}
+		names, err := getGroupNames(groupDir)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Fprintln(os.Stdout, name)
+		}
+	default:
+		return fmt.Errorf("unsupported shell: %s, expect bash, zsh or fish", args[0])
+	}
+	return nil
+}
+
+// completionCases flattens completionCommands into the candidates of each position
+func completionCases() []completionCase {
+	root := completionCase{key: "/"}
+	for _, cmd := range completionCommands {
+		root.words = append(root.words, cmd.name)
+	}
+	root.words = append(root.words, completionGlobalFlags...)
+	cases := []completionCase{root}
+	for _, cmd := range completionCommands {
+		c := completionCase{key: cmd.name + "/", words: append([]string{}, cmd.flags...), groups: cmd.groupArgs}
+		for _, sub := range cmd.subCommands {
+			c.words = append(c.words, sub.name)
+		}
+		if len(c.words) > 0 || c.groups {
+			cases = append(cases, c)
+		}
+		for _, sub := range cmd.subCommands {
+			if len(sub.flags) > 0 || sub.groupArgs {
+				cases = append(cases, completionCase{key: cmd.name + "/" + sub.name, words: sub.flags, groups: sub.groupArgs})
+			}
+		}
+	}
+	return cases
+}
+
+var nonIdentifierPattern = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// completionFuncName returns the shell function name completing program
+func completionFuncName(program string) string {
+	return "_" + nonIdentifierPattern.ReplaceAllString(program, "_")
+}
+
+func bashCompletion(program string) string {
+	var b strings.Builder
+	fn := completionFuncName(program)
+	fmt.Fprintf(&b, "# bash completion for %s\n", program)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString(`    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local cmd="" sub="" i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case "${COMP_WORDS[i]}" in
+`)
+	fmt.Fprintf(&b, "            %s) ((i++)) ;;\n", strings.Join(completionValueFlags, "|"))
+	b.WriteString(`            -*) ;;
+            *)
+                if [[ -z "$cmd" ]]; then
+                    cmd="${COMP_WORDS[i]}"
+                elif [[ -z "$sub" ]]; then
+                    sub="${COMP_WORDS[i]}"
+                fi
+                ;;
+        esac
+    done
+    local words=""
+    case "$cmd/$sub" in
+`)
+	for _, c := range completionCases() {
+		words := strings.Join(c.words, " ")
+		if c.groups {
+			words = strings.TrimSpace(words + fmt.Sprintf(" $(%s completion groups 2>/dev/null)", program))
+		}
+		fmt.Fprintf(&b, "        %q) words=\"%s\" ;;\n", c.key, words)
+	}
+	b.WriteString(`    esac
+    COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+`)
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, program)
+	return b.String()
+}
+
+func zshCompletion(program string) string {
+	var b strings.Builder
+	fn := completionFuncName(program)
+	fmt.Fprintf(&b, "#compdef %s\n", program)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString(`    local cmd="" sub="" i
+    for ((i = 2; i < CURRENT; i++)); do
+        case "${words[i]}" in
+`)
+	fmt.Fprintf(&b, "            %s) ((i++)) ;;\n", strings.Join(completionValueFlags, "|"))
+	b.WriteString(`            -*) ;;
+            *)
+                if [[ -z "$cmd" ]]; then
+                    cmd="${words[i]}"
+                elif [[ -z "$sub" ]]; then
+                    sub="${words[i]}"
+                fi
+                ;;
+        esac
+    done
+    local -a candidates
+    case "$cmd/$sub" in
+`)
+	for _, c := range completionCases() {
+		words := strings.Join(c.words, " ")
+		if c.groups {
+			words = strings.TrimSpace(words + fmt.Sprintf(` ${(f)"$(%s completion groups 2>/dev/null)"}`, program))
+		}
+		fmt.Fprintf(&b, "        %q) candidates=(%s) ;;\n", c.key, words)
+	}
+	b.WriteString(`    esac
+    compadd -- $candidates
+}
+`)
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, program)
+	return b.String()
+}
+
+func fishCompletion(program string) string {
+	var b strings.Builder
+	fn := "_" + completionFuncName(program) + "_candidates"
+	fmt.Fprintf(&b, "# fish completion for %s\n", program)
+	fmt.Fprintf(&b, "function %s\n", fn)
+	b.WriteString(`    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    set -l cmd ""
+    set -l sub ""
+    set -l skip 0
+    for token in $tokens
+        if test $skip = 1
+            set skip 0
+            continue
+        end
+        switch $token
+`)
+	fmt.Fprintf(&b, "            case %s\n", strings.Join(completionValueFlags, " "))
+	b.WriteString(`                set skip 1
+            case '-*'
+            case '*'
+                if test -z "$cmd"
+                    set cmd $token
+                else if test -z "$sub"
+                    set sub $token
+                end
+        end
+    end
+    switch "$cmd/$sub"
+`)
+	for _, c := range completionCases() {
+		fmt.Fprintf(&b, "        case %q\n", c.key)
+		if len(c.words) > 0 {
+			fmt.Fprintf(&b, "            printf '%%s\\n' %s\n", strings.Join(c.words, " "))
+		}
+		if c.groups {
+			fmt.Fprintf(&b, "            %s completion groups 2>/dev/null\n", program)
+		}
+	}
+	b.WriteString(`    end
+end
+`)
+	fmt.Fprintf(&b, "complete -c %s -f -a '(%s)'\n", program, fn)
+	return b.String()
+}
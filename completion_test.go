@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompletionCases(t *testing.T) {
+	cases := make(map[string]completionCase)
+	for _, c := range completionCases() {
+		cases[c.key] = c
+	}
+	if root := cases["/"]; !containsFold(root.words, "group") || !containsFold(root.words, "--config-dir") {
+		t.Errorf("Expected commands and global flags at the root, got %v", root.words)
+	}
+	if c := cases["group/edit"]; !c.groups || strings.Join(c.words, " ") != "--editor --goto" {
+		t.Errorf("Expected group edit to complete its flags and group names, got %+v", c)
+	}
+	if c, ok := cases["group/list"]; ok {
+		t.Errorf("Expected nothing to complete after group list, got %+v", c)
+	}
+}
+
+func TestBashCompletion(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	dir := t.TempDir()
+	// a fake program answering `completion groups`
+	if err := os.WriteFile(filepath.Join(dir, "wn"), []byte("#!/bin/sh\necho home\necho work\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := bashCompletion("wn") + `
+COMP_WORDS=(wn --config-dir /tmp group show ""); COMP_CWORD=5; _wn; echo "${COMPREPLY[@]}"
+COMP_WORDS=(wn se); COMP_CWORD=1; _wn; echo "${COMPREPLY[@]}"
+`
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Env = append(os.Environ(), "PATH="+dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %v\n%s", err, output)
+	}
+	expected := "--use --dir --agent --explain home work\nserve\n"
+	if string(output) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+	}
+}
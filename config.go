@@ -7,33 +7,53 @@ import (
 	"strings"
 
 	"github.com/xhd2015/less-gen/flags"
-	"github.com/xhd2015/xgo/support/cmd"
+	"github.com/xhd2015/whats_next/pkg/config"
 )
 
 // Mode represents the operation mode
-type Mode string
+type Mode = config.Mode
 
 const (
-	ModeNative Mode = "native"
-	ModeServer Mode = "server"
+	ModeNative = config.ModeNative
+	ModeServer = config.ModeServer
 )
 
-// Config represents the configuration stored in config.json
-type Config struct {
-	Editor          string `json:"editor"`
-	SelectedProfile string `json:"selectedProfile"`
-	Mode            Mode   `json:"mode"`
-}
+// Config represents the configuration stored in config.json, see config.Config
+type Config = config.Config
+
+type Webhook = config.Webhook
+
+type SlackConfig = config.SlackConfig
+
+type DigestConfig = config.DigestConfig
 
 const configHelp = `
 Usage:
-  whats_next config --editor=editor
+  whats_next config [--editor=editor]
+  whats_next config list
+  whats_next config get KEY
+  whats_next config set KEY VALUE
+  whats_next config unset KEY
+  whats_next config schema
+  whats_next config secret set|rm NAME
+
+Map entries are addressed as KEY.NAME, e.g. aliases.@api
 
 Options:
   --editor=editor  The editor to use for editing the config
 `
 
 func handleConfig(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "list", "get", "set", "unset":
+			return handleConfigKey(args[0], args[1:])
+		case "schema":
+			return handleConfigSchema(args[1:])
+		case "secret":
+			return handleConfigSecret(args[1:])
+		}
+	}
 	var editor string
 	args, err := flags.String("--editor", &editor).Help("-h,--help", configHelp).Parse(args)
 	if err != nil {
@@ -48,31 +68,68 @@ func handleConfig(args []string) error {
 		return err
 	}
 
-	editor = getEditor(editor)
-	return cmd.Run(editor, configPath)
+	return openInEditor(editor, configPath)
 }
 
-// readConfig reads the config from config.json
-func readConfig() (*Config, error) {
-	configFile, err := getConfigPath(false, "config.json")
+func handleConfigKey(subCmd string, args []string) error {
+	args, err := flags.Help("-h,--help", configHelp).Parse(args)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	wantArgs := map[string]int{"list": 0, "get": 1, "set": 2, "unset": 1}[subCmd]
+	if len(args) != wantArgs {
+		return fmt.Errorf("config %s: expect %d argument(s), got %d", subCmd, wantArgs, len(args))
+	}
+	if subCmd == "set" || subCmd == "unset" {
+		unlock, err := lockConfigDir()
+		if err != nil {
+			return err
+		}
+		defer unlock()
 	}
 
-	data, err := os.ReadFile(configFile)
+	config, err := readConfig()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return &Config{}, nil
+		return err
+	}
+	switch subCmd {
+	case "list":
+		listConfig(os.Stdout, config)
+		return nil
+	case "get":
+		value, err := getConfigValue(config, args[0])
+		if err != nil {
+			return err
 		}
-		return nil, err
+		fmt.Println(value)
+		return nil
+	case "set":
+		err = setConfigValue(config, args[0], args[1])
+	case "unset":
+		err = unsetConfigValue(config, args[0])
 	}
+	if err != nil {
+		return err
+	}
+	return writeConfig(config)
+}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+// readConfig reads the config from config.json
+func readConfig() (*Config, error) {
+	reader, err := getConfigReader()
+	if err != nil {
 		return nil, err
 	}
+	return reader.Global()
+}
 
-	return &config, nil
+// getConfigReader returns the reader of the config files in the config dir
+func getConfigReader() (*config.Reader, error) {
+	configDir, err := getConfigDir(false)
+	if err != nil {
+		return nil, err
+	}
+	return &config.Reader{Dir: configDir, Hostname: config.Hostname(), OnRead: warnConfigFile}, nil
 }
 
 // writeConfig writes the config to config.json
@@ -87,5 +144,5 @@ func writeConfig(config *Config) error {
 		return err
 	}
 
-	return os.WriteFile(configFile, data, 0644)
+	return writeFileAtomic(configFile, data, 0644)
 }
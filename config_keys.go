@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// configEnums lists the accepted values of enum config keys
+var configEnums = map[string][]string{
+	"mode":               {string(ModeNative), string(ModeServer)},
+	"builtinGuidelines":  builtinGuidelineNames,
+	"soundEvents":        soundEvents,
+	"idleAction":         idleActions,
+	"nativeIdleAction":   idleActions,
+	"serverIdleAction":   idleActions,
+	"logLevel":           logLevels,
+	"inputQueueOverflow": inputQueueOverflows,
+}
+
+// configValidators check the values of config keys that need more than a type check
+var configValidators = map[string]func(value string) error{
+	"sectionLevel": func(value string) error {
+		if n, err := strconv.Atoi(value); err == nil && (n < 0 || n > 6) {
+			return fmt.Errorf("expect 0-6")
+		}
+		return nil
+	},
+	"serverPort": func(value string) error {
+		if n, err := strconv.Atoi(value); err == nil && (n < 1 || n > 65535) {
+			return fmt.Errorf("expect 1-65535")
+		}
+		return nil
+	},
+	"idleTimeout":       validateDuration,
+	"nativeIdleTimeout": validateDuration,
+	"serverIdleTimeout": validateDuration,
+	"hardTimeout":       validateDuration,
+	"showSessionGap":    validateDuration,
+	"logMaxAge":         validateDuration,
+	"logMaxSize": func(value string) error {
+		size, err := parseByteSize(value)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("expect a positive size like 10MB")
+		}
+		return nil
+	},
+	"inputQueueSize": func(value string) error {
+		if n, err := strconv.Atoi(value); err == nil && n < 1 {
+			return fmt.Errorf("expect at least 1")
+		}
+		return nil
+	},
+	"logMaxFiles": func(value string) error {
+		if n, err := strconv.Atoi(value); err == nil && n < 1 {
+			return fmt.Errorf("expect at least 1")
+		}
+		return nil
+	},
+}
+
+func validateDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("expect a positive duration like 5m")
+	}
+	return nil
+}
+
+// configField is a config.json key bound to a field of Config
+type configField struct {
+	key   string
+	value reflect.Value
+}
+
+// configFields returns the fields of config keyed by their json names, in declaration order
+func configFields(config *Config) []configField {
+	v := reflect.ValueOf(config).Elem()
+	t := v.Type()
+	fields := make([]configField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		fields = append(fields, configField{key: key, value: v.Field(i)})
+	}
+	return fields
+}
+
+// lookupConfigField finds the field for key, which may be "name" or "name.subkey"
+// for map fields like "aliases.@api"
+func lookupConfigField(config *Config, key string) (field configField, subKey string, err error) {
+	name, subKey, hasSubKey := strings.Cut(key, ".")
+	for _, f := range configFields(config) {
+		if f.key != name {
+			continue
+		}
+		isMap := f.value.Kind() == reflect.Map
+		if hasSubKey && !isMap {
+			return configField{}, "", fmt.Errorf("%s is not a map", name)
+		}
+		if hasSubKey && subKey == "" {
+			return configField{}, "", fmt.Errorf("empty map key: %s", key)
+		}
+		return f, subKey, nil
+	}
+	return configField{}, "", fmt.Errorf("unknown config key: %s, run `%s config list` to see available keys", name, GetProgramName())
+}
+
+// formatConfigValue formats a field value for display, maps are shown as "k=v" pairs
+func formatConfigValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr || (v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct) {
+		// nested configs like hosts entries, or lists like webhooks
+		if v.Kind() == reflect.Slice && v.Len() == 0 {
+			return ""
+		}
+		data, _ := json.Marshal(v.Interface())
+		return string(data)
+	}
+	if v.Kind() != reflect.Map {
+		return fmt.Sprint(v.Interface())
+	}
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+formatConfigValue(v.MapIndex(reflect.ValueOf(k))))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// getConfigValue returns the value of key in config
+func getConfigValue(config *Config, key string) (string, error) {
+	field, subKey, err := lookupConfigField(config, key)
+	if err != nil {
+		return "", err
+	}
+	if subKey != "" {
+		value := field.value.MapIndex(reflect.ValueOf(subKey))
+		if !value.IsValid() {
+			return "", nil
+		}
+		return formatConfigValue(value), nil
+	}
+	return formatConfigValue(field.value), nil
+}
+
+// setConfigValue parses value according to the type of key and stores it in config
+func setConfigValue(config *Config, key string, value string) error {
+	field, subKey, err := lookupConfigField(config, key)
+	if err != nil {
+		return err
+	}
+	if subKey != "" {
+		if field.value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("cannot set entries of %s from the command line, use `%s config` to edit it", field.key, GetProgramName())
+		}
+		if field.value.IsNil() {
+			field.value.Set(reflect.MakeMap(field.value.Type()))
+		}
+		field.value.SetMapIndex(reflect.ValueOf(subKey), reflect.ValueOf(value).Convert(field.value.Type().Elem()))
+		return nil
+	}
+	if isStringListField(field.value) {
+		// comma separated, an empty value sets an empty list
+		list := sections.SplitList(value)
+		for _, item := range list {
+			if allowed, ok := configEnums[field.key]; ok && !containsFold(allowed, item) {
+				return fmt.Errorf("invalid %s: %s, expect one of %s", field.key, item, strings.Join(allowed, ", "))
+			}
+		}
+		if list == nil {
+			list = []string{}
+		}
+		field.value.Set(reflect.ValueOf(&list))
+		return nil
+	}
+	if allowed, ok := configEnums[field.key]; ok && !containsFold(allowed, value) {
+		return fmt.Errorf("invalid %s: %s, expect one of %s", field.key, value, strings.Join(allowed, ", "))
+	}
+	if validate, ok := configValidators[field.key]; ok {
+		if err := validate(value); err != nil {
+			return fmt.Errorf("invalid %s, %v: %s", field.key, err, value)
+		}
+	}
+
+	switch field.value.Kind() {
+	case reflect.String:
+		if _, ok := configEnums[field.key]; ok {
+			value = strings.ToLower(value)
+		}
+		field.value.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s, expect true or false: %s", field.key, value)
+		}
+		field.value.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s, expect an integer: %s", field.key, value)
+		}
+		field.value.SetInt(int64(n))
+	case reflect.Map:
+		return fmt.Errorf("%s is a map, use %s.<key> to set an entry", field.key, field.key)
+	default:
+		return fmt.Errorf("cannot set %s from the command line", field.key)
+	}
+	return nil
+}
+
+// isStringListField reports whether v is a *[]string, used for lists
+// where an empty list differs from an unset one
+func isStringListField(v reflect.Value) bool {
+	return v.Type() == reflect.TypeOf((*[]string)(nil))
+}
+
+// unsetConfigValue resets key to its zero value, or deletes a map entry
+func unsetConfigValue(config *Config, key string) error {
+	field, subKey, err := lookupConfigField(config, key)
+	if err != nil {
+		return err
+	}
+	if subKey != "" {
+		if !field.value.IsNil() {
+			field.value.SetMapIndex(reflect.ValueOf(subKey), reflect.Value{})
+		}
+		return nil
+	}
+	field.value.Set(reflect.Zero(field.value.Type()))
+	return nil
+}
+
+// listConfig prints every config key with its current value
+func listConfig(w io.Writer, config *Config) {
+	for _, field := range configFields(config) {
+		fmt.Fprintf(w, "%s=%s\n", field.key, formatConfigValue(field.value))
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	config := &Config{}
+	if err := setConfigValue(config, "mode", "Server"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Mode != ModeServer {
+		t.Errorf("Expected mode server, got %q", config.Mode)
+	}
+	if err := setConfigValue(config, "mode", "remote"); err == nil {
+		t.Errorf("Expected error for invalid mode")
+	}
+	if err := setConfigValue(config, "disableSymlinkResolution", "true"); err != nil || !config.DisableSymlinkResolution {
+		t.Errorf("Expected disableSymlinkResolution to be set, err: %v", err)
+	}
+	if err := setConfigValue(config, "sectionLevel", "9"); err == nil {
+		t.Errorf("Expected error for out of range sectionLevel")
+	}
+	if err := setConfigValue(config, "aliases.@api", "~/work/api"); err != nil || config.Aliases["@api"] != "~/work/api" {
+		t.Errorf("Expected alias to be set, err: %v", err)
+	}
+	if err := setConfigValue(config, "aliases", "x"); err == nil {
+		t.Errorf("Expected error when setting a map directly")
+	}
+	if err := setConfigValue(config, "unknown", "x"); err == nil {
+		t.Errorf("Expected error for unknown key")
+	}
+}
+
+func TestGetAndUnsetConfigValue(t *testing.T) {
+	config := &Config{Editor: "vim", Aliases: map[string]string{"@b": "~/b", "@a": "~/a"}}
+
+	value, err := getConfigValue(config, "editor")
+	if err != nil || value != "vim" {
+		t.Errorf("Expected vim, got %q, err: %v", value, err)
+	}
+	value, err = getConfigValue(config, "aliases")
+	if err != nil || value != "@a=~/a,@b=~/b" {
+		t.Errorf("Expected sorted aliases, got %q, err: %v", value, err)
+	}
+
+	if err := unsetConfigValue(config, "editor"); err != nil || config.Editor != "" {
+		t.Errorf("Expected editor to be unset, err: %v", err)
+	}
+	if err := unsetConfigValue(config, "aliases.@a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := config.Aliases["@a"]; ok {
+		t.Errorf("Expected alias @a to be removed")
+	}
+
+	var buf bytes.Buffer
+	listConfig(&buf, config)
+	if !bytes.Contains(buf.Bytes(), []byte("aliases=@b=~/b\n")) || !bytes.Contains(buf.Bytes(), []byte("editor=\n")) {
+		t.Errorf("Unexpected list output:\n%s", buf.String())
+	}
+}
+
+func TestSetConfigDuration(t *testing.T) {
+	config := &Config{}
+	if err := setConfigValue(config, "idleTimeout", "5m"); err != nil || config.IdleTimeout != "5m" {
+		t.Errorf("Expected idleTimeout 5m, err: %v", err)
+	}
+	for _, value := range []string{"5", "-1m", "soon"} {
+		if err := setConfigValue(config, "hardTimeout", value); err == nil {
+			t.Errorf("Expected error for hardTimeout %q", value)
+		}
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", TIMEOUT},
+		{"90s", 90 * time.Second},
+		{"1h", time.Hour},
+		{"invalid", TIMEOUT},
+		{"0s", TIMEOUT},
+	}
+	for _, tt := range tests {
+		if result := parseTimeout("test", tt.value, TIMEOUT); result != tt.expected {
+			t.Errorf("parseTimeout(%q): expected %v, got %v", tt.value, tt.expected, result)
+		}
+	}
+}
+
+func TestGetTimeoutsByMode(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	t.Setenv("WHATS_NEXT_TIMEOUT", "")
+	config := &Config{IdleTimeout: "4m", ServerIdleTimeout: "1m", HardTimeout: "20m"}
+	if err := writeConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	idle, hard := getTimeouts("", ModeNative)
+	if idle != 4*time.Minute || hard != 20*time.Minute {
+		t.Errorf("Expected native 4m/20m, got %v/%v", idle, hard)
+	}
+	idle, _ = getTimeouts("", ModeServer)
+	if idle != time.Minute {
+		t.Errorf("Expected server 1m, got %v", idle)
+	}
+
+	t.Setenv("WHATS_NEXT_TIMEOUT", "30s")
+	idle, _ = getTimeouts("", ModeServer)
+	if idle != 30*time.Second {
+		t.Errorf("Expected env override 30s, got %v", idle)
+	}
+}
+
+func TestSetSoundEvents(t *testing.T) {
+	config := &Config{}
+	if err := setConfigValue(config, "soundEvents", "reply"); err != nil || config.SoundEvents == nil || (*config.SoundEvents)[0] != "reply" {
+		t.Errorf("Expected soundEvents [reply], got %v, err: %v", config.SoundEvents, err)
+	}
+	if err := setConfigValue(config, "soundEvents", "ring"); err == nil {
+		t.Errorf("Expected error for unknown sound event")
+	}
+}
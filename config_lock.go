@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// configLockFile in the config dir serializes the read-modify-writes of the
+// files of the config dir across processes, like a running server answering
+// an agent while `whats_next add` runs in a terminal
+const configLockFile = ".lock"
+
+// configLockTimeout is how long to wait for another process to release the lock
+const configLockTimeout = 5 * time.Second
+
+const configLockRetryInterval = 20 * time.Millisecond
+
+// lockConfigDir takes the advisory lock of the config dir, waiting up to
+// configLockTimeout for the process holding it. The returned func releases it.
+// Calls must not be nested, the lock is not reentrant.
+func lockConfigDir() (func(), error) {
+	file, err := getConfigPath(true, configLockFile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(configLockTimeout)
+	for {
+		ok, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lock %s: %w", file, err)
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out after %v waiting for another %s process to release %s", configLockTimeout, GetProgramName(), file)
+		}
+		time.Sleep(configLockRetryInterval)
+	}
+	return func() {
+		if err := unlockFile(f); err != nil {
+			Errorf("unlock %s: %v", file, err)
+		}
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockConfigDirWaitsForRelease(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	unlock, err := lockConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan time.Time)
+	go func() {
+		unlock2, err := lockConfigDir()
+		if err != nil {
+			t.Error(err)
+			close(acquired)
+			return
+		}
+		acquired <- time.Now()
+		unlock2()
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	released := time.Now()
+	unlock()
+	select {
+	case at := <-acquired:
+		if at.Before(released) {
+			t.Errorf("Expected the lock held until released")
+		}
+	case <-time.After(configLockTimeout):
+		t.Fatal("Expected the lock acquired once released")
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+// durationPattern matches Go durations like "90s" or "1h30m"
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// jsonSchema is the subset of JSON Schema needed to describe config.json
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Minimum              *int                   `json:"minimum,omitempty"`
+	Maximum              *int                   `json:"maximum,omitempty"`
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// configSchemaHints add descriptions and constraints to the generated schema
+var configSchemaHints = map[string]jsonSchema{
+	"$schema":                        {Description: "Path or URL of this schema"},
+	"editor":                         {Description: "Command used to open files, may carry arguments like \"code --wait\""},
+	"selectedProfile":                {Description: "Profile from the group dir appended to every answer"},
+	"mode":                           {Description: "native reads input in the same terminal, server delegates to `whats_next serve`"},
+	"disableSymlinkResolution":       {Description: "Compare (project: ...) paths literally instead of resolving symlinks"},
+	"aliases":                        {Description: "Project aliases like \"@api\" usable as (project: @api)"},
+	"commandAliases":                 {Description: "Short commands like \"s\" expanding to command lines like \"group show --use\""},
+	"sectionLevel":                   {Description: "Deepest heading level that starts a new section, 0 splits on every heading", Minimum: intPtr(0), Maximum: intPtr(6)},
+	"gitRemote":                      {Description: "Remote compared to decide whether two checkouts are the same project, empty compares all"},
+	"idleTimeout":                    {Description: "How long to wait for input before telling the agent to keep thinking, like \"5m\"", Pattern: durationPattern},
+	"nativeIdleTimeout":              {Description: "Idle timeout in native mode, overrides idleTimeout", Pattern: durationPattern},
+	"serverIdleTimeout":              {Description: "Idle timeout in server mode, overrides idleTimeout", Pattern: durationPattern},
+	"serverPort":                     {Description: "Port of `whats_next serve` and its clients, defaults to 7654", Minimum: intPtr(1), Maximum: intPtr(65535)},
+	"serverBind":                     {Description: "Address the server listens on, e.g. \"0.0.0.0\" for LAN use, defaults to localhost"},
+	"serverToken":                    {Description: "Token the server requires on every request, required to bind a non-loopback address, or a secret reference like \"keychain:whats_next\""},
+	"builtinGuidelines":              {Description: "Built-in guidelines emitted when no profile is selected, all by default, [] for none"},
+	"disableProgramNameSubstitution": {Description: "Keep `whats_next` mentions instead of replacing them with the invoked program name"},
+	"replyLanguage":                  {Description: "Language the agent should answer in, e.g. \"Chinese\""},
+	"answerTemplate":                 {Description: "Go text/template wrapping the question, with .Question, .Profile, .ProfileName, .WorkingDir and .ReplyLanguage"},
+	"waitTimeMetadata":               {Description: "Append how long the user took to reply to the answer, e.g. \"(user replied after 4m 12s)\""},
+	"notify":                         {Description: "Fire a desktop notification when the agent starts waiting for input"},
+	"sound":                          {Description: "\"bell\" for the terminal bell, or an audio file played when an agent waits or a reply is delivered"},
+	"soundEvents":                    {Description: "Events playing the sound: connect, reply. All by default"},
+	"webhooks":                       {Description: "HTTP requests sent on events: connected, input, idle, shutdown"},
+	"logLevel":                       {Description: "Level of the logs in <config dir>/logs, debug adds input events, queue operations and HTTP requests"},
+	"logMaxSize":                     {Description: "Size ~/.whats_next.log and the server logs are rotated at, like \"10MB\", defaults to 10MB", Pattern: byteSizePattern},
+	"logMaxFiles":                    {Description: "Number of rotated log files kept, defaults to 3"},
+	"logMaxAge":                      {Description: "Rotated log files older than this are removed, like \"168h\"", Pattern: durationPattern},
+	"telemetry":                      {Description: "Count commands and enabled features, never content, see `whats_next telemetry`. Off by default"},
+	"apiAllowOrigins":                {Description: "Browser origins allowed to call /api/v1 of the server, a trailing * matches any rest, defaults to [\"vscode-webview://*\"]"},
+	"digest":                         {Description: "Email of `whats_next digest --email`, the daily summary of the transcript"},
+	"slack":                          {Description: "Bridge of `whats_next serve --slack`, posting waiting agents to a channel and taking replies in thread"},
+	"gistId":                         {Description: "Gist of `whats_next backup gist`, set by the first backup"},
+	"gistToken":                      {Description: "GitHub token with the gist scope, or a secret reference like \"keychain:github\""},
+	"hosts":                          {Description: "Overrides applied on hosts whose name matches the key, literally or as a regular expression"},
+	"idleAction":                     {Description: "What happens on idle timeout: thinking, message, wait or stop"},
+	"nativeIdleAction":               {Description: "Idle action in native mode, overrides idleAction, defaults to wait"},
+	"serverIdleAction":               {Description: "Idle action in server mode, overrides idleAction, defaults to thinking"},
+	"idleMessage":                    {Description: "Text sent to the agent by the message idle action"},
+	"awayMessage":                    {Description: "Text sent to agents while the server is paused, {until} is replaced by the time the user is back"},
+	"hardTimeout":                    {Description: "Longest a single server request waits for input, like \"30m\"", Pattern: durationPattern},
+	"showSessionGap":                 {Description: "Idle time after which (show: first N) sections count a new session, like \"30m\"", Pattern: durationPattern},
+	"inputQueueSize":                 {Description: "Number of answers the server queues for agents, defaults to 100", Minimum: intPtr(1)},
+	"inputQueueOverflow":             {Description: "When the queue is full: block waits in the server input and refuses slack and API answers, drop-oldest discards the oldest answer, spool keeps answers on disk until agents take them. Defaults to block"},
+}
+
+// configSchema generates the JSON Schema of config.json from Config
+func configSchema() *jsonSchema {
+	schema := buildConfigSchema(true)
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	schema.Title = "whats_next config.json"
+	return schema
+}
+
+// buildConfigSchema builds the object schema of Config,
+// the entries of hosts are configs without hosts of their own
+func buildConfigSchema(withHosts bool) *jsonSchema {
+	schema := &jsonSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*jsonSchema),
+		AdditionalProperties: false,
+	}
+	for _, field := range configFields(&Config{}) {
+		if field.key == "hosts" && !withHosts {
+			continue
+		}
+		property := configSchemaHints[field.key]
+		property.Type = jsonSchemaType(field.value.Type())
+		if field.key == "hosts" {
+			property.AdditionalProperties = buildConfigSchema(false)
+		} else if field.value.Kind() == reflect.Map {
+			property.AdditionalProperties = &jsonSchema{Type: jsonSchemaType(field.value.Type().Elem())}
+		}
+		if allowed, ok := configEnums[field.key]; ok {
+			if property.Type == "array" {
+				property.Items = &jsonSchema{Type: "string", Enum: allowed}
+			} else {
+				property.Enum = allowed
+			}
+		} else if property.Type == "array" && field.value.Type().Elem().Kind() == reflect.Struct {
+			property.Items = structSchema(field.value.Type().Elem(), configStructHints[field.key])
+		} else if field.value.Kind() == reflect.Ptr && field.value.Type().Elem().Kind() == reflect.Struct {
+			nested := structSchema(field.value.Type().Elem(), configStructHints[field.key])
+			property.Properties, property.AdditionalProperties = nested.Properties, nested.AdditionalProperties
+		} else if property.Type == "array" {
+			property.Items = &jsonSchema{Type: "string"}
+		}
+		schema.Properties[field.key] = &property
+	}
+	return schema
+}
+
+// configStructHints describe the keys of nested objects like webhooks entries
+var configStructHints = map[string]map[string]jsonSchema{
+	"webhooks": webhookSchemaHints,
+	"slack":    slackSchemaHints,
+	"digest":   digestSchemaHints,
+}
+
+var digestSchemaHints = map[string]jsonSchema{
+	"email":      {Description: "Address the digest is sent to"},
+	"from":       {Description: "Sender address, defaults to email"},
+	"smtpServer": {Description: "host:port of the SMTP server, e.g. \"smtp.gmail.com:587\""},
+	"username":   {Description: "SMTP username, no authentication if empty"},
+	"password":   {Description: "SMTP password, or a secret reference like \"keychain:smtp\""},
+}
+
+var slackSchemaHints = map[string]jsonSchema{
+	"token":        {Description: "Bot token with chat:write and channels:history scopes, or a secret reference like \"keychain:slack-token\""},
+	"channel":      {Description: "Id of the channel to post to, e.g. \"C0123456789\""},
+	"pollInterval": {Description: "How often threads are checked for replies, defaults to 5s", Pattern: durationPattern},
+}
+
+// webhookSchemaHints describe the keys of a webhooks entry
+var webhookSchemaHints = map[string]jsonSchema{
+	"url":      {Description: "URL requested, may be a secret reference like \"keychain:ntfy-url\""},
+	"events":   {Description: "Events sending the webhook, all by default", Items: &jsonSchema{Type: "string", Enum: webhookEvents}},
+	"method":   {Description: "HTTP method, defaults to POST"},
+	"headers":  {Description: "Request headers, values may be secret references like \"keychain:NAME\"", AdditionalProperties: &jsonSchema{Type: "string"}},
+	"template": {Description: "Go text/template of the body with .Event, .Time, .Host, .WorkingDir, .Project and .Content, defaults to JSON"},
+}
+
+// structSchema builds the object schema of nested objects like webhooks entries
+func structSchema(t reflect.Type, hints map[string]jsonSchema) *jsonSchema {
+	schema := &jsonSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*jsonSchema),
+		AdditionalProperties: false,
+	}
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		property := hints[key]
+		property.Type = jsonSchemaType(t.Field(i).Type)
+		schema.Properties[key] = &property
+	}
+	return schema
+}
+
+func jsonSchemaType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return "integer"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Ptr:
+		return jsonSchemaType(t.Elem())
+	case reflect.Slice:
+		return "array"
+	}
+	return ""
+}
+
+// validate returns problems of value, as decoded by encoding/json, against the schema
+func (s *jsonSchema) validate(path string, value interface{}) []string {
+	var problems []string
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expect an object", path)}
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if property, ok := s.Properties[key]; ok {
+				problems = append(problems, property.validate(keyPath, object[key])...)
+				continue
+			}
+			switch additional := s.AdditionalProperties.(type) {
+			case *jsonSchema:
+				problems = append(problems, additional.validate(keyPath, object[key])...)
+			case bool:
+				if !additional {
+					problems = append(problems, "unknown key: "+keyPath)
+				}
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expect a string", path)}
+		}
+		if len(s.Enum) > 0 && str != "" && !containsFold(s.Enum, str) {
+			problems = append(problems, fmt.Sprintf("invalid %s: %s, expect one of %s", path, str, strings.Join(s.Enum, ", ")))
+		}
+		if s.Pattern != "" && str != "" && !regexp.MustCompile(s.Pattern).MatchString(str) {
+			problems = append(problems, fmt.Sprintf("invalid %s: %s, expect to match %s", path, str, s.Pattern))
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expect an array", path)}
+		}
+		for i, item := range array {
+			if s.Items != nil {
+				problems = append(problems, s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+			}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expect true or false", path)}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int(n)) {
+			return []string{fmt.Sprintf("%s: expect an integer", path)}
+		}
+		if (s.Minimum != nil && int(n) < *s.Minimum) || (s.Maximum != nil && int(n) > *s.Maximum) {
+			problems = append(problems, fmt.Sprintf("invalid %s: %d, expect %d-%d", path, int(n), *s.Minimum, *s.Maximum))
+		}
+	}
+	return problems
+}
+
+// validateConfigJSON checks the content of a config.json against the schema,
+// a malformed json is returned as an error
+func validateConfigJSON(data []byte) ([]string, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return configSchema().validate("", value), nil
+}
+
+var warnedConfigFiles sync.Map // file -> bool
+
+// warnConfigProblems prints schema problems of a config.json to stderr, once per file per process
+func warnConfigProblems(configFile string, data []byte) {
+	if _, warned := warnedConfigFiles.LoadOrStore(configFile, true); warned {
+		return
+	}
+	problems, err := validateConfigJSON(data)
+	if err != nil {
+		return
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", configFile, problem)
+		Errorf("%s: %s", configFile, problem)
+	}
+}
+
+const configSchemaHelp = `
+Usage:
+  whats_next config schema
+
+Prints the JSON Schema of config.json, e.g. for editor completion:
+  whats_next config schema > ~/.config/whats_next/config.schema.json
+and add "$schema": "./config.schema.json" to config.json.
+`
+
+func handleConfigSchema(args []string) error {
+	args, err := flags.Help("-h,--help", configSchemaHelp).Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra arguments: %s", strings.Join(args, " "))
+	}
+	data, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
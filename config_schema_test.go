@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConfigSchema(t *testing.T) {
+	schema := configSchema()
+	for _, field := range configFields(&Config{}) {
+		if _, ok := schema.Properties[field.key]; !ok {
+			t.Errorf("Expected schema property for %s", field.key)
+		}
+		if configSchemaHints[field.key].Description == "" {
+			t.Errorf("Expected description for %s", field.key)
+		}
+	}
+	if got := strings.Join(schema.Properties["mode"].Enum, ","); got != "native,server" {
+		t.Errorf("Expected mode enum native,server, got %s", got)
+	}
+	if schema.Properties["aliases"].AdditionalProperties.(*jsonSchema).Type != "string" {
+		t.Errorf("Expected aliases values to be strings")
+	}
+	if _, err := json.Marshal(schema); err != nil {
+		t.Errorf("Expected schema to marshal: %v", err)
+	}
+}
+
+func TestValidateConfigJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{"valid", `{"$schema": "./config.schema.json", "editor": "vim", "mode": "server", "sectionLevel": 2, "idleTimeout": "1h30m", "aliases": {"@a": "~/a"}}`, nil},
+		{"unknown key", `{"editr": "vim"}`, []string{"unknown key: editr"}},
+		{"bad enum", `{"mode": "remote"}`, []string{"invalid mode: remote, expect one of native, server"}},
+		{"bad type", `{"disableSymlinkResolution": "yes", "sectionLevel": 1.5}`, []string{"disableSymlinkResolution: expect true or false", "sectionLevel: expect an integer"}},
+		{"out of range", `{"sectionLevel": 7}`, []string{"invalid sectionLevel: 7, expect 0-6"}},
+		{"bad duration", `{"hardTimeout": "ten minutes"}`, []string{"invalid hardTimeout: ten minutes, expect to match " + durationPattern}},
+		{"bad alias", `{"aliases": {"@a": 1}}`, []string{"aliases.@a: expect a string"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := validateConfigJSON([]byte(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(problems, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected %q, got %q", tt.expected, problems)
+			}
+		})
+	}
+
+	if _, err := validateConfigJSON([]byte(`{`)); err == nil {
+		t.Errorf("Expected error for malformed json")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+const rmHelp = `
+Usage:
+  whats_next rm TITLE...
+
+Removes the sections titled TITLE from custom.md, with their content and
+subsections. TITLE is the heading without the leading '#', as printed by
+` + "`whats_next list --custom`" + `, compared case-insensitively.
+`
+
+func handleRm(args []string) error {
+	args, err := flags.Help("-h,--help", rmHelp).Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires TITLE, see `%s list --custom`", GetProgramName())
+	}
+	customFile, err := getCustomFile(false)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	data, err := os.ReadFile(customFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s does not exist, nothing to remove", customFile)
+		}
+		return err
+	}
+	content := string(data)
+	for _, title := range args {
+		content, err = removeCustomSection(content, title)
+		if err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(customFile, []byte(content), 0644); err != nil {
+		return err
+	}
+	recordAudit(auditActionRemove, auditCustomName, "rm", string(data), content)
+	for _, title := range args {
+		fmt.Printf("removed %s\n", title)
+	}
+	return nil
+}
+
+// customSectionTitle returns the text of a section heading, without the leading '#'
+func customSectionTitle(heading string) string {
+	return strings.TrimSpace(strings.TrimLeft(heading, "#"))
+}
+
+// removeCustomSection removes the section titled title from content,
+// failing if no section or more than one has that title
+func removeCustomSection(content string, title string) (string, error) {
+	title = customSectionTitle(title)
+	content = sections.Normalize(content)
+	parsed := parseSections(content)
+	found := -1
+	for i, section := range parsed {
+		if section.Title == "" || !strings.EqualFold(customSectionTitle(section.Title), title) {
+			continue
+		}
+		if found >= 0 {
+			return "", fmt.Errorf("more than one section titled %q, edit custom.md with `%s edit`", title, GetProgramName())
+		}
+		found = i
+	}
+	if found < 0 {
+		return "", fmt.Errorf("no section titled %q, see `%s list --custom`", title, GetProgramName())
+	}
+
+	lines := strings.Split(content, "\n")
+	start := parsed[found].Line - 1
+	end := len(lines)
+	// subsections go with their section
+	level := sections.HeadingLevel(parsed[found].Title)
+	for _, section := range parsed[found+1:] {
+		if sections.HeadingLevel(section.Title) <= level {
+			end = section.Line - 1
+			break
+		}
+	}
+	lines = append(lines[:start], lines[end:]...)
+	result := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if result == "" {
+		return "", nil
+	}
+	return result + "\n", nil
+}
+
+// listCustomTitles writes the titles of the sections of custom.md, one per line
+func listCustomTitles(w io.Writer) error {
+	customFile, err := getCustomFile(false)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(customFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, section := range parseSections(string(data)) {
+		if section.Title != "" {
+			fmt.Fprintln(w, customSectionTitle(section.Title))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+
+const digestHelp = `
+Usage:
+  whats_next digest [--date DATE] [--out FILE] [--email]
+
+Summarizes a day of the transcript as markdown: the interactions and wait
+time per project, and the follow-ups given to agents.
+
+The transcript records each answer sent to an agent under transcripts/
+in the config dir, see history. For an end-of-day report, schedule it with cron:
+  55 23 * * * whats_next digest --email
+
+Options:
+  --date DATE   The day to summarize: YYYY-MM-DD, today or yesterday (default: today)
+  --out FILE    Write the digest to FILE instead of stdout
+  --email       Also send the digest to digest.email through digest.smtpServer
+`
+
+func handleDigest(args []string) error {
+	var date string
+	var out string
+	var email bool
+	args, err := flags.String("--date", &date).
+		String("--out", &out).
+		Bool("--email", &email).
+		Help("-h,--help", digestHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	day, err := parseDigestDate(date, time.Now())
+	if err != nil {
+		return err
+	}
+	var config *Config
+	if email {
+		// fail before the work if email is not configured
+		config, err = readConfig()
+		if err != nil {
+			return err
+		}
+		if config.Digest == nil || config.Digest.Email == "" || config.Digest.SMTPServer == "" {
+			return fmt.Errorf("--email requires digest.email and digest.smtpServer, run `%s config` to set them", GetProgramName())
+		}
+	}
+
+	entries, err := readTranscript(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	digest := buildDigest(day, entries)
+	if out != "" {
+		if err := os.WriteFile(out, []byte(digest), 0644); err != nil {
+			return err
+		}
+	} else {
+		fmt.Print(digest)
+	}
+	if email {
+		if err := sendDigestEmail(config.Digest, "whats_next digest "+day.Format(time.DateOnly), digest); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Sent digest to %s\n", config.Digest.Email)
+	}
+	return nil
+}
+
+// parseDigestDate returns the start of the local day of date
+func parseDigestDate(date string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch date {
+	case "", "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	day, err := time.ParseInLocation(time.DateOnly, date, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expect YYYY-MM-DD, today or yesterday", date)
+	}
+	return day, nil
+}
+
+type digestProject struct {
+	name         string
+	interactions int
+	idle         int
+	wait         time.Duration
+	followUps    []*transcriptEntry
+}
+
+// buildDigest renders the markdown digest of the transcript entries of day
+func buildDigest(day time.Time, entries []*transcriptEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# whats_next digest %s\n\n", day.Format(time.DateOnly))
+	if len(entries) == 0 {
+		b.WriteString("No interactions with agents.\n")
+		return b.String()
+	}
+
+	byName := make(map[string]*digestProject)
+	var total time.Duration
+	for _, entry := range entries {
+		name := entry.Project
+		if name == "" {
+			name = "(unknown)"
+		}
+		project := byName[name]
+		if project == nil {
+			project = &digestProject{name: name}
+			byName[name] = project
+		}
+		wait := time.Duration(entry.Wait * float64(time.Second))
+		project.interactions++
+		project.wait += wait
+		total += wait
+		if entry.Event == WebhookEventIdle {
+			project.idle++
+		} else if strings.TrimSpace(entry.Content) != "" {
+			project.followUps = append(project.followUps, entry)
+		}
+	}
+	projects := make([]*digestProject, 0, len(byName))
+	for _, project := range byName {
+		projects = append(projects, project)
+	}
+	// busiest first
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].interactions != projects[j].interactions {
+			return projects[i].interactions > projects[j].interactions
+		}
+		return projects[i].name < projects[j].name
+	})
+
+	fmt.Fprintf(&b, "%d interaction(s) across %d project(s), agents waited %s in total.\n\n",
+		len(entries), len(projects), formatDigestDuration(total))
+	b.WriteString("| Project | Interactions | Idle | Wait |\n")
+	b.WriteString("|---|---:|---:|---:|\n")
+	for _, project := range projects {
+		fmt.Fprintf(&b, "| %s | %d | %d | %s |\n", project.name, project.interactions, project.idle, formatDigestDuration(project.wait))
+	}
+
+	b.WriteString("\n## Follow-ups\n")
+	var hasFollowUps bool
+	for _, project := range projects {
+		if len(project.followUps) == 0 {
+			continue
+		}
+		hasFollowUps = true
+		fmt.Fprintf(&b, "\n### %s\n\n", project.name)
+		for _, entry := range project.followUps {
+			fmt.Fprintf(&b, "- %s %s\n", entry.Time.Local().Format("15:04"), digestSummary(entry.Content))
+		}
+	}
+	if !hasFollowUps {
+		b.WriteString("\nNone.\n")
+	}
+	return b.String()
+}
+
+// digestSummary returns the first line of content, shortened to fit a list item
+func digestSummary(content string) string {
+	content = strings.TrimSpace(content)
+	line, rest, _ := strings.Cut(content, "\n")
+	line = strings.TrimSpace(line)
+	const maxLen = 120
+	if runes := []rune(line); len(runes) > maxLen {
+		return string(runes[:maxLen]) + "…"
+	}
+	if strings.TrimSpace(rest) != "" {
+		return line + " …"
+	}
+	return line
+}
+
+func formatDigestDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
+
+// sendDigestEmail sends the markdown digest as a plain text email
+func sendDigestEmail(config *DigestConfig, subject string, body string) error {
+	host := config.SMTPServer
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	from := config.From
+	if from == "" {
+		from = config.Email
+	}
+	var auth smtp.Auth
+	if config.Username != "" {
+		password, err := resolveSecret(config.Password)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", config.Username, password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		from, config.Email, subject, time.Now().Format(time.RFC1123Z), strings.ReplaceAll(body, "\n", "\r\n"))
+	if err := smtp.SendMail(config.SMTPServer, auth, from, []string{config.Email}, []byte(msg)); err != nil {
+		return fmt.Errorf("send digest email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDigestDate(t *testing.T) {
+	now := time.Date(2024, 3, 1, 15, 4, 5, 0, time.Local)
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"", "2024-03-01"},
+		{"today", "2024-03-01"},
+		{"yesterday", "2024-02-29"},
+		{"2024-01-02", "2024-01-02"},
+	}
+	for _, tt := range tests {
+		day, err := parseDigestDate(tt.date, now)
+		if err != nil {
+			t.Fatalf("parseDigestDate(%q): %v", tt.date, err)
+		}
+		if got := day.Format(time.DateOnly); got != tt.want || day.Hour() != 0 {
+			t.Errorf("parseDigestDate(%q) = %v, want start of %s", tt.date, day, tt.want)
+		}
+	}
+	if _, err := parseDigestDate("last week", now); err == nil {
+		t.Errorf("expect error for an invalid date")
+	}
+}
+
+func TestDigestFromTranscript(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	day := time.Now()
+	for _, entry := range []*transcriptEntry{
+		{Time: day, Mode: ModeServer, Event: WebhookEventInput, WorkingDir: "/src/api", Project: "api", Wait: 60, Content: "run the tests\nthen commit"},
+		{Time: day, Mode: ModeNative, Event: WebhookEventIdle, WorkingDir: "/src/api", Project: "api", Wait: 600},
+		{Time: day, Mode: ModeNative, Event: WebhookEventInput, WorkingDir: "/src/web", Project: "web", Wait: 30, Content: "fix the header"},
+		{Time: day.AddDate(0, 0, -2), Mode: ModeNative, Event: WebhookEventInput, Project: "old", Content: "not today"},
+	} {
+		if err := appendTranscript(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start, _ := parseDigestDate("today", day)
+	entries, err := readTranscript(start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expect 3 entries of today, got %d", len(entries))
+	}
+	digest := buildDigest(start, entries)
+	for _, want := range []string{
+		"3 interaction(s) across 2 project(s), agents waited 12m in total.",
+		"| api | 2 | 1 | 11m |",
+		"| web | 1 | 0 | 30s |",
+		"### api",
+		"run the tests …",
+		"fix the header",
+	} {
+		if !strings.Contains(digest, want) {
+			t.Errorf("expect digest to contain %q, got:\n%s", want, digest)
+		}
+	}
+	if strings.Contains(digest, "not today") {
+		t.Errorf("expect entries of other days excluded, got:\n%s", digest)
+	}
+	// busiest project first
+	if strings.Index(digest, "| api") > strings.Index(digest, "| web") {
+		t.Errorf("expect api before web, got:\n%s", digest)
+	}
+}
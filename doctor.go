@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+const doctorHelp = `
+Usage:
+  whats_next doctor [--port=PORT] [--fix]
+
+Checks the environment and prints fixes for the problems found.
+
+Options:
+  --port=PORT  The server port to check (default: serverPort or 7654)
+  --fix        Apply the fixes that can be done automatically: stop orphaned
+               and outdated servers, and remove stale state files
+`
+
+type doctorStatus string
+
+const (
+	doctorOK   doctorStatus = "ok"
+	doctorWarn doctorStatus = "warn"
+	doctorFail doctorStatus = "fail"
+)
+
+// doctorCheck is the result of a single doctor check
+type doctorCheck struct {
+	Name    string
+	Status  doctorStatus
+	Message string
+	Fix     string
+	// Action applies Fix, run by doctor --fix
+	Action func() error
+}
+
+func handleDoctor(args []string) error {
+	var port int
+	var fix bool
+	args, err := flags.Int("--port", &port).
+		Bool("--fix", &fix).
+		Help("-h,--help", doctorHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	return runDoctor(os.Stdout, resolveServerPort(port, readServerConfig()), fix)
+}
+
+func runDoctor(w io.Writer, port int, fix bool) error {
+	var checks []doctorCheck
+	checks = append(checks, checkGit())
+	checks = append(checks, checkEditor())
+	checks = append(checks, checkConfigFile())
+	checks = append(checks, checkGroupDir())
+	checks = append(checks, checkSyncConflicts())
+	checks = append(checks, checkServer(port))
+	checks = append(checks, checkServerStates()...)
+	checks = append(checks, checkOrphanedServers()...)
+
+	var failed int
+	var fixable int
+	for _, check := range checks {
+		fmt.Fprintf(w, "%-6s %s: %s\n", "["+string(check.Status)+"]", check.Name, check.Message)
+		if check.Fix != "" {
+			fmt.Fprintf(w, "       fix: %s\n", check.Fix)
+		}
+		if check.Status != doctorOK && check.Action != nil {
+			if !fix {
+				fixable++
+			} else if err := check.Action(); err != nil {
+				fmt.Fprintf(w, "       fix failed: %v\n", err)
+			} else {
+				fmt.Fprintf(w, "       fixed\n")
+				continue
+			}
+		}
+		if check.Status == doctorFail {
+			failed++
+		}
+	}
+	if fixable > 0 {
+		fmt.Fprintf(w, "\n%d problem(s) can be fixed with: %s doctor --fix\n", fixable, GetProgramName())
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+func checkGit() doctorCheck {
+	path, err := exec.LookPath("git")
+	if err != nil {
+		return doctorCheck{
+			Name:    "git",
+			Status:  doctorWarn,
+			Message: "git not found in PATH, some git integrations may be limited",
+			Fix:     "install git from https://git-scm.com",
+		}
+	}
+	return doctorCheck{Name: "git", Status: doctorOK, Message: path}
+}
+
+func checkEditor() doctorCheck {
+	editor := getEditor("")
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return doctorCheck{Name: "editor", Status: doctorFail, Message: "empty editor command", Fix: fmt.Sprintf("%s config set editor vi", GetProgramName())}
+	}
+	path, err := exec.LookPath(fields[0])
+	if err != nil {
+		return doctorCheck{
+			Name:    "editor",
+			Status:  doctorWarn,
+			Message: fmt.Sprintf("%q not found in PATH", fields[0]),
+			Fix:     fmt.Sprintf("%s config set editor <editor>, or set $EDITOR", GetProgramName()),
+		}
+	}
+	return doctorCheck{Name: "editor", Status: doctorOK, Message: fmt.Sprintf("%s (%s)", editor, path)}
+}
+
+func checkConfigFile() doctorCheck {
+	configFile, err := getConfigPath(false, "config.json")
+	if err != nil {
+		return doctorCheck{Name: "config", Status: doctorFail, Message: err.Error()}
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return doctorCheck{Name: "config", Status: doctorOK, Message: "no config.json, using defaults"}
+		}
+		return doctorCheck{Name: "config", Status: doctorFail, Message: err.Error()}
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return doctorCheck{
+			Name:    "config",
+			Status:  doctorFail,
+			Message: fmt.Sprintf("%s is not valid: %v", configFile, err),
+			Fix:     fmt.Sprintf("%s config", GetProgramName()),
+		}
+	}
+
+	problems, _ := validateConfigJSON(data)
+	if len(problems) > 0 {
+		return doctorCheck{
+			Name:    "config",
+			Status:  doctorWarn,
+			Message: strings.Join(problems, "; "),
+			Fix:     fmt.Sprintf("%s config list, then %s config set/unset KEY", GetProgramName(), GetProgramName()),
+		}
+	}
+	return doctorCheck{Name: "config", Status: doctorOK, Message: configFile}
+}
+
+func checkGroupDir() doctorCheck {
+	groupDir, err := getGroupConfigPath(false)
+	if err != nil {
+		return doctorCheck{Name: "group dir", Status: doctorFail, Message: err.Error()}
+	}
+	stat, err := os.Stat(groupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return doctorCheck{Name: "group dir", Status: doctorOK, Message: groupDir + " does not exist yet, it is created on first edit"}
+		}
+		return doctorCheck{Name: "group dir", Status: doctorFail, Message: err.Error()}
+	}
+	if !stat.IsDir() {
+		return doctorCheck{Name: "group dir", Status: doctorFail, Message: groupDir + " is not a directory", Fix: "move the file away: mv " + groupDir + " " + groupDir + ".bak"}
+	}
+	probe, err := os.CreateTemp(groupDir, ".doctor-*")
+	if err != nil {
+		return doctorCheck{Name: "group dir", Status: doctorFail, Message: groupDir + " is not writable: " + err.Error(), Fix: "chmod u+rwx " + groupDir}
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return doctorCheck{Name: "group dir", Status: doctorOK, Message: groupDir}
+}
+
+func checkServer(port int) doctorCheck {
+	name := fmt.Sprintf("server :%d", port)
+	config := readServerConfig()
+	addr := getServerAddr(config.ServerBind, port)
+	serverMode := config.Mode == ModeServer
+
+	if !isAddrReachable(addr) {
+		if serverMode {
+			return doctorCheck{Name: name, Status: doctorWarn, Message: "mode is server but no server is running", Fix: fmt.Sprintf("%s serve --port %d", GetProgramName(), port)}
+		}
+		return doctorCheck{Name: name, Status: doctorOK, Message: "not running, not needed in native mode"}
+	}
+	ours, serverVersion := pingServer(addr)
+	if !ours {
+		return doctorCheck{
+			Name:    name,
+			Status:  doctorFail,
+			Message: "port is used by another process",
+			Fix:     fmt.Sprintf("stop that process, or use another port: %s serve --port PORT", GetProgramName()),
+		}
+	}
+	if !hasLiveServerState(port) {
+		return doctorCheck{
+			Name:    name,
+			Status:  doctorWarn,
+			Message: "running but not recorded in the servers dir, likely started by an older binary",
+			Fix:     fmt.Sprintf("%s serve --kill --port %d, then start it again", GetProgramName(), port),
+			Action:  func() error { return killServerAt(addr) },
+		}
+	}
+	if isVersionMismatch(serverVersion) {
+		return doctorCheck{
+			Name:    name,
+			Status:  doctorWarn,
+			Message: fmt.Sprintf("running version %s, but this is %s", serverVersion, getVersion()),
+			Fix:     fmt.Sprintf("%s serve --kill --port %d, then start it again", GetProgramName(), port),
+			Action:  func() error { return killServerAt(addr) },
+		}
+	}
+	return doctorCheck{Name: name, Status: doctorOK, Message: "running " + getVersion()}
+}
+
+// hasLiveServerState tells whether a running process recorded a state for port
+func hasLiveServerState(port int) bool {
+	states, _ := listServerStates()
+	for _, state := range states {
+		if state.Port == port && isProcessAlive(state.PID) {
+			return true
+		}
+	}
+	return false
+}
+
+// killServerAt asks the server at addr to shut down
+func killServerAt(addr string) error {
+	client := newServerClient(readServerConfig(), 2*time.Second)
+	resp, err := client.Get(fmt.Sprintf("http://%s/kill", addr))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kill %s: %s", addr, resp.Status)
+	}
+	return nil
+}
+
+// killProcess kills the process pid
+func killProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
+
+// pingServer checks if the server at addr answers /ping like ours,
+// and returns its version, empty for servers older than the version command
+func pingServer(addr string) (bool, string) {
+	client := newServerClient(readServerConfig(), time.Second)
+	resp, err := client.Get(fmt.Sprintf("http://%s/ping", addr))
+	if err != nil {
+		return false, ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return false, ""
+	}
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != pingResponse {
+		return false, ""
+	}
+	return true, resp.Header.Get(versionHeader)
+}
+
+func checkServerStates() []doctorCheck {
+	states, err := listServerStates()
+	if err != nil {
+		return []doctorCheck{{Name: "server state", Status: doctorWarn, Message: err.Error()}}
+	}
+	var checks []doctorCheck
+	for _, state := range states {
+		name := fmt.Sprintf("server state :%d", state.Port)
+		if state.PID == 0 {
+			checks = append(checks, doctorCheck{Name: name, Status: doctorWarn, Message: "unreadable state file " + state.File, Fix: "rm " + state.File})
+			continue
+		}
+		if !isProcessAlive(state.PID) {
+			file := state.File
+			checks = append(checks, doctorCheck{
+				Name:    name,
+				Status:  doctorWarn,
+				Message: fmt.Sprintf("stale state file, pid %d is not running", state.PID),
+				Fix:     "rm " + file,
+				Action:  func() error { return os.Remove(file) },
+			})
+			continue
+		}
+		if outdated, modTime := isServerOutdated(state); outdated {
+			addr := getServerAddr(readServerConfig().ServerBind, state.Port)
+			checks = append(checks, doctorCheck{
+				Name:    name,
+				Status:  doctorWarn,
+				Message: fmt.Sprintf("pid %d runs a binary older than this one, updated at %s", state.PID, modTime.Format(time.DateTime)),
+				Fix:     fmt.Sprintf("%s serve --kill --port %d, then start it again", GetProgramName(), state.Port),
+				Action:  func() error { return killServerAt(addr) },
+			})
+			continue
+		}
+		checks = append(checks, doctorCheck{Name: name, Status: doctorOK, Message: fmt.Sprintf("pid %d since %s", state.PID, state.StartedAt.Format(time.DateTime))})
+	}
+	return checks
+}
+
+// isServerOutdated tells whether the binary of the server in state was
+// updated since it started, returning the time of the current binary
+func isServerOutdated(state serverState) (bool, time.Time) {
+	if state.ExecutableModTime.IsZero() {
+		return false, time.Time{}
+	}
+	_, modTime := getExecutableModTime()
+	if state.Executable != "" {
+		if stat, err := os.Stat(state.Executable); err == nil && stat.ModTime().After(modTime) {
+			modTime = stat.ModTime()
+		}
+	}
+	return modTime.After(state.ExecutableModTime), modTime
+}
+
+// checkOrphanedServers finds serve processes not recorded in the servers dir,
+// which no client can reach or stop by port
+func checkOrphanedServers() []doctorCheck {
+	pids, err := listServeProcesses()
+	if err != nil {
+		return []doctorCheck{{Name: "server processes", Status: doctorWarn, Message: err.Error()}}
+	}
+	recorded := make(map[int]bool)
+	states, _ := listServerStates()
+	for _, state := range states {
+		recorded[state.PID] = true
+	}
+	var checks []doctorCheck
+	for _, pid := range pids {
+		if pid == os.Getpid() || recorded[pid] {
+			continue
+		}
+		checks = append(checks, doctorCheck{
+			Name:    fmt.Sprintf("server process %d", pid),
+			Status:  doctorWarn,
+			Message: "orphaned serve process, not recorded in the servers dir",
+			Fix:     fmt.Sprintf("kill %d", pid),
+			Action:  func() error { return killProcess(pid) },
+		})
+	}
+	return checks
+}
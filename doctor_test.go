@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckConfigFile(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	configFile := filepath.Join(configDir, "config.json")
+
+	if check := checkConfigFile(); check.Status != doctorOK {
+		t.Errorf("Expected ok without config.json, got %+v", check)
+	}
+
+	if err := os.WriteFile(configFile, []byte(`{"mode": "remote", "colour": "red"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check := checkConfigFile()
+	if check.Status != doctorWarn {
+		t.Fatalf("Expected warn, got %+v", check)
+	}
+	expected := "unknown key: colour; invalid mode: remote, expect one of native, server"
+	if check.Message != expected {
+		t.Errorf("Expected message %q, got %q", expected, check.Message)
+	}
+
+	if err := os.WriteFile(configFile, []byte(`{"mode": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if check := checkConfigFile(); check.Status != doctorFail {
+		t.Errorf("Expected fail for malformed json, got %+v", check)
+	}
+}
+
+func TestCheckServerStatesStale(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+
+	if err := writeServerState(7654, ""); err != nil {
+		t.Fatal(err)
+	}
+	checks := checkServerStates()
+	if len(checks) != 1 || checks[0].Status != doctorOK {
+		t.Errorf("Expected the current process to be alive, got %+v", checks)
+	}
+
+	staleFile := filepath.Join(configDir, "servers", "7655.json")
+	if err := os.WriteFile(staleFile, []byte(`{"pid": 999999999, "port": 7655}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checks = checkServerStates()
+	if len(checks) != 2 || checks[1].Status != doctorWarn || checks[1].Fix != "rm "+staleFile {
+		t.Errorf("Expected stale state warning, got %+v", checks)
+	}
+
+	removeServerState(7654)
+	if _, err := os.Stat(filepath.Join(configDir, "servers", "7654.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected state file to be removed, got %v", err)
+	}
+}
+
+func TestCheckServerStatesOutdated(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+
+	state := serverState{PID: os.Getpid(), Port: 7654}
+	state.Executable, state.ExecutableModTime = getExecutableModTime()
+	modTime := state.ExecutableModTime
+	if outdated, _ := isServerOutdated(state); outdated {
+		t.Errorf("Expected a server running the current binary to be up to date")
+	}
+	state.ExecutableModTime = time.Time{}
+	if outdated, _ := isServerOutdated(state); outdated {
+		t.Errorf("Expected a state without binary time to be up to date")
+	}
+	state.ExecutableModTime = modTime.Add(-time.Hour)
+	if outdated, _ := isServerOutdated(state); !outdated {
+		t.Errorf("Expected a server older than the binary to be outdated")
+	}
+}
+
+func TestIsServeCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"/usr/bin/whats_next", "serve"}, true},
+		{[]string{"whats_next", "--config-dir", "/tmp/x", "--debug", "serve", "--port", "7655"}, true},
+		{[]string{"whats_next", "serve", "--kill"}, false},
+		{[]string{"whats_next", "serve", "--tmux"}, false},
+		{[]string{"whats_next", "status"}, false},
+		{[]string{"vim", "serve"}, false},
+	}
+	for _, tt := range tests {
+		if got := isServeCommand(tt.args); got != tt.want {
+			t.Errorf("isServeCommand(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/xgo/support/cmd"
+	"golang.org/x/term"
+)
+
+// getEditor returns the editor command to use, in order of preference:
+// the --editor flag, Config.Editor (project config first), $VISUAL, $EDITOR, and finally
+// a platform default (notepad on windows, vi elsewhere)
+func getEditor(editor string) string {
+	if editor := getConfiguredEditor(editor); editor != "" {
+		return editor
+	}
+	return defaultEditor()
+}
+
+// getConfiguredEditor is getEditor without the platform default,
+// empty if the user chose no editor
+func getConfiguredEditor(editor string) string {
+	if editor != "" {
+		return editor
+	}
+	wd, _ := os.Getwd()
+	config, err := readEffectiveConfig(wd)
+	if err == nil && config.Editor != "" {
+		return config.Editor
+	}
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(env)); editor != "" {
+			return editor
+		}
+	}
+	return ""
+}
+
+func defaultEditor() string {
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// openInEditor opens file with the editor chosen by getEditor.
+// The editor may carry arguments, like "code --wait".
+func openInEditor(editor string, file string) error {
+	return openInEditorAt(editor, file, 0)
+}
+
+// openInEditorAt is like openInEditor, placing the cursor at line if it is positive
+func openInEditorAt(editor string, file string, line int) error {
+	editor = getEditor(editor)
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return fmt.Errorf("empty editor command")
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return fmt.Errorf("editor %q not found in PATH, choose one with --editor, `%s config set editor EDITOR` or $EDITOR", fields[0], GetProgramName())
+	}
+	args := append(fields[1:], editorFileArgs(fields[0], file, line)...)
+	// terminal editors like vi need stdin
+	return cmd.Debug().Stdin(os.Stdin).Run(fields[0], args...)
+}
+
+// editFile opens file at line like openInEditorAt. If the editor is not
+// installed, like `code` over SSH on a bare server, the file is edited full
+// screen in the terminal instead of failing.
+func editFile(editor string, file string, line int) error {
+	fields := strings.Fields(getEditor(editor))
+	if len(fields) > 0 && !isEditorInstalled(fields[0]) && term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintf(os.Stderr, "editor %q not found in PATH, editing in the terminal\n", fields[0])
+		_, err := editFileInTerminal(file, line)
+		return err
+	}
+	return openInEditorAt(editor, file, line)
+}
+
+func isEditorInstalled(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// editorFileArgs returns the arguments opening file at line with editor:
+// "+LINE FILE" for terminal editors, "--goto FILE:LINE" for VS Code and its forks.
+// Editors with an unknown syntax just open the file.
+func editorFileArgs(editor string, file string, line int) []string {
+	if line <= 0 {
+		return []string{file}
+	}
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(editor)), ".exe")
+	switch name {
+	case "vi", "vim", "nvim", "gvim", "mvim", "nano", "emacs", "emacsclient", "kak":
+		return []string{"+" + strconv.Itoa(line), file}
+	case "code", "code-insiders", "codium", "cursor", "windsurf":
+		return []string{"--goto", file + ":" + strconv.Itoa(line)}
+	case "subl", "zed", "hx", "helix", "micro":
+		return []string{file + ":" + strconv.Itoa(line)}
+	}
+	return []string{file}
+}
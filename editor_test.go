@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetEditorFallback(t *testing.T) {
+	// isolate from the user's config.json
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	if editor := getEditor(""); editor != defaultEditor() {
+		t.Errorf("Expected default editor %q, got %q", defaultEditor(), editor)
+	}
+
+	t.Setenv("EDITOR", "nano")
+	if editor := getEditor(""); editor != "nano" {
+		t.Errorf("Expected $EDITOR nano, got %q", editor)
+	}
+
+	t.Setenv("VISUAL", "code --wait")
+	if editor := getEditor(""); editor != "code --wait" {
+		t.Errorf("Expected $VISUAL to win over $EDITOR, got %q", editor)
+	}
+
+	if editor := getEditor("emacs"); editor != "emacs" {
+		t.Errorf("Expected explicit editor emacs, got %q", editor)
+	}
+}
+
+func TestOpenInEditorMissingBinary(t *testing.T) {
+	err := openInEditor("whats-next-no-such-editor", "file.md")
+	if err == nil {
+		t.Fatal("Expected error for missing editor")
+	}
+}
+
+func TestEditorFileArgs(t *testing.T) {
+	tests := []struct {
+		editor   string
+		line     int
+		expected string
+	}{
+		{"vim", 12, "+12 p.md"},
+		{"/usr/local/bin/nvim", 12, "+12 p.md"},
+		{"code", 12, "--goto p.md:12"},
+		{"Cursor.exe", 3, "--goto p.md:3"},
+		{"hx", 3, "p.md:3"},
+		{"ed", 3, "p.md"},
+		{"vim", 0, "p.md"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(editorFileArgs(tt.editor, "p.md", tt.line), " "); got != tt.expected {
+			t.Errorf("editorFileArgs(%q, %d) = %q, expected %q", tt.editor, tt.line, got, tt.expected)
+		}
+	}
+}
+
+func TestIsEditorInstalled(t *testing.T) {
+	if isEditorInstalled("whats-next-no-such-editor") {
+		t.Error("Expected a missing editor not to be installed")
+	}
+	if !isEditorInstalled("go") {
+		t.Error("Expected go to be installed")
+	}
+}
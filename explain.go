@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// sectionExplanation is why a section of a profile is emitted for a directory or not
+type sectionExplanation struct {
+	Section sections.Section
+	// Matched is whether the directives of the section match the directory and agent
+	Matched bool
+	// Included is whether the section survives sections.SelectMostSpecific
+	Included    bool
+	MatchReason sections.MatchReason
+	ProjectPath string
+	Specificity int
+	Priority    int
+}
+
+// explainSections matches each section of content against dir like sections.Filter,
+// keeping the sections that are filtered out. (show: ...) directives are not applied.
+func explainSections(content string, dir string, isCursor bool) []sectionExplanation {
+	parsed := parseSections(content)
+	matchHeadings := sections.InheritDirectives(parsed)
+
+	explanations := make([]sectionExplanation, len(parsed))
+	var matches []sections.SectionMatch
+	for i, result := range sections.MatchHeadings(matchHeadings, dir, matchConfig(dir, isCursor)) {
+		explanations[i] = sectionExplanation{
+			Section:     parsed[i],
+			Matched:     result.Include,
+			MatchReason: result.MatchReason,
+			ProjectPath: result.ProjectPath,
+			Specificity: result.Specificity,
+			Priority:    sections.Priority(matchHeadings[i]),
+		}
+		if result.Include {
+			matches = append(matches, sections.SectionMatch{
+				Section:     parsed[i],
+				MatchReason: result.MatchReason,
+				ProjectPath: result.ProjectPath,
+				Specificity: result.Specificity,
+				Heading:     matchHeadings[i],
+			})
+		}
+	}
+	// sections.SelectMostSpecific identifies sections by title and content too
+	for _, match := range sections.SelectMostSpecific(matches) {
+		for i := range explanations {
+			e := &explanations[i]
+			if e.Matched && e.Section.Title == match.Section.Title && e.Section.Content == match.Section.Content {
+				e.Included = true
+			}
+		}
+	}
+	return explanations
+}
+
+// describe returns the annotation of the section, e.g. "included: path, specificity 3, project /src/api"
+func (e sectionExplanation) describe() string {
+	if !e.Matched {
+		return "excluded"
+	}
+	details := []string{e.MatchReason.String()}
+	if e.MatchReason != sections.MatchReasonNoProject {
+		details = append(details, fmt.Sprintf("specificity %d", e.Specificity))
+	}
+	if e.ProjectPath != "" {
+		details = append(details, "project "+e.ProjectPath)
+	}
+	if e.Priority != 0 {
+		details = append(details, fmt.Sprintf("priority %d", e.Priority))
+	}
+	status := "included"
+	if !e.Included {
+		status = "dropped, a more specific project matches"
+	}
+	return status + ": " + strings.Join(details, ", ")
+}
+
+// writeExplanation writes the sections of content, each heading followed by
+// an HTML comment telling whether and why it is emitted in dir
+func writeExplanation(w io.Writer, content string, dir string, isCursor bool) {
+	for _, e := range explainSections(content, dir, isCursor) {
+		if e.Section.Title != "" {
+			fmt.Fprintln(w, e.Section.Title)
+		}
+		fmt.Fprintf(w, "<!-- explain: %s -->\n", e.describe())
+		if e.Section.Content != "" {
+			fmt.Fprintln(w, e.Section.Content)
+		}
+	}
+}
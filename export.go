@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+const exportHelp = `
+Usage:
+  whats_next export cursor [PROFILE] [--dir DIR]
+
+Converts the sections of a profile into DIR/.cursor/rules/*.mdc files:
+sections applying to DIR as a whole are always applied, sections of a
+(project: ...) under DIR get globs matching the files of that directory.
+Re-running the export replaces the files generated before.
+
+PROFILE defaults to the selected profile.
+
+Options:
+  --dir DIR  The repository to export to (default: current directory)
+`
+
+// cursorRulesDir is where cursor reads project rules from
+const cursorRulesDir = ".cursor/rules"
+
+// cursorRule is a section converted to a .cursor/rules/*.mdc file
+type cursorRule struct {
+	Name        string
+	Description string
+	Globs       []string
+	AlwaysApply bool
+	Content     string
+}
+
+func handleExport(args []string) error {
+	var dir string
+	args, err := flags.String("--dir", &dir).Help("-h,--help", exportHelp).Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] != "cursor" {
+		return fmt.Errorf("requires format: cursor")
+	}
+	args = args[1:]
+	if len(args) > 1 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args[1:], " "))
+	}
+	if dir == "" {
+		dir, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	} else {
+		config, err := readEffectiveConfig(dir)
+		if err != nil {
+			return err
+		}
+		name = config.SelectedProfile
+		if name == "" {
+			return fmt.Errorf("no profile selected, requires PROFILE or `%s use PROFILE`", GetProgramName())
+		}
+	}
+	name = strings.TrimSuffix(name, ".md")
+
+	groupDir, err := getGroupConfigPath(false)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(filepath.Join(groupDir, addMDSuffix(name)))
+	if err != nil {
+		return err
+	}
+	profile := expandIncludes(string(content), filepath.Dir(groupDir))
+	rules := buildCursorRules(profile, dir, name)
+	return writeCursorRules(dir, name, rules)
+}
+
+// buildCursorRules converts the sections of profile applying to dir, or to
+// a directory under it, into cursor rules named after the profile
+func buildCursorRules(profile string, dir string, profileName string) []cursorRule {
+	parsed := parseSections(profile)
+	headings := sections.InheritDirectives(parsed)
+	data := newTemplateData(dir, profileName)
+
+	var rules []cursorRule
+	used := make(map[string]bool)
+	for i, section := range parsed {
+		content := strings.TrimSpace(section.Content)
+		if content == "" {
+			continue
+		}
+		globs, ok := cursorRuleGlobs(headings[i], dir)
+		if !ok {
+			continue
+		}
+		title := sections.HeadingTitle(section.Title)
+		ruleName := profileName + "-" + slugify(title)
+		for n := 2; used[ruleName]; n++ {
+			ruleName = fmt.Sprintf("%s-%s-%d", profileName, slugify(title), n)
+		}
+		used[ruleName] = true
+
+		content = replaceWhatsNextWithProgramName(expandTemplates(content, data))
+		rules = append(rules, cursorRule{
+			Name:        ruleName,
+			Description: title,
+			Globs:       globs,
+			AlwaysApply: len(globs) == 0,
+			Content:     content,
+		})
+	}
+	return rules
+}
+
+// cursorRuleGlobs decides how a section with the matching heading applies to the
+// repository dir: nil globs if it applies to all of dir, globs relative to dir if it
+// applies to a (project: ...) under dir, and false if it does not apply at all
+func cursorRuleGlobs(heading string, dir string) ([]string, bool) {
+	heading = sections.ApplyCommentDirectives(heading)
+	project, hasProject := sections.DirectiveValue(heading, "project")
+	cfg := matchConfig(dir, true)
+	if !sections.MatchHeading(sections.StripDirectives(heading, []string{"project"}), dir, cfg).Include {
+		return nil, false
+	}
+	if !hasProject {
+		return nil, true
+	}
+	if sections.MatchHeading(heading, dir, cfg).Include {
+		return nil, true
+	}
+
+	projectPath, subPath, ok := sections.ExpandProjectPath(project, cfg.Aliases)
+	if !ok {
+		return nil, false
+	}
+	if !filepath.IsAbs(projectPath) {
+		projectPath = filepath.Join(dir, projectPath)
+	}
+	projectPath = filepath.Clean(projectPath)
+
+	// a monorepo subpath of this repository or of a worktree of it
+	if subPath != "" {
+		if projectPath == dir || sections.IsGitWorktree(dir, projectPath, cfg) {
+			return []string{filepath.ToSlash(subPath) + "/**"}, true
+		}
+		return nil, false
+	}
+	rel, err := filepath.Rel(dir, projectPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, false
+	}
+	return []string{filepath.ToSlash(rel) + "/**"}, true
+}
+
+// cursorRuleMarker marks the rules generated by export, which are
+// replaced or removed by the next export of the same profile
+func cursorRuleMarker(profileName string) string {
+	return fmt.Sprintf("<!-- generated by `whats_next export cursor %s`, edit the profile instead -->", profileName)
+}
+
+// formatCursorRule renders rule as a .mdc file
+func formatCursorRule(rule cursorRule, profileName string) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "description: %s\n", yamlString(rule.Description))
+	if len(rule.Globs) > 0 {
+		fmt.Fprintf(&b, "globs: %s\n", strings.Join(rule.Globs, ","))
+	}
+	fmt.Fprintf(&b, "alwaysApply: %t\n", rule.AlwaysApply)
+	b.WriteString("---\n")
+	b.WriteString(cursorRuleMarker(profileName))
+	b.WriteString("\n\n")
+	b.WriteString(rule.Content)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// writeCursorRules writes rules to dir/.cursor/rules, removing the
+// files of previous exports of the profile that are no longer generated
+func writeCursorRules(dir string, profileName string, rules []cursorRule) error {
+	rulesDir := filepath.Join(dir, filepath.FromSlash(cursorRulesDir))
+	if err := os.MkdirAll(rulesDir, 0755); err != nil {
+		return err
+	}
+	written := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		file := filepath.Join(rulesDir, rule.Name+".mdc")
+		if err := os.WriteFile(file, []byte(formatCursorRule(rule, profileName)), 0644); err != nil {
+			return err
+		}
+		written[file] = true
+		fmt.Printf("wrote %s\n", file)
+	}
+
+	previous, err := filepath.Glob(filepath.Join(rulesDir, profileName+"-*.mdc"))
+	if err != nil {
+		return err
+	}
+	marker := cursorRuleMarker(profileName)
+	for _, file := range previous {
+		if written[file] {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil || !strings.Contains(string(content), marker) {
+			// not generated by us
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		fmt.Printf("removed %s\n", file)
+	}
+	if len(rules) == 0 {
+		fmt.Printf("no section of %s applies to %s\n", profileName, dir)
+	}
+	return nil
+}
+
+// slugify turns a title into a file name like "build-rules"
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "section"
+	}
+	return slug
+}
+
+// yamlString quotes s if it cannot be written as a plain YAML scalar
+func yamlString(s string) string {
+	if s == "" || strings.ContainsAny(s, ":#'\"[]{},&*!|>%@`") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildCursorRules(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	repo := t.TempDir()
+	other := t.TempDir()
+
+	profile := strings.Join([]string{
+		"# General",
+		"be concise",
+		"# API rules (project: " + filepath.Join(repo, "services/api") + ")",
+		"use the api client",
+		"# Repo rules <!-- project: " + repo + " -->",
+		"run the tests",
+		"# Other (project: " + other + ")",
+		"not exported",
+		"# Claude only (agents: claude)",
+		"not exported",
+	}, "\n")
+	rules := buildCursorRules(profile, repo, "work")
+
+	var got []string
+	for _, rule := range rules {
+		got = append(got, rule.Name+"|"+strings.Join(rule.Globs, ",")+"|"+rule.Content)
+	}
+	expected := []string{
+		"work-general||be concise",
+		"work-api-rules|services/api/**|use the api client",
+		"work-repo-rules||run the tests",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected rules:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestWriteCursorRulesRemovesStale(t *testing.T) {
+	repo := t.TempDir()
+	rulesDir := filepath.Join(repo, ".cursor", "rules")
+	if err := writeCursorRules(repo, "work", []cursorRule{{Name: "work-a", Description: "A: first", AlwaysApply: true, Content: "a"}, {Name: "work-b", Content: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	handWritten := filepath.Join(rulesDir, "work-mine.mdc")
+	if err := os.WriteFile(handWritten, []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCursorRules(repo, "work", []cursorRule{{Name: "work-a", Description: "A: first", AlwaysApply: true, Content: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(rulesDir, "work-a.mdc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "---\ndescription: \"A: first\"\nalwaysApply: true\n---\n") {
+		t.Errorf("Unexpected rule file:\n%s", content)
+	}
+	if _, err := os.Stat(filepath.Join(rulesDir, "work-b.mdc")); !os.IsNotExist(err) {
+		t.Errorf("Expected stale rule to be removed, got: %v", err)
+	}
+	if _, err := os.Stat(handWritten); err != nil {
+		t.Errorf("Expected hand written rule to be kept: %v", err)
+	}
+}
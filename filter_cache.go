@@ -0,0 +1,36 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// filterCache memoizes the section matching for the lifetime of a serve process,
+// where the same profile is matched against the same directories on every answer.
+// It is disabled for one-shot commands.
+var filterCache struct {
+	mu    sync.Mutex
+	cache *sections.Cache
+}
+
+// enableFilterCache turns on the memoization of section matching
+func enableFilterCache() {
+	filterCache.mu.Lock()
+	defer filterCache.mu.Unlock()
+	filterCache.cache = sections.NewCache()
+}
+
+// invalidateFilterCache drops the memoized matches, called when a profile is reloaded
+func invalidateFilterCache() {
+	if cache := getFilterCache(); cache != nil {
+		cache.Invalidate()
+	}
+}
+
+// getFilterCache returns the filter cache, nil if it is disabled
+func getFilterCache() *sections.Cache {
+	filterCache.mu.Lock()
+	defer filterCache.mu.Unlock()
+	return filterCache.cache
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+func useFilterCache(t testing.TB) {
+	enableFilterCache()
+	t.Cleanup(func() {
+		filterCache.mu.Lock()
+		filterCache.cache = nil
+		filterCache.mu.Unlock()
+	})
+}
+
+func TestFilterCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if matchConfig(t.TempDir(), false).Cache != nil {
+		t.Fatalf("expected no filter cache before it is enabled")
+	}
+	useFilterCache(t)
+	dir := t.TempDir()
+	cfg := matchConfig(dir, false)
+	if cfg.Cache == nil {
+		t.Fatalf("expected the filter cache once enabled")
+	}
+	content := fmt.Sprintf("# Project (project: %s)\nproject rules\n", dir)
+	if got := sections.Filter(content, dir, cfg); !strings.Contains(got, "project rules") {
+		t.Errorf("unexpected content:\n%s", got)
+	}
+}
+
+// benchmarkProfile returns a profile of n project sections, one of them matching dir
+func benchmarkProfile(n int, dir string) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		project := filepath.Join(filepath.Dir(dir), fmt.Sprintf("project-%d", i))
+		if i == n/2 {
+			project = dir
+		}
+		fmt.Fprintf(&b, "# Section %d (project: %s)\n- rule %d\n\n## Details\n- detail %d\n", i, project, i, i)
+	}
+	return b.String()
+}
+
+func BenchmarkFilter(b *testing.B) {
+	b.Setenv("HOME", b.TempDir())
+	dir := b.TempDir()
+	content := benchmarkProfile(300, dir)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sections.Filter(content, dir, matchConfig(dir, false))
+	}
+}
+
+func BenchmarkFilterCached(b *testing.B) {
+	b.Setenv("HOME", b.TempDir())
+	useFilterCache(b)
+	dir := b.TempDir()
+	content := benchmarkProfile(300, dir)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sections.Filter(content, dir, matchConfig(dir, false))
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+// githubAPIURL is the base of the GitHub API, replaced in tests
+var githubAPIURL = "https://api.github.com"
+
+// gistGroupPrefix prefixes group files in the gist, which has no directories
+const gistGroupPrefix = "group--"
+
+const backupHelp = `
+Usage:
+  whats_next backup gist [--token TOKEN]
+
+Uploads the group files and custom.md to a private GitHub gist, created on the
+first backup and updated afterwards. Its id is saved as gistId in config.json.
+
+The token needs the gist scope, it defaults to gistToken in config.json,
+which may be a secret reference like "keychain:github", then $GITHUB_TOKEN.
+
+Options:
+  --token TOKEN  The GitHub token
+`
+
+const restoreHelp = `
+Usage:
+  whats_next restore gist [--token TOKEN] [--id GIST_ID] [--force]
+
+Downloads the group files and custom.md from the gist of backup gist.
+Existing files with different content are kept unless --force is given,
+then they are saved as FILE.bak first.
+
+Options:
+  --token TOKEN    The GitHub token, see backup gist
+  --id GIST_ID     The gist to restore from (default: gistId in config.json)
+  --force          Overwrite existing files
+`
+
+func handleBackup(args []string) error {
+	var token string
+	args, err := flags.String("--token", &token).Help("-h,--help", backupHelp).Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || args[0] != "gist" {
+		return fmt.Errorf("requires target: gist")
+	}
+	config, err := readConfig()
+	if err != nil {
+		return err
+	}
+	token, err = resolveGistToken(token, config)
+	if err != nil {
+		return err
+	}
+	files, err := collectBackupFiles()
+	if err != nil {
+		return err
+	}
+	id, err := backupToGist(token, config.GistID, files)
+	if err != nil {
+		return err
+	}
+	if id != config.GistID {
+		unlock, err := lockConfigDir()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		if err := saveGistID(id); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Backed up %d file(s) to gist %s\n", len(files), id)
+	return nil
+}
+
+func handleRestore(args []string) error {
+	var token string
+	var id string
+	var force bool
+	args, err := flags.String("--token", &token).
+		String("--id", &id).
+		Bool("--force", &force).
+		Help("-h,--help", restoreHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || args[0] != "gist" {
+		return fmt.Errorf("requires source: gist")
+	}
+	config, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if id == "" {
+		id = config.GistID
+	}
+	if id == "" {
+		return fmt.Errorf("no gist to restore from, requires --id GIST_ID")
+	}
+	token, err = resolveGistToken(token, config)
+	if err != nil {
+		return err
+	}
+	files, err := fetchGist(token, id)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := restoreBackupFiles(files, force); err != nil {
+		return err
+	}
+	return saveGistID(id)
+}
+
+// saveGistID saves id as Config.GistID, re-reading the config
+// as it may have changed during the network call.
+// The caller holds the config dir lock.
+func saveGistID(id string) error {
+	config, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if config.GistID == id {
+		return nil
+	}
+	config.GistID = id
+	return writeConfig(config)
+}
+
+// resolveGistToken returns the --token flag, then Config.GistToken, then $GITHUB_TOKEN
+func resolveGistToken(token string, config *Config) (string, error) {
+	if token == "" {
+		token = config.GistToken
+	}
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		return "", fmt.Errorf("requires a GitHub token with the gist scope: --token, gistToken in config.json or $GITHUB_TOKEN")
+	}
+	return resolveSecret(token)
+}
+
+// collectBackupFiles returns the group files and custom.md keyed by their gist file names
+func collectBackupFiles() (map[string]string, error) {
+	files := make(map[string]string)
+	groupDir, err := getGroupConfigPath(false)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(groupDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		// skip backups like work.md.bak
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(groupDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[gistGroupPrefix+entry.Name()] = string(content)
+	}
+	customFile, err := getCustomFile(false)
+	if err != nil {
+		return nil, err
+	}
+	if content, err := os.ReadFile(customFile); err == nil {
+		files["custom.md"] = string(content)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return files, nil
+}
+
+// gistFilePath returns the local path of a gist file name, false for files not from a backup
+func gistFilePath(name string) (string, bool, error) {
+	if name == "custom.md" {
+		file, err := getCustomFile(true)
+		return file, true, err
+	}
+	groupName, ok := strings.CutPrefix(name, gistGroupPrefix)
+	if !ok || groupName == "" || strings.ContainsAny(groupName, `/\`) {
+		return "", false, nil
+	}
+	groupDir, err := getGroupConfigPath(true)
+	if err != nil {
+		return "", false, err
+	}
+	return filepath.Join(groupDir, groupName), true, nil
+}
+
+// restoreBackupFiles writes files fetched from a gist to the config dir
+func restoreBackupFiles(files map[string]string, force bool) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var skipped int
+	for _, name := range names {
+		file, ok, err := gistFilePath(name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		content := files[name]
+		existing, err := os.ReadFile(file)
+		if err == nil && string(existing) != content {
+			if !force {
+				fmt.Printf("skipped %s, it differs from the backup (use --force)\n", file)
+				skipped++
+				continue
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		// the changed file is kept in file.bak
+		if err := writeFileAtomic(file, []byte(content), 0644); err != nil {
+			return err
+		}
+		recordAudit(auditActionRestore, strings.TrimPrefix(name, gistGroupPrefix), "restore gist", string(existing), content)
+		fmt.Printf("restored %s\n", file)
+	}
+	if skipped > 0 {
+		return fmt.Errorf("%d file(s) not restored", skipped)
+	}
+	return nil
+}
+
+type gistFile struct {
+	Content   string `json:"content,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	RawURL    string `json:"raw_url,omitempty"`
+}
+
+type gist struct {
+	ID          string               `json:"id,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Public      bool                 `json:"public"`
+	Files       map[string]*gistFile `json:"files"`
+}
+
+// backupToGist creates a private gist with files if id is empty, otherwise
+// replaces the files of the gist, deleting the ones no longer present.
+// It returns the id of the gist.
+func backupToGist(token string, id string, files map[string]string) (string, error) {
+	if len(files) == 0 {
+		return "", fmt.Errorf("nothing to back up")
+	}
+	body := map[string]interface{}{"description": "whats_next profiles"}
+	gistFiles := make(map[string]*gistFile, len(files))
+	for name, content := range files {
+		if strings.TrimSpace(content) == "" {
+			// gists reject empty files
+			content = "\n<!-- empty -->\n"
+		}
+		gistFiles[name] = &gistFile{Content: content}
+	}
+
+	method, path := http.MethodPost, "/gists"
+	if id != "" {
+		existing, err := fetchGist(token, id)
+		if err != nil {
+			return "", err
+		}
+		for name := range existing {
+			if _, ok := gistFiles[name]; !ok {
+				// a null file deletes it from the gist
+				gistFiles[name] = nil
+			}
+		}
+		method, path = http.MethodPatch, "/gists/"+id
+	} else {
+		body["public"] = false
+	}
+	body["files"] = gistFiles
+
+	var result gist
+	if err := githubRequest(token, method, path, body, &result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+// fetchGist returns the files of gist id by name
+func fetchGist(token string, id string) (map[string]string, error) {
+	var result gist
+	if err := githubRequest(token, http.MethodGet, "/gists/"+id, nil, &result); err != nil {
+		return nil, err
+	}
+	files := make(map[string]string, len(result.Files))
+	for name, file := range result.Files {
+		if file == nil {
+			continue
+		}
+		content := file.Content
+		if file.Truncated && file.RawURL != "" {
+			raw, err := fetchRaw(token, file.RawURL)
+			if err != nil {
+				return nil, err
+			}
+			content = raw
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+var githubClient = &http.Client{Timeout: 30 * time.Second}
+
+func githubRequest(token string, method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, githubAPIURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := githubClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("github %s %s: %s %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func fetchRaw(token string, rawURL string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := githubClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("github GET %s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
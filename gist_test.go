@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeGistServer keeps a single gist in memory
+func fakeGistServer(t *testing.T) map[string]string {
+	files := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ghp_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet {
+			var body gist
+			json.NewDecoder(r.Body).Decode(&body)
+			for name, file := range body.Files {
+				if file == nil {
+					delete(files, name)
+					continue
+				}
+				files[name] = file.Content
+			}
+		}
+		result := gist{ID: "g1", Files: make(map[string]*gistFile)}
+		for name, content := range files {
+			result.Files[name] = &gistFile{Content: content}
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(server.Close)
+	old := githubAPIURL
+	githubAPIURL = server.URL
+	t.Cleanup(func() { githubAPIURL = old })
+	return files
+}
+
+func TestBackupAndRestoreGist(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	t.Setenv("GITHUB_TOKEN", "ghp_test")
+	remote := fakeGistServer(t)
+
+	groupDir := filepath.Join(configDir, "group")
+	os.MkdirAll(groupDir, 0755)
+	os.WriteFile(filepath.Join(groupDir, "work.md"), []byte("# Work"), 0644)
+	os.WriteFile(filepath.Join(groupDir, "old.md"), []byte("# Old"), 0644)
+	os.WriteFile(filepath.Join(configDir, "custom.md"), []byte("custom"), 0644)
+
+	if err := handleBackup([]string{"gist"}); err != nil {
+		t.Fatal(err)
+	}
+	config, _ := readConfig()
+	if config.GistID != "g1" || len(remote) != 3 {
+		t.Fatalf("Expected gist g1 with 3 files, got %q %v", config.GistID, remote)
+	}
+
+	os.Remove(filepath.Join(groupDir, "old.md"))
+	if err := handleBackup([]string{"gist"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := remote["group--old.md"]; ok || remote["group--work.md"] != "# Work" {
+		t.Fatalf("Expected old.md to be deleted from the gist, got %v", remote)
+	}
+
+	// restore on a new machine
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	if err := handleRestore([]string{"gist", "--id", "g1"}); err != nil {
+		t.Fatal(err)
+	}
+	groupDir, _ = getGroupConfigPath(false)
+	if content, _ := os.ReadFile(filepath.Join(groupDir, "work.md")); string(content) != "# Work" {
+		t.Errorf("Expected work.md to be restored, got %q", content)
+	}
+
+	os.WriteFile(filepath.Join(groupDir, "work.md"), []byte("# Changed"), 0644)
+	if err := handleRestore([]string{"gist"}); err == nil {
+		t.Errorf("Expected changed files not to be overwritten without --force")
+	}
+	if err := handleRestore([]string{"gist", "--force"}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(groupDir, "work.md.bak")); string(content) != "# Changed" {
+		t.Errorf("Expected a backup of the changed file, got %q", content)
+	}
+}
+
+func TestCollectBackupFilesSkipsBackups(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	groupDir := filepath.Join(configDir, "group")
+	os.MkdirAll(groupDir, 0755)
+	os.WriteFile(filepath.Join(groupDir, "work.md"), []byte("# Work"), 0644)
+	os.WriteFile(filepath.Join(groupDir, "work.md.bak"), []byte("# Old"), 0644)
+
+	files, err := collectBackupFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files["group--work.md"] != "# Work" {
+		t.Errorf("Expected only work.md, got %v", files)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// gitTimeout bounds each git lookup of project matching, so that a slow
+// network file system or a huge repository cannot stall every answer
+const gitTimeout = 5 * time.Second
+
+// gitRunner answers the git questions of project matching and templates
+type gitRunner interface {
+	// TopLevel returns the root of the checkout containing dir
+	TopLevel(dir string) (string, error)
+	// Worktrees returns the worktrees of the repository containing dir,
+	// the main worktree comes first, like `git worktree list`
+	Worktrees(dir string) ([]string, error)
+	// RemoteURLs returns the URLs of the remote name of the repository
+	// containing dir, or of all its remotes if name is empty
+	RemoteURLs(dir string, name string) ([]string, error)
+	// Branch returns the current branch, or "HEAD" if it is detached
+	Branch(dir string) (string, error)
+}
+
+// defaultGitRunner reads the repositories on disk, replaced by a fake in tests
+var defaultGitRunner gitRunner = timeoutGitRunner{runner: localGitRunner{}, timeout: gitTimeout}
+
+// getGitBranch returns the current branch of the repository containing dir,
+// or "HEAD" if it is detached
+func getGitBranch(dir string) (string, error) {
+	return defaultGitRunner.Branch(dir)
+}
+
+// localGitRunner reads the repositories on disk with go-git, without a git binary
+type localGitRunner struct{}
+
+// openGitRepo opens the git repository containing dir, including linked worktrees
+func openGitRepo(dir string) (*git.Repository, error) {
+	return git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+}
+
+func (localGitRunner) TopLevel(dir string) (string, error) {
+	root, _, err := findDotGit(dir)
+	return root, err
+}
+
+func (localGitRunner) RemoteURLs(dir string, name string) ([]string, error) {
+	repo, err := openGitRepo(dir)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		remote, err := repo.Remote(name)
+		if err != nil {
+			return nil, err
+		}
+		return remote.Config().URLs, nil
+	}
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, remote := range remotes {
+		urls = append(urls, remote.Config().URLs...)
+	}
+	return urls, nil
+}
+
+func (localGitRunner) Branch(dir string) (string, error) {
+	repo, err := openGitRepo(dir)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if !head.Name().IsBranch() {
+		return "HEAD", nil
+	}
+	return head.Name().Short(), nil
+}
+
+// findDotGit walks up from dir to find the checkout root containing a .git entry
+func findDotGit(dir string) (root string, dotGit string, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if _, err := os.Stat(dotGit); err == nil {
+			return dir, dotGit, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("not a git repository")
+		}
+		dir = parent
+	}
+}
+
+// readGitDirFile reads a file like ".git" or "gitdir" holding a path,
+// relative paths are resolved against the directory of the file
+func readGitDirFile(file string, prefix string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), prefix))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(file), path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// getGitCommonDir returns the checkout root of dir and the git dir
+// shared by all worktrees of the repository
+func getGitCommonDir(dir string) (root string, commonDir string, err error) {
+	root, dotGit, err := findDotGit(dir)
+	if err != nil {
+		return "", "", err
+	}
+	stat, err := os.Stat(dotGit)
+	if err != nil {
+		return "", "", err
+	}
+	if stat.IsDir() {
+		return root, dotGit, nil
+	}
+	// linked worktree: .git is a file containing "gitdir: <common>/worktrees/<name>"
+	gitDir, err := readGitDirFile(dotGit, "gitdir:")
+	if err != nil {
+		return "", "", err
+	}
+	commonDir, err = readGitDirFile(filepath.Join(gitDir, "commondir"), "")
+	if err != nil {
+		return "", "", err
+	}
+	return root, commonDir, nil
+}
+
+func (localGitRunner) Worktrees(dir string) ([]string, error) {
+	_, commonDir, err := getGitCommonDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	worktrees := []string{filepath.Dir(commonDir)}
+
+	entries, err := os.ReadDir(filepath.Join(commonDir, "worktrees"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return worktrees, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		// gitdir holds the path of the .git file inside the linked worktree
+		worktreeDotGit, err := readGitDirFile(filepath.Join(commonDir, "worktrees", entry.Name(), "gitdir"), "")
+		if err != nil {
+			continue
+		}
+		worktrees = append(worktrees, filepath.Dir(worktreeDotGit))
+	}
+	return worktrees, nil
+}
+
+// timeoutGitRunner fails the calls of runner taking longer than timeout,
+// the call itself keeps running in the background until it returns
+type timeoutGitRunner struct {
+	runner  gitRunner
+	timeout time.Duration
+}
+
+// runGitWithTimeout returns the result of call, or an error once timeout has passed
+func runGitWithTimeout[T any](timeout time.Duration, what string, dir string, call func() (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := call()
+		done <- result{value, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timer.C:
+		var zero T
+		Errorf("git %s of %s timed out after %v", what, dir, timeout)
+		return zero, fmt.Errorf("git %s of %s timed out after %v", what, dir, timeout)
+	}
+}
+
+func (r timeoutGitRunner) TopLevel(dir string) (string, error) {
+	return runGitWithTimeout(r.timeout, "top level", dir, func() (string, error) { return r.runner.TopLevel(dir) })
+}
+
+func (r timeoutGitRunner) Worktrees(dir string) ([]string, error) {
+	return runGitWithTimeout(r.timeout, "worktrees", dir, func() ([]string, error) { return r.runner.Worktrees(dir) })
+}
+
+func (r timeoutGitRunner) RemoteURLs(dir string, name string) ([]string, error) {
+	return runGitWithTimeout(r.timeout, "remotes", dir, func() ([]string, error) { return r.runner.RemoteURLs(dir, name) })
+}
+
+func (r timeoutGitRunner) Branch(dir string) (string, error) {
+	return runGitWithTimeout(r.timeout, "branch", dir, func() (string, error) { return r.runner.Branch(dir) })
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// fakeGitRunner answers from fixed repositories, keyed by their top level
+type fakeGitRunner struct {
+	// worktrees lists the worktrees of each repository, main worktree first
+	worktrees map[string][]string
+	remotes   map[string]map[string]string
+	branches  map[string]string
+	// delay makes every call slow, to test timeouts
+	delay time.Duration
+}
+
+func (f *fakeGitRunner) TopLevel(dir string) (string, error) {
+	time.Sleep(f.delay)
+	for _, worktrees := range f.worktrees {
+		for _, worktree := range worktrees {
+			if dir == worktree || strings.HasPrefix(dir, worktree+"/") {
+				return worktree, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("not a git repository: %s", dir)
+}
+
+func (f *fakeGitRunner) Worktrees(dir string) ([]string, error) {
+	root, err := f.TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, worktrees := range f.worktrees {
+		for _, worktree := range worktrees {
+			if worktree == root {
+				return worktrees, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("not a git repository: %s", dir)
+}
+
+func (f *fakeGitRunner) RemoteURLs(dir string, name string) ([]string, error) {
+	root, err := f.TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+	remotes := f.remotes[root]
+	if name != "" {
+		url, ok := remotes[name]
+		if !ok {
+			return nil, fmt.Errorf("remote not found: %s", name)
+		}
+		return []string{url}, nil
+	}
+	var urls []string
+	for _, url := range remotes {
+		urls = append(urls, url)
+	}
+	return urls, nil
+}
+
+func (f *fakeGitRunner) Branch(dir string) (string, error) {
+	root, err := f.TopLevel(dir)
+	if err != nil {
+		return "", err
+	}
+	return f.branches[root], nil
+}
+
+func useFakeGitRunner(t *testing.T, runner gitRunner) {
+	t.Helper()
+	saved := defaultGitRunner
+	defaultGitRunner = runner
+	t.Cleanup(func() { defaultGitRunner = saved })
+}
+
+func TestGitMatchingWithFakeRunner(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	useFakeGitRunner(t, &fakeGitRunner{
+		worktrees: map[string][]string{
+			"api":  {"/work/api", "/work/api-feature", "/tmp/api-hotfix"},
+			"fork": {"/work/api-fork"},
+			"web":  {"/work/web"},
+		},
+		remotes: map[string]map[string]string{
+			"/work/api":      {"origin": "git@github.com:acme/api.git"},
+			"/work/api-fork": {"origin": "git@github.com:me/api.git", "upstream": "https://github.com/acme/api"},
+			"/work/web":      {"origin": "git@github.com:acme/web.git"},
+		},
+		branches: map[string]string{"/work/api-feature": "feature"},
+	})
+
+	// project matching asks the default runner
+	dir := "/work/api-feature/pkg"
+	if !sections.IsGitWorktree(dir, "/work/api", matchConfig(dir, true)) {
+		t.Errorf("expected %s to match the worktrees of /work/api", dir)
+	}
+	if sections.IsGitWorktree("/work/web", "/work/api", matchConfig("/work/web", true)) {
+		t.Errorf("expected /work/web not to match /work/api")
+	}
+	if branch, err := getGitBranch("/work/api-feature"); err != nil || branch != "feature" {
+		t.Errorf("getGitBranch = %q, %v, want feature", branch, err)
+	}
+}
+
+func TestTimeoutGitRunner(t *testing.T) {
+	slow := &fakeGitRunner{
+		worktrees: map[string][]string{"api": {"/work/api"}},
+		delay:     time.Second,
+	}
+	runner := timeoutGitRunner{runner: slow, timeout: 10 * time.Millisecond}
+
+	start := time.Now()
+	_, err := runner.TopLevel("/work/api")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("timeout took %v", elapsed)
+	}
+
+	// the slow call still runs in the background, use another runner
+	runner = timeoutGitRunner{runner: &fakeGitRunner{worktrees: slow.worktrees}, timeout: time.Second}
+	root, err := runner.TopLevel("/work/api/cmd")
+	if err != nil || root != "/work/api" {
+		t.Errorf("TopLevel = %q, %v, want /work/api", root, err)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+const historyHelp = `
+Usage:
+  whats_next history [--today] [--project DIR] [--full]
+  whats_next history search TERM [--today] [--project DIR] [--full]
+  whats_next history replay ID [--print] [--port PORT]
+  whats_next history export [--since DATE] [--project DIR] [--full] [-o FILE]
+
+Lists the exchanges with agents recorded under transcripts/ in the config dir,
+oldest first: the id, the time, the project, how long the agent waited and the reply.
+
+search lists the replies containing TERM, ignoring case.
+
+replay sends a past reply again: it is queued on the running server for the
+next waiting agent, or printed when no server is running or with --print,
+e.g. to pipe it: whats_next history replay 2024-01-02.3 --print | whats_next
+
+export renders the replies as a markdown document grouped by project and day,
+e.g. to share how a feature was steered.
+
+Options:
+  --today          Only list today's exchanges
+  --project DIR    Only list exchanges of agents working in DIR or below it
+  --full           Also print what was sent to the agent, with the guidelines
+  --print          Print the reply of replay instead of queueing it
+  --port PORT      The server port of replay (default: serverPort or 7654)
+  --since DATE     Only export exchanges from DATE: YYYY-MM-DD, today or yesterday
+  -o,--out FILE    Write the export to FILE instead of stdout
+`
+
+func handleHistory(args []string) error {
+	var today bool
+	var project string
+	var full bool
+	var printOnly bool
+	var port int
+	var since string
+	var out string
+	args, err := flags.Bool("--today", &today).
+		String("--project", &project).
+		Bool("--full", &full).
+		Bool("--print", &printOnly).
+		Int("--port", &port).
+		String("--since", &since).
+		String("-o,--out", &out).
+		Help("-h,--help", historyHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	var subCommand string
+	if len(args) > 0 {
+		subCommand, args = args[0], args[1:]
+	}
+	switch subCommand {
+	case "replay":
+		if len(args) != 1 {
+			return fmt.Errorf("requires ID, see `%s history`", GetProgramName())
+		}
+		return replayHistory(args[0], printOnly, port)
+	case "search":
+		if len(args) != 1 {
+			return fmt.Errorf("requires TERM")
+		}
+	case "", "export":
+		if len(args) > 0 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+		}
+	default:
+		return fmt.Errorf("unrecognized history command: %s", subCommand)
+	}
+
+	var from time.Time
+	if today {
+		from, _ = parseDigestDate("today", time.Now())
+	}
+	if since != "" {
+		from, err = parseDigestDate(since, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	entries, err := readTranscript(from, time.Time{})
+	if err != nil {
+		return err
+	}
+	if project != "" {
+		project, err = filepath.Abs(project)
+		if err != nil {
+			return err
+		}
+		entries = filterTranscriptByDir(entries, project)
+	}
+	if subCommand == "search" {
+		entries = searchTranscript(entries, args[0])
+	}
+	if subCommand == "export" {
+		doc := exportHistoryMarkdown(entries, full)
+		if out == "" {
+			fmt.Print(doc)
+			return nil
+		}
+		if err := os.WriteFile(out, []byte(doc), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d exchange(s) to %s\n", len(entries), out)
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "No history")
+		return nil
+	}
+	printHistory(os.Stdout, entries, full)
+	return nil
+}
+
+// exportHistoryMarkdown renders entries as markdown grouped by project, then by day,
+// in the order of their first exchange
+func exportHistoryMarkdown(entries []*transcriptEntry, full bool) string {
+	var b strings.Builder
+	b.WriteString("# whats_next history\n")
+	if len(entries) == 0 {
+		b.WriteString("\nNo exchanges.\n")
+		return b.String()
+	}
+
+	var projects []string
+	byProject := make(map[string][]*transcriptEntry)
+	for _, entry := range entries {
+		project := entry.WorkingDir
+		if project == "" {
+			project = "(unknown project)"
+		}
+		if _, ok := byProject[project]; !ok {
+			projects = append(projects, project)
+		}
+		byProject[project] = append(byProject[project], entry)
+	}
+
+	for _, project := range projects {
+		fmt.Fprintf(&b, "\n## %s\n", project)
+		var day string
+		for _, entry := range byProject[project] {
+			at := entry.Time.Local()
+			if d := at.Format(time.DateOnly); d != day {
+				day = d
+				fmt.Fprintf(&b, "\n### %s\n", day)
+			}
+			wait := formatDigestDuration(time.Duration(entry.Wait * float64(time.Second)))
+			fmt.Fprintf(&b, "\n**%s** after waiting %s\n\n", at.Format("15:04"), wait)
+			if entry.Event == WebhookEventIdle {
+				b.WriteString("_No reply, the idle response was sent._\n")
+			} else {
+				for _, line := range strings.Split(strings.TrimRight(entry.Content, "\n"), "\n") {
+					b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+				}
+			}
+			if full && entry.Sent != "" {
+				b.WriteString("\n<details>\n<summary>Sent to the agent</summary>\n\n````\n")
+				b.WriteString(strings.TrimRight(entry.Sent, "\n"))
+				b.WriteString("\n````\n\n</details>\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// filterTranscriptByDir keeps the entries of agents working in dir or below it
+func filterTranscriptByDir(entries []*transcriptEntry, dir string) []*transcriptEntry {
+	var filtered []*transcriptEntry
+	for _, entry := range entries {
+		if entry.WorkingDir == "" {
+			continue
+		}
+		rel, err := filepath.Rel(dir, entry.WorkingDir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// searchTranscript keeps the replies containing term, ignoring case
+func searchTranscript(entries []*transcriptEntry, term string) []*transcriptEntry {
+	term = strings.ToLower(term)
+	var found []*transcriptEntry
+	for _, entry := range entries {
+		if entry.Event == WebhookEventInput && strings.Contains(strings.ToLower(entry.Content), term) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// replayHistory queues the reply of entry id on the running server, or prints it
+func replayHistory(id string, printOnly bool, port int) error {
+	entry, err := findTranscriptEntry(id)
+	if err != nil {
+		return err
+	}
+	if entry.Event != WebhookEventInput || strings.TrimSpace(entry.Content) == "" {
+		return fmt.Errorf("history %s is not a reply", id)
+	}
+	if !printOnly {
+		config := readServerConfig()
+		addr := getServerAddr(config.ServerBind, resolveServerPort(port, config))
+		if isAddrReachable(addr) {
+			if err := postReply(addr, entry.Content, entry.WorkingDir); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Queued %s on server %s\n", id, addr)
+			return nil
+		}
+	}
+	fmt.Println(entry.Content)
+	return nil
+}
+
+func printHistory(w io.Writer, entries []*transcriptEntry, full bool) {
+	for _, entry := range entries {
+		project := entry.Project
+		if project == "" {
+			project = "-"
+		}
+		reply := digestSummary(entry.Content)
+		if entry.Event == WebhookEventIdle {
+			reply = "(idle)"
+		}
+		wait := formatDigestDuration(time.Duration(entry.Wait * float64(time.Second)))
+		fmt.Fprintf(w, "%s  %s  %s  waited %s  %s\n", entry.ID, entry.Time.Local().Format("15:04"), project, wait, reply)
+		if full && entry.Sent != "" {
+			for _, line := range strings.Split(strings.TrimRight(entry.Sent, "\n"), "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
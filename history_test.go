@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordTranscriptPerDay(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	recordTranscript(ModeNative, WebhookEventInput, "/src/api", time.Now().Add(-time.Minute), "run the tests", "run the tests\n\nguidelines")
+	if err := appendTranscript(&transcriptEntry{Time: time.Now().AddDate(0, 0, -1), Event: WebhookEventInput, WorkingDir: "/src/web", Project: "web", Content: "fix the header"}); err != nil {
+		t.Fatal(err)
+	}
+
+	today := time.Now().Format(time.DateOnly) + ".jsonl"
+	if _, err := os.Stat(filepath.Join(dir, transcriptDir, today)); err != nil {
+		t.Fatalf("expect today's transcript file: %v", err)
+	}
+	all, err := readTranscript(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].Project != "web" {
+		t.Fatalf("expect 2 entries oldest first, got %+v", all)
+	}
+	if all[1].Sent != "run the tests\n\nguidelines" || all[1].Wait < 60 {
+		t.Errorf("unexpected entry: %+v", all[1])
+	}
+
+	from, _ := parseDigestDate("today", time.Now())
+	todays, err := readTranscript(from, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todays) != 1 || todays[0].Project != "api" {
+		t.Errorf("expect only today's entry, got %+v", todays)
+	}
+}
+
+func TestFilterTranscriptByDir(t *testing.T) {
+	entries := []*transcriptEntry{
+		{WorkingDir: "/src/api"},
+		{WorkingDir: "/src/api/cmd"},
+		{WorkingDir: "/src/api-v2"},
+		{WorkingDir: ""},
+	}
+	filtered := filterTranscriptByDir(entries, "/src/api")
+	if len(filtered) != 2 || filtered[1].WorkingDir != "/src/api/cmd" {
+		t.Errorf("unexpected entries: %+v", filtered)
+	}
+}
+
+func TestPrintHistory(t *testing.T) {
+	at := time.Date(2024, 1, 2, 10, 4, 0, 0, time.Local)
+	var buf bytes.Buffer
+	printHistory(&buf, []*transcriptEntry{
+		{ID: "2024-01-02.1", Time: at, Event: WebhookEventInput, Project: "api", Wait: 90, Content: "run the tests", Sent: "run the tests\nguidelines"},
+		{ID: "2024-01-02.2", Time: at, Event: WebhookEventIdle, Wait: 600},
+	}, true)
+	want := "2024-01-02.1  10:04  api  waited 2m  run the tests\n" +
+		"    run the tests\n" +
+		"    guidelines\n" +
+		"\n" +
+		"2024-01-02.2  10:04  -  waited 10m  (idle)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected history:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistorySearchAndReplay(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	for _, content := range []string{"Run the tests", "", "fix the header"} {
+		event := WebhookEventInput
+		if content == "" {
+			event = WebhookEventIdle
+		}
+		recordTranscript(ModeServer, event, "/src/api", time.Now(), content, content)
+	}
+	entries, err := readTranscript(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := searchTranscript(entries, "the TESTS")
+	if len(found) != 1 || found[0].Content != "Run the tests" {
+		t.Fatalf("unexpected search result: %+v", found)
+	}
+
+	id := found[0].ID
+	entry, err := findTranscriptEntry(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Content != "Run the tests" {
+		t.Errorf("unexpected entry of %s: %+v", id, entry)
+	}
+	if _, err := findTranscriptEntry(entries[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := replayHistory(entries[1].ID, true, 0); err == nil {
+		t.Errorf("expect error replaying an idle response")
+	}
+	if _, err := findTranscriptEntry("yesterday"); err == nil {
+		t.Errorf("expect error for an invalid id")
+	}
+
+	h := &serveHandler{inputChan: make(chan InputMessage, 1)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleReplyRequest(h, w, r)
+	}))
+	defer server.Close()
+	_, portStr, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	port, _ := strconv.Atoi(portStr)
+	if err := replayHistory(id, false, port); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-h.inputChan; msg.Content != "Run the tests" || msg.WorkingDir != "/src/api" {
+		t.Errorf("unexpected replayed message: %+v", msg)
+	}
+}
+
+func TestExportHistoryMarkdown(t *testing.T) {
+	day1 := time.Date(2024, 1, 2, 10, 4, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	doc := exportHistoryMarkdown([]*transcriptEntry{
+		{Time: day1, Event: WebhookEventInput, WorkingDir: "/src/api", Wait: 60, Content: "run the tests\nthen commit", Sent: "run the tests\nguidelines"},
+		{Time: day1, Event: WebhookEventInput, WorkingDir: "/src/web", Wait: 30, Content: "fix the header"},
+		{Time: day2, Event: WebhookEventIdle, WorkingDir: "/src/api", Wait: 600},
+	}, false)
+	want := `# whats_next history
+
+## /src/api
+
+### 2024-01-02
+
+**10:04** after waiting 1m
+
+> run the tests
+> then commit
+
+### 2024-01-03
+
+**10:04** after waiting 10m
+
+_No reply, the idle response was sent._
+
+## /src/web
+
+### 2024-01-02
+
+**10:04** after waiting 30s
+
+> fix the header
+`
+	if doc != want {
+		t.Errorf("unexpected export:\n%s\nwant:\n%s", doc, want)
+	}
+}
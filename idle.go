@@ -0,0 +1,59 @@
+package main
+
+// Idle actions decide what happens when the idle timeout passes without input
+const (
+	// IdleActionThinking tells the agent the user is thinking and to run the command again
+	IdleActionThinking = "thinking"
+	// IdleActionMessage emits Config.IdleMessage instead
+	IdleActionMessage = "message"
+	// IdleActionWait emits nothing and keeps waiting, until the hard timeout in server mode
+	IdleActionWait = "wait"
+	// IdleActionStop tells the agent to stop
+	IdleActionStop = "stop"
+)
+
+var idleActions = []string{IdleActionThinking, IdleActionMessage, IdleActionWait, IdleActionStop}
+
+// getIdleAction returns the idle action of mode: the mode specific setting,
+// then idleAction, then the default which keeps waiting in native mode
+// and sends the thinking text in server mode
+func getIdleAction(config *Config, mode Mode) string {
+	action := config.IdleAction
+	switch mode {
+	case ModeServer:
+		if config.ServerIdleAction != "" {
+			action = config.ServerIdleAction
+		}
+		if action == "" {
+			action = IdleActionThinking
+		}
+	default:
+		if config.NativeIdleAction != "" {
+			action = config.NativeIdleAction
+		}
+		if action == "" {
+			action = IdleActionWait
+		}
+	}
+	if !containsFold(idleActions, action) {
+		Errorf("invalid idle action %q, using %s", action, IdleActionThinking)
+		return IdleActionThinking
+	}
+	return action
+}
+
+// getIdleResponse returns the text sent to the agent for an idle action,
+// and false if the action keeps waiting instead
+func getIdleResponse(config *Config, action string) (string, bool) {
+	switch action {
+	case IdleActionWait:
+		return "", false
+	case IdleActionStop:
+		return "The user is away. Stop here, don't execute `" + GetProgramName() + "` again until the user asks.", true
+	case IdleActionMessage:
+		if config.IdleMessage != "" {
+			return config.IdleMessage, true
+		}
+	}
+	return isThinking(), true
+}
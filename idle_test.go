@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetIdleAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		mode     Mode
+		expected string
+	}{
+		{"native default", Config{}, ModeNative, IdleActionWait},
+		{"server default", Config{}, ModeServer, IdleActionThinking},
+		{"shared", Config{IdleAction: "stop"}, ModeNative, IdleActionStop},
+		{"mode wins", Config{IdleAction: "stop", ServerIdleAction: "wait"}, ModeServer, IdleActionWait},
+		{"other mode ignored", Config{NativeIdleAction: "stop"}, ModeServer, IdleActionThinking},
+		{"invalid", Config{IdleAction: "sleep"}, ModeServer, IdleActionThinking},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if action := getIdleAction(&tt.config, tt.mode); action != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, action)
+			}
+		})
+	}
+}
+
+func TestGetIdleResponse(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	config := &Config{IdleMessage: "Take a break, then check back."}
+
+	if _, ok := getIdleResponse(config, IdleActionWait); ok {
+		t.Errorf("Expected wait to emit nothing")
+	}
+	if response, _ := getIdleResponse(config, IdleActionMessage); response != config.IdleMessage {
+		t.Errorf("Expected custom message, got %q", response)
+	}
+	if response, _ := getIdleResponse(&Config{}, IdleActionMessage); response != isThinking() {
+		t.Errorf("Expected thinking text without a custom message, got %q", response)
+	}
+	if response, _ := getIdleResponse(config, IdleActionStop); !strings.Contains(response, "Stop here") {
+		t.Errorf("Expected stop instruction, got %q", response)
+	}
+}
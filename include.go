@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// maxIncludeDepth limits nested includes so that a fragment
+// including itself (directly or indirectly) cannot loop forever
+const maxIncludeDepth = 10
+
+// expandIncludes replaces lines like "<!-- include: fragments/go-style.md -->"
+// with the content of the referenced file.
+// Relative paths are resolved against baseDir (the config dir).
+// Directives inside code blocks are left untouched, and directives
+// whose file cannot be read are kept as is.
+func expandIncludes(content string, baseDir string) string {
+	return expandIncludesDepth(content, baseDir, 0)
+}
+
+func expandIncludesDepth(content string, baseDir string, depth int) string {
+	if !strings.Contains(content, "<!--") {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	var result []string
+	var inCodeBlock bool
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") {
+			inCodeBlock = !inCodeBlock
+		}
+		if inCodeBlock || depth >= maxIncludeDepth {
+			result = append(result, line)
+			continue
+		}
+		includePath, ok := parseIncludeDirective(trimmedLine)
+		if !ok {
+			result = append(result, line)
+			continue
+		}
+		included, err := os.ReadFile(resolveIncludePath(includePath, baseDir))
+		if err != nil {
+			Errorf("include %s: %v", includePath, err)
+			result = append(result, line)
+			continue
+		}
+		includedContent := strings.TrimSuffix(sections.Normalize(string(included)), "\n")
+		result = append(result, expandIncludesDepth(includedContent, baseDir, depth+1))
+	}
+	return strings.Join(result, "\n")
+}
+
+// parseIncludeDirective extracts the path from a line like "<!-- include: path -->"
+func parseIncludeDirective(line string) (string, bool) {
+	inner, ok := strings.CutPrefix(line, "<!--")
+	if !ok {
+		return "", false
+	}
+	inner, ok = strings.CutSuffix(inner, "-->")
+	if !ok {
+		return "", false
+	}
+	inner = strings.TrimSpace(inner)
+	includePath, ok := strings.CutPrefix(inner, "include:")
+	if !ok {
+		return "", false
+	}
+	includePath = strings.TrimSpace(includePath)
+	if includePath == "" {
+		return "", false
+	}
+	return includePath, true
+}
+
+// resolveIncludePath expands tilde and environment variables,
+// and resolves relative paths against baseDir
+func resolveIncludePath(includePath string, baseDir string) string {
+	if strings.HasPrefix(includePath, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err == nil {
+			includePath = filepath.Join(homeDir, includePath[2:])
+		}
+	}
+	includePath = os.ExpandEnv(includePath)
+	if filepath.IsAbs(includePath) {
+		return includePath
+	}
+	return filepath.Join(baseDir, includePath)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandIncludes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "whats_next_include_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	fragmentsDir := filepath.Join(tempDir, "fragments")
+	if err := os.MkdirAll(fragmentsDir, 0755); err != nil {
+		t.Fatalf("Failed to create fragments dir: %v", err)
+	}
+	files := map[string]string{
+		"go-style.md": "# Go Style\nUse gofmt.\n",
+		"nested.md":   "# Nested\n<!-- include: fragments/go-style.md -->",
+		"self.md":     "# Self\n<!-- include: fragments/self.md -->",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(fragmentsDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "no include",
+			content:  "# Title\nContent",
+			expected: "# Title\nContent",
+		},
+		{
+			name:     "simple include",
+			content:  "# Title\n<!-- include: fragments/go-style.md -->\nAfter",
+			expected: "# Title\n# Go Style\nUse gofmt.\nAfter",
+		},
+		{
+			name:     "absolute path include",
+			content:  "<!--include:" + filepath.Join(fragmentsDir, "go-style.md") + "-->",
+			expected: "# Go Style\nUse gofmt.",
+		},
+		{
+			name:     "nested include",
+			content:  "<!-- include: fragments/nested.md -->",
+			expected: "# Nested\n# Go Style\nUse gofmt.",
+		},
+		{
+			name:     "missing file kept as is",
+			content:  "# Title\n<!-- include: fragments/missing.md -->",
+			expected: "# Title\n<!-- include: fragments/missing.md -->",
+		},
+		{
+			name:     "include inside code block ignored",
+			content:  "```md\n<!-- include: fragments/go-style.md -->\n```",
+			expected: "```md\n<!-- include: fragments/go-style.md -->\n```",
+		},
+		{
+			name:     "regular comment untouched",
+			content:  "<!-- just a comment -->",
+			expected: "<!-- just a comment -->",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := expandIncludes(tt.content, tempDir)
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("self include terminates", func(t *testing.T) {
+		result := expandIncludes("<!-- include: fragments/self.md -->", tempDir)
+		if result == "" {
+			t.Errorf("Expected non-empty result")
+		}
+	})
+}
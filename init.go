@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+const initHelp = `
+Usage:
+  whats_next init [--yes]
+
+Interactively sets up config.json and a starter profile.
+Existing settings are offered as defaults.
+
+Options:
+  --yes  Accept all defaults without asking
+`
+
+func handleInit(args []string) error {
+	var yes bool
+	args, err := flags.Bool("--yes", &yes).Help("-h,--help", initHelp).Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	var r io.Reader = os.Stdin
+	if yes {
+		r = strings.NewReader("")
+	}
+	return runInitWizard(r, os.Stdout)
+}
+
+// runInitWizard asks for the main settings on w, reading answers from r.
+// An empty answer, or the end of r, keeps the default.
+func runInitWizard(r io.Reader, w io.Writer) error {
+	config, err := readConfig()
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(r)
+	ask := func(question string, def string, validate func(string) error) (string, error) {
+		for {
+			fmt.Fprintf(w, "%s [%s]: ", question, def)
+			line, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return "", err
+			}
+			answer := strings.TrimSpace(line)
+			if answer == "" {
+				if err == io.EOF {
+					fmt.Fprintln(w)
+				}
+				answer = def
+			}
+			if validate == nil {
+				return answer, nil
+			}
+			validateErr := validate(answer)
+			if validateErr == nil {
+				return answer, nil
+			}
+			fmt.Fprintf(w, "  %v\n", validateErr)
+			if err == io.EOF {
+				return "", validateErr
+			}
+		}
+	}
+
+	mode := string(config.Mode)
+	if mode == "" {
+		mode = string(ModeNative)
+	}
+	mode, err = ask("Mode, native or server", mode, func(value string) error {
+		if !containsFold(configEnums["mode"], value) {
+			return fmt.Errorf("expect one of %s", strings.Join(configEnums["mode"], ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	config.Mode = Mode(strings.ToLower(mode))
+
+	// keep following $VISUAL/$EDITOR unless another editor is chosen
+	defaultEditor := getEditor(config.Editor)
+	editor, err := ask("Editor", defaultEditor, nil)
+	if err != nil {
+		return err
+	}
+	if editor != defaultEditor || config.Editor != "" {
+		config.Editor = editor
+	}
+
+	idleTimeout := config.IdleTimeout
+	if idleTimeout == "" {
+		idleTimeout = TIMEOUT.String()
+	}
+	idleTimeout, err = ask("Idle timeout before the agent keeps thinking", idleTimeout, validateDuration)
+	if err != nil {
+		return err
+	}
+	if idleTimeout != TIMEOUT.String() || config.IdleTimeout != "" {
+		config.IdleTimeout = idleTimeout
+	}
+
+	createProfile, err := ask("Create a starter profile? (y/n)", "y", func(value string) error {
+		if !containsFold([]string{"y", "yes", "n", "no"}, value) {
+			return fmt.Errorf("expect y or n")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(strings.ToLower(createProfile), "y") {
+		name := config.SelectedProfile
+		if name == "" {
+			name = "default"
+		}
+		name, err = ask("Profile name", name, nil)
+		if err != nil {
+			return err
+		}
+		groupFile, err := createStarterProfile(name)
+		if err != nil {
+			return err
+		}
+		config.SelectedProfile = strings.TrimSuffix(name, ".md")
+		fmt.Fprintf(w, "Profile: %s\n", groupFile)
+	}
+
+	// the answers took a while, only apply them to the current config
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	latest, err := readConfig()
+	if err != nil {
+		return err
+	}
+	latest.Mode = config.Mode
+	latest.Editor = config.Editor
+	latest.IdleTimeout = config.IdleTimeout
+	latest.SelectedProfile = config.SelectedProfile
+	if err := writeConfig(latest); err != nil {
+		return err
+	}
+	configFile, err := getConfigPath(false, "config.json")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Config: %s\n", configFile)
+	return nil
+}
+
+// createStarterProfile writes the built-in guidelines to a new profile,
+// an existing profile is kept untouched
+func createStarterProfile(name string) (string, error) {
+	groupDir, err := getConfigPath(true, "group")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(groupDir, 0755); err != nil {
+		return "", err
+	}
+	groupFile := filepath.Join(groupDir, addMDSuffix(name))
+	if _, err := os.Stat(groupFile); err == nil {
+		return groupFile, nil
+	}
+	var b strings.Builder
+	if err := showW(&b); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(groupFile, []byte(b.String()), 0644); err != nil {
+		return "", err
+	}
+	return groupFile, nil
+}
+
+// hintInit suggests `whats_next init` on stderr when there is no config.json yet
+func hintInit() {
+	configFile, err := getConfigPath(false, "config.json")
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "tip: run `%s init` to set up your config\n", GetProgramName())
+	}
+}
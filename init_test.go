@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunInitWizard(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+
+	var out bytes.Buffer
+	answers := "remote\nserver\nnano\n5\n5m\nyes\nwork\n"
+	if err := runInitWizard(strings.NewReader(answers), &out); err != nil {
+		t.Fatalf("runInitWizard failed: %v\n%s", err, out.String())
+	}
+
+	config, err := readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Mode != ModeServer || config.Editor != "nano" || config.IdleTimeout != "5m" || config.SelectedProfile != "work" {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+	if !strings.Contains(out.String(), "expect one of native, server") {
+		t.Errorf("Expected invalid mode to be reported, got:\n%s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "group", "work.md")); err != nil {
+		t.Errorf("Expected starter profile: %v", err)
+	}
+}
+
+func TestRunInitWizardDefaults(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "vim")
+
+	var out bytes.Buffer
+	if err := runInitWizard(strings.NewReader(""), &out); err != nil {
+		t.Fatalf("runInitWizard failed: %v\n%s", err, out.String())
+	}
+	config, err := readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Mode != ModeNative || config.Editor != "" || config.IdleTimeout != "" || config.SelectedProfile != "default" {
+		t.Errorf("Expected defaults to be kept implicit, got %+v", config)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !headless
+
 package main
 
 import (
@@ -24,6 +26,19 @@ type multiLineEditorModel struct {
 
 	getUserPrompt func(hasInput bool) string
 
+	// notice is shown below the input until the next key, e.g. an unknown /prompt
+	notice string
+
+	// todoDir scopes the items added with "/todo", empty for all agents
+	todoDir string
+
+	// onTogglePause pauses or resumes the server on Ctrl+P, nil outside the server
+	onTogglePause func() string
+
+	// editingFile is the file edited full screen when no editor is installed:
+	// the text is saved verbatim with Ctrl+S, without the answer commands
+	editingFile string
+
 	showTimer func() bool
 
 	onInputExit   func()
@@ -32,6 +47,9 @@ type multiLineEditorModel struct {
 
 type timerTickMsg time.Time
 
+// noticeMsg sets the notice of the input, e.g. when a profile is reloaded
+type noticeMsg string
+
 type enableTimerMsg struct{}
 type disableTimerMsg struct{}
 
@@ -50,7 +68,7 @@ func timerTick() tea.Cmd {
 
 func (m multiLineEditorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	inputLength := m.textarea.Length()
-	// Logf("input model update: %T ", msg)
+	Debugf("input model update: %T", msg)
 	var cmd tea.Cmd
 
 	if m.hasInput != nil {
@@ -100,8 +118,16 @@ func (m multiLineEditorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	if m.editingFile != "" {
+		return m.updateEditingFile(msg)
+	}
+
 	switch msg := msg.(type) {
+	case noticeMsg:
+		m.notice = string(msg)
+		return m, nil
 	case tea.KeyMsg:
+		m.notice = ""
 		// Set hasInput when user types any content (except control keys that don't add content)
 		switch msg.Type {
 		case tea.KeyCtrlC, tea.KeyCtrlD, tea.KeyCtrlS, tea.KeyEsc:
@@ -110,6 +136,11 @@ func (m multiLineEditorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		switch msg.Type {
+		case tea.KeyCtrlP:
+			if m.onTogglePause != nil {
+				m.notice = m.onTogglePause()
+				return m, nil
+			}
 		case tea.KeyCtrlC:
 			m.cancelled = true
 			return m, tea.Quit
@@ -153,6 +184,28 @@ func (m multiLineEditorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 
+				// Replace "/prompt NAME" by the saved prompt
+				if prompt, ok, err := expandPromptCommand(lastLine); ok {
+					if err != nil {
+						m.notice = err.Error()
+						return m, nil
+					}
+					lines[len(lines)-1] = prompt
+					m.textarea.SetValue(strings.Join(lines, "\n"))
+					return m, nil
+				}
+
+				// Add "/todo TEXT" as a todo item instead of sending it
+				if item, ok, err := expandTodoCommand(lastLine, m.todoDir); ok {
+					if err != nil {
+						m.notice = err.Error()
+						return m, nil
+					}
+					m.textarea.SetValue(strings.Join(lines[:len(lines)-1], "\n"))
+					m.notice = fmt.Sprintf("added todo #%d", item.ID)
+					return m, nil
+				}
+
 				// Check for exit command on last line
 				if lastLine == "exit" {
 					m.cancelled = true
@@ -196,7 +249,34 @@ func (m multiLineEditorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updateEditingFile handles the keys when editing a file full screen
+func (m multiLineEditorModel) updateEditingFile(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// leave room for the title and the help line
+		m.textarea.SetWidth(msg.Width)
+		m.textarea.SetHeight(max(msg.Height-3, 1))
+		return m, nil
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlS:
+			m.content = m.textarea.Value()
+			m.finished = true
+			return m, tea.Quit
+		case tea.KeyCtrlC, tea.KeyEsc:
+			m.cancelled = true
+			return m, tea.Quit
+		}
+	}
+	var cmd tea.Cmd
+	m.textarea, cmd = m.textarea.Update(msg)
+	return m, cmd
+}
+
 func (m multiLineEditorModel) View() string {
+	if m.editingFile != "" {
+		return fmt.Sprintf("editing %s\n%s\nCtrl+S to save and quit • Esc to quit without saving", m.editingFile, m.textarea.View())
+	}
 	var userPrompt string
 
 	if m.getUserPrompt != nil {
@@ -205,33 +285,12 @@ func (m multiLineEditorModel) View() string {
 		userPrompt = "user> "
 	}
 
-	helpText := "\n\nType 'END'(Ctrl+S) to submit • Type 'CLEAR'(Ctrl+D) to reset • Type 'exit'(esc) to quit"
-	return fmt.Sprintf("%s\n%s%s", userPrompt, m.textarea.View(), helpText)
-}
-
-func renderUserPrompt(showTimer bool, showClient bool, remaining time.Duration, waitingClient int) string {
-	var timer string
-	if showTimer {
-		if remaining > 0 {
-			minutes := int(remaining.Minutes())
-			seconds := int(remaining.Seconds()) % 60
-
-			timer = fmt.Sprintf(" (%dm %02ds)", minutes, seconds)
-		} else {
-			timer = " (0m0s)"
-		}
+	helpText := "\n\nType 'END'(Ctrl+S) to submit • Type 'CLEAR'(Ctrl+D) to reset • Type 'exit'(esc) to quit • '/prompt NAME' to insert a saved prompt • '/todo TEXT' to add a pending task"
+	if m.onTogglePause != nil {
+		helpText += " • Ctrl+P to pause/resume"
 	}
-
-	var client string
-	if showClient {
-		if waitingClient == 0 {
-			client = " (staging)"
-		} else if waitingClient == 1 {
-			client = " (client connected)"
-		} else {
-			client = fmt.Sprintf(" (%d clients connected)", waitingClient)
-		}
+	if m.notice != "" {
+		helpText = "\n" + m.notice + helpText
 	}
-
-	return "user" + timer + ">" + client
+	return fmt.Sprintf("%s\n%s%s", userPrompt, m.textarea.View(), helpText)
 }
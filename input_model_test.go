@@ -0,0 +1,46 @@
+//go:build !headless
+
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPromptCommandInEditor(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	if err := handlePrompt([]string{"save", "review", "review the diff\nthen list the risks"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ta := textarea.New()
+	ta.SetValue("first\n/prompt review")
+	model, _ := multiLineEditorModel{textarea: ta}.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m := model.(multiLineEditorModel)
+	if got := m.textarea.Value(); got != "first\nreview the diff\nthen list the risks" {
+		t.Errorf("Expected /prompt replaced in the editor, got %q", got)
+	}
+	if m.finished {
+		t.Errorf("Expected the draft not to be submitted")
+	}
+}
+
+func TestEditingFileSavesVerbatim(t *testing.T) {
+	ta := textarea.New()
+	ta.Focus()
+	ta.SetValue("# Rules\nexit\nEND")
+	var model tea.Model = multiLineEditorModel{textarea: ta, editingFile: "p.md"}
+
+	// Enter only inserts a newline, "exit" and "END" are plain text
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	m := model.(multiLineEditorModel)
+	if !m.finished || m.cancelled {
+		t.Fatalf("Expected Ctrl+S to save, finished=%v cancelled=%v", m.finished, m.cancelled)
+	}
+	if m.content != "# Rules\nexit\nEND\n" {
+		t.Errorf("Expected the text saved verbatim, got %q", m.content)
+	}
+}
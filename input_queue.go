@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultInputQueueSize is the number of answers the server queues
+// for agents when inputQueueSize is not set
+const defaultInputQueueSize = 100
+
+// The overflow policies of the input queue of the server, see inputQueueOverflow
+const (
+	// inputQueueBlock makes the server input wait until an agent takes
+	// an answer, answers from slack and the API are refused
+	inputQueueBlock = "block"
+	// inputQueueDropOldest discards the oldest queued answer to make room
+	inputQueueDropOldest = "drop-oldest"
+	// inputQueueSpool appends answers to inputSpoolFile, they are moved
+	// back to the queue as agents take answers
+	inputQueueSpool = "spool"
+)
+
+var inputQueueOverflows = []string{inputQueueBlock, inputQueueDropOldest, inputQueueSpool}
+
+// inputSpoolFile is the file under the config dir holding the answers
+// spooled while the queue is full, one JSON object per line.
+// Answers left when the server stops are queued on its next start.
+const inputSpoolFile = "input_spool.jsonl"
+
+// spooledInput is an InputMessage in inputSpoolFile
+type spooledInput struct {
+	Content    string `json:"content"`
+	WorkingDir string `json:"workingDir,omitempty"`
+}
+
+// getInputQueueSize returns the capacity of the input queue of the server
+func getInputQueueSize(config *Config) int {
+	if config.InputQueueSize > 0 {
+		return config.InputQueueSize
+	}
+	return defaultInputQueueSize
+}
+
+// getInputQueueOverflow returns the overflow policy of the input queue of the server
+func getInputQueueOverflow(config *Config) string {
+	policy := strings.ToLower(config.InputQueueOverflow)
+	for _, known := range inputQueueOverflows {
+		if policy == known {
+			return policy
+		}
+	}
+	return inputQueueBlock
+}
+
+// renderQueueDepth tells how many answers wait for an agent, empty if none
+func renderQueueDepth(queued int, size int) string {
+	if queued == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d/%d queued)", queued, size)
+}
+
+// spoolInput appends msg to inputSpoolFile
+func spoolInput(msg InputMessage) error {
+	file, err := getConfigPath(true, inputSpoolFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(spooledInput{Content: msg.Content, WorkingDir: msg.WorkingDir})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readSpooledInput returns the answers in inputSpoolFile, oldest first
+func readSpooledInput() ([]InputMessage, error) {
+	file, err := getConfigPath(false, inputSpoolFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var msgs []InputMessage
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var spooled spooledInput
+		if err := json.Unmarshal([]byte(line), &spooled); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		msgs = append(msgs, InputMessage{Content: spooled.Content, WorkingDir: spooled.WorkingDir})
+	}
+	return msgs, nil
+}
+
+// writeSpooledInput replaces inputSpoolFile with msgs, removing it if none
+func writeSpooledInput(msgs []InputMessage) error {
+	file, err := getConfigPath(true, inputSpoolFile)
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, msg := range msgs {
+		data, err := json.Marshal(spooledInput{Content: msg.Content, WorkingDir: msg.WorkingDir})
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return os.WriteFile(file, buf.Bytes(), 0600)
+}
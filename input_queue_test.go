@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestEnqueueInputOverflow(t *testing.T) {
+	h := &serveHandler{inputChan: make(chan InputMessage, 2), inputOverflow: inputQueueBlock}
+	for _, content := range []string{"a", "b"} {
+		if !h.enqueueInput(InputMessage{Content: content}) {
+			t.Fatalf("Expected %s queued", content)
+		}
+	}
+	if h.enqueueInput(InputMessage{Content: "c"}) {
+		t.Errorf("Expected the block policy to refuse input when full")
+	}
+
+	h.inputOverflow = inputQueueDropOldest
+	if !h.enqueueInput(InputMessage{Content: "c"}) {
+		t.Fatalf("Expected the drop-oldest policy to make room")
+	}
+	if first, second := <-h.inputChan, <-h.inputChan; first.Content != "b" || second.Content != "c" {
+		t.Errorf("Expected b and c left, got %q and %q", first.Content, second.Content)
+	}
+}
+
+func TestEnqueueInputSpool(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	h := &serveHandler{inputChan: make(chan InputMessage, 1), inputOverflow: inputQueueSpool}
+	for _, content := range []string{"a", "b", "c"} {
+		if !h.enqueueInput(InputMessage{Content: content, WorkingDir: "/work"}) {
+			t.Fatalf("Expected %s queued or spooled", content)
+		}
+	}
+	// agents take the answers in order, the spooled ones moved back to the queue
+	for _, expected := range []string{"a", "b", "c"} {
+		msg := <-h.inputChan
+		if msg.Content != expected || msg.WorkingDir != "/work" {
+			t.Errorf("Expected %s for /work, got %q for %q", expected, msg.Content, msg.WorkingDir)
+		}
+		h.refillInput()
+	}
+	if msgs, err := readSpooledInput(); err != nil || len(msgs) != 0 {
+		t.Errorf("Expected the spool emptied, got %v, %v", msgs, err)
+	}
+
+	// answers left spooled are queued by the next server
+	if err := spoolInput(InputMessage{Content: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	next := &serveHandler{inputChan: make(chan InputMessage, 1), inputOverflow: inputQueueBlock}
+	next.loadSpooledInput()
+	if msg := <-next.inputChan; msg.Content != "d" {
+		t.Errorf("Expected the spooled d queued on start, got %q", msg.Content)
+	}
+}
+
+func TestInputQueueConfig(t *testing.T) {
+	if size := getInputQueueSize(&Config{}); size != defaultInputQueueSize {
+		t.Errorf("Expected the default size, got %d", size)
+	}
+	if policy := getInputQueueOverflow(&Config{InputQueueOverflow: "spill"}); policy != inputQueueBlock {
+		t.Errorf("Expected an unknown policy to block, got %s", policy)
+	}
+	if policy := getInputQueueOverflow(&Config{InputQueueOverflow: "Drop-Oldest"}); policy != inputQueueDropOldest {
+		t.Errorf("Expected the policy matched case-insensitively, got %s", policy)
+	}
+	if got := renderQueueDepth(0, 100); got != "" {
+		t.Errorf("Expected nothing rendered for an empty queue, got %q", got)
+	}
+	if got := renderQueueDepth(3, 100); got != " (3/100 queued)" {
+		t.Errorf("Unexpected queue depth: %q", got)
+	}
+}
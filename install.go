@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+const installHelp = `
+Usage:
+  whats_next install cursor|claude|windsurf [--global] [--dir DIR]
+
+Writes the general guideline, adapted to the agent and with the name this
+program is invoked as, into the rules the agent reads:
+
+  cursor     DIR/.cursor/rules/whats_next.mdc
+  claude     DIR/CLAUDE.md, or ~/.claude/CLAUDE.md with --global
+  windsurf   DIR/.windsurf/rules/whats_next.md, or
+             ~/.codeium/windsurf/memories/global_rules.md with --global
+
+The guideline is kept between markers, so the rest of the file is left
+untouched and installing again replaces it.
+
+Options:
+  --global   Install for all projects of the user
+  --dir DIR  The project to install to (default: current directory)
+`
+
+// installMarkerBegin and installMarkerEnd surround the block written by install
+const (
+	installMarkerBegin = "<!-- whats_next:begin, managed by `whats_next install` -->"
+	installMarkerEnd   = "<!-- whats_next:end -->"
+)
+
+// installTarget is an agent install can write rules for
+type installTarget struct {
+	name string
+	// projectFile and globalFile are relative to the project dir and the home dir,
+	// an empty globalFile means the agent has no global rules file
+	projectFile string
+	globalFile  string
+	// header starts the file when it is created, e.g. front matter
+	header string
+	// note adapts the guideline to how the agent runs commands
+	note string
+}
+
+var installTargets = []installTarget{
+	{
+		name:        "cursor",
+		projectFile: ".cursor/rules/whats_next.mdc",
+		header:      "---\ndescription: Follow-up with whats_next\nalwaysApply: true\n---\n",
+		note:        "Run it with the terminal tool in the foreground, never in the background: it blocks until the user answers.",
+	},
+	{
+		name:        "claude",
+		projectFile: "CLAUDE.md",
+		globalFile:  ".claude/CLAUDE.md",
+		note:        "Run it with the Bash tool and a timeout of 600000 ms, the user may take a while to answer. If it times out, run it again.",
+	},
+	{
+		name:        "windsurf",
+		projectFile: ".windsurf/rules/whats_next.md",
+		globalFile:  ".codeium/windsurf/memories/global_rules.md",
+		header:      "---\ntrigger: always_on\n---\n",
+		note:        "Run it with the run_command tool and wait for it to finish: it blocks until the user answers.",
+	},
+}
+
+func getInstallTarget(name string) (*installTarget, error) {
+	var names []string
+	for i, target := range installTargets {
+		if target.name == name {
+			return &installTargets[i], nil
+		}
+		names = append(names, target.name)
+	}
+	return nil, fmt.Errorf("unknown agent %q, expect one of: %s", name, strings.Join(names, ", "))
+}
+
+func handleInstall(args []string) error {
+	var global bool
+	var dir string
+	args, err := flags.Bool("--global", &global).
+		String("--dir", &dir).
+		Help("-h,--help", installHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("requires agent: cursor, claude or windsurf")
+	}
+	target, err := getInstallTarget(args[0])
+	if err != nil {
+		return err
+	}
+	file, err := target.resolveFile(global, dir)
+	if err != nil {
+		return err
+	}
+	changed, err := writeInstallBlock(file, target.header, formatInstallBlock(target))
+	if err != nil {
+		return err
+	}
+	if !changed {
+		fmt.Printf("%s is up to date\n", file)
+		return nil
+	}
+	fmt.Printf("wrote %s\n", file)
+	return nil
+}
+
+// resolveFile returns the rules file of the target, for the user with global
+// or for the project in dir
+func (t *installTarget) resolveFile(global bool, dir string) (string, error) {
+	if global {
+		if t.globalFile == "" {
+			return "", fmt.Errorf("%s has no global rules file, install per project, or paste the output of `%s show` in its settings", t.name, GetProgramName())
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, filepath.FromSlash(t.globalFile)), nil
+	}
+	if dir == "" {
+		var err error
+		dir, err = os.Getwd()
+		if err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(t.projectFile)), nil
+}
+
+// formatInstallBlock renders the guideline for target between the markers
+func formatInstallBlock(target *installTarget) string {
+	var b strings.Builder
+	b.WriteString(installMarkerBegin)
+	b.WriteString("\n")
+	b.WriteString(strings.TrimSpace(getGeneralGuideline()))
+	b.WriteString("\n\n")
+	b.WriteString(target.note)
+	b.WriteString("\n")
+	b.WriteString(installMarkerEnd)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// writeInstallBlock replaces the block between the markers in file, or appends
+// it, creating the file with header if it does not exist.
+// It reports whether the file changed.
+func writeInstallBlock(file string, header string, block string) (bool, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return false, err
+		}
+		return true, os.WriteFile(file, []byte(header+block), 0644)
+	}
+	updated := replaceInstallBlock(string(content), block)
+	if updated == string(content) {
+		return false, nil
+	}
+	return true, os.WriteFile(file, []byte(updated), 0644)
+}
+
+// replaceInstallBlock replaces the marked block of content by block,
+// which is appended after a blank line if content has none
+func replaceInstallBlock(content string, block string) string {
+	begin := strings.Index(content, installMarkerBegin)
+	if begin >= 0 {
+		if end := strings.Index(content[begin:], installMarkerEnd); end >= 0 {
+			rest := strings.TrimPrefix(content[begin+end+len(installMarkerEnd):], "\n")
+			return content[:begin] + block + rest
+		}
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if content != "" {
+		content += "\n"
+	}
+	return content + block
+}
+
+// removeInstallBlockFromFile removes the block written by install from file,
+// and the file if nothing but its header is left
+func removeInstallBlockFromFile(file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	s := string(content)
+	begin := strings.Index(s, installMarkerBegin)
+	if begin < 0 {
+		return nil
+	}
+	end := strings.Index(s[begin:], installMarkerEnd)
+	if end < 0 {
+		return fmt.Errorf("%s: no end marker after %s", file, installMarkerBegin)
+	}
+	rest := strings.TrimPrefix(s[begin+end+len(installMarkerEnd):], "\n")
+	s = strings.TrimRight(s[:begin], "\n")
+	if s != "" && rest != "" {
+		s += "\n\n"
+	} else if s != "" {
+		s += "\n"
+	}
+	s += rest
+	for _, target := range installTargets {
+		if target.header != "" && strings.TrimSpace(s) == strings.TrimSpace(target.header) {
+			s = ""
+		}
+	}
+	if strings.TrimSpace(s) == "" {
+		return os.Remove(file)
+	}
+	return os.WriteFile(file, []byte(s), 0644)
+}
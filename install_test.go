@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplaceInstallBlock(t *testing.T) {
+	block := installMarkerBegin + "\nnew\n" + installMarkerEnd + "\n"
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"", block},
+		{"# Mine", "# Mine\n\n" + block},
+		{"# Mine\n\n" + installMarkerBegin + "\nold\n" + installMarkerEnd + "\n# After\n", "# Mine\n\n" + block + "# After\n"},
+	}
+	for _, tt := range tests {
+		if got := replaceInstallBlock(tt.content, block); got != tt.want {
+			t.Errorf("replaceInstallBlock(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestInstallClaude(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	file := filepath.Join(dir, "CLAUDE.md")
+	if err := os.WriteFile(file, []byte("# Project rules\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := handleInstall([]string{"claude", "--dir", dir}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(content)
+	if !strings.HasPrefix(s, "# Project rules\n\n"+installMarkerBegin) || strings.Count(s, installMarkerBegin) != 1 {
+		t.Errorf("Expected the block appended once, got:\n%s", s)
+	}
+	if !strings.Contains(s, "`"+GetProgramName()+"`") || !strings.Contains(s, "Bash tool") {
+		t.Errorf("Expected the guideline adapted to claude, got:\n%s", s)
+	}
+
+	if err := handleInstall([]string{"cursor", "--global"}); err == nil {
+		t.Errorf("Expected cursor --global to fail")
+	}
+}
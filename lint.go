@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// knownAgents are the agent names accepted by (agents: ...)
+var knownAgents = []string{"cursor", "claude"}
+
+var directiveKeyPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// Diagnostic is a problem found in a profile by strict parsing
+type Diagnostic struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+}
+
+// isStrictMode reports whether WHATS_NEXT_STRICT is set, in which case
+// rendering a profile also reports its diagnostics to stderr
+func isStrictMode() bool {
+	switch strings.ToLower(os.Getenv("WHATS_NEXT_STRICT")) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// reportStrictDiagnostics lints content and prints diagnostics to w when strict mode is on
+func reportStrictDiagnostics(w io.Writer, file string, content string, configDir string) {
+	if !isStrictMode() {
+		return
+	}
+	for _, d := range lintProfile(file, content, configDir) {
+		fmt.Fprintf(w, "warning: %s\n", d)
+		Errorf("%s", d)
+	}
+}
+
+// lintProfile checks a profile for malformed directives, unclosed code fences,
+// unknown parenthetical tags and broken includes.
+// Relative include paths are resolved against configDir.
+func lintProfile(file string, content string, configDir string) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(line int, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, Diagnostic{File: file, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	content = sections.Normalize(content)
+	lines := strings.Split(content, "\n")
+	var inCodeBlock bool
+	var codeBlockStart int
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") {
+			inCodeBlock = !inCodeBlock
+			codeBlockStart = i + 1
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+		if includePath, ok := parseIncludeDirective(trimmedLine); ok {
+			if _, err := os.Stat(resolveIncludePath(includePath, configDir)); err != nil {
+				report(i+1, "include file not found: %s", includePath)
+			}
+		}
+	}
+	if inCodeBlock {
+		report(codeBlockStart, "unclosed code fence")
+	}
+
+	config := readConfigOrDefault("")
+	for _, section := range parseSections(content) {
+		lintHeading(sections.ApplyCommentDirectives(section.Title), config, func(format string, args ...interface{}) {
+			report(section.Line, format, args...)
+		})
+		metaKeys := make([]string, 0, len(section.Meta))
+		for key := range section.Meta {
+			metaKeys = append(metaKeys, key)
+		}
+		sort.Strings(metaKeys)
+		for _, key := range metaKeys {
+			value := section.Meta[key]
+			if key == "disabled" {
+				continue
+			}
+			if !containsFold(sections.ValueDirectives, key) {
+				report(section.Line, "unknown front-matter key: %s", key)
+				continue
+			}
+			lintDirectiveValue(key, value, config, func(format string, args ...interface{}) {
+				report(section.Line, format, args...)
+			})
+		}
+	}
+	return diagnostics
+}
+
+// lintHeading reports problems with the parenthesized directives of a heading
+func lintHeading(heading string, config *Config, report func(format string, args ...interface{})) {
+	start := 0
+	for {
+		parenStart := strings.Index(heading[start:], "(")
+		if parenStart == -1 {
+			return
+		}
+		parenStart += start
+
+		parenEnd := strings.Index(heading[parenStart:], ")")
+		if parenEnd == -1 {
+			report("malformed directive, missing ')': %s", heading[parenStart:])
+			return
+		}
+		parenEnd += parenStart
+		start = parenEnd + 1
+
+		content := strings.TrimSpace(heading[parenStart+1 : parenEnd])
+		key, value, hasValue := strings.Cut(content, ":")
+		key = strings.TrimSpace(key)
+		if !hasValue {
+			if directiveKeyPattern.MatchString(content) && !containsFold(sections.FlagDirectives, content) {
+				report("unknown directive: (%s)", content)
+			}
+			continue
+		}
+		if !directiveKeyPattern.MatchString(key) {
+			continue
+		}
+		if !containsFold(sections.ValueDirectives, key) {
+			report("unknown directive: (%s)", content)
+			continue
+		}
+		lintDirectiveValue(key, strings.TrimSpace(value), config, report)
+	}
+}
+
+// lintDirectiveValue reports an invalid value of a known directive
+func lintDirectiveValue(key string, value string, config *Config, report func(format string, args ...interface{})) {
+	if value == "" {
+		report("empty value for directive: %s", key)
+		return
+	}
+	switch key {
+	case "project":
+		if _, ok := sections.ResolveAlias(value, config.Aliases); !ok {
+			report("unknown project alias: %s", value)
+		}
+	case "priority":
+		if _, err := strconv.Atoi(value); err != nil {
+			report("invalid priority, expect an integer: %s", value)
+		}
+	case "agents":
+		for _, agent := range sections.SplitList(value) {
+			if !containsFold(knownAgents, agent) {
+				report("unknown agent: %s, expect one of %s", agent, strings.Join(knownAgents, ", "))
+			}
+		}
+	case "show":
+		if value == "daily" {
+			return
+		}
+		n, ok := strings.CutPrefix(value, "first")
+		if !ok {
+			report("invalid show, expect 'first N' or 'daily': %s", value)
+			return
+		}
+		if _, err := strconv.Atoi(strings.TrimSpace(n)); err != nil {
+			report("invalid show, expect 'first N' or 'daily': %s", value)
+		}
+	}
+}
+
+// lintProfiles lints the named profiles, or all profiles and custom.md if names is empty
+func lintProfiles(w io.Writer, names []string) error {
+	configDir, err := getConfigDir(false)
+	if err != nil {
+		return err
+	}
+	groupDir := filepath.Join(configDir, "group")
+
+	var files []string
+	if len(names) == 0 {
+		allNames, err := getGroupNames(groupDir)
+		if err != nil {
+			return err
+		}
+		for _, name := range allNames {
+			files = append(files, filepath.Join(groupDir, addMDSuffix(name)))
+		}
+		customFile := filepath.Join(configDir, "custom.md")
+		if _, err := os.Stat(customFile); err == nil {
+			files = append(files, customFile)
+		}
+	} else {
+		for _, name := range names {
+			files = append(files, filepath.Join(groupDir, addMDSuffix(name)))
+		}
+	}
+
+	var problems int
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		for _, d := range lintProfile(file, string(content), configDir) {
+			fmt.Fprintln(w, d)
+			problems++
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %d problem(s)", problems)
+	}
+	return nil
+}
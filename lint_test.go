@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestLintProfile(t *testing.T) {
+	content := `# Good(project: /some/path)(priority: 1)
+Content
+
+# Unclosed(project: /some/path
+Content
+
+# Unknown(foo: bar)(cursor-only)(typo-flag)
+Content
+
+# Plain text (not a directive at all)
+Content
+
+# Bad values(priority: high)(show: weekly)(agents: cursor, vim)
+---
+projekt: /some/path
+---
+Content
+<!-- include: fragments/missing.md -->
+
+` + "```bash" + `
+# not a heading (foo: bar)
+`
+
+	// include and code fence problems are reported first, then heading problems
+	expected := []string{
+		"custom.md:18: include file not found: fragments/missing.md",
+		"custom.md:20: unclosed code fence",
+		"custom.md:4: malformed directive, missing ')': (project: /some/path",
+		"custom.md:7: unknown directive: (foo: bar)",
+		"custom.md:7: unknown directive: (typo-flag)",
+		"custom.md:13: invalid priority, expect an integer: high",
+		"custom.md:13: invalid show, expect 'first N' or 'daily': weekly",
+		"custom.md:13: unknown agent: vim, expect one of cursor, claude",
+		"custom.md:13: unknown front-matter key: projekt",
+	}
+
+	diagnostics := lintProfile("custom.md", content, t.TempDir())
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, d.String())
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d:\n%v", len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Diagnostic %d: expected %q, got %q", i, expected[i], got[i])
+		}
+	}
+}
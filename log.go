@@ -3,28 +3,71 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 var (
 	infoLogger  *slog.Logger
 	errorLogger *slog.Logger
-	infoFile    *os.File
-	errorFile   *os.File
+	infoFile    *rotatingFile
+	errorFile   *rotatingFile
+
+	// logLevel is the level of info.txt, see resolveLogLevel
+	logLevel = new(slog.LevelVar)
 )
 
+// logLevels are the accepted values of Config.LogLevel
+var logLevels = []string{"debug", "info", "warn", "error"}
+
+// logLevelFlag is set by the --verbose and --debug global flags,
+// which also turn logging on for any command
+var logLevelFlag string
+
+func parseLogLevel(level string) (slog.Level, bool) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo, false
+	}
+	return l, true
+}
+
+// resolveLogLevel returns the level of the --verbose or --debug flag,
+// then Config.LogLevel, defaulting to info
+func resolveLogLevel(config *Config) slog.Level {
+	for _, level := range []string{logLevelFlag, config.LogLevel} {
+		if l, ok := parseLogLevel(level); ok && level != "" {
+			return l
+		}
+	}
+	return slog.LevelInfo
+}
+
 // initLoggers initializes the slog loggers for info and error logging
-func initLoggers() error {
+// in logsDir, defaulting to <configDir>/logs. It does nothing if they are
+// already initialized, e.g. by --debug.
+func initLoggers(logsDir string) error {
+	if infoLogger != nil {
+		return nil
+	}
+	logLevel.Set(resolveLogLevel(readConfigOrDefault("")))
+	if logsDir == "" {
+		var err error
+		logsDir, err = getLogsDir()
+		if err != nil {
+			return err
+		}
+	}
 	// Create logs directory if it doesn't exist
-	logsDir := "logs"
 	if err := os.MkdirAll(logsDir, 0755); err != nil {
 		// Fallback to current directory if logs dir creation fails
 		return fmt.Errorf("failed to create logs directory: %w", err)
 	}
 
 	// Setup info logger
-	tmpInfoFile, err := os.OpenFile(filepath.Join(logsDir, "info.txt"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	tmpInfoFile, err := openLogFile(filepath.Join(logsDir, "info.txt"))
 	if err != nil {
 		// Fallback to stdout if file creation fails
 		return fmt.Errorf("failed to create info file: %w", err)
@@ -32,11 +75,11 @@ func initLoggers() error {
 	infoFile = tmpInfoFile
 
 	infoLogger = slog.New(slog.NewTextHandler(tmpInfoFile, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: logLevel,
 	}))
 
 	// Setup error logger
-	tmpErrorFile, err := os.OpenFile(filepath.Join(logsDir, "error.txt"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	tmpErrorFile, err := openLogFile(filepath.Join(logsDir, "error.txt"))
 	if err != nil {
 		// Fallback to stderr if file creation fails
 		return fmt.Errorf("failed to create error file: %w", err)
@@ -49,7 +92,13 @@ func initLoggers() error {
 	return nil
 }
 
+// getLogsDir returns the default directory of the server logs
+func getLogsDir() (string, error) {
+	return getConfigPath(false, "logs")
+}
+
 func closeLoggers() error {
+	infoLogger, errorLogger = nil, nil
 	if infoFile != nil {
 		if err := infoFile.Close(); err != nil {
 			return fmt.Errorf("failed to close info file: %w", err)
@@ -63,6 +112,19 @@ func closeLoggers() error {
 	return nil
 }
 
+// isDebug tells whether Debugf messages are logged, to skip preparing them otherwise
+func isDebug() bool {
+	return infoLogger != nil && logLevel.Level() <= slog.LevelDebug
+}
+
+// Debugf logs a debug message to info.txt if the level is debug
+func Debugf(format string, args ...interface{}) {
+	if !isDebug() {
+		return
+	}
+	infoLogger.Debug(fmt.Sprintf(format, args...))
+}
+
 // Logf logs an info message to info.txt using slog
 func Logf(format string, args ...interface{}) {
 	if infoLogger == nil {
@@ -80,3 +142,43 @@ func Errorf(format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
 	errorLogger.Error(message)
 }
+
+// debugHTTPHandler logs a summary of each request and response at debug level
+func debugHTTPHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isDebug() {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		Debugf("http: %s %s from %s", r.Method, r.URL.RequestURI(), r.RemoteAddr)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(rec, r)
+		Debugf("http: %s %s -> %d, %d bytes in %v", r.Method, r.URL.Path, rec.status, rec.size, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// statusRecorder records the status and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(p)
+	r.size += n
+	return n, err
+}
+
+// Flush keeps streaming responses working through the recorder
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
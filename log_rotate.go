@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultLogMaxSize is the size a log file is rotated at
+	defaultLogMaxSize = 10 << 20
+	// defaultLogMaxFiles is the number of rotated files kept
+	defaultLogMaxFiles = 3
+)
+
+// byteSizePattern matches sizes like "512KB" or "10MB"
+const byteSizePattern = `^(?i)[0-9]+\s*(B|KB|MB|GB)?$`
+
+var byteSizeRegexp = regexp.MustCompile(byteSizePattern)
+
+// parseByteSize parses sizes like "512KB" or "10MB", plain numbers are bytes
+func parseByteSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if !byteSizeRegexp.MatchString(value) {
+		return 0, fmt.Errorf("expect a size like 10MB: %s", value)
+	}
+	upper := strings.ToUpper(value)
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper, unit = strings.TrimSuffix(upper, u.suffix), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(upper), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * unit, nil
+}
+
+// logRotation is how log files are rotated
+type logRotation struct {
+	maxSize  int64
+	maxFiles int
+	// maxAge removes rotated files older than it, and rotates a file
+	// not written for that long when opened. Zero keeps files by count only.
+	maxAge time.Duration
+}
+
+// getLogRotation reads the rotation of config, invalid values fall back to the defaults
+func getLogRotation(config *Config) logRotation {
+	rotation := logRotation{maxSize: defaultLogMaxSize, maxFiles: defaultLogMaxFiles}
+	if config == nil {
+		return rotation
+	}
+	if config.LogMaxSize != "" {
+		if size, err := parseByteSize(config.LogMaxSize); err == nil && size > 0 {
+			rotation.maxSize = size
+		}
+	}
+	if config.LogMaxFiles > 0 {
+		rotation.maxFiles = config.LogMaxFiles
+	}
+	if config.LogMaxAge != "" {
+		if age, err := time.ParseDuration(config.LogMaxAge); err == nil && age > 0 {
+			rotation.maxAge = age
+		}
+	}
+	return rotation
+}
+
+// rotatingFile is an append-only log file moved to FILE.1, FILE.2...
+// once it reaches the max size, keeping at most maxFiles of them
+type rotatingFile struct {
+	path     string
+	rotation logRotation
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+// openLogFile opens path for appending with the rotation of the config
+func openLogFile(path string) (*rotatingFile, error) {
+	return openRotatingFile(path, getLogRotation(readConfigOrDefault("")))
+}
+
+func openRotatingFile(path string, rotation logRotation) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, rotation: rotation}
+	if rotation.maxAge > 0 {
+		if stat, err := os.Stat(path); err == nil && stat.Size() > 0 && time.Since(stat.ModTime()) > rotation.maxAge {
+			if err := f.shift(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.removeExpired()
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, stat.Size()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.rotation.maxSize {
+		if err := f.rotate(); err != nil {
+			// keep logging to the current file
+			fmt.Fprintf(os.Stderr, "warning: rotate %s: %v\n", f.path, err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	shiftErr := f.shift()
+	// reopen even if shifting failed, appending to the same file
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.removeExpired()
+	return shiftErr
+}
+
+// shift renames FILE.N to FILE.N+1 and FILE to FILE.1, dropping the oldest
+func (f *rotatingFile) shift() error {
+	os.Remove(f.rotatedPath(f.rotation.maxFiles))
+	for i := f.rotation.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(f.rotatedPath(i), f.rotatedPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(f.path, f.rotatedPath(1))
+}
+
+func (f *rotatingFile) rotatedPath(i int) string {
+	return f.path + "." + strconv.Itoa(i)
+}
+
+// removeExpired removes rotated files older than maxAge, and the ones beyond
+// maxFiles left by a previous larger setting
+func (f *rotatingFile) removeExpired() {
+	matches, _ := filepath.Glob(f.path + ".*")
+	for _, match := range matches {
+		i, err := strconv.Atoi(strings.TrimPrefix(match, f.path+"."))
+		if err != nil || i < 1 {
+			continue
+		}
+		if i > f.rotation.maxFiles {
+			os.Remove(match)
+			continue
+		}
+		if f.rotation.maxAge > 0 {
+			if stat, err := os.Stat(match); err == nil && time.Since(stat.ModTime()) > f.rotation.maxAge {
+				os.Remove(match)
+			}
+		}
+	}
+}
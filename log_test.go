@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitLoggersInConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	if err := initLoggers(""); err != nil {
+		t.Fatal(err)
+	}
+	Logf("hello %s", "logs")
+	if err := closeLoggers(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "logs", "info.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "hello logs") {
+		t.Errorf("unexpected log: %s", content)
+	}
+
+	logDir := filepath.Join(t.TempDir(), "custom")
+	if err := initLoggers(logDir); err != nil {
+		t.Fatal(err)
+	}
+	closeLoggers()
+	if _, err := os.Stat(filepath.Join(logDir, "error.txt")); err != nil {
+		t.Errorf("expect logs in --log-dir: %v", err)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	t.Cleanup(func() { logLevelFlag = "" })
+	args, err := extractGlobalFlags([]string{"serve", "--debug", "--port", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(args, " ") != "serve --port 1" || logLevelFlag != "debug" {
+		t.Fatalf("unexpected args %q, level %q", args, logLevelFlag)
+	}
+	if level := resolveLogLevel(&Config{LogLevel: "error"}); level != slog.LevelDebug {
+		t.Errorf("expect the flag to win over config, got %v", level)
+	}
+	logLevelFlag = ""
+	if level := resolveLogLevel(&Config{LogLevel: "warn"}); level != slog.LevelWarn {
+		t.Errorf("expect config level, got %v", level)
+	}
+	if level := resolveLogLevel(&Config{}); level != slog.LevelInfo {
+		t.Errorf("expect info by default, got %v", level)
+	}
+}
+
+func TestDebugf(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	t.Cleanup(func() { logLevelFlag = "" })
+	read := func() string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(dir, "logs", "info.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	if err := initLoggers(""); err != nil {
+		t.Fatal(err)
+	}
+	Debugf("hidden detail")
+	closeLoggers()
+	if strings.Contains(read(), "hidden detail") {
+		t.Errorf("expect debug messages skipped at info level")
+	}
+
+	logLevelFlag = "debug"
+	if err := initLoggers(""); err != nil {
+		t.Fatal(err)
+	}
+	handler := debugHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status", nil))
+	closeLoggers()
+	if log := read(); !strings.Contains(log, "GET /status -> 418") {
+		t.Errorf("expect the HTTP summary logged, got:\n%s", log)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+	"golang.org/x/term"
+)
+
+// logsPollInterval is how often followed log files are checked for new lines
+const logsPollInterval = 200 * time.Millisecond
+
+const logsHelp = `
+Usage:
+  whats_next logs tail [--client|--server] [-n LINES] [--no-follow] [--log-dir DIR]
+  whats_next logs path [--client|--server] [--log-dir DIR]
+
+tail prints the last lines of the logs, then follows them until interrupted,
+coloring the levels. By default it follows both the client log
+~/.whats_next.log and the server logs info.txt and error.txt under
+<config dir>/logs, written by serve --log, --verbose or --debug.
+
+path prints the paths of the log files.
+
+Options:
+  --client         Only the client log
+  --server         Only the server logs
+  -n LINES         Number of last lines printed first (default: 20)
+  --no-follow      Exit after printing the last lines
+  --log-dir DIR    The server log dir, if serve was given --log-dir
+`
+
+func handleLogs(args []string) error {
+	var client bool
+	var server bool
+	lines := 20
+	var noFollow bool
+	var logDir string
+	args, err := flags.Bool("--client", &client).
+		Bool("--server", &server).
+		Int("-n", &lines).
+		Bool("--no-follow", &noFollow).
+		String("--log-dir", &logDir).
+		Help("-h,--help", logsHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("requires: tail or path")
+	}
+	if !client && !server {
+		client, server = true, true
+	}
+	files, err := getLogFiles(client, server, logDir)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "path":
+		for _, file := range files {
+			fmt.Println(file)
+		}
+		return nil
+	case "tail":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		color := term.IsTerminal(int(os.Stdout.Fd()))
+		return tailLogs(ctx, os.Stdout, files, lines, !noFollow, color)
+	}
+	return fmt.Errorf("unrecognized logs command: %s", args[0])
+}
+
+// getLogFiles returns the client and server log files
+func getLogFiles(client bool, server bool, logDir string) ([]string, error) {
+	var files []string
+	if client {
+		file, err := getClientLogPath()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if server {
+		if logDir == "" {
+			var err error
+			logDir, err = getLogsDir()
+			if err != nil {
+				return nil, err
+			}
+		}
+		files = append(files, filepath.Join(logDir, "info.txt"), filepath.Join(logDir, "error.txt"))
+	}
+	return files, nil
+}
+
+// followedLog is a log file being followed
+type followedLog struct {
+	file   string
+	label  string
+	offset int64
+	// partial is the last line not ended yet
+	partial []byte
+}
+
+// tailLogs writes the last lines of files to w, then the lines appended to
+// them until ctx is done if follow is set. Files may not exist yet, and are
+// read from their start again when rotated.
+func tailLogs(ctx context.Context, w io.Writer, files []string, lines int, follow bool, color bool) error {
+	logs := make([]*followedLog, 0, len(files))
+	for _, file := range files {
+		log := &followedLog{file: file, label: logLabel(file)}
+		last, offset, err := lastLines(file, lines)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for _, line := range last {
+			writeLogLine(w, log.label, line, color, len(files) > 1)
+		}
+		log.offset = offset
+		logs = append(logs, log)
+	}
+	if !follow {
+		return nil
+	}
+	ticker := time.NewTicker(logsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		for _, log := range logs {
+			newLines, err := log.read()
+			if err != nil {
+				return err
+			}
+			for _, line := range newLines {
+				writeLogLine(w, log.label, line, color, len(files) > 1)
+			}
+		}
+	}
+}
+
+// read returns the complete lines appended since the last read
+func (l *followedLog) read() ([]string, error) {
+	stat, err := os.Stat(l.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// rotated away, or not created yet
+			l.offset, l.partial = 0, nil
+			return nil, nil
+		}
+		return nil, err
+	}
+	if stat.Size() < l.offset {
+		// rotated, start over with the new file
+		l.offset, l.partial = 0, nil
+	}
+	if stat.Size() == l.offset {
+		return nil, nil
+	}
+	f, err := os.Open(l.file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(l.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	l.offset += int64(len(data))
+	data = append(l.partial, data...)
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		l.partial = data
+		return nil, nil
+	}
+	l.partial = append([]byte(nil), data[end+1:]...)
+	return strings.Split(string(data[:end]), "\n"), nil
+}
+
+// lastLines returns the last n complete lines of file and the size read
+func lastLines(file string, n int) ([]string, int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	var lines []string
+	var offset int64
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// an incomplete last line is left to follow
+			break
+		}
+		offset += int64(len(line))
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	if n <= 0 {
+		lines = nil
+	}
+	return lines, offset, nil
+}
+
+func logLabel(file string) string {
+	switch filepath.Base(file) {
+	case "info.txt":
+		return "server"
+	case "error.txt":
+		return "server error"
+	}
+	return "client"
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiGray   = "\x1b[90m"
+)
+
+// writeLogLine writes line, prefixed with the label of its file if several are followed
+func writeLogLine(w io.Writer, label string, line string, color bool, prefix bool) {
+	if color {
+		line = colorizeLogLevel(line)
+	}
+	if prefix {
+		if color {
+			label = ansiGray + label + ansiReset
+		}
+		fmt.Fprintf(w, "[%s] %s\n", label, line)
+		return
+	}
+	fmt.Fprintln(w, line)
+}
+
+// logLevelColors are the colors of the levels of slog lines, and of the tags of client lines
+var logLevelColors = []struct {
+	token string
+	color string
+}{
+	{"level=ERROR", ansiRed},
+	{"level=WARN", ansiYellow},
+	{"level=INFO", ansiBlue},
+	{"level=DEBUG", ansiGray},
+	{"[stderr]", ansiRed},
+	{"[signal]", ansiYellow},
+}
+
+// colorizeLogLevel colors the level of a slog or client log line
+func colorizeLogLevel(line string) string {
+	for _, level := range logLevelColors {
+		if i := strings.Index(line, level.token); i >= 0 {
+			return line[:i] + level.color + level.token + ansiReset + line[i+len(level.token):]
+		}
+	}
+	return line
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for the tail goroutine and the test
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func TestTailLogs(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "info.txt")
+	if err := os.WriteFile(file, []byte("one\ntwo\nthree\npart"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- tailLogs(ctx, &out, []string{file}, 2, true, false)
+	}()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expect %q in output, got:\n%s", want, out.String())
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitFor("two\nthree\n")
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("ial\nfour\n")
+	f.Close()
+	waitFor("partial\nfour\n")
+
+	// rotation starts over with the new file
+	if err := os.Rename(file, file+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("five\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("five\n")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); strings.Contains(got, "one") {
+		t.Errorf("expect only the last 2 lines first, got:\n%s", got)
+	}
+}
+
+func TestWriteLogLine(t *testing.T) {
+	var buf bytes.Buffer
+	writeLogLine(&buf, "server", `time=now level=ERROR msg="boom"`, true, true)
+	want := "[" + ansiGray + "server" + ansiReset + "] time=now " + ansiRed + "level=ERROR" + ansiReset + ` msg="boom"` + "\n"
+	if buf.String() != want {
+		t.Errorf("unexpected line: %q, want %q", buf.String(), want)
+	}
+	buf.Reset()
+	writeLogLine(&buf, "client", "[12:00] [stdout] hello", false, false)
+	if buf.String() != "[12:00] [stdout] hello\n" {
+		t.Errorf("unexpected line: %q", buf.String())
+	}
+}
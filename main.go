@@ -6,11 +6,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/xhd2015/less-gen/flags"
-	"github.com/xhd2015/xgo/support/cmd"
+	"github.com/xhd2015/whats_next/pkg/config"
+	"github.com/xhd2015/whats_next/pkg/sections"
 	"golang.org/x/term"
 )
 
@@ -23,7 +25,12 @@ func GetProgramName() string {
 	return "whats_next"
 }
 
+// getGeneralGuideline returns builtins/general.md if it exists, otherwise the default
 func getGeneralGuideline() string {
+	return getBuiltinText("general.md")
+}
+
+func defaultGeneralGuideline() string {
 	programName := GetProgramName()
 	return `
 # Follow-up with ` + "`" + programName + "`" + `
@@ -126,15 +133,47 @@ Commands:
   show
   edit
   add
+  rm
   where
+  init
+  install
+  uninstall
+  config
+  doctor
+  export
+  completion
+  url
+  backup
+  restore
+  digest
+  history
+  recover
+  count
+  remind
+  todo
+  plan
+  push
+  reply
+  ask
+  pause
+  resume
+  prompt
+  status
+  telemetry
+  logs
+  version
 
   list
   use
   group
 
 Options:
-  --port PORT    Connect to server on specified port (default: 7654)
-  --editor EDITOR
+  --port PORT    Connect to server on specified port (default: serverPort or 7654)
+  --editor EDITOR  Editor of edit and group edit, edits in the terminal if not installed
+  --config-dir DIR  Use DIR as the config directory (env: WHATS_NEXT_CONFIG_DIR)
+  --copy         Also copy the wrapped question to the clipboard
+  --verbose      Log to <config dir>/logs, see logLevel in config
+  --debug        Log with debug details: input events, queue and HTTP requests
 
 Sub commands for group:
   list
@@ -143,11 +182,14 @@ Sub commands for group:
   use
   rm, remove
   mv, rename
+  lint
+  audit [name]   Show the recorded changes of a profile, or of all
 `
 }
 
 func main() {
 	err := handleCommands(os.Args[1:])
+	waitWebhooks()
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -159,6 +201,22 @@ var USE_BACKSLAHS = false
 const DISABLE_TIMER = false
 
 func handleCommands(args []string) error {
+	args, err := extractGlobalFlags(args)
+	if err != nil {
+		return err
+	}
+	if logLevelFlag != "" {
+		if err := initLoggers(""); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+		defer closeLoggers()
+	}
+	args, err = expandCommandAlias(args)
+	if err != nil {
+		return err
+	}
+	mergeSyncConflictsOnStart(args)
+	defer recordTelemetry(args)
 	if len(args) > 0 {
 		cmd := args[0]
 		// If first arg starts with "-", treat as options for the default whats_next command
@@ -167,11 +225,7 @@ func handleCommands(args []string) error {
 		}
 		switch cmd {
 		case "show":
-			showArgs := args[1:]
-			if len(showArgs) > 0 && !strings.HasPrefix(showArgs[0], "-") {
-				return group(append([]string{"show"}, showArgs...))
-			}
-			return show(showArgs)
+			return show(args[1:])
 		case "edit":
 			editArgs := args[1:]
 			if len(editArgs) > 0 && !strings.HasPrefix(editArgs[0], "-") {
@@ -184,51 +238,218 @@ func handleCommands(args []string) error {
 			return group(append([]string{"list"}, args[1:]...))
 		case "add":
 			return add(args[1:])
+		case "rm":
+			return handleRm(args[1:])
+		case "count":
+			return handleCount(args[1:])
+		case "remind":
+			return handleRemind(args[1:])
+		case "todo":
+			return handleTodo(args[1:])
+		case "plan":
+			return handlePlan(args[1:])
+		case "push":
+			return handlePush(args[1:])
+		case "reply":
+			return handleReply(args[1:])
+		case "ask":
+			return handleAsk(args[1:])
+		case "pause":
+			return handlePause(args[1:], true)
+		case "resume":
+			return handlePause(args[1:], false)
 		case "where":
 			return where(args[1:])
 		case "config":
 			return handleConfig(args[1:])
 		case "group":
 			return group(args[1:])
+		case "doctor":
+			return handleDoctor(args[1:])
+		case "export":
+			return handleExport(args[1:])
+		case "completion":
+			return handleCompletion(args[1:])
+		case "url":
+			return handleURL(args[1:])
+		case "backup":
+			return handleBackup(args[1:])
+		case "restore":
+			return handleRestore(args[1:])
+		case "status":
+			return handleStatus(args[1:])
+		case "telemetry":
+			return handleTelemetry(args[1:])
+		case "logs":
+			return handleLogs(args[1:])
+		case "version":
+			return handleVersion(args[1:])
+		case "prompt":
+			return handlePrompt(args[1:])
+		case "recover":
+			return handleRecover(args[1:])
+		case "install":
+			return handleInstall(args[1:])
+		case "uninstall":
+			return handleUninstall(args[1:])
+		case "history":
+			return handleHistory(args[1:])
+		case "digest":
+			return handleDigest(args[1:])
+		case "init":
+			return handleInit(args[1:])
 		case "serve":
 			return handleServer(args[1:])
 		case "--help", "help":
 			return handleHelp(args[1:])
 		default:
-			return fmt.Errorf("unrecognized command: %s", cmd)
+			if suggestion, ok := suggestCommand(cmd); ok {
+				return fmt.Errorf("unrecognized command: %s, did you mean %s?", cmd, suggestion)
+			}
+			return fmt.Errorf("unrecognized command: %s, define it with `%s config set commandAliases.%s COMMAND`", cmd, GetProgramName(), cmd)
 		}
 	}
+	if shouldShowRootMenu() {
+		return runRootMenu()
+	}
 	return handleWhatsNext(args)
 }
 
-func show(args []string) error {
-	return showW(os.Stdout)
-}
-
-func showW(w io.Writer) error {
-	fmt.Fprintln(w, strings.TrimPrefix(getGeneralGuideline(), "\n"))
-
-	fmt.Fprintln(w, strings.TrimPrefix(toolCallAwareness, "\n"))
-
-	fmt.Fprintln(w, strings.TrimPrefix(runningCommand, "\n"))
+const showHelp = `
+Usage:
+  whats_next show [PROFILE] [--use] [--dir DIR] [--agent AGENT] [--explain]
 
-	fmt.Fprintln(w, strings.TrimPrefix(noTest, "\n"))
+Prints the built-in guidelines and custom.md, or the profile PROFILE.
 
-	fmt.Fprintln(w, strings.TrimPrefix(dontIgnoreLint, "\n"))
+With --dir or --agent, prints instead exactly the guidelines an agent working
+in DIR receives with each reply: the selected profile of DIR, or PROFILE, with
+the (project: ...), (agents: ...) and other directives applied.
 
-	fmt.Fprintln(w, strings.TrimPrefix(serverImplementation, "\n"))
+With --explain, prints every section of that profile followed by a comment
+telling whether it is emitted in DIR, how it matched (no-project, path, glob,
+worktree or excluded) and its specificity. Among the sections matching a
+project path, only those of the most specific one are emitted.
 
-	fmt.Fprintln(w, strings.TrimPrefix(ignoreLint, "\n"))
+Options:
+  --use            Also select PROFILE, like ` + "`whats_next use`" + `
+  --dir DIR        The directory of the agent (default: the current directory)
+  --agent AGENT    The agent, cursor or claude (default: detected from the environment)
+  --explain        Annotate each section with why it is emitted or not
+`
 
-	fmt.Fprintln(w, strings.TrimPrefix(verify, "\n"))
+func show(args []string) error {
+	return showWith(args, false)
+}
 
-	fmt.Fprintln(w, strings.TrimPrefix(pattern, "\n"))
+// showWith implements show, and group show if profile is set,
+// which selects a profile when none is given
+func showWith(args []string, profile bool) error {
+	var use bool
+	var dir string
+	var agent string
+	var explain bool
+	args, err := flags.Bool("--use", &use).
+		String("--dir", &dir).
+		String("--agent", &agent).
+		Bool("--explain", &explain).
+		Help("-h,--help", showHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args[1:], " "))
+	}
+	if dir == "" && agent == "" && !explain {
+		if len(args) > 0 || profile {
+			return groupShow(use, args)
+		}
+		if use {
+			return fmt.Errorf("--use requires PROFILE")
+		}
+		return showW(os.Stdout)
+	}
+	if use {
+		return fmt.Errorf("--use cannot be used with --dir, --agent or --explain")
+	}
+	var name string
+	if len(args) > 0 {
+		name = args[0]
+	}
+	return showPreview(os.Stdout, name, dir, agent, explain)
+}
 
-	fmt.Fprintln(w, strings.TrimPrefix(recover, "\n"))
+// showPreview writes the guidelines an agent sees in dir, using the profile
+// selected there if profile is empty. With explain, it writes the annotated
+// sections of the profile instead.
+func showPreview(w io.Writer, profile string, dir string, agent string, explain bool) error {
+	var err error
+	if dir == "" {
+		dir, err = os.Getwd()
+	} else {
+		dir, err = filepath.Abs(dir)
+	}
+	if err != nil {
+		return err
+	}
+	if stat, err := os.Stat(dir); err != nil {
+		return err
+	} else if !stat.IsDir() {
+		return fmt.Errorf("not a directory: %s", dir)
+	}
+	cursor := isCursor()
+	switch strings.ToLower(agent) {
+	case "":
+	case "cursor":
+		cursor = true
+	case "claude":
+		cursor = false
+	default:
+		return fmt.Errorf("unrecognized agent: %s, expect cursor or claude", agent)
+	}
 
-	fmt.Fprintln(w, strings.TrimPrefix(goCompileInstruction, "\n"))
+	config, err := readEffectiveConfig(dir)
+	if err != nil {
+		return err
+	}
+	if profile == "" {
+		profile = config.SelectedProfile
+	} else {
+		groupDir, err := getGroupConfigPath(false)
+		if err != nil {
+			return err
+		}
+		profile = strings.TrimSuffix(profile, ".md")
+		if _, err := os.Stat(filepath.Join(groupDir, addMDSuffix(profile))); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("no profile named %s", profile)
+			}
+			return err
+		}
+	}
+	if !explain {
+		writeGuidelines(w, config, profile, dir, cursor, false)
+		return nil
+	}
+	if profile == "" {
+		return fmt.Errorf("no profile selected in %s, the built-in guidelines have no sections to explain", dir)
+	}
+	groupDir, err := getGroupConfigPath(false)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(filepath.Join(groupDir, addMDSuffix(profile)))
+	if err != nil {
+		return err
+	}
+	writeExplanation(w, expandIncludes(string(content), filepath.Dir(groupDir)), dir, cursor)
+	return nil
+}
 
-	fmt.Fprintln(w, strings.TrimPrefix(dumpPrompt, "\n"))
+func showW(w io.Writer) error {
+	for _, builtin := range builtinTexts {
+		fmt.Fprintln(w, strings.TrimPrefix(getBuiltinText(builtin.file), "\n"))
+	}
 
 	customFile, err := getCustomFile(false)
 	if err != nil {
@@ -262,8 +483,12 @@ func edit(args []string) error {
 	if err != nil {
 		return err
 	}
-	openCmd := getEditor(editor)
-	return cmd.Debug().Run(openCmd, file)
+	before := readAuditSnapshot(file)
+	if err := editFile(editor, file, 0); err != nil {
+		return err
+	}
+	recordAudit(auditActionEdit, auditCustomName, "edit", before, readAuditSnapshot(file))
+	return nil
 }
 
 func group(args []string) error {
@@ -274,19 +499,37 @@ func group(args []string) error {
 	args = args[1:]
 
 	if groupCmd == "use" {
-		return groupShow(true, args)
-	}
-	if groupCmd == "show" {
-		var use bool
-		args, err := flags.Bool("--use", &use).Parse(args)
+		var dryRun bool
+		args, err := flags.Bool("--dry-run", &dryRun).
+			Help("-h,--help", useHelp).
+			Parse(args)
 		if err != nil {
 			return err
 		}
-		return groupShow(use, args)
+		if dryRun {
+			return useDryRun(os.Stdout, args)
+		}
+		return groupShow(true, args)
+	}
+	if groupCmd == "show" {
+		return showWith(args, true)
 	}
 
 	switch groupCmd {
+	case "lint":
+		return lintProfiles(os.Stdout, args)
 	case "list":
+		var custom bool
+		args, err := flags.Bool("--custom", &custom).Parse(args)
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+		}
+		if custom {
+			return listCustomTitles(os.Stdout)
+		}
 		groupDir, err := getConfigPath(true, "group")
 		if err != nil {
 			return err
@@ -296,7 +539,8 @@ func group(args []string) error {
 			return err
 		}
 		var selectedProfile string
-		config, err := readConfig()
+		wd, _ := os.Getwd()
+		config, err := readEffectiveConfig(wd)
 		if err == nil && config.SelectedProfile != "" {
 			selectedProfile = config.SelectedProfile
 		}
@@ -311,7 +555,10 @@ func group(args []string) error {
 		return nil
 	case "edit":
 		var editor string
-		args, err := flags.String("--editor", &editor).Parse(args)
+		var gotoSection string
+		args, err := flags.String("--editor", &editor).
+			String("--goto", &gotoSection).
+			Parse(args)
 		if err != nil {
 			return err
 		}
@@ -339,15 +586,49 @@ func group(args []string) error {
 			if err := showW(&b); err != nil {
 				return err
 			}
-			if err := os.WriteFile(groupFile, []byte(b.String()), 0644); err != nil {
+			if err := writeFileAtomic(groupFile, []byte(b.String()), 0644); err != nil {
 				return err
 			}
+			recordAudit(auditActionCreate, name, "group edit", "", b.String())
 		}
 		if stat != nil && stat.IsDir() {
 			return fmt.Errorf("group config is a dir, not a file: %s", groupFile)
 		}
-		openCmd := getEditor(editor)
-		return cmd.Debug().Run(openCmd, groupFile)
+		var line int
+		if gotoSection != "" {
+			content, err := os.ReadFile(groupFile)
+			if err != nil {
+				return err
+			}
+			line, err = sections.FindSectionLine(string(content), gotoSection)
+			if err != nil {
+				return err
+			}
+		}
+		before := readAuditSnapshot(groupFile)
+		if err := editFile(editor, groupFile, line); err != nil {
+			return err
+		}
+		recordAudit(auditActionEdit, name, "group edit", before, readAuditSnapshot(groupFile))
+		return nil
+	case "audit":
+		if len(args) > 1 {
+			return fmt.Errorf("requires at most one name")
+		}
+		var name string
+		if len(args) == 1 {
+			name = args[0]
+		}
+		entries, err := readAudit(name)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("no changes recorded")
+			return nil
+		}
+		printAudit(os.Stdout, entries)
+		return nil
 	case "rename", "mv":
 		if len(args) != 2 {
 			return fmt.Errorf("requires old name and new name")
@@ -372,6 +653,7 @@ func group(args []string) error {
 		if err := os.Rename(oldFile, newFile); err != nil {
 			return err
 		}
+		recordRenameAudit(oldName, newName, "group rename")
 		return nil
 	case "rm", "remove":
 		if len(args) != 1 {
@@ -384,31 +666,17 @@ func group(args []string) error {
 		}
 		name = addMDSuffix(name)
 		groupFile := filepath.Join(groupDir, name)
+		before := readAuditSnapshot(groupFile)
 		if err := os.Remove(groupFile); err != nil {
 			return err
 		}
+		recordAudit(auditActionRemove, name, "group rm", before, "")
 		return nil
 	default:
 		return fmt.Errorf("unrecognized %s", groupCmd)
 	}
 }
 
-func getEditor(editor string) string {
-	if editor != "" {
-		return editor
-	}
-
-	// read config
-	config, err := readConfig()
-	if err != nil {
-		return "code"
-	}
-	if config.Editor != "" {
-		return config.Editor
-	}
-	return "code"
-}
-
 func handleHelp(args []string) error {
 	fmt.Print(strings.TrimPrefix(getHelp(), "\n"))
 	return nil
@@ -489,7 +757,8 @@ func getGroupNames(groupDir string) ([]string, error) {
 	}
 	result := make([]string, 0, len(entries))
 	for _, entry := range entries {
-		if entry.IsDir() {
+		// skip backups and temporary files of writeFileAtomic
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
 			continue
 		}
 		result = append(result, strings.TrimSuffix(entry.Name(), ".md"))
@@ -499,31 +768,57 @@ func getGroupNames(groupDir string) ([]string, error) {
 
 func getAddHelp() string {
 	return `
-` + GetProgramName() + ` add [content]
+` + GetProgramName() + ` add [content|-] [--file FILE] [--group NAME]
+
+Appends content to custom.md, or to a profile with --group.
+Content "-" is read from stdin. Without content in a terminal, it is written
+in the editor chosen by --editor, config editor, $VISUAL or $EDITOR, or else
+inline, starting with the title.
 
 Options:
-  --title TITLE
+  --title TITLE    Add a heading before the content
+  --file FILE      Read the content from FILE
+  --group NAME     Append to the profile NAME instead of custom.md
+  --editor EDITOR  The editor to write the content in
 
 `
 }
 
 func add(args []string) error {
 	var title string
+	var file string
+	var groupName string
+	var editor string
+	// "-" reads stdin, taken out before the flags parser rejects it as a flag
+	var stdin bool
+	if i := slices.Index(args, "-"); i >= 0 {
+		stdin = true
+		args = slices.Delete(slices.Clone(args), i, i+1)
+	}
 	args, readErr := flags.String("--title", &title).
+		String("--file", &file).
+		String("--group", &groupName).
+		String("--editor", &editor).
 		Help("-h,--help", getAddHelp()).
 		Parse(args)
 	if readErr != nil {
 		return readErr
 	}
-	if len(args) == 0 {
-		return fmt.Errorf("requires content")
+	var content string
+	if file == "" && !stdin && len(args) == 0 && term.IsTerminal(int(os.Stdin.Fd())) {
+		content, readErr = composeAddContent(editor, formatAddTitle(title))
+		// the title is part of what was composed
+		title = ""
+	} else {
+		content, args, readErr = readAddContent(args, file, stdin)
+	}
+	if readErr != nil {
+		return readErr
 	}
-	content := args[0]
 	content = strings.TrimSpace(content)
 	if content == "" {
 		return fmt.Errorf("requires non-empty content")
 	}
-	args = args[1:]
 
 	if len(args) > 0 {
 		return fmt.Errorf("unrecognized extra arguments: %v", strings.Join(args, ","))
@@ -533,18 +828,34 @@ func add(args []string) error {
 	if readErr != nil {
 		return readErr
 	}
+	auditName := auditCustomName
+	if groupName != "" {
+		groupDir, err := getGroupConfigPath(true)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(groupDir, 0755); err != nil {
+			return err
+		}
+		auditName = addMDSuffix(groupName)
+		customFile = filepath.Join(groupDir, auditName)
+	}
 
+	unlock, readErr := lockConfigDir()
+	if readErr != nil {
+		return readErr
+	}
+	defer unlock()
 	custom, readErr := os.ReadFile(customFile)
 	if readErr != nil {
 		if !os.IsNotExist(readErr) {
 			return readErr
 		}
 	}
+	before := string(custom)
 
 	if title != "" {
-		if !strings.HasPrefix(title, "# ") {
-			title = "# " + title
-		}
+		title = formatAddTitle(title)
 		custom = append(custom, []byte(title)...)
 		custom = append(custom, []byte("\n")...)
 	}
@@ -552,33 +863,96 @@ func add(args []string) error {
 	custom = append(custom, []byte(content)...)
 	custom = append(custom, []byte("\n")...)
 
-	if err := os.WriteFile(customFile, custom, 0644); err != nil {
+	if err := writeFileAtomic(customFile, custom, 0644); err != nil {
 		return err
 	}
+	recordAudit(auditActionAdd, auditName, "add", before, string(custom))
 
 	return nil
 }
 
-func where(args []string) error {
-	if len(args) > 0 {
-		return fmt.Errorf("where command does not accept arguments")
+// formatAddTitle turns title into a heading, empty if title is
+func formatAddTitle(title string) string {
+	if title == "" || strings.HasPrefix(title, "# ") {
+		return title
 	}
+	return "# " + title
+}
 
-	configDir, err := getConfigDir(false)
+// composeAddContent lets the user write the content to add, starting with heading
+func composeAddContent(editor string, heading string) (string, error) {
+	initial := ""
+	if heading != "" {
+		initial = heading + "\n"
+	}
+	editor = getConfiguredEditor(editor)
+	if editor == "" {
+		return composeInTerminal(initial)
+	}
+	f, err := os.CreateTemp("", "whats_next-add-*.md")
 	if err != nil {
-		return err
+		return "", err
+	}
+	tmpFile := f.Name()
+	defer os.Remove(tmpFile)
+	_, err = f.WriteString(initial)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := openInEditor(editor, tmpFile); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(tmpFile)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(data)) == strings.TrimSpace(initial) {
+		// only the heading is left, nothing to add
+		return "", nil
 	}
+	return string(data), nil
+}
 
-	fmt.Println(configDir)
-	return nil
+// readAddContent returns the content to add: read from file if set,
+// from stdin if set, otherwise the first arg.
+// The remaining args are returned.
+func readAddContent(args []string, file string, stdin bool) (string, []string, error) {
+	if file != "" && stdin {
+		return "", nil, fmt.Errorf("--file and - cannot be used together")
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", nil, err
+		}
+		return string(data), args, nil
+	}
+	if stdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", nil, err
+		}
+		return string(data), args, nil
+	}
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("requires content, --file or - for stdin")
+	}
+	return args[0], args[1:], nil
 }
 
+// configDirFlag is set by the global --config-dir flag
+var configDirFlag string
+
+// getConfigDir returns the config directory: the --config-dir flag,
+// then $WHATS_NEXT_CONFIG_DIR, then whats_next under the user config dir
 func getConfigDir(createDir bool) (string, error) {
-	conf, err := os.UserConfigDir()
+	configDir, err := config.ResolveDir(configDirFlag)
 	if err != nil {
 		return "", err
 	}
-	configDir := filepath.Join(conf, "whats_next")
 	if createDir {
 		if err := os.MkdirAll(configDir, 0755); err != nil {
 			return "", err
@@ -587,6 +961,40 @@ func getConfigDir(createDir bool) (string, error) {
 	return configDir, nil
 }
 
+// extractGlobalFlags removes global flags like --config-dir from args,
+// they may appear anywhere before a "--"
+func extractGlobalFlags(args []string) ([]string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if arg == "--verbose" || arg == "--debug" {
+			logLevelFlag = "info"
+			if arg == "--debug" {
+				logLevelFlag = "debug"
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--config-dir="); ok {
+			configDirFlag = value
+			continue
+		}
+		if arg == "--config-dir" {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requires a value", arg)
+			}
+			configDirFlag = args[i+1]
+			i++
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, nil
+}
+
 func getConfigPath(createDir bool, name string) (string, error) {
 	configDir, err := getConfigDir(createDir)
 	if err != nil {
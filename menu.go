@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// rootMenuTimeout is how long the root menu waits for a key before falling
+// back to waiting for input, in case an agent was taken for a human
+const rootMenuTimeout = 15 * time.Second
+
+// agentEnvVars are set in the terminals of agents
+var agentEnvVars = []string{"CLAUDECODE", "CURSOR_AGENT", "WHATS_NEXT_AGENT"}
+
+// rootMenuItem is an entry of the menu shown when run standalone
+type rootMenuItem struct {
+	label string
+	run   func() error
+}
+
+func getRootMenuItems() []rootMenuItem {
+	return []rootMenuItem{
+		{"Wait for input, as an agent would", func() error { return handleWhatsNext(nil) }},
+		{"Start the server", func() error { return handleServer(nil) }},
+		{"Use a profile", func() error { return group([]string{"use"}) }},
+		{"Edit a profile", func() error { return group([]string{"edit"}) }},
+		{"Show the status of the server", func() error { return handleStatus(nil) }},
+	}
+}
+
+// shouldShowRootMenu tells whether running without args should show the menu:
+// a human in a terminal who has not selected a profile yet
+func shouldShowRootMenu() bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return false
+	}
+	for _, env := range agentEnvVars {
+		if os.Getenv(env) != "" {
+			return false
+		}
+	}
+	wd, _ := os.Getwd()
+	config, err := readEffectiveConfig(wd)
+	return err == nil && config.SelectedProfile == ""
+}
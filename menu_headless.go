@@ -0,0 +1,37 @@
+//go:build headless
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// runRootMenu lets the user choose what to do by number in a headless build,
+// Enter alone waits for input
+func runRootMenu() error {
+	items := getRootMenuItems()
+	for i, item := range items {
+		fmt.Fprintf(os.Stdout, "%d. %s\n", i+1, item.label)
+	}
+	fmt.Fprintf(os.Stdout, "Choose 1-%d, q to quit: ", len(items))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return err
+	}
+	answer = strings.TrimSpace(answer)
+	switch answer {
+	case "":
+		return items[0].run()
+	case "q", "Q":
+		return nil
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(items) {
+		return fmt.Errorf("invalid choice: %s", answer)
+	}
+	return items[n-1].run()
+}
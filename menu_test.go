@@ -0,0 +1,10 @@
+package main
+
+import "testing"
+
+func TestShouldShowRootMenuForAgents(t *testing.T) {
+	t.Setenv("CLAUDECODE", "1")
+	if shouldShowRootMenu() {
+		t.Errorf("Expected no menu for agents")
+	}
+}
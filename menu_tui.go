@@ -0,0 +1,109 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type rootMenuModel struct {
+	items  []string
+	cursor int
+	// deadline chooses the first item if no key is pressed before it, zero once one is
+	deadline time.Time
+	// chosen is the index of the chosen item, -1 if quit
+	chosen int
+}
+
+type rootMenuTickMsg time.Time
+
+func rootMenuTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return rootMenuTickMsg(t)
+	})
+}
+
+func (m rootMenuModel) Init() tea.Cmd {
+	return rootMenuTick()
+}
+
+func (m rootMenuModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case rootMenuTickMsg:
+		if m.deadline.IsZero() {
+			return m, nil
+		}
+		if !time.Time(msg).Before(m.deadline) {
+			m.chosen = 0
+			return m, tea.Quit
+		}
+		return m, rootMenuTick()
+	case tea.KeyMsg:
+		// any key stops the countdown
+		m.deadline = time.Time{}
+		switch msg.String() {
+		case "ctrl+c", "esc", "q":
+			m.chosen = -1
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.items)-1 {
+				m.cursor++
+			}
+		case "enter":
+			m.chosen = m.cursor
+			return m, tea.Quit
+		default:
+			var n int
+			if _, err := fmt.Sscanf(msg.String(), "%d", &n); err == nil && n >= 1 && n <= len(m.items) {
+				m.chosen = n - 1
+				return m, tea.Quit
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m rootMenuModel) View() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: no profile selected, what do you want to do?\n\n", GetProgramName())
+	for i, item := range m.items {
+		cursor := " "
+		if i == m.cursor {
+			cursor = ">"
+		}
+		fmt.Fprintf(&b, "%s %d. %s\n", cursor, i+1, item)
+	}
+	b.WriteString("\n↑/↓ or 1-9 to choose • enter to confirm • q to quit")
+	if !m.deadline.IsZero() {
+		remaining := time.Until(m.deadline)
+		fmt.Fprintf(&b, " • waiting for input in %ds", int(remaining.Round(time.Second).Seconds()))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// runRootMenu lets the user choose what to do and runs it
+func runRootMenu() error {
+	items := getRootMenuItems()
+	model := rootMenuModel{deadline: time.Now().Add(rootMenuTimeout)}
+	for _, item := range items {
+		model.items = append(model.items, item.label)
+	}
+	finalModel, err := tea.NewProgram(model).Run()
+	if err != nil {
+		return err
+	}
+	chosen := finalModel.(rootMenuModel).chosen
+	if chosen < 0 {
+		return nil
+	}
+	return items[chosen].run()
+}
@@ -0,0 +1,41 @@
+//go:build !headless
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRootMenuModel(t *testing.T) {
+	now := time.Now()
+	m := rootMenuModel{items: []string{"wait", "serve", "use"}, deadline: now.Add(rootMenuTimeout)}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = next.(rootMenuModel)
+	if m.cursor != 1 || !m.deadline.IsZero() {
+		t.Fatalf("Expected cursor 1 and the countdown stopped, got %d %v", m.cursor, m.deadline)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if chosen := next.(rootMenuModel).chosen; chosen != 1 {
+		t.Errorf("Expected item 1 chosen, got %d", chosen)
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("3")})
+	if chosen := next.(rootMenuModel).chosen; chosen != 2 {
+		t.Errorf("Expected item 2 chosen by number, got %d", chosen)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if chosen := next.(rootMenuModel).chosen; chosen != -1 {
+		t.Errorf("Expected quit, got %d", chosen)
+	}
+
+	// without a key, waiting for input is chosen at the deadline
+	m = rootMenuModel{items: []string{"wait", "serve"}, cursor: 1, deadline: now}
+	next, cmd := m.Update(rootMenuTickMsg(now))
+	if chosen := next.(rootMenuModel).chosen; chosen != 0 || cmd == nil {
+		t.Errorf("Expected the first item at the deadline, got %d", chosen)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// notifyWaiting fires a desktop notification saying the agent of workingDir
+// is waiting for input, if enabled by Config.Notify.
+// It does not block, failures are only logged.
+func notifyWaiting(config *Config, workingDir string) {
+	if config == nil || !config.Notify {
+		return
+	}
+	project := filepath.Base(workingDir)
+	if workingDir == "" {
+		project = "agent"
+	}
+	message := fmt.Sprintf("%s is waiting for your input", project)
+	go func() {
+		if err := sendDesktopNotification(GetProgramName(), message); err != nil {
+			Errorf("desktop notification: %v", err)
+		}
+	}()
+}
+
+// sendDesktopNotification shows a notification with osascript on macOS,
+// notify-send on Linux and a toast on Windows
+func sendDesktopNotification(title string, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript(title, message))
+	default:
+		cmd = exec.Command("notify-send", title, message)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v %s", cmd.Path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// windowsToastScript builds a PowerShell script showing a toast notification
+func windowsToastScript(title string, message string) string {
+	quote := func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$texts = $template.GetElementsByTagName('text')
+$texts.Item(0).AppendChild($template.CreateTextNode(` + quote(title) + `)) | Out-Null
+$texts.Item(1).AppendChild($template.CreateTextNode(` + quote(message) + `)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('PowerShell').Show($toast)`
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAppleScriptString(t *testing.T) {
+	if s := appleScriptString(`say "hi" \o/`); s != `"say \"hi\" \\o/"` {
+		t.Errorf("Unexpected quoting: %s", s)
+	}
+}
+
+func TestWindowsToastScript(t *testing.T) {
+	script := windowsToastScript("whats_next", "it's waiting")
+	if !strings.Contains(script, `CreateTextNode('it''s waiting')`) {
+		t.Errorf("Expected message to be quoted, got:\n%s", script)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
 )
 
 // TestGitWorktreeDetection tests the git worktree detection functionality
@@ -37,6 +39,41 @@ func TestGitWorktreeDetection(t *testing.T) {
 	t.Run("main_repo_and_worktree", func(t *testing.T) {
 		testMainRepoAndWorktree(t, tmpDir)
 	})
+
+	t.Run("fork_with_non_origin_remote", func(t *testing.T) {
+		testForkWithNonOriginRemote(t, tmpDir)
+	})
+}
+
+// testForkWithNonOriginRemote tests same-repo detection when the canonical remote is not origin
+func testForkWithNonOriginRemote(t *testing.T, tmpDir string) {
+	fork := filepath.Join(tmpDir, "fork_checkout")
+	canonical := filepath.Join(tmpDir, "canonical_checkout")
+	for _, dir := range []string{fork, canonical} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		runGitCmd(t, dir, "init")
+	}
+	runGitCmd(t, fork, "remote", "add", "origin", "git@github.com:me/project.git")
+	runGitCmd(t, fork, "remote", "add", "upstream", "https://github.com/org/project.git")
+	runGitCmd(t, canonical, "remote", "add", "origin", "git@github.com:org/project.git")
+
+	// separate repositories, only their remotes relate them
+	remoteConfig := func(remote string) *sections.Config {
+		cfg := matchConfig(fork, true)
+		cfg.GitRemote = remote
+		return cfg
+	}
+	if !sections.IsGitWorktree(fork, canonical, remoteConfig("")) {
+		t.Error("Expected fork and canonical checkout to share a remote")
+	}
+	if sections.IsGitWorktree(fork, canonical, remoteConfig("origin")) {
+		t.Error("Expected origin remotes to differ")
+	}
+	if sections.IsGitWorktree(fork, canonical, remoteConfig("upstream")) {
+		t.Error("Expected no match when the canonical checkout has no upstream remote")
+	}
 }
 
 // testSameRepoWorktrees tests detection between different worktrees of the same repository
@@ -69,22 +106,22 @@ func testSameRepoWorktrees(t *testing.T, tmpDir string) {
 	runGitCmd(t, mainRepo, "worktree", "add", worktree2)
 
 	// Test: main repo should detect worktree1 as related
-	if !isGitWorktree(worktree1, mainRepo) {
+	if !sections.IsGitWorktree(worktree1, mainRepo, matchConfig(worktree1, true)) {
 		t.Error("Expected worktree1 to be detected as related to main repo")
 	}
 
 	// Test: worktree1 should detect main repo as related
-	if !isGitWorktree(mainRepo, worktree1) {
+	if !sections.IsGitWorktree(mainRepo, worktree1, matchConfig(mainRepo, true)) {
 		t.Error("Expected main repo to be detected as related to worktree1")
 	}
 
 	// Test: worktree1 should detect worktree2 as related (same main repo)
-	if !isGitWorktree(worktree1, worktree2) {
+	if !sections.IsGitWorktree(worktree1, worktree2, matchConfig(worktree1, true)) {
 		t.Error("Expected worktree1 to be detected as related to worktree2")
 	}
 
 	// Test: worktree2 should detect worktree1 as related (same main repo)
-	if !isGitWorktree(worktree2, worktree1) {
+	if !sections.IsGitWorktree(worktree2, worktree1, matchConfig(worktree2, true)) {
 		t.Error("Expected worktree2 to be detected as related to worktree1")
 	}
 }
@@ -118,11 +155,11 @@ func testDifferentReposSameOrigin(t *testing.T, tmpDir string) {
 	runGitCmd(t, tmpDir, "clone", bareRepo, "clone2")
 
 	// Test: different clones with same origin should be detected as related
-	if !isGitWorktree(clone1, clone2) {
+	if !sections.IsGitWorktree(clone1, clone2, matchConfig(clone1, true)) {
 		t.Error("Expected clone1 to be detected as related to clone2 (same origin)")
 	}
 
-	if !isGitWorktree(clone2, clone1) {
+	if !sections.IsGitWorktree(clone2, clone1, matchConfig(clone2, true)) {
 		t.Error("Expected clone2 to be detected as related to clone1 (same origin)")
 	}
 }
@@ -160,20 +197,20 @@ func testNoOriginRemote(t *testing.T, tmpDir string) {
 	runGitCmd(t, repo2, "config", "user.name", "Test User")
 
 	// Test: repo1 and its worktree should be detected as related (no origin needed)
-	if !isGitWorktree(repo1, worktree) {
+	if !sections.IsGitWorktree(repo1, worktree, matchConfig(repo1, true)) {
 		t.Error("Expected repo1 to be detected as related to its worktree (no origin)")
 	}
 
-	if !isGitWorktree(worktree, repo1) {
+	if !sections.IsGitWorktree(worktree, repo1, matchConfig(worktree, true)) {
 		t.Error("Expected worktree to be detected as related to repo1 (no origin)")
 	}
 
 	// Test: repo1 and repo2 should NOT be detected as related (different repos, no origin)
-	if isGitWorktree(repo1, repo2) {
+	if sections.IsGitWorktree(repo1, repo2, matchConfig(repo1, true)) {
 		t.Error("Expected repo1 to NOT be detected as related to repo2 (different repos, no origin)")
 	}
 
-	if isGitWorktree(repo2, repo1) {
+	if sections.IsGitWorktree(repo2, repo1, matchConfig(repo2, true)) {
 		t.Error("Expected repo2 to NOT be detected as related to repo1 (different repos, no origin)")
 	}
 }
@@ -199,16 +236,16 @@ func testNonGitDirectories(t *testing.T, tmpDir string) {
 	runGitCmd(t, gitRepo, "init")
 
 	// Test: non-git directories should not be detected as related
-	if isGitWorktree(nonGitDir1, nonGitDir2) {
+	if sections.IsGitWorktree(nonGitDir1, nonGitDir2, matchConfig(nonGitDir1, true)) {
 		t.Error("Expected non-git directories to NOT be detected as related")
 	}
 
 	// Test: non-git directory and git repo should not be detected as related
-	if isGitWorktree(nonGitDir1, gitRepo) {
+	if sections.IsGitWorktree(nonGitDir1, gitRepo, matchConfig(nonGitDir1, true)) {
 		t.Error("Expected non-git directory to NOT be detected as related to git repo")
 	}
 
-	if isGitWorktree(gitRepo, nonGitDir1) {
+	if sections.IsGitWorktree(gitRepo, nonGitDir1, matchConfig(gitRepo, true)) {
 		t.Error("Expected git repo to NOT be detected as related to non-git directory")
 	}
 }
@@ -238,16 +275,16 @@ func testMainRepoAndWorktree(t *testing.T, tmpDir string) {
 	featureWorktree := filepath.Join(tmpDir, "feature_worktree")
 	runGitCmd(t, mainRepo, "worktree", "add", featureWorktree, "feature")
 
-	// Test shouldIncludeSection with project specification
+	// Test sections.MatchHeading with project specification
 	heading := "# Test Section (project: " + mainRepo + ")"
 
 	// From main repo, should include section
-	if include, _, _, _ := shouldIncludeSection(heading, mainRepo, true); !include {
+	if !sections.MatchHeading(heading, mainRepo, matchConfig(mainRepo, true)).Include {
 		t.Error("Expected section to be included when in main repo")
 	}
 
 	// From worktree, should include section (related to main repo)
-	if include, _, _, _ := shouldIncludeSection(heading, featureWorktree, true); !include {
+	if !sections.MatchHeading(heading, featureWorktree, matchConfig(featureWorktree, true)).Include {
 		t.Error("Expected section to be included when in worktree of specified project")
 	}
 
@@ -255,12 +292,12 @@ func testMainRepoAndWorktree(t *testing.T, tmpDir string) {
 	worktreeHeading := "# Test Section (project: " + featureWorktree + ")"
 
 	// From main repo, should include section (related to worktree)
-	if include, _, _, _ := shouldIncludeSection(worktreeHeading, mainRepo, true); !include {
+	if !sections.MatchHeading(worktreeHeading, mainRepo, matchConfig(mainRepo, true)).Include {
 		t.Error("Expected section to be included when main repo is related to specified worktree")
 	}
 
 	// From worktree, should include section
-	if include, _, _, _ := shouldIncludeSection(worktreeHeading, featureWorktree, true); !include {
+	if !sections.MatchHeading(worktreeHeading, featureWorktree, matchConfig(featureWorktree, true)).Include {
 		t.Error("Expected section to be included when in specified worktree")
 	}
 }
@@ -309,7 +346,7 @@ More content
 `
 
 	// Filter content from worktree perspective
-	filteredContent := filterContentByDir(content, worktree, true)
+	filteredContent := sections.Filter(content, worktree, matchConfig(worktree, true))
 
 	// The project path should be replaced with the worktree path
 	expectedContent := `# Section 1
@@ -335,7 +372,7 @@ More content
 	}
 
 	// Filter content from subdirectory (should be direct path match, no replacement)
-	filteredFromSubdir := filterContentByDir(content, subDir, true)
+	filteredFromSubdir := sections.Filter(content, subDir, matchConfig(subDir, true))
 
 	// Should keep original project path since it's a direct path match
 	expectedFromSubdir := `# Section 1
@@ -390,7 +427,7 @@ Another section with no project specification
 `
 
 	// Test from grandchild directory - should only show grandchild-specific sections
-	filteredFromGrandchild := filterContentByDir(content, grandchildDir, true)
+	filteredFromGrandchild := sections.Filter(content, grandchildDir, matchConfig(grandchildDir, true))
 	expectedFromGrandchild := `# General Section
 This section has no project specification
 
@@ -406,7 +443,7 @@ Another section with no project specification
 	}
 
 	// Test from child directory - should only show child-specific sections
-	filteredFromChild := filterContentByDir(content, childDir, true)
+	filteredFromChild := sections.Filter(content, childDir, matchConfig(childDir, true))
 	expectedFromChild := `# General Section
 This section has no project specification
 
@@ -422,7 +459,7 @@ Another section with no project specification
 	}
 
 	// Test from parent directory - should only show parent-specific sections
-	filteredFromParent := filterContentByDir(content, parentDir, true)
+	filteredFromParent := sections.Filter(content, parentDir, matchConfig(parentDir, true))
 	expectedFromParent := `# General Section
 This section has no project specification
 
@@ -443,7 +480,7 @@ Another section with no project specification
 		t.Fatalf("Failed to create outside dir: %v", err)
 	}
 
-	filteredFromOutside := filterContentByDir(content, outsideDir, true)
+	filteredFromOutside := sections.Filter(content, outsideDir, matchConfig(outsideDir, true))
 	expectedFromOutside := `# General Section
 This section has no project specification
 
@@ -456,6 +493,60 @@ Another section with no project specification
 	}
 }
 
+// TestMonorepoSubpathSpec tests "(project: repo#subpath)" specs across worktrees
+func TestMonorepoSubpathSpec(t *testing.T) {
+	_, tmpDir, err := mkdirTempResolved("whats_next_monorepo_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	mainRepo := filepath.Join(tmpDir, "mono")
+	paymentsDir := filepath.Join(mainRepo, "services", "payments")
+	if err := os.MkdirAll(paymentsDir, 0755); err != nil {
+		t.Fatalf("Failed to create payments dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(mainRepo, "services", "orders"), 0755); err != nil {
+		t.Fatalf("Failed to create orders dir: %v", err)
+	}
+	runGitCmd(t, mainRepo, "init")
+	runGitCmd(t, mainRepo, "config", "user.email", "test@example.com")
+	runGitCmd(t, mainRepo, "config", "user.name", "Test User")
+	for _, service := range []string{"payments", "orders"} {
+		file := filepath.Join(mainRepo, "services", service, "main.go")
+		if err := os.WriteFile(file, []byte("package main"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	runGitCmd(t, mainRepo, "add", ".")
+	runGitCmd(t, mainRepo, "commit", "-m", "Initial commit")
+
+	worktree := filepath.Join(tmpDir, "mono_feature")
+	runGitCmd(t, mainRepo, "worktree", "add", worktree)
+
+	heading := "# Payments (project: " + mainRepo + "#services/payments)"
+	tests := []struct {
+		name           string
+		cwd            string
+		expected       bool
+		expectedReason sections.MatchReason
+	}{
+		{"main repo subpath", paymentsDir, true, sections.MatchReasonPathMatch},
+		{"main repo other service", filepath.Join(mainRepo, "services", "orders"), false, sections.MatchReasonNone},
+		{"main repo root", mainRepo, false, sections.MatchReasonNone},
+		{"worktree subpath", filepath.Join(worktree, "services", "payments"), true, sections.MatchReasonGitWorktree},
+		{"worktree other service", filepath.Join(worktree, "services", "orders"), false, sections.MatchReasonNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := sections.MatchHeading(heading, tt.cwd, matchConfig(tt.cwd, true))
+			if result.Include != tt.expected || result.MatchReason != tt.expectedReason {
+				t.Errorf("Expected include=%v reason=%v, got include=%v reason=%v", tt.expected, tt.expectedReason, result.Include, result.MatchReason)
+			}
+		})
+	}
+}
+
 // runGitCmd runs a git command in the specified directory
 func runGitCmd(t *testing.T, dir string, args ...string) {
 	cmd := exec.Command("git", args...)
@@ -466,3 +557,22 @@ func runGitCmd(t *testing.T, dir string, args ...string) {
 			dir, strings.Join(args, " "), string(output), err)
 	}
 }
+
+// mkdirTempResolved creates a temporary directory and returns both the original path
+// (for cleanup) and the resolved path (for testing). This handles symlink issues
+// on macOS where /var is a symlink to /private/var.
+func mkdirTempResolved(pattern string) (originalPath, resolvedPath string, err error) {
+	originalPath, err = os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Resolve symlinks to get the canonical path
+	resolvedPath, err = filepath.EvalSymlinks(originalPath)
+	if err != nil {
+		// If symlink resolution fails, use the original path
+		resolvedPath = originalPath
+	}
+
+	return originalPath, resolvedPath, nil
+}
@@ -1,33 +1,33 @@
 package main
 
 import (
-	"os"
-	"path/filepath"
 	"testing"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
 )
 
 func TestParseSections(t *testing.T) {
 	tests := []struct {
 		name     string
 		content  string
-		expected []Section
+		expected []sections.Section
 	}{
 		{
 			name:     "empty content",
 			content:  "",
-			expected: []Section{},
+			expected: []sections.Section{},
 		},
 		{
 			name:     "no sections",
 			content:  "Just some text\nwithout headers",
-			expected: []Section{},
+			expected: []sections.Section{},
 		},
 		{
 			name: "single section",
 			content: `# Header 1
 Content line 1
 Content line 2`,
-			expected: []Section{
+			expected: []sections.Section{
 				{
 					Title:   "# Header 1",
 					Content: "Content line 1\nContent line 2",
@@ -45,7 +45,7 @@ More content 2
 
 ## Header 3
 Content 3`,
-			expected: []Section{
+			expected: []sections.Section{
 				{
 					Title:   "# Header 1",
 					Content: "Content 1\n",
@@ -71,7 +71,7 @@ echo "hello"
 
 # Header 2
 More content`,
-			expected: []Section{
+			expected: []sections.Section{
 				{
 					Title:   "# Header 1",
 					Content: "Some text\n```bash\n# This should not be a header\necho \"hello\"\n```\n",
@@ -97,7 +97,7 @@ func main() {
 # This markdown header should not be parsed
 ## Neither should this
 ` + "```",
-			expected: []Section{
+			expected: []sections.Section{
 				{
 					Title:   "# Header 1",
 					Content: "```go\nfunc main() {\n    // # Not a header\n    fmt.Println(\"hello\")\n}\n```\n",
@@ -118,7 +118,7 @@ Project specific content
 
 # Another Section(project: /other/path)
 Other project content`,
-			expected: []Section{
+			expected: []sections.Section{
 				{
 					Title:   "# General Section",
 					Content: "General content\n",
@@ -155,189 +155,146 @@ Other project content`,
 	}
 }
 
-func TestShouldIncludeSection(t *testing.T) {
-	// Create a temporary directory for testing
-	tempDir, err := os.MkdirTemp("", "whats_next_test")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
+func TestParseSectionsWithFrontMatter(t *testing.T) {
+	content := `# Build Rules
+---
+project: ~/work/api
+agents: [cursor, "claude"]
+priority: 10
+---
+Build content.
+
+# Plain
+---
+Not front matter, just a rule.`
+
+	parsed := parseSections(content)
+	if len(parsed) != 2 {
+		t.Fatalf("Expected 2 sections, got %d", len(parsed))
 	}
-	defer os.RemoveAll(tempDir)
-
-	// Create subdirectory
-	subDir := filepath.Join(tempDir, "subdir")
-	if err := os.MkdirAll(subDir, 0755); err != nil {
-		t.Fatalf("Failed to create subdir: %v", err)
+	if parsed[0].Content != "Build content.\n" {
+		t.Errorf("Expected front matter to be stripped, got %q", parsed[0].Content)
 	}
-
-	tests := []struct {
-		name     string
-		heading  string
-		cwd      string
-		expected bool
-	}{
-		{
-			name:     "no project specification",
-			heading:  "# General Section",
-			cwd:      tempDir,
-			expected: true,
-		},
-		{
-			name:     "malformed project specification - no closing paren",
-			heading:  "# Section(project: /some/path",
-			cwd:      tempDir,
-			expected: true,
-		},
-		{
-			name:     "exact path match",
-			heading:  "# Section(project: " + tempDir + ")",
-			cwd:      tempDir,
-			expected: true,
-		},
-		{
-			name:     "subdirectory match",
-			heading:  "# Section(project: " + tempDir + ")",
-			cwd:      subDir,
-			expected: true,
-		},
-		{
-			name:     "no match - different path",
-			heading:  "# Section(project: /completely/different/path)",
-			cwd:      tempDir,
-			expected: false,
-		},
-		{
-			name:     "relative path match",
-			heading:  "# Section(project: .)",
-			cwd:      tempDir,
-			expected: true,
-		},
-		{
-			name:     "whitespace in project path",
-			heading:  "# Section(project:   " + tempDir + "   )",
-			cwd:      tempDir,
-			expected: true,
-		},
+	expectedMeta := map[string]string{
+		"project":  "~/work/api",
+		"agents":   "cursor, claude",
+		"priority": "10",
 	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result, _, _, _ := shouldIncludeSection(tt.heading, tt.cwd, true)
-			if result != tt.expected {
-				t.Errorf("Expected %v, got %v for heading %q with cwd %q", tt.expected, result, tt.heading, tt.cwd)
-			}
-		})
+	if len(parsed[0].Meta) != len(expectedMeta) {
+		t.Errorf("Expected meta %v, got %v", expectedMeta, parsed[0].Meta)
 	}
-}
-
-func TestFilterContentByProject(t *testing.T) {
-	// Create a temporary directory for testing using the helper
-	originalTempDir, tempDir, err := mkdirTempResolved("whats_next_test")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
+	for key, value := range expectedMeta {
+		if parsed[0].Meta[key] != value {
+			t.Errorf("Meta %s: expected %q, got %q", key, value, parsed[0].Meta[key])
+		}
 	}
-	defer os.RemoveAll(originalTempDir) // Use original path for cleanup
-
-	// Save original working directory
-	originalWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get working directory: %v", err)
+	if parsed[1].Meta != nil {
+		t.Errorf("Expected no meta for unclosed block, got %v", parsed[1].Meta)
 	}
-
-	// Change to temp directory for testing (use resolved path)
-	if err := os.Chdir(tempDir); err != nil {
-		t.Fatalf("Failed to change to temp dir: %v", err)
+	if parsed[1].Content != "---\nNot front matter, just a rule." {
+		t.Errorf("Expected content unchanged, got %q", parsed[1].Content)
 	}
-	defer os.Chdir(originalWd)
-
-	content := `# General Section
-This should always be shown.
-
-# Current Project Section(project: ` + tempDir + `)
-This should be shown when in the project directory.
-
-# Other Project Section(project: /some/other/path)
-This should NOT be shown.
-
-# Another General Section
-This should also be shown.`
-
-	expected := `# General Section
-This should always be shown.
+}
 
-# Current Project Section(project: ` + tempDir + `)
-This should be shown when in the project directory.
+func TestParseSectionsWithSplitLevel(t *testing.T) {
+	content := `# Header 1
+Content 1
+## Sub 1
+Sub content 1
+# Header 2
+Content 2`
 
-# Another General Section
-This should also be shown.`
+	parsed := sections.Parse(content, 1)
+	if len(parsed) != 2 {
+		t.Fatalf("Expected 2 sections, got %d", len(parsed))
+	}
+	if parsed[0].Content != "Content 1\n## Sub 1\nSub content 1" {
+		t.Errorf("Expected subsection kept in parent, got %q", parsed[0].Content)
+	}
 
-	result, err := filterContentByProject(content)
-	if err != nil {
-		t.Fatalf("filterContentByProject failed: %v", err)
+	if parsed := sections.Parse(content, 0); len(parsed) != 3 {
+		t.Errorf("Expected 3 sections when splitting on every heading, got %d", len(parsed))
 	}
 
-	if result != expected {
-		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	withFrontMatter := "---\nsectionLevel: 1\n---\n" + content
+	if level, ok := sections.ProfileSplitLevel(withFrontMatter); !ok || level != 1 {
+		t.Errorf("Expected split level 1 from front matter, got %d", level)
+	}
+	if parsed := parseSections(withFrontMatter); len(parsed) != 2 {
+		t.Errorf("Expected 2 sections with front matter split level, got %d", len(parsed))
 	}
 }
 
-func TestFilterContentByProjectWithCodeBlocks(t *testing.T) {
-	// Create a temporary directory for testing using the helper
-	originalTempDir, tempDir, err := mkdirTempResolved("whats_next_test")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
-	}
-	defer os.RemoveAll(originalTempDir) // Use original path for cleanup
+func TestParseSectionsWithWindowsFormatting(t *testing.T) {
+	content := "\ufeff# Header 1\r\nContent 1\r\n\t## Sub 1\r\n```bash\r\n  # not a heading\r\n```\r\n  # Header 2\r\n        # indented code, not a heading\r\nContent 2"
 
-	// Save original working directory
-	originalWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get working directory: %v", err)
+	parsed := parseSections(content)
+	expected := []sections.Section{
+		{Title: "# Header 1", Content: "Content 1"},
+		{Title: "## Sub 1", Content: "```bash\n  # not a heading\n```"},
+		{Title: "# Header 2", Content: "        # indented code, not a heading\nContent 2"},
 	}
-
-	// Change to temp directory for testing (use resolved path)
-	if err := os.Chdir(tempDir); err != nil {
-		t.Fatalf("Failed to change to temp dir: %v", err)
+	if len(parsed) != len(expected) {
+		t.Fatalf("Expected %d sections, got %d: %#v", len(expected), len(parsed), parsed)
 	}
-	defer os.Chdir(originalWd)
-
-	content := `# General Section
-Code example:
-` + "```bash" + `
-# This should not be treated as heading
-echo "hello"
-` + "```" + `
+	for i := range expected {
+		if parsed[i].Title != expected[i].Title {
+			t.Errorf("Section %d title: expected %q, got %q", i, expected[i].Title, parsed[i].Title)
+		}
+		if parsed[i].Content != expected[i].Content {
+			t.Errorf("Section %d content: expected %q, got %q", i, expected[i].Content, parsed[i].Content)
+		}
+	}
+}
 
-# Project Section(project: ` + tempDir + `)
-This should be shown.
-` + "```go" + `
-// # This comment should not be a heading
-func main() {}
-` + "```" + `
+func TestParseSectionsWithSetextHeadings(t *testing.T) {
+	content := `Header 1
+========
+Content 1
 
-# Other Project Section(project: /other/path)
-This should be filtered out.`
+Header 2
+--------
+Content 2
 
-	expected := `# General Section
-Code example:
-` + "```bash" + `
-# This should not be treated as heading
-echo "hello"
+# ATX Header
+---
+key: value
+---
+Not a setext title
+===
+- list item
 ` + "```" + `
-
-# Project Section(project: ` + tempDir + `)
-This should be shown.
-` + "```go" + `
-// # This comment should not be a heading
-func main() {}
+code
+---
 ` + "```" + `
-`
 
-	result, err := filterContentByProject(content)
-	if err != nil {
-		t.Fatalf("filterContentByProject failed: %v", err)
-	}
+After a blank line
 
-	if result != expected {
-		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+---
+Trailing`
+
+	parsed := parseSections(content)
+	expected := []sections.Section{
+		{Title: "# Header 1", Content: "Content 1\n"},
+		{Title: "## Header 2", Content: "Content 2\n"},
+		{Title: "# ATX Header", Content: ""},
+		{Title: "# Not a setext title", Content: "- list item\n```\ncode\n---\n```\n\nAfter a blank line\n\n---\nTrailing"},
+	}
+	if len(parsed) != len(expected) {
+		t.Fatalf("Expected %d sections, got %d: %#v", len(expected), len(parsed), parsed)
+	}
+	for i := range expected {
+		if parsed[i].Title != expected[i].Title {
+			t.Errorf("Section %d title: expected %q, got %q", i, expected[i].Title, parsed[i].Title)
+		}
+		if parsed[i].Content != expected[i].Content {
+			t.Errorf("Section %d content: expected %q, got %q", i, expected[i].Content, parsed[i].Content)
+		}
+	}
+	if parsed[2].Meta["key"] != "value" {
+		t.Errorf("Expected front matter under ATX heading, got %v", parsed[2].Meta)
+	}
+	if parsed[1].Line != 5 {
+		t.Errorf("Expected setext heading line 5, got %d", parsed[1].Line)
 	}
 }
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+// awayUntilPlaceholder in awayMessage is replaced by the time the user is back
+const awayUntilPlaceholder = "{until}"
+
+const pauseHelp = `
+Usage:
+  whats_next pause [DURATION|HH:MM] [--port PORT]
+  whats_next resume [--port PORT]
+
+Pauses the running server: waiting and new agents are answered right away
+that the user is away, instead of being held, until resume, or until
+DURATION (e.g. 45m) has elapsed or the time HH:MM.
+
+The answer is the awayMessage config, where {until} is replaced by the time
+the user is back, or a default asking the agent to wait until then.
+
+In the server input, Ctrl+P pauses and resumes too.
+
+Options:
+  --port PORT  The server port (default: serverPort or 7654)
+`
+
+func handlePause(args []string, pause bool) error {
+	var port int
+	args, err := flags.Int("--port", &port).
+		Help("-h,--help", pauseHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	var until time.Time
+	if pause && len(args) > 0 {
+		until, err = parsePauseUntil(args[0], time.Now())
+		if err != nil {
+			return err
+		}
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	config := readServerConfig()
+	addr := getServerAddr(config.ServerBind, resolveServerPort(port, config))
+	path := "/resume"
+	form := url.Values{}
+	if pause {
+		path = "/pause"
+		if !until.IsZero() {
+			form.Set("until", until.Format(time.RFC3339))
+		}
+	}
+	client := newServerClient(config, 5*time.Second)
+	resp, err := client.PostForm(tokenServerURL(addr, path), form)
+	if err != nil {
+		if !isAddrReachable(addr) {
+			return fmt.Errorf("server %s is not running", addr)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	switch {
+	case !pause:
+		fmt.Fprintf(os.Stderr, "Server %s resumed\n", addr)
+	case until.IsZero():
+		fmt.Fprintf(os.Stderr, "Server %s paused until resume\n", addr)
+	default:
+		fmt.Fprintf(os.Stderr, "Server %s paused until %s\n", addr, until.Format("15:04"))
+	}
+	return nil
+}
+
+// parsePauseUntil parses a duration like "45m", or a time of day like "13:30",
+// the next one after now
+func parsePauseUntil(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	t, err := time.ParseInLocation("15:04", s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid pause end: %s, expect a duration like 45m or a time like 13:30", s)
+	}
+	until := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !until.After(now) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, nil
+}
+
+// getAwayResponse returns what agents are answered while the server is paused
+func getAwayResponse(config *Config, until time.Time) string {
+	if config != nil && config.AwayMessage != "" {
+		untilText := "further notice"
+		if !until.IsZero() {
+			untilText = until.Format("15:04")
+		}
+		return strings.ReplaceAll(config.AwayMessage, awayUntilPlaceholder, untilText)
+	}
+	if until.IsZero() {
+		return "The user is away. Stop here, don't execute `" + GetProgramName() + "` again until the user asks."
+	}
+	wait := time.Until(until).Round(time.Second)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return fmt.Sprintf("The user is away until %s. Wait until then with `sleep %d`, then execute `%s` again.",
+		until.Format("15:04"), int(wait.Seconds()), GetProgramName())
+}
+
+// pause makes the server answer agents that the user is away until until,
+// zero meaning until resume, releasing the agents waiting now
+func (h *serveHandler) pause(until time.Time) {
+	h.do(func(s *serveState) {
+		s.paused = true
+		s.pausedUntil = until
+		if s.pauseSignal != nil {
+			close(s.pauseSignal)
+			s.pauseSignal = nil
+		}
+	})
+}
+
+func (h *serveHandler) resume() {
+	h.do(func(s *serveState) {
+		s.paused = false
+		s.pausedUntil = time.Time{}
+	})
+}
+
+// getPause tells whether the server is paused and until when, resuming once that passed
+func (h *serveHandler) getPause() (bool, time.Time) {
+	var paused bool
+	var until time.Time
+	h.do(func(s *serveState) {
+		if s.paused && !s.pausedUntil.IsZero() && !time.Now().Before(s.pausedUntil) {
+			s.paused = false
+			s.pausedUntil = time.Time{}
+		}
+		paused, until = s.paused, s.pausedUntil
+	})
+	return paused, until
+}
+
+// getPauseSignal returns a channel closed when the server is paused
+func (h *serveHandler) getPauseSignal() <-chan struct{} {
+	var signal chan struct{}
+	h.do(func(s *serveState) {
+		if s.pauseSignal == nil {
+			s.pauseSignal = make(chan struct{})
+		}
+		signal = s.pauseSignal
+	})
+	return signal
+}
+
+// togglePause pauses or resumes the server from its input, returning a notice
+func (h *serveHandler) togglePause() string {
+	if paused, _ := h.getPause(); paused {
+		h.resume()
+		return "resumed, agents wait for input again"
+	}
+	h.pause(time.Time{})
+	return "paused, agents are told the user is away (Ctrl+P to resume)"
+}
+
+// handlePauseRequest serves POST /pause with an optional RFC 3339 until form value, and POST /resume.
+// Like /reply, they require the token of the server, see allowFormPost.
+func handlePauseRequest(h *serveHandler, w http.ResponseWriter, r *http.Request, pause bool) {
+	if !allowFormPost(h, w, r) {
+		return
+	}
+	if !pause {
+		h.resume()
+		Logf("resumed")
+		return
+	}
+	var until time.Time
+	if value := r.FormValue("until"); value != "" {
+		var err error
+		until, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "invalid until: "+value, http.StatusBadRequest)
+			return
+		}
+	}
+	h.pause(until)
+	Logf("paused until %v", until)
+}
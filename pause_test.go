@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlePauseRequestToken(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	h := &serveHandler{replyToken: "secret"}
+	post := func(target string, origin string) int {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		handlePauseRequest(h, rec, req, true)
+		return rec.Code
+	}
+
+	if code := post("/pause", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected a pause without token to be rejected, got %d", code)
+	}
+	// a web page posting with the token is still rejected by its origin
+	if code := post("/pause?token=secret", "https://example.com"); code != http.StatusForbidden {
+		t.Errorf("Expected a foreign origin to be rejected, got %d", code)
+	}
+	if paused, _ := h.getPause(); paused {
+		t.Fatalf("Expected the rejected requests not to pause")
+	}
+	if code := post("/pause?token=secret", ""); code != http.StatusOK {
+		t.Fatalf("Expected a pause with token to succeed, got %d", code)
+	}
+	if paused, _ := h.getPause(); !paused {
+		t.Errorf("Expected the server paused")
+	}
+}
@@ -0,0 +1,205 @@
+// Package config is the config of whats_next: config.json in the config
+// dir, with per-host overrides, and the .whats_next/config.json of a
+// project, for tools embedding whats_next that need the same settings.
+package config
+
+// FileName is the name of the config file, in the config dir and in ProjectDir
+const FileName = "config.json"
+
+// Mode represents the operation mode
+type Mode string
+
+const (
+	ModeNative Mode = "native"
+	ModeServer Mode = "server"
+)
+
+// Config represents the configuration stored in config.json
+type Config struct {
+	// Schema points editors to the JSON Schema from `whats_next config schema`
+	Schema string `json:"$schema,omitempty"`
+
+	Editor          string `json:"editor"`
+	SelectedProfile string `json:"selectedProfile"`
+	Mode            Mode   `json:"mode"`
+
+	// DisableSymlinkResolution compares (project: ...) paths literally
+	// instead of resolving symlinks of both the cwd and the project path
+	DisableSymlinkResolution bool `json:"disableSymlinkResolution,omitempty"`
+
+	// Aliases maps names like "@api" to project paths like "~/work/company/api",
+	// usable in headings as (project: @api)
+	Aliases map[string]string `json:"aliases,omitempty"`
+
+	// CommandAliases maps short command names to command lines,
+	// e.g. "s": "group show --use". Built-in commands cannot be shadowed.
+	CommandAliases map[string]string `json:"commandAliases,omitempty"`
+
+	// SectionLevel is the deepest heading level that starts a new section,
+	// e.g. 1 keeps "##" headings inside their "#" section. 0 splits on every heading.
+	// A profile can override it with a front-matter block before its first heading.
+	SectionLevel int `json:"sectionLevel,omitempty"`
+
+	// GitRemote is the remote compared to decide whether two checkouts belong
+	// to the same project, e.g. "upstream". Empty compares all remotes.
+	GitRemote string `json:"gitRemote,omitempty"`
+
+	// IdleTimeout is how long to wait for input before telling the agent
+	// to keep thinking, as a Go duration like "5m". Defaults to 3m.
+	IdleTimeout string `json:"idleTimeout,omitempty"`
+
+	// NativeIdleTimeout and ServerIdleTimeout override IdleTimeout in their mode:
+	// in native mode the agent blocks on the process, in server mode it polls
+	NativeIdleTimeout string `json:"nativeIdleTimeout,omitempty"`
+	ServerIdleTimeout string `json:"serverIdleTimeout,omitempty"`
+
+	// IdleAction decides what happens on idle timeout: thinking, message,
+	// wait or stop. NativeIdleAction and ServerIdleAction override it per mode.
+	IdleAction       string `json:"idleAction,omitempty"`
+	NativeIdleAction string `json:"nativeIdleAction,omitempty"`
+	ServerIdleAction string `json:"serverIdleAction,omitempty"`
+
+	// IdleMessage is sent to the agent by the "message" idle action
+	IdleMessage string `json:"idleMessage,omitempty"`
+
+	// AwayMessage is sent to agents while the server is paused,
+	// with {until} replaced by the time the user is back
+	AwayMessage string `json:"awayMessage,omitempty"`
+
+	// InputQueueSize is the number of answers the server queues for agents, defaults to 100
+	InputQueueSize int `json:"inputQueueSize,omitempty"`
+
+	// InputQueueOverflow decides what happens to an answer when the queue is full:
+	// block, drop-oldest or spool, Defaults to block.
+	InputQueueOverflow string `json:"inputQueueOverflow,omitempty"`
+
+	// HardTimeout is the longest a single server request waits for input,
+	// as a Go duration like "30m". Defaults to 10m.
+	HardTimeout string `json:"hardTimeout,omitempty"`
+
+	// ShowSessionGap is the idle time after which the calls of an agent start a
+	// new session for (show: first N) sections, like "30m". Defaults to 1h.
+	ShowSessionGap string `json:"showSessionGap,omitempty"`
+
+	// ServerPort is the port of `whats_next serve` and its clients, defaults to 7654
+	ServerPort int `json:"serverPort,omitempty"`
+
+	// ServerBind is the address the server listens on, e.g. "0.0.0.0" for LAN use.
+	// Defaults to localhost.
+	ServerBind string `json:"serverBind,omitempty"`
+
+	// ServerToken is required by the server on every request when set, and
+	// sent by its clients. Binding a non-loopback address requires it.
+	// May be a secret reference.
+	ServerToken string `json:"serverToken,omitempty"`
+
+	// BuiltinGuidelines lists the built-in guidelines emitted when no profile
+	// is selected: general, toolCalls or runningCommand. nil emits all of them.
+	BuiltinGuidelines *[]string `json:"builtinGuidelines,omitempty"`
+
+	// DisableProgramNameSubstitution keeps `whats_next` mentions as written
+	// instead of replacing them with the name the program was invoked as
+	DisableProgramNameSubstitution bool `json:"disableProgramNameSubstitution,omitempty"`
+
+	// ReplyLanguage asks the agent to answer in a language, e.g. "Chinese".
+	// A profile can override it with a replyLanguage front-matter key.
+	ReplyLanguage string `json:"replyLanguage,omitempty"`
+
+	// AnswerTemplate is a Go text/template wrapping the user's question, with
+	// .Question, .Profile, .ProfileName, .WorkingDir and .ReplyLanguage.
+	// A profile can override it with an answerTemplateFile front-matter key.
+	AnswerTemplate string `json:"answerTemplate,omitempty"`
+
+	// WaitTimeMetadata appends how long the user took to reply to the wrapped
+	// answer, e.g. "(user replied after 4m 12s)"
+	WaitTimeMetadata bool `json:"waitTimeMetadata,omitempty"`
+
+	// Notify fires a desktop notification when the agent starts waiting for input
+	Notify bool `json:"notify,omitempty"`
+
+	// Sound plays when an agent starts waiting or a reply is delivered:
+	// "bell" rings the terminal bell, otherwise it is an audio file path
+	Sound string `json:"sound,omitempty"`
+
+	// SoundEvents limits the events playing Sound: connect or reply. nil plays on all.
+	SoundEvents *[]string `json:"soundEvents,omitempty"`
+
+	// Webhooks are HTTP requests sent on lifecycle events
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+
+	// LogLevel is the level of the logs: debug, info, warn or error. Defaults to info.
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// LogMaxSize is the size log files are rotated at, like "10MB", defaults to 10MB
+	LogMaxSize string `json:"logMaxSize,omitempty"`
+
+	// LogMaxFiles is the number of rotated log files kept, defaults to 3
+	LogMaxFiles int `json:"logMaxFiles,omitempty"`
+
+	// LogMaxAge removes rotated log files older than it, as a Go duration like "168h"
+	LogMaxAge string `json:"logMaxAge,omitempty"`
+
+	// APIAllowOrigins are the browser origins allowed to call /api/v1 of the server,
+	// "*" suffix matching any rest. nil allows VS Code webviews.
+	APIAllowOrigins []string `json:"apiAllowOrigins,omitempty"`
+
+	// Slack configures the bridge of `whats_next serve --slack`
+	Slack *SlackConfig `json:"slack,omitempty"`
+
+	// Digest configures the email of `whats_next digest --email`
+	Digest *DigestConfig `json:"digest,omitempty"`
+
+	// GistID is the gist of `whats_next backup gist`, set by the first backup
+	GistID string `json:"gistId,omitempty"`
+
+	// GistToken is the GitHub token of backup and restore, may be a secret reference
+	GistToken string `json:"gistToken,omitempty"`
+
+	// Telemetry turns on the anonymous usage counts of `whats_next telemetry`
+	Telemetry bool `json:"telemetry,omitempty"`
+
+	// Hosts holds per-host overrides keyed by hostname or a regular expression
+	// matching it, e.g. {"work-laptop": {"editor": "code --wait"}}
+	Hosts map[string]*Config `json:"hosts,omitempty"`
+}
+
+// Webhook is an HTTP request sent on lifecycle events, e.g. to push
+// a phone notification through ntfy or Pushover
+type Webhook struct {
+	// URL may be a secret reference like "keychain:ntfy-url"
+	URL string `json:"url"`
+	// Events limits the events sending the webhook: connected, input, idle
+	// or shutdown. Empty sends on all.
+	Events []string `json:"events,omitempty"`
+	// Method defaults to POST
+	Method string `json:"method,omitempty"`
+	// Headers values may be secret references like "keychain:pushover-token"
+	Headers map[string]string `json:"headers,omitempty"`
+	// Template is a Go text/template rendering the body from the event data,
+	// defaults to the data as JSON
+	Template string `json:"template,omitempty"`
+}
+
+// SlackConfig configures the slack bridge of `whats_next serve --slack`
+type SlackConfig struct {
+	// Token is a bot token with chat:write and channels:history scopes,
+	// or a secret reference like "keychain:slack-token"
+	Token string `json:"token"`
+	// Channel is the id of the channel to post to, e.g. "C0123456789"
+	Channel string `json:"channel"`
+	// PollInterval is how often threads are checked for replies, like "5s"
+	PollInterval string `json:"pollInterval,omitempty"`
+}
+
+// DigestConfig configures the email sent by `whats_next digest --email`
+type DigestConfig struct {
+	// Email is the address the digest is sent to
+	Email string `json:"email"`
+	// From defaults to Email
+	From string `json:"from,omitempty"`
+	// SMTPServer is the host:port of the SMTP server, e.g. "smtp.gmail.com:587"
+	SMTPServer string `json:"smtpServer"`
+	Username   string `json:"username,omitempty"`
+	// Password may be a secret reference like "keychain:smtp"
+	Password string `json:"password,omitempty"`
+}
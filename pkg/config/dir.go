@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirEnv is the environment variable overriding the default config dir
+const DirEnv = "WHATS_NEXT_CONFIG_DIR"
+
+// ResolveDir returns the absolute config dir: dir if not empty, then
+// $WHATS_NEXT_CONFIG_DIR, then whats_next under the user config dir.
+// A leading ~/ is expanded to the home dir.
+func ResolveDir(dir string) (string, error) {
+	if dir == "" {
+		dir = os.Getenv(DirEnv)
+	}
+	if dir == "" {
+		conf, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(conf, "whats_next"), nil
+	}
+	if strings.HasPrefix(dir, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(homeDir, dir[2:])
+	}
+	return filepath.Abs(dir)
+}
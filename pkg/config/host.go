@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Hostname returns the hostname of this machine, or "" if unknown
+func Hostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
+}
+
+// MatchesHost reports whether a hosts key matches hostname.
+// The key matches the full or the short hostname ("laptop" for "laptop.local")
+// case-insensitively, or is a regular expression matching the whole hostname.
+func MatchesHost(key string, hostname string) bool {
+	if hostname == "" {
+		return false
+	}
+	shortName, _, _ := strings.Cut(hostname, ".")
+	if strings.EqualFold(key, hostname) || strings.EqualFold(key, shortName) {
+		return true
+	}
+	re, err := regexp.Compile("(?i)^(?:" + key + ")$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(hostname) || re.MatchString(shortName)
+}
+
+// ApplyHostOverrides merges the Hosts entries matching hostname into config.
+// Regular expression keys are applied first in sorted order,
+// so that an entry naming the host literally wins.
+func ApplyHostOverrides(config *Config, hostname string) {
+	if len(config.Hosts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(config.Hosts))
+	for key := range config.Hosts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	shortName, _, _ := strings.Cut(hostname, ".")
+	isLiteral := func(key string) bool {
+		return strings.EqualFold(key, hostname) || strings.EqualFold(key, shortName)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return !isLiteral(keys[i]) && isLiteral(keys[j])
+	})
+	hosts := config.Hosts
+	for _, key := range keys {
+		override := hosts[key]
+		if override == nil || !MatchesHost(key, hostname) {
+			continue
+		}
+		overlay := *override
+		overlay.Hosts = nil
+		Merge(config, &overlay)
+	}
+}
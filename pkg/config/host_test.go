@@ -0,0 +1,51 @@
+package config
+
+import "testing"
+
+func TestMatchesHost(t *testing.T) {
+	tests := []struct {
+		key      string
+		hostname string
+		expected bool
+	}{
+		{"laptop", "laptop", true},
+		{"Laptop", "laptop.local", true},
+		{"laptop.local", "laptop.local", true},
+		{"desk-.*", "desk-01.corp", true},
+		{"desk-.*", "my-desk-01", false},
+		{"desk", "desktop", false},
+		{"[invalid", "[invalid", true},
+		{"laptop", "", false},
+	}
+	for _, tt := range tests {
+		if result := MatchesHost(tt.key, tt.hostname); result != tt.expected {
+			t.Errorf("MatchesHost(%q, %q): expected %v, got %v", tt.key, tt.hostname, tt.expected, result)
+		}
+	}
+}
+
+func TestApplyHostOverrides(t *testing.T) {
+	config := &Config{
+		Editor:     "vim",
+		ServerPort: 7654,
+		Hosts: map[string]*Config{
+			"laptop":   {Editor: "code --wait", Aliases: map[string]string{"@api": "~/work/api"}},
+			"lap.*":    {Editor: "zed", SelectedProfile: "mobile"},
+			"desktop":  {ServerPort: 8000},
+			"dead-key": nil,
+		},
+	}
+	ApplyHostOverrides(config, "laptop.local")
+	if config.Editor != "code --wait" {
+		t.Errorf("Expected literal host entry to win, got editor %q", config.Editor)
+	}
+	if config.SelectedProfile != "mobile" {
+		t.Errorf("Expected regex host entry to apply, got profile %q", config.SelectedProfile)
+	}
+	if config.ServerPort != 7654 {
+		t.Errorf("Expected other hosts to be ignored, got port %d", config.ServerPort)
+	}
+	if config.Aliases["@api"] != "~/work/api" {
+		t.Errorf("Expected host aliases, got %v", config.Aliases)
+	}
+}
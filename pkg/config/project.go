@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// ProjectDir is the directory holding a project's config.json,
+// looked up from the working directory towards the root
+const ProjectDir = ".whats_next"
+
+// ProjectFields are the json keys a project config may set.
+// A project config comes with the repository, so fields that send data
+// somewhere, run commands or hold secrets, like webhooks, slack, editor or
+// gistToken, are left to the global config.
+var ProjectFields = map[string]bool{
+	"mode":              true,
+	"selectedProfile":   true,
+	"aliases":           true,
+	"sectionLevel":      true,
+	"gitRemote":         true,
+	"idleTimeout":       true,
+	"nativeIdleTimeout": true,
+	"serverIdleTimeout": true,
+	"idleAction":        true,
+	"nativeIdleAction":  true,
+	"serverIdleAction":  true,
+	"idleMessage":       true,
+	"hardTimeout":       true,
+	"showSessionGap":    true,
+	"replyLanguage":     true,
+}
+
+// FindProject walks up from dir to find .whats_next/config.json
+func FindProject(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		configFile := filepath.Join(dir, ProjectDir, FileName)
+		if stat, err := os.Stat(configFile); err == nil && !stat.IsDir() {
+			return configFile, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// RestrictProject clears the fields of config not in ProjectFields,
+// returning the json keys of the non-zero ones it cleared
+func RestrictProject(config *Config) []string {
+	var ignored []string
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if ProjectFields[name] || name == "$schema" {
+			continue
+		}
+		field := value.Field(i)
+		if field.IsZero() {
+			continue
+		}
+		ignored = append(ignored, name)
+		field.Set(reflect.Zero(field.Type()))
+	}
+	return ignored
+}
+
+// Merge overrides fields of config with the non-zero fields of overlay,
+// map fields are merged entry by entry
+func Merge(config *Config, overlay *Config) {
+	dst := reflect.ValueOf(config).Elem()
+	src := reflect.ValueOf(overlay).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		value := src.Field(i)
+		if value.IsZero() {
+			continue
+		}
+		if value.Kind() != reflect.Map {
+			dst.Field(i).Set(value)
+			continue
+		}
+		if dst.Field(i).IsNil() {
+			dst.Field(i).Set(reflect.MakeMap(value.Type()))
+		}
+		iter := value.MapRange()
+		for iter.Next() {
+			dst.Field(i).SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+}
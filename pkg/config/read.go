@@ -0,0 +1,87 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Reader reads the config files of a config dir
+type Reader struct {
+	// Dir is the config dir holding FileName, see ResolveDir
+	Dir string
+	// Hostname picks the Hosts overrides, see ApplyHostOverrides
+	Hostname string
+	// OnRead, if not nil, is called with every config file read and its
+	// content, e.g. to warn about unknown keys. ignored are the fields of a
+	// project config cleared by RestrictProject. err tells a project config
+	// does not parse, it is then skipped.
+	OnRead func(file string, data []byte, ignored []string, err error)
+}
+
+// Global reads FileName in Dir, empty if it does not exist
+func (r *Reader) Global() (*Config, error) {
+	configFile := filepath.Join(r.Dir, FileName)
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	r.onRead(configFile, data, nil, nil)
+	return &config, nil
+}
+
+// Host reads the global config with the overrides of Hostname applied,
+// for settings a project config must not influence
+func (r *Reader) Host() (*Config, error) {
+	config, err := r.Global()
+	if err != nil {
+		return nil, err
+	}
+	ApplyHostOverrides(config, r.Hostname)
+	return config, nil
+}
+
+// Effective reads the host config, and merges the project config found
+// from workingDir over it, if any. Only ProjectFields are taken from the
+// project config. The result must not be written back to the global
+// config, otherwise project settings would leak into it.
+func (r *Reader) Effective(workingDir string) (*Config, error) {
+	config, err := r.Host()
+	if err != nil {
+		return nil, err
+	}
+	if workingDir == "" {
+		return config, nil
+	}
+	configFile, ok := FindProject(workingDir)
+	if !ok {
+		return config, nil
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	var projectConfig Config
+	if err := json.Unmarshal(data, &projectConfig); err != nil {
+		r.onRead(configFile, data, nil, fmt.Errorf("parse %s: %w", configFile, err))
+		return config, nil
+	}
+	ignored := RestrictProject(&projectConfig)
+	r.onRead(configFile, data, ignored, nil)
+	Merge(config, &projectConfig)
+	return config, nil
+}
+
+func (r *Reader) onRead(file string, data []byte, ignored []string, err error) {
+	if r.OnRead != nil {
+		r.OnRead(file, data, ignored, err)
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReaderEffective(t *testing.T) {
+	configDir := t.TempDir()
+	reader := &Reader{Dir: configDir}
+	globalConfig := `{"editor": "vim", "mode": "native", "selectedProfile": "default", "aliases": {"@a": "~/a"}}`
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(globalConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	projectDir := t.TempDir()
+	subDir := filepath.Join(projectDir, "pkg", "sub")
+	if err := os.MkdirAll(filepath.Join(projectDir, ProjectDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	projectConfig := `{"mode": "server", "idleTimeout": "5m", "aliases": {"@b": "~/b"}}`
+	if err := os.WriteFile(filepath.Join(projectDir, ProjectDir, "config.json"), []byte(projectConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := reader.Effective(subDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Mode != ModeServer || config.IdleTimeout != "5m" {
+		t.Errorf("Expected project mode and timeout, got %q %q", config.Mode, config.IdleTimeout)
+	}
+	if config.Editor != "vim" || config.SelectedProfile != "default" {
+		t.Errorf("Expected global editor and profile to be kept, got %q %q", config.Editor, config.SelectedProfile)
+	}
+	if config.Aliases["@a"] != "~/a" || config.Aliases["@b"] != "~/b" {
+		t.Errorf("Expected aliases to be merged, got %v", config.Aliases)
+	}
+
+	config, err = reader.Effective(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Mode != ModeNative {
+		t.Errorf("Expected global mode outside the project, got %q", config.Mode)
+	}
+}
+
+func TestReaderEffectiveIgnoresUnsafeFields(t *testing.T) {
+	configDir := t.TempDir()
+	var reported []string
+	reader := &Reader{Dir: configDir, OnRead: func(file string, data []byte, ignored []string, err error) {
+		reported = append(reported, ignored...)
+	}}
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"editor": "vim"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, ProjectDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	projectConfig := `{"mode": "server", "editor": "evil", "webhooks": [{"url": "https://example.com"}], "commandAliases": {"s": "uninstall"}, "apiAllowOrigins": ["*"]}`
+	if err := os.WriteFile(filepath.Join(projectDir, ProjectDir, "config.json"), []byte(projectConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := reader.Effective(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Mode != ModeServer {
+		t.Errorf("Expected project mode, got %q", config.Mode)
+	}
+	if config.Editor != "vim" {
+		t.Errorf("Expected global editor, got %q", config.Editor)
+	}
+	if len(config.Webhooks) != 0 || len(config.CommandAliases) != 0 || len(config.APIAllowOrigins) != 0 {
+		t.Errorf("Expected project webhooks, command aliases and origins to be ignored, got %+v", config)
+	}
+	if strings.Join(reported, ",") != "editor,commandAliases,webhooks,apiAllowOrigins" {
+		t.Errorf("Expected the ignored fields reported, got %v", reported)
+	}
+}
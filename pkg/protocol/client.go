@@ -0,0 +1,195 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client talks to a running `whats_next serve`
+type Client struct {
+	// Addr is the host:port of the server
+	Addr string
+	// Token is sent in TokenHeader if not empty
+	Token string
+	// HTTPClient defaults to http.DefaultClient. Waiting for the user
+	// takes minutes, it should not set a short Timeout.
+	HTTPClient *http.Client
+	// OnVersion is called with the VersionHeader of the responses, if set
+	OnVersion func(serverVersion string)
+}
+
+// NewClient returns a client of the server at addr, like "localhost:7654"
+func NewClient(addr string) *Client {
+	return &Client{Addr: addr}
+}
+
+// WaitOptions are the optional parameters of Wait
+type WaitOptions struct {
+	ProgramName string
+	Version     string
+	Session     string
+}
+
+// Wait blocks until the user answers the agent in workingDir, and returns
+// the answer wrapped with the guidelines of workingDir.
+// It returns ErrUserExit if the user quits the server, ErrTimeout if the
+// server stops waiting, and ErrCancelled if ctx is done.
+func (c *Client) Wait(ctx context.Context, workingDir string, opts WaitOptions) (string, error) {
+	params := url.Values{}
+	params.Set(ParamWorkingDir, workingDir)
+	if opts.ProgramName != "" {
+		params.Set(ParamProgramName, opts.ProgramName)
+	}
+	if opts.Version != "" {
+		params.Set(ParamVersion, opts.Version)
+	}
+	params.Set(ParamSession, opts.Session)
+	return c.getText(ctx, "/?"+params.Encode())
+}
+
+// Ask blocks until the user answers question for the agent in workingDir,
+// and returns the answer formatted as an approval or a denial
+func (c *Client) Ask(ctx context.Context, workingDir string, question string, session string) (string, error) {
+	params := url.Values{}
+	params.Set(ParamWorkingDir, workingDir)
+	params.Set(ParamAsk, question)
+	params.Set(ParamSession, session)
+	return c.getText(ctx, "/?"+params.Encode())
+}
+
+// Ping reports an error if no whats_next server answers at Addr
+func (c *Client) Ping(ctx context.Context) error {
+	body, err := c.getText(ctx, "/ping")
+	if err != nil {
+		return err
+	}
+	if body != PingResponse {
+		return fmt.Errorf("%s is not a whats_next server: %s", c.Addr, body)
+	}
+	return nil
+}
+
+// Sessions lists the agents waiting for input
+func (c *Client) Sessions(ctx context.Context) (*Sessions, error) {
+	var sessions Sessions
+	if err := c.doJSON(ctx, http.MethodGet, APIPrefix+"sessions", nil, &sessions); err != nil {
+		return nil, err
+	}
+	return &sessions, nil
+}
+
+// PostMessage queues msg on the single input queue of the server, taken
+// by the next agent waiting whatever its directory. msg.WorkingDir is only
+// the working dir of the answer for an agent that sent none.
+func (c *Client) PostMessage(ctx context.Context, msg Message) error {
+	return c.doJSON(ctx, http.MethodPost, APIPrefix+"messages", &msg, nil)
+}
+
+// Profiles lists the profiles, with the selected one
+func (c *Client) Profiles(ctx context.Context) ([]Profile, error) {
+	var result struct {
+		Profiles []Profile `json:"profiles"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, APIPrefix+"profiles", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Profiles, nil
+}
+
+// Profile returns the profile name with its content
+func (c *Client) Profile(ctx context.Context, name string) (*Profile, error) {
+	var profile Profile
+	if err := c.doJSON(ctx, http.MethodGet, APIPrefix+"profiles/"+url.PathEscape(name), nil, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) setToken(req *http.Request) {
+	if c.Token != "" {
+		req.Header.Set(TokenHeader, c.Token)
+	}
+}
+
+func (c *Client) getText(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+c.Addr+path, nil)
+	if err != nil {
+		return "", err
+	}
+	c.setToken(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("%w: %w", ErrCancelled, ctx.Err())
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+	if c.OnVersion != nil {
+		c.OnVersion(resp.Header.Get(VersionHeader))
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusRequestTimeout:
+		return "", ErrTimeout
+	default:
+		return "", fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	text := strings.TrimRight(string(body), "\n")
+	if text == ExitResponse {
+		return "", ErrUserExit
+	}
+	return text, nil
+}
+
+func (c *Client) doJSON(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+c.Addr+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.setToken(req)
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr Error
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			return fmt.Errorf("server returned %s", resp.Status)
+		}
+		return fmt.Errorf("server returned %s: %s", resp.Status, apiErr.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,119 @@
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(strings.TrimPrefix(server.URL, "http://"))
+}
+
+func TestClientWaitAndAsk(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(VersionHeader, "v1.2.3")
+		q := r.URL.Query()
+		if question := q.Get(ParamAsk); question != "" {
+			fmt.Fprintf(w, "APPROVED: %s in %s\n", question, q.Get(ParamWorkingDir))
+			return
+		}
+		fmt.Fprintf(w, "answer for %s (%s)\n", q.Get(ParamWorkingDir), q.Get(ParamSession))
+	})
+	var serverVersion string
+	c.OnVersion = func(version string) { serverVersion = version }
+	ctx := context.Background()
+	answer, err := c.Wait(ctx, "/src/api", WaitOptions{Session: "s1"})
+	if err != nil || answer != "answer for /src/api (s1)" {
+		t.Errorf("Unexpected wait answer %q: %v", answer, err)
+	}
+	if serverVersion != "v1.2.3" {
+		t.Errorf("Expected the server version to be reported, got %q", serverVersion)
+	}
+	answer, err = c.Ask(ctx, "/src/api", "Push?", "")
+	if err != nil || answer != "APPROVED: Push? in /src/api" {
+		t.Errorf("Unexpected ask answer %q: %v", answer, err)
+	}
+}
+
+func TestClientAPI(t *testing.T) {
+	var posted Message
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/ping":
+			fmt.Fprintln(w, PingResponse)
+		case APIPrefix + "sessions":
+			json.NewEncoder(w).Encode(&Sessions{Sessions: []Session{{ID: 1, WorkingDir: "/src/api"}}})
+		case APIPrefix + "messages":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(&Error{Error: "not found"})
+		}
+	})
+	ctx := context.Background()
+	if err := c.Ping(ctx); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+	sessions, err := c.Sessions(ctx)
+	if err != nil || len(sessions.Sessions) != 1 || sessions.Sessions[0].WorkingDir != "/src/api" {
+		t.Errorf("Unexpected sessions %+v: %v", sessions, err)
+	}
+	if err := c.PostMessage(ctx, Message{Text: "run the tests", WorkingDir: "/src/api"}); err != nil || posted.Text != "run the tests" {
+		t.Errorf("Unexpected message %+v: %v", posted, err)
+	}
+	if _, err := c.Profile(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
+
+func TestClientWaitErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get(ParamWorkingDir) {
+		case "/exit":
+			fmt.Fprintln(w, ExitResponse)
+		case "/timeout":
+			http.Error(w, ErrTimeout.Error(), http.StatusRequestTimeout)
+		default:
+			<-r.Context().Done()
+		}
+	})
+	if _, err := c.Wait(context.Background(), "/exit", WaitOptions{}); !errors.Is(err, ErrUserExit) {
+		t.Errorf("Expected ErrUserExit, got %v", err)
+	}
+	if _, err := c.Wait(context.Background(), "/timeout", WaitOptions{}); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Wait(ctx, "/src/api", WaitOptions{}); !errors.Is(err, ErrCancelled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected ErrCancelled, got %v", err)
+	}
+}
+
+func TestClientToken(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(TokenHeader) != "secret" {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintln(w, PingResponse)
+	})
+	if err := c.Ping(context.Background()); err == nil {
+		t.Errorf("Expected ping without token to fail")
+	}
+	c.Token = "secret"
+	if err := c.Ping(context.Background()); err != nil {
+		t.Errorf("Expected ping with token to succeed: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+// Package protocol is the client/server protocol of `whats_next serve`,
+// for tools like editor plugins and bots that talk to the server directly
+// instead of running `whats_next` as a subprocess.
+//
+// An agent waits for the answer of the user with a GET on "/", the
+// server holds the request until the user answers in its input and
+// replies with the answer as plain text. The JSON API under APIPrefix
+// lists the waiting agents and queues replies to them.
+package protocol
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultPort is the port of the server when serverPort is not configured
+const DefaultPort = 7654
+
+// The query parameters of a GET on "/"
+const (
+	// ParamWorkingDir is the directory of the agent, deciding its guidelines
+	ParamWorkingDir = "workingDir"
+	// ParamProgramName is the name the agent runs whats_next by
+	ParamProgramName = "programName"
+	// ParamVersion is the version of the client
+	ParamVersion = "version"
+	// ParamSession identifies the agent session in the tool call counts
+	ParamSession = "session"
+	// ParamAsk is a yes/no question, answered as an approval or a denial
+	ParamAsk = "ask"
+	// ParamToken is the server token, for clients that cannot set TokenHeader
+	ParamToken = "token"
+)
+
+const (
+	// VersionHeader carries the version of the server in every response
+	VersionHeader = "X-Whats-Next-Version"
+	// TokenHeader carries the server token, required on every request
+	// when the server has one configured
+	TokenHeader = "X-Whats-Next-Token"
+	// PingResponse is the body of GET /ping
+	PingResponse = "whats_next serve"
+	// APIPrefix is the root of the JSON API.
+	// Breaking changes go to a new version.
+	APIPrefix = "/api/v1/"
+	// ExitResponse is the answer to the agents when the user quits the server
+	ExitResponse = "exit"
+)
+
+var (
+	// ErrUserExit is returned when the user quits instead of answering
+	ErrUserExit = errors.New("user exit")
+	// ErrTimeout is returned when the user does not answer in time
+	ErrTimeout = errors.New("timeout waiting for input")
+	// ErrCancelled is returned when the wait is cancelled by its caller
+	ErrCancelled = errors.New("cancelled")
+)
+
+// Session is an agent waiting for input, listed by GET /api/v1/sessions
+type Session struct {
+	ID         int64     `json:"id"`
+	WorkingDir string    `json:"workingDir"`
+	Project    string    `json:"project,omitempty"`
+	Since      time.Time `json:"since"`
+	// Wait is how long the client has been waiting, in seconds
+	Wait float64 `json:"wait"`
+}
+
+// Sessions is the body of GET /api/v1/sessions
+type Sessions struct {
+	Sessions []Session `json:"sessions"`
+	// Typing tells an answer is being typed in the server terminal
+	Typing bool `json:"typing"`
+}
+
+// Message is the body of POST /api/v1/messages
+type Message struct {
+	Text string `json:"text"`
+	// WorkingDir does not pick the agent, see Client.PostMessage
+	WorkingDir string `json:"workingDir,omitempty"`
+}
+
+// Profile is returned by GET /api/v1/profiles and /api/v1/profiles/NAME
+type Profile struct {
+	Name     string `json:"name"`
+	Selected bool   `json:"selected,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// Error is the body of the API responses that fail
+type Error struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,106 @@
+package sections
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+)
+
+// maxCacheEntries bounds the memoized matches, the cache is simply
+// cleared when it is full
+const maxCacheEntries = 256
+
+// cacheKey identifies the matching of one profile content in one directory
+type cacheKey struct {
+	contentHash [sha256.Size]byte
+	dir         string
+	// settings are the fields of the Config the matching depends on
+	settings string
+}
+
+type cacheEntry struct {
+	sections []Section
+	headings []string
+	results  []Result
+}
+
+// Cache memoizes the section matching of Filter, for a long running process
+// matching the same profile against the same directories again and again.
+// It does not watch the profiles or the git state, Invalidate it when they change.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+}
+
+// NewCache returns an empty cache
+func NewCache() *Cache {
+	return &Cache{entries: make(map[cacheKey]*cacheEntry)}
+}
+
+// Invalidate drops the memoized matches
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[cacheKey]*cacheEntry)
+}
+
+func (c *Cache) match(content string, dir string, cfg *Config) ([]Section, []string, []Result) {
+	key := cacheKey{
+		contentHash: sha256.Sum256([]byte(content)),
+		dir:         dir,
+		settings:    fmt.Sprint(cfg.Agent, cfg.Aliases, cfg.GitRemote, cfg.DisableSymlinkResolution, cfg.SplitLevel),
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return entry.sections, entry.headings, rematchVolatileSections(entry.headings, entry.results, dir, cfg)
+	}
+
+	sections := ParseProfile(content, cfg.SplitLevel)
+	headings := InheritDirectives(sections)
+	results := MatchHeadings(headings, dir, cfg)
+
+	c.mu.Lock()
+	if len(c.entries) >= maxCacheEntries {
+		c.entries = make(map[cacheKey]*cacheEntry)
+	}
+	c.entries[key] = &cacheEntry{
+		sections: sections,
+		headings: headings,
+		results:  results,
+	}
+	c.mu.Unlock()
+	return sections, headings, results
+}
+
+// rematchVolatileSections matches again the sections whose (has: ...) or
+// (lang: ...) directives depend on the files in dir, which change without
+// the profile changing. These checks are cheap, unlike the git lookups:
+// (has: ...) stats the files every time, (lang: ...) reuses the language
+// detected within langCacheTTL, so it sees a change after at most that long.
+func rematchVolatileSections(headings []string, cached []Result, dir string, cfg *Config) []Result {
+	var results []Result
+	for i, heading := range headings {
+		if !isVolatileHeading(heading) {
+			continue
+		}
+		if results == nil {
+			results = append([]Result(nil), cached...)
+		}
+		results[i] = MatchHeading(heading, dir, cfg)
+	}
+	if results == nil {
+		return cached
+	}
+	return results
+}
+
+func isVolatileHeading(heading string) bool {
+	if _, ok := DirectiveValue(heading, "has"); ok {
+		return true
+	}
+	_, ok := DirectiveValue(heading, "lang")
+	return ok
+}
@@ -0,0 +1,49 @@
+package sections
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache()
+	dir := t.TempDir()
+	content := fmt.Sprintf("# Project (project: %s)\nproject rules\n# Go (has: go.mod)\ngo rules\n", dir)
+
+	got := Filter(content, dir, &Config{Agent: "claude", Cache: cache})
+	if !strings.Contains(got, "project rules") || strings.Contains(got, "go rules") {
+		t.Fatalf("unexpected content before go.mod:\n%s", got)
+	}
+	if len(cache.entries) != 1 {
+		t.Fatalf("expected 1 cache entry, got %d", len(cache.entries))
+	}
+
+	// (has: ...) sections are matched again on a cache hit
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got = Filter(content, dir, &Config{Agent: "claude", Cache: cache})
+	if !strings.Contains(got, "go rules") {
+		t.Errorf("expected go rules once go.mod exists:\n%s", got)
+	}
+
+	// another agent is another entry
+	Filter(content, dir, &Config{Agent: AgentCursor, Cache: cache})
+	if len(cache.entries) != 2 {
+		t.Errorf("expected 2 cache entries, got %d", len(cache.entries))
+	}
+
+	// so are other aliases, a config change needs no invalidation
+	Filter(content, dir, &Config{Agent: AgentCursor, Aliases: map[string]string{"api": dir}, Cache: cache})
+	if len(cache.entries) != 3 {
+		t.Errorf("expected 3 cache entries, got %d", len(cache.entries))
+	}
+
+	cache.Invalidate()
+	if len(cache.entries) != 0 {
+		t.Errorf("expected an empty cache after invalidation, got %d entries", len(cache.entries))
+	}
+}
@@ -0,0 +1,245 @@
+package sections
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeepProgramName opts a section, and its subsections,
+// out of program-name substitution: "# Setup (keep-program-name)"
+const KeepProgramName = "keep-program-name"
+
+// ValueDirectives are the "(name: value)" directives understood in headings,
+// they may also be set by the front-matter block of a section
+var ValueDirectives = []string{"project", "agents", "os", "has", "lang", "priority", "show"}
+
+// FlagDirectives are the "(name)" directives understood in headings
+var FlagDirectives = []string{"cursor-only", "disabled", KeepProgramName}
+
+// DirectiveValue returns the value of a directive like "(name: value)" in a heading
+func DirectiveValue(heading string, name string) (string, bool) {
+	start := 0
+	for {
+		parenStart := strings.Index(heading[start:], "(")
+		if parenStart == -1 {
+			break
+		}
+		parenStart += start
+
+		parenEnd := strings.Index(heading[parenStart:], ")")
+		if parenEnd == -1 {
+			break
+		}
+		parenEnd += parenStart
+
+		content := strings.TrimSpace(heading[parenStart+1 : parenEnd])
+		if value, ok := strings.CutPrefix(content, name+":"); ok {
+			return strings.TrimSpace(value), true
+		}
+
+		start = parenEnd + 1
+	}
+	return "", false
+}
+
+// HasDirective checks if a heading contains a flag directive like "(disabled)"
+func HasDirective(heading string, name string) bool {
+	start := 0
+	for {
+		parenStart := strings.Index(heading[start:], "(")
+		if parenStart == -1 {
+			return false
+		}
+		parenStart += start
+
+		parenEnd := strings.Index(heading[parenStart:], ")")
+		if parenEnd == -1 {
+			return false
+		}
+		parenEnd += parenStart
+
+		if strings.TrimSpace(heading[parenStart+1:parenEnd]) == name {
+			return true
+		}
+		start = parenEnd + 1
+	}
+}
+
+// hasCursorOnlyDirective checks if a heading contains the (cursor-only) directive
+// Handles arbitrary whitespace and multiple directives
+func hasCursorOnlyDirective(heading string) bool {
+	// Look for pattern like "(cursor-only)" with potential whitespace
+	start := 0
+	for {
+		parenStart := strings.Index(heading[start:], "(")
+		if parenStart == -1 {
+			break
+		}
+		parenStart += start
+
+		parenEnd := strings.Index(heading[parenStart:], ")")
+		if parenEnd == -1 {
+			break
+		}
+		parenEnd += parenStart
+
+		// Extract content inside parentheses
+		content := heading[parenStart+1 : parenEnd]
+		// Trim whitespace and check if it contains "cursor-only"
+		trimmedContent := strings.TrimSpace(content)
+		if strings.Contains(trimmedContent, "cursor-only") {
+			return true
+		}
+
+		start = parenEnd + 1
+	}
+	return false
+}
+
+// Priority returns the priority declared by "(priority: N)" in a heading,
+// sections without a valid priority default to 0
+func Priority(heading string) int {
+	value, ok := DirectiveValue(heading, "priority")
+	if !ok {
+		return 0
+	}
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+// HeadingTitle returns the text of a heading without "#" and directives
+func HeadingTitle(heading string) string {
+	heading = ApplyCommentDirectives(heading)
+	names := append(append([]string{}, ValueDirectives...), FlagDirectives...)
+	return strings.TrimSpace(strings.TrimLeft(StripDirectives(heading, names), "#"))
+}
+
+// StripDirectives removes the "(name: value)" and "(name)" directives of names from heading
+func StripDirectives(heading string, names []string) string {
+	var b strings.Builder
+	for {
+		parenStart := strings.Index(heading, "(")
+		if parenStart == -1 {
+			break
+		}
+		parenEnd := strings.Index(heading[parenStart:], ")")
+		if parenEnd == -1 {
+			break
+		}
+		parenEnd += parenStart
+
+		name, _, _ := strings.Cut(heading[parenStart+1:parenEnd], ":")
+		if containsFold(names, strings.TrimSpace(name)) {
+			b.WriteString(strings.TrimRight(heading[:parenStart], " \t"))
+		} else {
+			b.WriteString(heading[:parenEnd+1])
+		}
+		heading = heading[parenEnd+1:]
+	}
+	b.WriteString(heading)
+	return b.String()
+}
+
+// ApplyFrontMatter returns the heading with the metadata appended as
+// parenthesized directives, directives already present in the heading win
+func ApplyFrontMatter(heading string, meta map[string]string) string {
+	if len(meta) == 0 {
+		return heading
+	}
+	for _, key := range ValueDirectives {
+		value, ok := meta[key]
+		if !ok || value == "" {
+			continue
+		}
+		if _, exists := DirectiveValue(heading, key); exists {
+			continue
+		}
+		heading += " (" + key + ": " + value + ")"
+	}
+	if isTruthy(meta["disabled"]) && !HasDirective(heading, "disabled") {
+		heading += " (disabled)"
+	}
+	return heading
+}
+
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
+// ApplyCommentDirectives converts directives written as HTML comments, like
+// "# Build rules <!-- project: ~/work/api -->", into parenthesized directives
+// so that the rendered markdown stays clean while matching works the same.
+// A comment may hold several directives separated by ";".
+func ApplyCommentDirectives(heading string) string {
+	if !strings.Contains(heading, "<!--") {
+		return heading
+	}
+	var directives []string
+	for {
+		start := strings.Index(heading, "<!--")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(heading[start:], "-->")
+		if end == -1 {
+			break
+		}
+		end += start
+		for _, directive := range strings.Split(heading[start+len("<!--"):end], ";") {
+			directive = strings.TrimSpace(directive)
+			if directive != "" {
+				directives = append(directives, directive)
+			}
+		}
+		heading = heading[:start] + heading[end+len("-->"):]
+	}
+	heading = strings.TrimRight(heading, " \t")
+	for _, directive := range directives {
+		heading += " (" + directive + ")"
+	}
+	return heading
+}
+
+// FindSectionLine returns the 1-based line of the heading titled title in content,
+// compared without "#" and directives, ignoring case. A unique title prefix also matches.
+func FindSectionLine(content string, title string) (int, error) {
+	title = strings.TrimSpace(title)
+	var prefixMatches []Section
+	for _, section := range Parse(Normalize(content), 0) {
+		sectionTitle := HeadingTitle(section.Title)
+		if strings.EqualFold(sectionTitle, title) {
+			return section.Line, nil
+		}
+		if strings.HasPrefix(strings.ToLower(sectionTitle), strings.ToLower(title)) {
+			prefixMatches = append(prefixMatches, section)
+		}
+	}
+	switch len(prefixMatches) {
+	case 0:
+		return 0, fmt.Errorf("section not found: %s", title)
+	case 1:
+		return prefixMatches[0].Line, nil
+	}
+	titles := make([]string, 0, len(prefixMatches))
+	for _, section := range prefixMatches {
+		titles = append(titles, HeadingTitle(section.Title))
+	}
+	return 0, fmt.Errorf("ambiguous section %s, matches: %s", title, strings.Join(titles, ", "))
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package sections
+
+import "testing"
+
+func TestPriority(t *testing.T) {
+	tests := []struct {
+		heading  string
+		expected int
+	}{
+		{"# No priority", 0},
+		{"# Critical(priority: 10)", 10},
+		{"# Last (priority:-3)", -3},
+		{"# Invalid(priority: high)", 0},
+		{"# Mixed(project: /some/path)(priority: 2)", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.heading, func(t *testing.T) {
+			result := Priority(tt.heading)
+			if result != tt.expected {
+				t.Errorf("Priority(%q) = %d, expected %d", tt.heading, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyCommentDirectives(t *testing.T) {
+	tests := []struct {
+		heading  string
+		expected string
+	}{
+		{"# Plain", "# Plain"},
+		{"# Build <!-- project: ~/work/api -->", "# Build (project: ~/work/api)"},
+		{"# Build <!-- project: ~/a; priority: 2 --> <!-- cursor-only -->", "# Build (project: ~/a) (priority: 2) (cursor-only)"},
+		{"# Unclosed <!-- project: ~/a", "# Unclosed <!-- project: ~/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.heading, func(t *testing.T) {
+			if result := ApplyCommentDirectives(tt.heading); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFindSectionLine(t *testing.T) {
+	content := "# General\nbe concise\n\n## Build rules (project: ~/work)\nmake\n\nTesting\n-------\ngo test\n## Build images\n"
+	tests := []struct {
+		title    string
+		expected int
+		err      bool
+	}{
+		{"general", 1, false},
+		{"Build rules", 4, false},
+		{"testing", 7, false},
+		{"Build im", 10, false},
+		{"Build", 0, true},
+		{"Deploy", 0, true},
+	}
+	for _, tt := range tests {
+		line, err := FindSectionLine(content, tt.title)
+		if (err != nil) != tt.err || line != tt.expected {
+			t.Errorf("FindSectionLine(%q) = %d, %v, expected %d", tt.title, line, err, tt.expected)
+		}
+	}
+}
+
+func TestHeadingTitle(t *testing.T) {
+	if got := HeadingTitle("## Build (project: ~/work) rules (cursor-only) (v2)"); got != "Build rules (v2)" {
+		t.Errorf("Expected %q, got %q", "Build rules (v2)", got)
+	}
+}
@@ -0,0 +1,160 @@
+package sections
+
+import (
+	"fmt"
+	"strings"
+)
+
+// gitTopLevel returns the root directory of the git checkout containing dir
+func (cfg *Config) gitTopLevel(dir string) string {
+	if cfg.Git == nil {
+		return ""
+	}
+	root, err := cfg.Git.TopLevel(dir)
+	if err != nil {
+		return ""
+	}
+	return root
+}
+
+// gitRemoteURL returns the first URL of the named remote of the repository containing dir
+func (cfg *Config) gitRemoteURL(dir string, name string) (string, error) {
+	urls, err := cfg.Git.RemoteURLs(dir, name)
+	if err != nil {
+		return "", err
+	}
+	if len(urls) == 0 {
+		return "", fmt.Errorf("remote %s has no url", name)
+	}
+	return urls[0], nil
+}
+
+// IsGitWorktree checks if the current directory is a git worktree of the specified project
+func IsGitWorktree(currentDir, projectDir string, cfg *Config) bool {
+	if cfg.Git == nil {
+		return false
+	}
+	// First, try using git worktree command to check direct relationship
+	if isWorktreeRelated(currentDir, projectDir, cfg.Git) {
+		return true
+	}
+
+	// Fallback to remote URL comparison
+	return hasSameGitRemote(currentDir, projectDir, cfg)
+}
+
+// isWorktreeRelated checks if two directories are related through git worktree
+func isWorktreeRelated(currentDir, projectDir string, git Git) bool {
+	// Check if currentDir is a worktree of projectDir
+	if isWorktreeOf(currentDir, projectDir, git) {
+		return true
+	}
+
+	// Check if projectDir is a worktree of currentDir
+	if isWorktreeOf(projectDir, currentDir, git) {
+		return true
+	}
+
+	// Check if both are worktrees of the same main repository
+	return haveSameMainWorktree(currentDir, projectDir, git)
+}
+
+// isWorktreeOf checks if targetDir is a worktree of mainDir
+func isWorktreeOf(targetDir, mainDir string, git Git) bool {
+	worktrees, err := git.Worktrees(mainDir)
+	if err != nil {
+		return false
+	}
+
+	for _, worktree := range worktrees {
+		if isSameDir(worktree, targetDir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// haveSameMainWorktree checks if two directories belong to the same git repository
+func haveSameMainWorktree(dir1, dir2 string, git Git) bool {
+	main1 := getMainWorktreePath(dir1, git)
+	main2 := getMainWorktreePath(dir2, git)
+
+	if main1 == "" || main2 == "" {
+		return false
+	}
+
+	return isSameDir(main1, main2)
+}
+
+// getMainWorktreePath returns the path to the main worktree for a given directory
+func getMainWorktreePath(dir string, git Git) string {
+	worktrees, err := git.Worktrees(dir)
+	if err != nil || len(worktrees) == 0 {
+		return ""
+	}
+	// The first worktree entry is the main worktree
+	return worktrees[0]
+}
+
+// hasSameGitRemote checks if two directories share a git remote URL.
+// If cfg.GitRemote is empty, all remotes are compared, so fork-based
+// checkouts whose canonical remote is not named origin still match.
+func hasSameGitRemote(currentDir, projectDir string, cfg *Config) bool {
+	if cfg.GitRemote != "" {
+		currentURL, err := cfg.gitRemoteURL(currentDir, cfg.GitRemote)
+		if err != nil {
+			return false
+		}
+		projectURL, err := cfg.gitRemoteURL(projectDir, cfg.GitRemote)
+		if err != nil {
+			return false
+		}
+		// Normalize URLs for comparison (handle different formats like SSH vs HTTPS)
+		return normalizeGitURL(currentURL) == normalizeGitURL(projectURL)
+	}
+
+	currentURLs, err := cfg.Git.RemoteURLs(currentDir, "")
+	if err != nil || len(currentURLs) == 0 {
+		return false
+	}
+	projectURLs, err := cfg.Git.RemoteURLs(projectDir, "")
+	if err != nil {
+		return false
+	}
+	normalized := make(map[string]bool, len(currentURLs))
+	for _, url := range currentURLs {
+		normalized[normalizeGitURL(url)] = true
+	}
+	for _, url := range projectURLs {
+		if normalized[normalizeGitURL(url)] {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeGitURL normalizes git URLs for comparison
+// Converts SSH format to HTTPS-like format for consistent comparison
+func normalizeGitURL(url string) string {
+	url = strings.TrimSpace(url)
+
+	// Convert SSH format (git@github.com:user/repo.git) to normalized format
+	if strings.HasPrefix(url, "git@") {
+		// Extract host and path
+		parts := strings.SplitN(url, ":", 2)
+		if len(parts) == 2 {
+			host := strings.TrimPrefix(parts[0], "git@")
+			path := parts[1]
+			url = "https://" + host + "/" + path
+		}
+	}
+
+	// Remove .git suffix
+	url = strings.TrimSuffix(url, ".git")
+
+	// Remove trailing slash
+	url = strings.TrimSuffix(url, "/")
+
+	return strings.ToLower(url)
+}
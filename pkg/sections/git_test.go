@@ -0,0 +1,110 @@
+package sections
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeGit answers from fixed repositories, keyed by their top level
+type fakeGit struct {
+	// worktrees lists the worktrees of each repository, main worktree first
+	worktrees map[string][]string
+	remotes   map[string]map[string]string
+}
+
+func (f *fakeGit) TopLevel(dir string) (string, error) {
+	for _, worktrees := range f.worktrees {
+		for _, worktree := range worktrees {
+			if dir == worktree || strings.HasPrefix(dir, worktree+"/") {
+				return worktree, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("not a git repository: %s", dir)
+}
+
+func (f *fakeGit) Worktrees(dir string) ([]string, error) {
+	root, err := f.TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, worktrees := range f.worktrees {
+		for _, worktree := range worktrees {
+			if worktree == root {
+				return worktrees, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("not a git repository: %s", dir)
+}
+
+func (f *fakeGit) RemoteURLs(dir string, name string) ([]string, error) {
+	root, err := f.TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+	remotes := f.remotes[root]
+	if name != "" {
+		url, ok := remotes[name]
+		if !ok {
+			return nil, fmt.Errorf("remote not found: %s", name)
+		}
+		return []string{url}, nil
+	}
+	var urls []string
+	for _, url := range remotes {
+		urls = append(urls, url)
+	}
+	return urls, nil
+}
+
+func TestIsGitWorktree(t *testing.T) {
+	git := &fakeGit{
+		worktrees: map[string][]string{
+			"api":  {"/work/api", "/work/api-feature", "/tmp/api-hotfix"},
+			"fork": {"/work/api-fork"},
+			"web":  {"/work/web"},
+		},
+		remotes: map[string]map[string]string{
+			"/work/api":      {"origin": "git@github.com:acme/api.git"},
+			"/work/api-fork": {"origin": "git@github.com:me/api.git", "upstream": "https://github.com/acme/api"},
+			"/work/web":      {"origin": "git@github.com:acme/web.git"},
+		},
+	}
+	cfg := &Config{Git: git}
+
+	tests := []struct {
+		name       string
+		currentDir string
+		projectDir string
+		want       bool
+	}{
+		{"linked worktree of project", "/work/api-feature/pkg", "/work/api", true},
+		{"project of linked worktree", "/work/api", "/work/api-feature", true},
+		{"sibling worktrees", "/tmp/api-hotfix", "/work/api-feature", true},
+		{"fork sharing a remote", "/work/api-fork", "/work/api", true},
+		{"unrelated repository", "/work/web", "/work/api", false},
+		{"not a repository", "/tmp/scratch", "/work/api", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsGitWorktree(tt.currentDir, tt.projectDir, cfg); got != tt.want {
+				t.Errorf("IsGitWorktree(%q, %q) = %v, want %v", tt.currentDir, tt.projectDir, got, tt.want)
+			}
+		})
+	}
+
+	if got := getMainWorktreePath("/tmp/api-hotfix", git); got != "/work/api" {
+		t.Errorf("getMainWorktreePath = %q, want /work/api", got)
+	}
+	if got := cfg.gitTopLevel("/work/api-feature/pkg/util"); got != "/work/api-feature" {
+		t.Errorf("gitTopLevel = %q, want /work/api-feature", got)
+	}
+	if hasSameGitRemote("/work/api-fork", "/work/api", &Config{Git: git, GitRemote: "origin"}) {
+		t.Errorf("comparing the origin remote of a fork should not match")
+	}
+	if IsGitWorktree("/work/api-feature", "/work/api", &Config{}) {
+		t.Errorf("expected no git matching without Config.Git")
+	}
+}
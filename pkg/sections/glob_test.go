@@ -1,4 +1,4 @@
-package main
+package sections
 
 import (
 	"os"
@@ -138,7 +138,7 @@ func TestMatchesGlobPattern(t *testing.T) {
 	}
 }
 
-func TestShouldIncludeSectionWithGlob(t *testing.T) {
+func TestMatchHeadingWithGlob(t *testing.T) {
 	// Create a temporary directory structure for testing using the helper
 	originalTempDir, tempDir, err := mkdirTempResolved("glob_section_test")
 	if err != nil {
@@ -198,15 +198,15 @@ func TestShouldIncludeSectionWithGlob(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, _, _, _ := shouldIncludeSection(tt.heading, tt.cwd, true)
+			result := MatchHeading(tt.heading, tt.cwd, &Config{Agent: AgentCursor}).Include
 			if result != tt.expected {
-				t.Errorf("shouldIncludeSection(%q, %q) = %v, expected %v", tt.heading, tt.cwd, result, tt.expected)
+				t.Errorf("MatchHeading(%q, %q) = %v, expected %v", tt.heading, tt.cwd, result, tt.expected)
 			}
 		})
 	}
 }
 
-func TestFilterContentByProjectWithGlob(t *testing.T) {
+func TestFilterWithGlob(t *testing.T) {
 	// Create a temporary directory structure for testing using the helper
 	originalTempDir, tempDir, err := mkdirTempResolved("glob_filter_test")
 	if err != nil {
@@ -220,18 +220,6 @@ func TestFilterContentByProjectWithGlob(t *testing.T) {
 		t.Fatalf("Failed to create frontend dir: %v", err)
 	}
 
-	// Save original working directory
-	originalWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get working directory: %v", err)
-	}
-
-	// Change to frontend directory for testing
-	if err := os.Chdir(frontendDir); err != nil {
-		t.Fatalf("Failed to change to frontend dir: %v", err)
-	}
-	defer os.Chdir(originalWd)
-
 	content := `# General Section
 Always shown.
 
@@ -253,17 +241,14 @@ Should be shown for frontend projects.
 # Any Project Section(project: ` + tempDir + `/**)
 Should be shown for any project under tempDir.`
 
-	result, err := filterContentByProject(content)
-	if err != nil {
-		t.Fatalf("filterContentByProject failed: %v", err)
-	}
+	result := Filter(content, frontendDir, &Config{Agent: AgentCursor})
 
 	if result != expected {
 		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
 	}
 }
 
-func TestShouldIncludeSectionWithExpandedPaths(t *testing.T) {
+func TestMatchHeadingWithExpandedPaths(t *testing.T) {
 	// Create a temporary directory structure for testing using the helper
 	originalTempDir, tempDir, err := mkdirTempResolved("expanded_paths_test")
 	if err != nil {
@@ -345,15 +330,15 @@ func TestShouldIncludeSectionWithExpandedPaths(t *testing.T) {
 				tt.setup()
 			}
 
-			result, _, _, _ := shouldIncludeSection(tt.heading, tt.cwd, true)
+			result := MatchHeading(tt.heading, tt.cwd, &Config{Agent: AgentCursor}).Include
 			if result != tt.expected {
-				t.Errorf("shouldIncludeSection(%q, %q) = %v, expected %v", tt.heading, tt.cwd, result, tt.expected)
+				t.Errorf("MatchHeading(%q, %q) = %v, expected %v", tt.heading, tt.cwd, result, tt.expected)
 			}
 		})
 	}
 }
 
-func TestFilterContentByProjectWithExpandedPaths(t *testing.T) {
+func TestFilterWithExpandedPaths(t *testing.T) {
 	// Create a temporary directory structure for testing using the helper
 	originalTempDir, tempDir, err := mkdirTempResolved("expanded_filter_test")
 	if err != nil {
@@ -367,18 +352,6 @@ func TestFilterContentByProjectWithExpandedPaths(t *testing.T) {
 		t.Fatalf("Failed to create project dir: %v", err)
 	}
 
-	// Save original working directory
-	originalWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get working directory: %v", err)
-	}
-
-	// Change to project directory for testing
-	if err := os.Chdir(projectDir); err != nil {
-		t.Fatalf("Failed to change to project dir: %v", err)
-	}
-	defer os.Chdir(originalWd)
-
 	// Get home directory for tilde expansion
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -400,10 +373,7 @@ Should be shown only if running from GOROOT/src.
 # Other Section(project: /some/other/path)
 Should NOT be shown.`
 
-	result, err := filterContentByProject(content)
-	if err != nil {
-		t.Fatalf("filterContentByProject failed: %v", err)
-	}
+	result := Filter(content, projectDir, &Config{Agent: AgentCursor})
 
 	// Check that general section is always included
 	if !strings.Contains(result, "# General Section") {
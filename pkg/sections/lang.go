@@ -0,0 +1,196 @@
+package sections
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLangScanFiles limits the number of files inspected when
+// building the extension histogram of a directory
+const maxLangScanFiles = 2000
+
+// langMarkerFiles maps well-known project files to their language
+var langMarkerFiles = []struct {
+	file string
+	lang string
+}{
+	{"go.mod", "go"},
+	{"Cargo.toml", "rust"},
+	{"tsconfig.json", "typescript"},
+	{"package.json", "javascript"},
+	{"pyproject.toml", "python"},
+	{"requirements.txt", "python"},
+	{"setup.py", "python"},
+	{"pom.xml", "java"},
+	{"build.gradle", "java"},
+	{"build.gradle.kts", "kotlin"},
+	{"Gemfile", "ruby"},
+	{"Package.swift", "swift"},
+	{"composer.json", "php"},
+	{"mix.exs", "elixir"},
+}
+
+// langExtensions maps file extensions to their language
+var langExtensions = map[string]string{
+	".go":    "go",
+	".rs":    "rust",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".mjs":   "javascript",
+	".py":    "python",
+	".java":  "java",
+	".kt":    "kotlin",
+	".rb":    "ruby",
+	".swift": "swift",
+	".php":   "php",
+	".ex":    "elixir",
+	".exs":   "elixir",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".m":     "objective-c",
+	".dart":  "dart",
+	".lua":   "lua",
+	".sh":    "shell",
+}
+
+// langSkipDirs are not scanned for the extension histogram
+var langSkipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	".venv":        true,
+}
+
+// langAliases maps alternative names used in (lang: ...) to detected names
+var langAliases = map[string]string{
+	"golang": "go",
+	"ts":     "typescript",
+	"js":     "javascript",
+	"node":   "javascript",
+	"py":     "python",
+	"rs":     "rust",
+	"c++":    "cpp",
+	"c#":     "csharp",
+	"objc":   "objective-c",
+	"bash":   "shell",
+	"sh":     "shell",
+}
+
+// langCacheTTL is how long a detected language is reused, so that a long
+// running process sees a dir whose files change without walking it every time
+var langCacheTTL = 30 * time.Second
+
+// detectedLang is a language detected at a time
+type detectedLang struct {
+	lang string
+	at   time.Time
+}
+
+var detectedLangs sync.Map // dir -> detectedLang
+
+// detectLanguage returns the dominant language of dir, or "" if unknown.
+// A single marker file (go.mod, package.json...) decides directly,
+// otherwise the most frequent source file extension wins.
+// Results are cached for langCacheTTL.
+func detectLanguage(dir string) string {
+	if cached, ok := detectedLangs.Load(dir); ok {
+		detected := cached.(detectedLang)
+		if time.Since(detected.at) < langCacheTTL {
+			return detected.lang
+		}
+	}
+	lang := detectLanguageUncached(dir)
+	detectedLangs.Store(dir, detectedLang{lang: lang, at: time.Now()})
+	return lang
+}
+
+func detectLanguageUncached(dir string) string {
+	markerLangs := make(map[string]bool)
+	var markerLang string
+	for _, marker := range langMarkerFiles {
+		if _, err := os.Stat(filepath.Join(dir, marker.file)); err == nil {
+			if marker.lang == "javascript" && markerLangs["typescript"] {
+				// package.json next to tsconfig.json
+				continue
+			}
+			markerLangs[marker.lang] = true
+			markerLang = marker.lang
+		}
+	}
+	if len(markerLangs) == 1 {
+		return markerLang
+	}
+
+	histogram := make(map[string]int)
+	var scanned int
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && (langSkipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		scanned++
+		if scanned > maxLangScanFiles {
+			return filepath.SkipAll
+		}
+		if lang, ok := langExtensions[strings.ToLower(filepath.Ext(d.Name()))]; ok {
+			if len(markerLangs) == 0 || markerLangs[lang] {
+				histogram[lang]++
+			}
+		}
+		return nil
+	})
+
+	var dominant string
+	var maxCount int
+	for lang, count := range histogram {
+		if count > maxCount || (count == maxCount && lang < dominant) {
+			dominant = lang
+			maxCount = count
+		}
+	}
+	if dominant == "" && len(markerLangs) > 0 {
+		// no source files, pick the first marker found
+		for _, marker := range langMarkerFiles {
+			if markerLangs[marker.lang] {
+				return marker.lang
+			}
+		}
+	}
+	return dominant
+}
+
+// matchesLanguage checks if the dominant language of dir is one of langs
+func matchesLanguage(dir string, langs []string) bool {
+	detected := detectLanguage(dir)
+	if detected == "" {
+		return false
+	}
+	for _, lang := range langs {
+		lang = strings.ToLower(lang)
+		if alias, ok := langAliases[lang]; ok {
+			lang = alias
+		}
+		if lang == detected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package sections
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	writeFiles := func(t *testing.T, dir string, files ...string) {
+		for _, file := range files {
+			path := filepath.Join(dir, file)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("Failed to create dir: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(""), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", file, err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected string
+	}{
+		{"go marker", []string{"go.mod"}, "go"},
+		{"typescript marker wins over package.json", []string{"package.json", "tsconfig.json"}, "typescript"},
+		{"histogram", []string{"a.py", "b.py", "c.sh"}, "python"},
+		{"histogram among markers", []string{"go.mod", "package.json", "main.go", "web/a.js", "web/b.js"}, "javascript"},
+		{"skip node_modules", []string{"main.go", "node_modules/x/a.js", "node_modules/x/b.js"}, "go"},
+		{"unknown", []string{"README.md"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files...)
+			if got := detectLanguageUncached(dir); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatchHeadingWithLangDirective(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module test"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	tests := []struct {
+		heading  string
+		expected bool
+	}{
+		{"# Go (lang: go)", true},
+		{"# Golang (lang: golang)", true},
+		{"# Multi (lang: rust, go)", true},
+		{"# Python (lang: python)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.heading, func(t *testing.T) {
+			result := MatchHeading(tt.heading, dir, &Config{Agent: AgentCursor}).Include
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v for heading %q", tt.expected, result, tt.heading)
+			}
+		})
+	}
+}
+
+func TestDetectLanguageExpires(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := detectLanguage(dir); got != "go" {
+		t.Fatalf("Expected go, got %q", got)
+	}
+	if err := os.Rename(filepath.Join(dir, "go.mod"), filepath.Join(dir, "Cargo.toml")); err != nil {
+		t.Fatal(err)
+	}
+	if got := detectLanguage(dir); got != "go" {
+		t.Errorf("Expected the cached go within langCacheTTL, got %q", got)
+	}
+
+	saved := langCacheTTL
+	langCacheTTL = 0
+	defer func() { langCacheTTL = saved }()
+	if got := detectLanguage(dir); got != "rust" {
+		t.Errorf("Expected rust detected again once expired, got %q", got)
+	}
+}
@@ -0,0 +1,567 @@
+package sections
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gobwas/glob"
+)
+
+// AgentCursor is the agent matched by (cursor-only) sections
+const AgentCursor = "cursor"
+
+// Git answers the git questions of project matching
+type Git interface {
+	// TopLevel returns the root of the checkout containing dir
+	TopLevel(dir string) (string, error)
+	// Worktrees returns the worktrees of the repository containing dir,
+	// the main worktree comes first, like `git worktree list`
+	Worktrees(dir string) ([]string, error)
+	// RemoteURLs returns the URLs of the remote name of the repository
+	// containing dir, or of all its remotes if name is empty
+	RemoteURLs(dir string, name string) ([]string, error)
+}
+
+// Config configures the matching of sections against a directory
+type Config struct {
+	// Agent is the agent matched by (agents: ...), e.g. "cursor" or "claude"
+	Agent string
+	// Aliases maps the "@name" of (project: @name) to a path
+	Aliases map[string]string
+	// GitRemote is the remote compared to match the clones of a project,
+	// all remotes are compared if empty
+	GitRemote string
+	// DisableSymlinkResolution compares project paths without resolving symlinks
+	DisableSymlinkResolution bool
+	// SplitLevel is the deepest heading level that starts a new section
+	// of a profile without a sectionLevel front-matter, 0 means every heading
+	SplitLevel int
+	// Git matches the worktrees and clones of a project, they do not match if nil
+	Git Git
+	// AllowShow tells whether a matched section with a (show: ...)
+	// directive is emitted, (show: ...) directives are ignored if nil
+	AllowShow func(heading string, show string) bool
+	// Cache memoizes the matching across calls if not nil
+	Cache *Cache
+}
+
+// MatchReason represents why a section was included
+type MatchReason int
+
+const (
+	MatchReasonNone MatchReason = iota
+	MatchReasonNoProject
+	MatchReasonPathMatch
+	MatchReasonGlobMatch
+	MatchReasonGitWorktree
+)
+
+// String returns the name of the reason shown by show --explain
+func (r MatchReason) String() string {
+	switch r {
+	case MatchReasonNoProject:
+		return "no-project"
+	case MatchReasonPathMatch:
+		return "path"
+	case MatchReasonGlobMatch:
+		return "glob"
+	case MatchReasonGitWorktree:
+		return "worktree"
+	}
+	return "excluded"
+}
+
+// Result is the match of a heading against a directory
+type Result struct {
+	Include     bool
+	MatchReason MatchReason
+	ProjectPath string // The resolved absolute project path
+	Specificity int    // Higher number means more specific (deeper path)
+}
+
+// SectionMatch represents a section that matches with its specificity information
+type SectionMatch struct {
+	Section     Section
+	MatchReason MatchReason
+	ProjectPath string // The resolved absolute project path
+	Specificity int    // Higher number means more specific (deeper path)
+	Priority    int    // Higher priority sections are emitted first
+	Heading     string // The heading used for matching, including inherited directives
+	Index       int    // The index of the section in the parsed content
+}
+
+// ParseProfile parses a whole profile: the content is normalized, and the
+// split level is read from a front-matter block before the first heading:
+//
+//	---
+//	sectionLevel: 1
+//	---
+//	# First section
+//
+// falling back to defaultSplitLevel.
+func ParseProfile(content string, defaultSplitLevel int) []Section {
+	content = Normalize(content)
+	splitLevel, ok := ProfileSplitLevel(content)
+	if !ok {
+		splitLevel = defaultSplitLevel
+	}
+	return Parse(content, splitLevel)
+}
+
+// Filter returns the sections of the profile content that match dir,
+// most specific projects first within the same priority.
+// Sections matched via a git worktree show dir as their project.
+func Filter(content string, dir string, cfg *Config) string {
+	// Subsections are matched against headings carrying their parent's directives
+	sections, matchHeadings, results := cfg.match(content, dir)
+	var matches []SectionMatch
+
+	// Collect all matching sections with their specificity information
+	for i, result := range results {
+		if result.Include {
+			matches = append(matches, SectionMatch{
+				Section:     sections[i],
+				MatchReason: result.MatchReason,
+				ProjectPath: result.ProjectPath,
+				Specificity: result.Specificity,
+				Priority:    Priority(matchHeadings[i]),
+				Heading:     matchHeadings[i],
+				Index:       i,
+			})
+		}
+	}
+
+	// Group matches by project path and find the most specific ones
+	filteredMatches := SelectMostSpecific(matches)
+
+	// Apply (show: first N) and (show: daily) directives,
+	// a hidden section hides its subsections with it
+	if cfg.AllowShow != nil {
+		var shownMatches []SectionMatch
+		hiddenUntil := -1
+		for _, match := range filteredMatches {
+			if match.Index < hiddenUntil {
+				continue
+			}
+			if show, ok := DirectiveValue(match.Heading, "show"); ok && !cfg.AllowShow(match.Heading, show) {
+				hiddenUntil = subsectionsEnd(sections, match.Index)
+				continue
+			}
+			shownMatches = append(shownMatches, match)
+		}
+		filteredMatches = shownMatches
+	}
+
+	// Order by priority, sections with the same priority keep file order
+	sort.SliceStable(filteredMatches, func(i, j int) bool {
+		return filteredMatches[i].Priority > filteredMatches[j].Priority
+	})
+
+	// Convert back to sections and apply project path replacement
+	var filteredSections []Section
+	for _, match := range filteredMatches {
+		section := match.Section
+		// Replace project path if matched via git worktree
+		if match.MatchReason == MatchReasonGitWorktree {
+			section.Title = replaceProjectPath(section.Title, dir)
+		}
+		filteredSections = append(filteredSections, section)
+	}
+
+	// Reconstruct the content from filtered sections, keeping a blank line
+	// between them: the last section of the profile may not end with one,
+	// and is no longer last once reordered by priority
+	var result []string
+	for _, section := range filteredSections {
+		if n := len(result); n > 0 && !strings.HasSuffix(result[n-1], "\n") {
+			result = append(result, "")
+		}
+		result = append(result, section.Title)
+		if section.Content != "" {
+			result = append(result, section.Content)
+		}
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// match parses content and matches each section against dir.
+// It returns the sections, the headings they are matched by, and the results.
+// The returned slices may be shared with the cache and must not be modified.
+func (cfg *Config) match(content string, dir string) ([]Section, []string, []Result) {
+	if cfg.Cache != nil {
+		return cfg.Cache.match(content, dir, cfg)
+	}
+	sections := ParseProfile(content, cfg.SplitLevel)
+	headings := InheritDirectives(sections)
+	return sections, headings, MatchHeadings(headings, dir, cfg)
+}
+
+// maxConcurrentMatches limits the number of sections matched at the same time,
+// each match may spawn several git processes
+const maxConcurrentMatches = 8
+
+// MatchHeadings evaluates MatchHeading for each heading in parallel,
+// since git calls dominate the latency of project-scoped sections.
+// Results are returned in the order of headings.
+func MatchHeadings(headings []string, dir string, cfg *Config) []Result {
+	results := make([]Result, len(headings))
+	sem := make(chan struct{}, maxConcurrentMatches)
+	var wg sync.WaitGroup
+	for i, heading := range headings {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, heading string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = MatchHeading(heading, dir, cfg)
+		}(i, heading)
+	}
+	wg.Wait()
+	return results
+}
+
+// inheritedValueDirectives are the "(name: value)" directives a subsection inherits:
+// its scope, not its ordering or visibility
+var inheritedValueDirectives = []string{"project", "agents"}
+
+// InheritDirectives returns, for each section, the heading used for matching.
+// HTML-comment directives and front-matter metadata are applied as heading directives first.
+// A subsection (e.g. "##") without its own (project: ...), (agents: ...) or (cursor-only)
+// directive inherits the one from its closest parent section, so a project section
+// can be organized into subsections without repeating the directives on every heading.
+func InheritDirectives(sections []Section) []string {
+	type parentHeading struct {
+		level   int
+		heading string
+	}
+	var parents []parentHeading
+	headings := make([]string, 0, len(sections))
+	for _, section := range sections {
+		level := HeadingLevel(section.Title)
+		for len(parents) > 0 && parents[len(parents)-1].level >= level {
+			parents = parents[:len(parents)-1]
+		}
+
+		heading := ApplyFrontMatter(ApplyCommentDirectives(section.Title), section.Meta)
+		if len(parents) > 0 {
+			parent := parents[len(parents)-1].heading
+			for _, name := range inheritedValueDirectives {
+				if _, ok := DirectiveValue(heading, name); ok {
+					continue
+				}
+				if value, ok := DirectiveValue(parent, name); ok {
+					heading += " (" + name + ": " + value + ")"
+				}
+			}
+			if !hasCursorOnlyDirective(heading) && hasCursorOnlyDirective(parent) {
+				heading += " (cursor-only)"
+			}
+		}
+		parents = append(parents, parentHeading{level: level, heading: heading})
+		headings = append(headings, heading)
+	}
+	return headings
+}
+
+// subsectionsEnd returns the index following the last subsection of sections[i]
+func subsectionsEnd(sections []Section, i int) int {
+	level := HeadingLevel(sections[i].Title)
+	end := i + 1
+	for end < len(sections) && HeadingLevel(sections[end].Title) > level {
+		end++
+	}
+	return end
+}
+
+// SelectMostSpecific filters matches to only include those from the most specific project paths
+// while preserving the original order of sections
+func SelectMostSpecific(matches []SectionMatch) []SectionMatch {
+	if len(matches) == 0 {
+		return matches
+	}
+
+	// Separate exact path matches from glob matches
+	var exactMatches []SectionMatch
+	var globMatches []SectionMatch
+	var noProjectMatches []SectionMatch
+
+	for _, match := range matches {
+		if match.MatchReason == MatchReasonNoProject {
+			noProjectMatches = append(noProjectMatches, match)
+		} else if match.MatchReason == MatchReasonGlobMatch {
+			globMatches = append(globMatches, match)
+		} else {
+			exactMatches = append(exactMatches, match)
+		}
+	}
+
+	var result []SectionMatch
+
+	// Always include sections without project specifications
+	result = append(result, noProjectMatches...)
+
+	// For exact path matches, find the most specific ones
+	if len(exactMatches) > 0 {
+		maxExactSpecificity := 0
+		for _, match := range exactMatches {
+			if match.Specificity > maxExactSpecificity {
+				maxExactSpecificity = match.Specificity
+			}
+		}
+
+		// Include only exact matches with maximum specificity
+		for _, match := range exactMatches {
+			if match.Specificity == maxExactSpecificity {
+				result = append(result, match)
+			}
+		}
+	}
+
+	// Always include all glob matches (they serve different purposes)
+	result = append(result, globMatches...)
+
+	// Sort result to preserve original order by iterating through original matches
+	var orderedResult []SectionMatch
+	for _, originalMatch := range matches {
+		for _, resultMatch := range result {
+			// Compare by title and content since these are the unique identifiers
+			if originalMatch.Section.Title == resultMatch.Section.Title &&
+				originalMatch.Section.Content == resultMatch.Section.Content {
+				orderedResult = append(orderedResult, resultMatch)
+				break
+			}
+		}
+	}
+
+	return orderedResult
+}
+
+// replaceProjectPath replaces the project path specification in a heading with the actual current directory
+func replaceProjectPath(heading, actualDir string) string {
+	// Look for pattern like "(project: /path/to/project)"
+	projectStart := strings.Index(heading, "(project:")
+	if projectStart == -1 {
+		return heading
+	}
+
+	projectEnd := strings.Index(heading[projectStart:], ")")
+	if projectEnd == -1 {
+		return heading
+	}
+	projectEnd += projectStart
+
+	// Replace the project specification with the actual directory
+	before := heading[:projectStart]
+	after := heading[projectEnd+1:]
+	newProjectSpec := "(project: " + actualDir + ")"
+
+	return before + newProjectSpec + after
+}
+
+// MatchHeading checks if a section heading should be included
+// based on project path matching and cursor-only directive
+func MatchHeading(heading, cwd string, cfg *Config) Result {
+	// Directives may also be written as HTML comments
+	heading = ApplyCommentDirectives(heading)
+
+	// Check for (cursor-only) directive
+	if hasCursorOnlyDirective(heading) && !strings.EqualFold(cfg.Agent, AgentCursor) {
+		return Result{}
+	}
+	// Check for (disabled) directive
+	if HasDirective(heading, "disabled") {
+		return Result{}
+	}
+	// Check for (agents: cursor, claude) directive
+	if agents, ok := DirectiveValue(heading, "agents"); ok && !containsFold(SplitList(agents), cfg.Agent) {
+		return Result{}
+	}
+	// Check for (os: darwin, linux) directive
+	if oses, ok := DirectiveValue(heading, "os"); ok && !containsFold(SplitList(oses), runtime.GOOS) {
+		return Result{}
+	}
+	// Check for (has: go.mod, package.json) directive
+	if files, ok := DirectiveValue(heading, "has"); ok && !hasAnyFile(cwd, SplitList(files)) {
+		return Result{}
+	}
+	// Check for (lang: go) directive
+	if langs, ok := DirectiveValue(heading, "lang"); ok && !matchesLanguage(cwd, SplitList(langs)) {
+		return Result{}
+	}
+	// Look for pattern like "(project: /path/to/project)"
+	projectStart := strings.Index(heading, "(project:")
+	if projectStart == -1 {
+		// No project specification, include the section
+		return Result{Include: true, MatchReason: MatchReasonNoProject}
+	}
+
+	projectEnd := strings.Index(heading[projectStart:], ")")
+	if projectEnd == -1 {
+		// Malformed project specification, include the section
+		return Result{Include: true, MatchReason: MatchReasonNoProject}
+	}
+
+	// Extract the project path
+	projectSpec := heading[projectStart+len("(project:") : projectStart+projectEnd]
+	projectPath := strings.TrimSpace(projectSpec)
+
+	projectPath, subPath, ok := ExpandProjectPath(projectPath, cfg.Aliases)
+	if !ok {
+		// Unknown alias, cannot match any directory
+		return Result{}
+	}
+
+	// Convert to absolute path - handle relative paths relative to cwd
+	var absProjectPath string
+	if filepath.IsAbs(projectPath) {
+		absProjectPath = projectPath
+	} else {
+		absProjectPath = filepath.Join(cwd, projectPath)
+	}
+	absProjectPath = filepath.Clean(absProjectPath)
+
+	// For monorepo subpaths, the cwd must be under the subdirectory of the repo
+	repoPath := absProjectPath
+	if subPath != "" {
+		absProjectPath = filepath.Join(repoPath, subPath)
+	}
+
+	absCwd, err := filepath.Abs(cwd)
+	if err != nil {
+		// If we can't resolve cwd, include the section
+		return Result{Include: true, MatchReason: MatchReasonNoProject}
+	}
+
+	// Calculate specificity based on path depth (more path segments = more specific)
+	// For glob patterns, use a different calculation to avoid conflicts
+	var specificity int
+	if containsGlobPattern(projectPath) {
+		// For glob patterns, count non-glob segments to determine specificity
+		// This allows different glob patterns to coexist
+		segments := strings.Split(strings.Trim(absProjectPath, string(filepath.Separator)), string(filepath.Separator))
+		nonGlobSegments := 0
+		for _, segment := range segments {
+			if !containsGlobPattern(segment) {
+				nonGlobSegments++
+			}
+		}
+		// Use a base specificity for globs plus non-glob segments
+		// This ensures glob patterns don't compete with exact path matches
+		specificity = 1000 + nonGlobSegments
+	} else {
+		// For exact paths, use path depth
+		specificity = len(strings.Split(strings.Trim(absProjectPath, string(filepath.Separator)), string(filepath.Separator)))
+	}
+
+	resolveSymlinks := !cfg.DisableSymlinkResolution
+
+	// Check if project path contains glob patterns
+	if containsGlobPattern(projectPath) {
+		// Use the gobwas/glob library for pattern matching
+		g, err := glob.Compile(foldPathCase(absProjectPath), filepath.Separator)
+		if err != nil {
+			// If pattern compilation fails, include the section
+			return Result{Include: true, MatchReason: MatchReasonNoProject}
+		}
+		if g.Match(normalizeMatchPath(absCwd, resolveSymlinks)) || g.Match(foldPathCase(absCwd)) {
+			return Result{Include: true, MatchReason: MatchReasonGlobMatch, ProjectPath: absProjectPath, Specificity: specificity}
+		}
+		return Result{}
+	}
+
+	// Check if current working directory is the project directory or a subdirectory
+	if isSubpath(absCwd, absProjectPath, resolveSymlinks) {
+		return Result{Include: true, MatchReason: MatchReasonPathMatch, ProjectPath: absProjectPath, Specificity: specificity}
+	}
+
+	// Check if current directory is a git worktree of the specified project
+	if subPath == "" {
+		if IsGitWorktree(absCwd, absProjectPath, cfg) {
+			return Result{Include: true, MatchReason: MatchReasonGitWorktree, ProjectPath: absProjectPath, Specificity: specificity}
+		}
+		return Result{}
+	}
+
+	// For monorepo subpaths, check the subdirectory within the checkout containing cwd
+	topLevel := cfg.gitTopLevel(absCwd)
+	if topLevel == "" {
+		return Result{}
+	}
+	checkoutSubPath := filepath.Join(topLevel, subPath)
+	if !isSubpath(absCwd, checkoutSubPath, resolveSymlinks) {
+		return Result{}
+	}
+	if IsGitWorktree(topLevel, repoPath, cfg) {
+		return Result{Include: true, MatchReason: MatchReasonGitWorktree, ProjectPath: absProjectPath, Specificity: specificity}
+	}
+
+	return Result{}
+}
+
+// ExpandProjectPath resolves aliases, "~/" and environment variables of a
+// (project: ...) path, and splits its monorepo subpath.
+// It reports false for unknown aliases.
+func ExpandProjectPath(projectPath string, aliases map[string]string) (string, string, bool) {
+	// Replace alias like "@api" with the path configured in aliases
+	projectPath, ok := ResolveAlias(projectPath, aliases)
+	if !ok {
+		return "", "", false
+	}
+
+	// Split monorepo subpath like "~/work/mono#services/payments"
+	projectPath, subPath := splitProjectSubpath(projectPath)
+
+	// Expand tilde to home directory
+	if strings.HasPrefix(projectPath, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err == nil {
+			projectPath = filepath.Join(homeDir, projectPath[2:])
+		}
+	}
+
+	// Expand environment variables in the project path
+	return os.ExpandEnv(projectPath), subPath, true
+}
+
+// splitProjectSubpath splits a project spec like "~/work/mono#services/payments"
+// into the repository path and the subpath within the repository
+func splitProjectSubpath(projectPath string) (string, string) {
+	idx := strings.LastIndex(projectPath, "#")
+	if idx <= 0 {
+		return projectPath, ""
+	}
+	subPath := strings.Trim(projectPath[idx+1:], `/\`)
+	if subPath == "" {
+		return projectPath[:idx], ""
+	}
+	return projectPath[:idx], filepath.FromSlash(subPath)
+}
+
+// containsGlobPattern checks if a path contains glob pattern characters
+func containsGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[]{}")
+}
+
+// hasAnyFile checks if any of the files (or glob patterns) exists under dir
+func hasAnyFile(dir string, files []string) bool {
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if containsGlobPattern(file) {
+			if found, err := filepath.Glob(path); err == nil && len(found) > 0 {
+				return true
+			}
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,491 @@
+package sections
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// agentConfig returns the config matching for cursor, or for claude
+func agentConfig(isCursor bool) *Config {
+	if isCursor {
+		return &Config{Agent: AgentCursor}
+	}
+	return &Config{Agent: "claude"}
+}
+
+func TestMatchHeading(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "whats_next_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create subdirectory
+	subDir := filepath.Join(tempDir, "subdir")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create subdir: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		heading  string
+		cwd      string
+		expected bool
+	}{
+		{
+			name:     "no project specification",
+			heading:  "# General Section",
+			cwd:      tempDir,
+			expected: true,
+		},
+		{
+			name:     "malformed project specification - no closing paren",
+			heading:  "# Section(project: /some/path",
+			cwd:      tempDir,
+			expected: true,
+		},
+		{
+			name:     "exact path match",
+			heading:  "# Section(project: " + tempDir + ")",
+			cwd:      tempDir,
+			expected: true,
+		},
+		{
+			name:     "subdirectory match",
+			heading:  "# Section(project: " + tempDir + ")",
+			cwd:      subDir,
+			expected: true,
+		},
+		{
+			name:     "no match - different path",
+			heading:  "# Section(project: /completely/different/path)",
+			cwd:      tempDir,
+			expected: false,
+		},
+		{
+			name:     "relative path match",
+			heading:  "# Section(project: .)",
+			cwd:      tempDir,
+			expected: true,
+		},
+		{
+			name:     "whitespace in project path",
+			heading:  "# Section(project:   " + tempDir + "   )",
+			cwd:      tempDir,
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := MatchHeading(tt.heading, tt.cwd, &Config{Agent: AgentCursor}).Include
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v for heading %q with cwd %q", tt.expected, result, tt.heading, tt.cwd)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	// Create a temporary directory for testing using the helper
+	originalTempDir, tempDir, err := mkdirTempResolved("whats_next_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(originalTempDir) // Use original path for cleanup
+
+	content := `# General Section
+This should always be shown.
+
+# Current Project Section(project: ` + tempDir + `)
+This should be shown when in the project directory.
+
+# Other Project Section(project: /some/other/path)
+This should NOT be shown.
+
+# Another General Section
+This should also be shown.`
+
+	expected := `# General Section
+This should always be shown.
+
+# Current Project Section(project: ` + tempDir + `)
+This should be shown when in the project directory.
+
+# Another General Section
+This should also be shown.`
+
+	result := Filter(content, tempDir, &Config{Agent: AgentCursor})
+
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFilterWithCodeBlocks(t *testing.T) {
+	// Create a temporary directory for testing using the helper
+	originalTempDir, tempDir, err := mkdirTempResolved("whats_next_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(originalTempDir) // Use original path for cleanup
+
+	content := `# General Section
+Code example:
+` + "```bash" + `
+# This should not be treated as heading
+echo "hello"
+` + "```" + `
+
+# Project Section(project: ` + tempDir + `)
+This should be shown.
+` + "```go" + `
+// # This comment should not be a heading
+func main() {}
+` + "```" + `
+
+# Other Project Section(project: /other/path)
+This should be filtered out.`
+
+	expected := `# General Section
+Code example:
+` + "```bash" + `
+# This should not be treated as heading
+echo "hello"
+` + "```" + `
+
+# Project Section(project: ` + tempDir + `)
+This should be shown.
+` + "```go" + `
+// # This comment should not be a heading
+func main() {}
+` + "```" + `
+`
+
+	result := Filter(content, tempDir, &Config{Agent: AgentCursor})
+
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFilterWithPriority(t *testing.T) {
+	content := `# Reference(priority: -1)
+Verbose reference.
+
+# General
+General content.
+
+# Critical(priority: 10)
+Critical content.
+
+# Important(priority: 5)
+Important content.`
+
+	expected := `# Critical(priority: 10)
+Critical content.
+
+# Important(priority: 5)
+Important content.
+
+# General
+General content.
+
+# Reference(priority: -1)
+Verbose reference.
+`
+
+	result := Filter(content, t.TempDir(), &Config{Agent: AgentCursor})
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFilterWithSubsectionInheritance(t *testing.T) {
+	tempDir := t.TempDir()
+
+	content := `# General
+General content.
+
+# Project(project: ` + tempDir + `)
+Project content.
+
+## Project Build
+Build content.
+
+## Project Test
+Test content.
+
+# Other(project: /some/other/path)
+Other content.
+
+## Other Build
+Other build content.
+
+# Cursor Rules(cursor-only)
+Cursor content.
+
+## Cursor Details
+Cursor details.`
+
+	expected := `# General
+General content.
+
+# Project(project: ` + tempDir + `)
+Project content.
+
+## Project Build
+Build content.
+
+## Project Test
+Test content.
+`
+
+	result := Filter(content, tempDir, &Config{Agent: "claude"})
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestInheritDirectives(t *testing.T) {
+	sections := []Section{
+		{Title: "# Parent(project: /a)(cursor-only)"},
+		{Title: "## Child"},
+		{Title: "### Grandchild(project: /b)"},
+		{Title: "## Sibling(priority: 3)"},
+		{Title: "# Top"},
+		{Title: "## Top Child"},
+		{Title: "# Ranked(agents: claude)(priority: 5)(show: daily)"},
+		{Title: "## Ranked Child"},
+	}
+	expected := []string{
+		"# Parent(project: /a)(cursor-only)",
+		"## Child (project: /a) (cursor-only)",
+		"### Grandchild(project: /b) (cursor-only)",
+		"## Sibling(priority: 3) (project: /a) (cursor-only)",
+		"# Top",
+		"## Top Child",
+		"# Ranked(agents: claude)(priority: 5)(show: daily)",
+		"## Ranked Child (agents: claude)",
+	}
+	result := InheritDirectives(sections)
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d headings, got %d", len(expected), len(result))
+	}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Errorf("Heading %d: expected %q, got %q", i, expected[i], result[i])
+		}
+	}
+}
+
+func TestFilterWithFrontMatter(t *testing.T) {
+	tempDir := t.TempDir()
+
+	content := `# Project
+---
+project: ` + tempDir + `
+---
+Project content.
+
+# Other
+---
+project: /some/other/path
+---
+Other content.
+
+# Claude Only
+---
+agents: claude
+---
+Claude content.
+
+# Disabled
+---
+disabled: true
+---
+Disabled content.
+
+# Current OS
+---
+os: ` + runtime.GOOS + `
+priority: 1
+---
+OS content.`
+
+	expected := `# Current OS
+OS content.
+
+# Project
+Project content.
+`
+
+	result := Filter(content, tempDir, &Config{Agent: AgentCursor})
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestMatchHeadingWithConditionDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		heading  string
+		isCursor bool
+		expected bool
+	}{
+		{"disabled", "# Section (disabled)", true, false},
+		{"agents match cursor", "# Section (agents: cursor, claude)", true, true},
+		{"agents match claude", "# Section (agents: claude)", false, true},
+		{"agents mismatch", "# Section (agents: claude)", true, false},
+		{"os match", "# Section (os: " + runtime.GOOS + ")", true, true},
+		{"os mismatch", "# Section (os: plan9-not-real)", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := MatchHeading(tt.heading, t.TempDir(), agentConfig(tt.isCursor)).Include
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v for heading %q", tt.expected, result, tt.heading)
+			}
+		})
+	}
+}
+
+func TestMatchHeadingWithHasDirective(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "go.mod"), []byte("module test"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "cmd"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		heading  string
+		expected bool
+	}{
+		{"file exists", "# Go (has: go.mod)", true},
+		{"file missing", "# Node (has: package.json)", false},
+		{"any of files", "# Any (has: package.json, go.mod)", true},
+		{"dir exists", "# Cmd (has: cmd)", true},
+		{"glob pattern", "# Glob (has: *.mod)", true},
+		{"glob no match", "# Glob (has: *.csproj)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := MatchHeading(tt.heading, tempDir, &Config{Agent: AgentCursor}).Include
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v for heading %q", tt.expected, result, tt.heading)
+			}
+		})
+	}
+}
+
+func TestMatchHeadingsPreservesOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	var headings []string
+	for i := 0; i < 50; i++ {
+		if i%2 == 0 {
+			headings = append(headings, "# Section "+strconv.Itoa(i)+"(project: "+tempDir+")")
+		} else {
+			headings = append(headings, "# Section "+strconv.Itoa(i)+"(project: /some/other/path)")
+		}
+	}
+	results := MatchHeadings(headings, tempDir, &Config{Agent: AgentCursor})
+	if len(results) != len(headings) {
+		t.Fatalf("Expected %d results, got %d", len(headings), len(results))
+	}
+	for i, result := range results {
+		if result.Include != (i%2 == 0) {
+			t.Errorf("Section %d: expected include=%v, got %v", i, i%2 == 0, result.Include)
+		}
+	}
+}
+
+func TestFilterWithCommentDirectives(t *testing.T) {
+	tempDir := t.TempDir()
+
+	content := `# Build rules <!-- project: ` + tempDir + ` -->
+Build content.
+
+## Details
+Details content.
+
+# Other rules <!-- project: /some/other/path -->
+Other content.
+
+# Important <!-- priority: 5; agents: cursor -->
+Important content.`
+
+	expected := `# Important <!-- priority: 5; agents: cursor -->
+Important content.
+
+# Build rules <!-- project: ` + tempDir + ` -->
+Build content.
+
+## Details
+Details content.
+`
+
+	result := Filter(content, tempDir, &Config{Agent: AgentCursor})
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFilterWithPriorityKeepsBlankLines(t *testing.T) {
+	content := `# First
+First content.
+
+# Second (priority: 1)
+Second content.
+
+# Third (priority: 2)
+Third content.`
+
+	expected := `# Third (priority: 2)
+Third content.
+
+# Second (priority: 1)
+Second content.
+
+# First
+First content.
+`
+
+	result := Filter(content, t.TempDir(), &Config{Agent: "claude"})
+	if result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
+
+func TestFilterWithShow(t *testing.T) {
+	content := `# Always
+Always content.
+
+# Reminder(show: first 1)
+Reminder content.
+
+## Reminder Details
+Details content.`
+
+	var asked []string
+	result := Filter(content, t.TempDir(), &Config{Agent: AgentCursor, AllowShow: func(heading string, show string) bool {
+		asked = append(asked, show)
+		return false
+	}})
+	if result != "# Always\nAlways content.\n" {
+		t.Errorf("Expected only the always section, got:\n%s", result)
+	}
+	if len(asked) != 1 {
+		t.Errorf("Expected only the section to be asked, its subsection goes with it, got %v", asked)
+	}
+
+	if result := Filter(content, t.TempDir(), &Config{Agent: AgentCursor}); result != content {
+		t.Errorf("Expected show directives to be ignored without a filter, got:\n%s", result)
+	}
+}
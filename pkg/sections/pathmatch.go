@@ -0,0 +1,89 @@
+package sections
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// isCaseInsensitiveFS reports whether paths on the current OS are
+// compared case-insensitively (default file systems on macOS and Windows)
+func isCaseInsensitiveFS() bool {
+	return runtime.GOOS == "darwin" || runtime.GOOS == "windows"
+}
+
+// normalizeMatchPath prepares a path for prefix comparison:
+// separators are cleaned, symlinks are resolved when the path exists
+// and resolveSymlinks is set, and case is folded on case-insensitive file systems
+func normalizeMatchPath(path string, resolveSymlinks bool) string {
+	path = filepath.Clean(filepath.FromSlash(path))
+	if resolveSymlinks {
+		path = evalSymlinksPartial(path)
+	}
+	return foldPathCase(path)
+}
+
+// isSubpath reports whether path is dir or below it, both normalized by
+// normalizeMatchPath. Unlike a prefix comparison, /src/api does not contain
+// /src/api-v2, and paths on another drive or UNC share never match.
+func isSubpath(path string, dir string, resolveSymlinks bool) bool {
+	return IsUnderDir(normalizeMatchPath(path, resolveSymlinks), normalizeMatchPath(dir, resolveSymlinks))
+}
+
+// IsUnderDir tells whether path is dir or below it
+func IsUnderDir(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// evalSymlinksPartial resolves symlinks of the longest existing prefix of path,
+// so a project path that does not exist yet still resolves its parents
+// (e.g. /tmp/new-project -> /private/tmp/new-project on macOS)
+func evalSymlinksPartial(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path
+	}
+	return filepath.Join(evalSymlinksPartial(parent), filepath.Base(path))
+}
+
+// foldPathCase lowercases a path on case-insensitive file systems
+func foldPathCase(path string) string {
+	if isCaseInsensitiveFS() {
+		return strings.ToLower(path)
+	}
+	return path
+}
+
+// ResolveAlias replaces a leading alias like "@api" (optionally
+// followed by a subpath, e.g. "@api/cmd") with its configured path
+func ResolveAlias(projectPath string, aliases map[string]string) (string, bool) {
+	if !strings.HasPrefix(projectPath, "@") {
+		return projectPath, true
+	}
+	name, rest := projectPath, ""
+	if idx := strings.IndexAny(projectPath, `/\`); idx != -1 {
+		name, rest = projectPath[:idx], projectPath[idx:]
+	}
+	target, ok := aliases[name]
+	if !ok {
+		target, ok = aliases[strings.TrimPrefix(name, "@")]
+	}
+	if !ok {
+		return "", false
+	}
+	return target + rest, true
+}
+
+// isSameDir compares two directories after resolving symlinks
+func isSameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return normalizeMatchPath(absA, true) == normalizeMatchPath(absB, true)
+}
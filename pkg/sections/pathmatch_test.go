@@ -0,0 +1,151 @@
+package sections
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeMatchPath(t *testing.T) {
+	_, tempDir, err := mkdirTempResolved("whats_next_path_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	realDir := filepath.Join(tempDir, "Real")
+	if err := os.MkdirAll(realDir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	linkDir := filepath.Join(tempDir, "link")
+	if err := os.Symlink(realDir, linkDir); err != nil {
+		t.Skipf("symlink not supported: %v", err)
+	}
+
+	if got, want := normalizeMatchPath(linkDir, true), normalizeMatchPath(realDir, true); got != want {
+		t.Errorf("Expected symlink to resolve to %q, got %q", want, got)
+	}
+	if got, want := normalizeMatchPath(linkDir, false), filepath.Clean(foldPathCase(linkDir)); got != want {
+		t.Errorf("Expected symlink kept when resolution is disabled, got %q", got)
+	}
+	if got, want := normalizeMatchPath(filepath.Join(linkDir, "missing"), true), filepath.Join(normalizeMatchPath(realDir, true), "missing"); got != want {
+		t.Errorf("Expected missing path to resolve its parent to %q, got %q", want, got)
+	}
+	if got, want := normalizeMatchPath(realDir+"/./sub/..", true), normalizeMatchPath(realDir, true); got != want {
+		t.Errorf("Expected cleaned path %q, got %q", want, got)
+	}
+
+	upper := normalizeMatchPath(strings.ToUpper(realDir), false)
+	lower := normalizeMatchPath(strings.ToLower(realDir), false)
+	if isCaseInsensitiveFS() && upper != lower {
+		t.Errorf("Expected case-insensitive match, got %q and %q", upper, lower)
+	}
+	if !isCaseInsensitiveFS() && upper == lower {
+		t.Errorf("Expected case-sensitive comparison, got %q and %q", upper, lower)
+	}
+}
+
+func TestMatchHeadingThroughSymlink(t *testing.T) {
+	_, tempDir, err := mkdirTempResolved("whats_next_symlink_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	realDir := filepath.Join(tempDir, "real")
+	if err := os.MkdirAll(filepath.Join(realDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	linkDir := filepath.Join(tempDir, "link")
+	if err := os.Symlink(realDir, linkDir); err != nil {
+		t.Skipf("symlink not supported: %v", err)
+	}
+
+	cfg := &Config{Agent: AgentCursor}
+	result := MatchHeading("# Section(project: "+realDir+")", filepath.Join(linkDir, "sub"), cfg)
+	if !result.Include || result.MatchReason != MatchReasonPathMatch {
+		t.Errorf("Expected path match through symlinked cwd, got include=%v reason=%v", result.Include, result.MatchReason)
+	}
+
+	result = MatchHeading("# Section(project: "+linkDir+")", realDir, cfg)
+	if !result.Include || result.MatchReason != MatchReasonPathMatch {
+		t.Errorf("Expected path match through symlinked project, got include=%v reason=%v", result.Include, result.MatchReason)
+	}
+}
+
+func TestResolveAlias(t *testing.T) {
+	aliases := map[string]string{
+		"@api": "~/work/company/api",
+		"web":  "/work/web",
+	}
+	tests := []struct {
+		projectPath string
+		expected    string
+		expectedOK  bool
+	}{
+		{"/plain/path", "/plain/path", true},
+		{"@api", "~/work/company/api", true},
+		{"@api/cmd/server", "~/work/company/api/cmd/server", true},
+		{"@web", "/work/web", true},
+		{"@unknown", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.projectPath, func(t *testing.T) {
+			result, ok := ResolveAlias(tt.projectPath, aliases)
+			if result != tt.expected || ok != tt.expectedOK {
+				t.Errorf("ResolveAlias(%q) = %q, %v, expected %q, %v", tt.projectPath, result, ok, tt.expected, tt.expectedOK)
+			}
+		})
+	}
+}
+
+func TestIsSubpath(t *testing.T) {
+	tests := []struct {
+		path string
+		dir  string
+		want bool
+	}{
+		{"/src/api", "/src/api", true},
+		{"/src/api/cmd", "/src/api", true},
+		{"/src/api/", "/src/api", true},
+		{"/src/api-v2", "/src/api", false},
+		{"/src", "/src/api", false},
+		{"/src/web", "/src/api", false},
+	}
+	if runtime.GOOS == "windows" {
+		tests = append(tests, []struct {
+			path string
+			dir  string
+			want bool
+		}{
+			{`C:\src\api\cmd`, `c:/src/API`, true},
+			{`C:\src\api-v2`, `C:\src\api`, false},
+			{`D:\src\api`, `C:\src\api`, false},
+			{`\\server\share\api\cmd`, `\\server\share\api`, true},
+			{`\\server\other\api`, `\\server\share\api`, false},
+		}...)
+	}
+	for _, tt := range tests {
+		if got := isSubpath(filepath.FromSlash(tt.path), filepath.FromSlash(tt.dir), false); got != tt.want {
+			t.Errorf("isSubpath(%q, %q) = %v, want %v", tt.path, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestMatchHeadingSiblingPrefix(t *testing.T) {
+	_, tempDir, err := mkdirTempResolved("whats_next_sibling_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	project := filepath.Join(tempDir, "api")
+	sibling := filepath.Join(tempDir, "api-v2")
+	if include := MatchHeading("# API (project: "+project+")", sibling, &Config{Agent: "claude"}).Include; include {
+		t.Errorf("Expected %s not to match the project %s", sibling, project)
+	}
+	if result := MatchHeading("# API (project: "+project+")", filepath.Join(project, "cmd"), &Config{Agent: "claude"}); !result.Include || result.MatchReason != MatchReasonPathMatch {
+		t.Errorf("Expected a subdirectory to match, got %v %v", result.Include, result.MatchReason)
+	}
+}
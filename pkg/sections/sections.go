@@ -0,0 +1,273 @@
+// Package sections parses whats_next profiles, markdown files whose
+// headings split the content into guideline sections, and filters
+// the sections whose directives match a directory.
+//
+// Reading the config and the git state is left to the caller,
+// see Config.
+package sections
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FrontMatterFence opens and closes a metadata block right under a heading:
+//
+//	# Build rules
+//	---
+//	project: ~/work/api
+//	agents: [cursor, claude]
+//	---
+const FrontMatterFence = "---"
+
+// Section represents a markdown section with a title and content
+type Section struct {
+	Title   string
+	Content string
+	// Line is the 1-based line number of the heading
+	Line int
+	// Meta holds the front-matter block right under the heading, if any
+	Meta map[string]string
+}
+
+// Parse parses markdown content into a list of sections.
+// Each section starts with a heading (line starting with #) and contains
+// all content until the next heading.
+// Headings deeper than splitLevel stay in the content of their parent
+// section, 0 means every heading starts a section.
+func Parse(content string, splitLevel int) []Section {
+	lines := strings.Split(content, "\n")
+	var sections []Section
+	var currentSection *Section
+	var inCodeBlock bool
+
+	// Front-matter blocks may start at the beginning of the content
+	// or right under a heading, their closing fence is not a setext underline
+	expectFrontMatter := true
+	var inFrontMatter bool
+
+	// prevText is the previous line if it is a paragraph text line,
+	// which becomes a setext heading when followed by "===" or "---"
+	var prevText string
+	var hasPrevText bool
+
+	startSection := func(title string, line int) {
+		// If we have a current section, save it
+		if currentSection != nil {
+			sections = append(sections, *currentSection)
+		}
+
+		// Start new section
+		currentSection = &Section{
+			Title:   title,
+			Content: "",
+			Line:    line,
+		}
+	}
+
+	for i, line := range lines {
+		// Track code block state
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") {
+			inCodeBlock = !inCodeBlock
+		}
+
+		if !inCodeBlock {
+			if inFrontMatter {
+				if trimmedLine == FrontMatterFence || !isFrontMatterLine(trimmedLine) {
+					inFrontMatter = false
+				}
+			} else if expectFrontMatter && trimmedLine == FrontMatterFence {
+				inFrontMatter = true
+			} else if level := SetextHeadingLevel(trimmedLine); level > 0 && hasPrevText && (splitLevel <= 0 || level <= splitLevel) {
+				// Setext heading: the previous text line becomes the title
+				if currentSection != nil {
+					if idx := strings.LastIndex(currentSection.Content, "\n"); idx != -1 {
+						currentSection.Content = currentSection.Content[:idx]
+					} else {
+						currentSection.Content = ""
+					}
+				}
+				startSection(strings.Repeat("#", level)+" "+prevText, i)
+				expectFrontMatter = true
+				hasPrevText = false
+				continue
+			}
+		}
+		if trimmedLine != "" {
+			expectFrontMatter = false
+		}
+
+		// Check if this is a heading line (only if not in a code block or front matter)
+		heading, isHeading := TrimHeadingIndent(line)
+		if !inCodeBlock && !inFrontMatter && isHeading && (splitLevel <= 0 || HeadingLevel(heading) <= splitLevel) {
+			startSection(heading, i+1)
+			expectFrontMatter = true
+			hasPrevText = false
+		} else {
+			// Add line to current section content
+			if currentSection != nil {
+				if currentSection.Content != "" {
+					currentSection.Content += "\n"
+				}
+				currentSection.Content += line
+			}
+			hasPrevText = !inCodeBlock && !inFrontMatter && trimmedLine != "" && !strings.HasPrefix(trimmedLine, "```") &&
+				trimmedLine != FrontMatterFence && !isHeading
+			prevText = trimmedLine
+		}
+	}
+
+	// Add the last section if it exists
+	if currentSection != nil {
+		sections = append(sections, *currentSection)
+	}
+
+	// Split the front-matter block from the content
+	for i := range sections {
+		if meta, rest, ok := ExtractFrontMatter(sections[i].Content); ok {
+			sections[i].Meta = meta
+			sections[i].Content = rest
+		}
+	}
+
+	return sections
+}
+
+// ProfileSplitLevel returns the sectionLevel set in the front-matter block
+// of a profile, and false if it sets none
+func ProfileSplitLevel(content string) (int, bool) {
+	level, err := strconv.Atoi(ProfileFrontMatter(content)["sectionlevel"])
+	if err != nil {
+		return 0, false
+	}
+	return level, true
+}
+
+// Normalize removes a UTF-8 BOM and converts CRLF/CR line endings
+// to LF, as found in profiles edited on Windows or pasted from web pages
+func Normalize(content string) string {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if strings.Contains(content, "\r") {
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+		content = strings.ReplaceAll(content, "\r", "\n")
+	}
+	return content
+}
+
+// SetextHeadingLevel returns 1 for a "===" underline, 2 for a "---" underline,
+// and 0 if the line is not a setext underline
+func SetextHeadingLevel(trimmedLine string) int {
+	if len(trimmedLine) < 2 {
+		return 0
+	}
+	if strings.Trim(trimmedLine, "=") == "" {
+		return 1
+	}
+	if strings.Trim(trimmedLine, "-") == "" {
+		return 2
+	}
+	return 0
+}
+
+// TrimHeadingIndent returns the heading with up to 3 leading spaces or tabs
+// removed, and whether the line is a heading
+func TrimHeadingIndent(line string) (string, bool) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if len(line)-len(trimmed) > 3 || !strings.HasPrefix(trimmed, "#") {
+		return line, false
+	}
+	return trimmed, true
+}
+
+// HeadingLevel returns the level of a heading, i.e. the number of leading '#'
+func HeadingLevel(heading string) int {
+	level := 0
+	for level < len(heading) && heading[level] == '#' {
+		level++
+	}
+	return level
+}
+
+// ExtractFrontMatter splits a metadata block from the beginning of a section's content.
+// It returns the parsed key/value pairs and the remaining content.
+// If the content does not start with a complete block, it is returned unchanged.
+func ExtractFrontMatter(content string) (map[string]string, string, bool) {
+	lines := strings.Split(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != FrontMatterFence {
+		return nil, content, false
+	}
+	meta := make(map[string]string)
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == FrontMatterFence {
+			return meta, strings.Join(lines[i+1:], "\n"), true
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			// not a metadata block
+			return nil, content, false
+		}
+		meta[strings.ToLower(strings.TrimSpace(key))] = unquoteFrontMatterValue(value)
+	}
+	return nil, content, false
+}
+
+// ProfileFrontMatter returns the profile-wide settings from a front-matter block
+// at the beginning of a profile, before its first heading:
+//
+//	---
+//	sectionLevel: 1
+//	replyLanguage: Chinese
+//	---
+//
+// Keys are lowercased, nil is returned if there is no such block.
+func ProfileFrontMatter(content string) map[string]string {
+	content = Normalize(content)
+	if !strings.HasPrefix(strings.TrimSpace(content), FrontMatterFence) {
+		return nil
+	}
+	meta, _, ok := ExtractFrontMatter(strings.TrimLeft(content, "\n"))
+	if !ok {
+		return nil
+	}
+	return meta
+}
+
+// SplitList splits a comma separated directive value like "cursor, claude"
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = trimQuotes(strings.TrimSpace(item))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isFrontMatterLine checks if a trimmed line can appear inside a metadata block
+func isFrontMatterLine(trimmedLine string) bool {
+	return trimmedLine == "" || strings.HasPrefix(trimmedLine, "#") || strings.Contains(trimmedLine, ":")
+}
+
+// unquoteFrontMatterValue trims a value and removes surrounding quotes or list brackets,
+// so that `[cursor, "claude"]` becomes `cursor, claude`
+func unquoteFrontMatterValue(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		items := SplitList(value[1 : len(value)-1])
+		return strings.Join(items, ", ")
+	}
+	return trimQuotes(value)
+}
+
+func trimQuotes(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
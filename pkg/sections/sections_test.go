@@ -0,0 +1,36 @@
+package sections
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	content := Normalize("\ufeff# Build\r\n---\r\nproject: ~/work/api\r\n---\r\nrun make\r\n## Details\r\nmore\r\nSetext\r\n===\r\nbody")
+	sections := Parse(content, 1)
+	if len(sections) != 2 {
+		t.Fatalf("Expected 2 sections, got %d: %#v", len(sections), sections)
+	}
+	if sections[0].Title != "# Build" || sections[0].Content != "run make\n## Details\nmore" || sections[0].Meta["project"] != "~/work/api" {
+		t.Errorf("Unexpected first section: %#v", sections[0])
+	}
+	if sections[1].Title != "# Setext" || sections[1].Content != "body" || sections[1].Line != 8 {
+		t.Errorf("Unexpected setext section: %#v", sections[1])
+	}
+	if got := len(Parse(content, 0)); got != 3 {
+		t.Errorf("Expected every heading to split at level 0, got %d sections", got)
+	}
+}
+
+func TestProfileSplitLevel(t *testing.T) {
+	if level, ok := ProfileSplitLevel("---\nsectionLevel: 2\n---\n# A\n"); !ok || level != 2 {
+		t.Errorf("Expected level 2, got %d, %v", level, ok)
+	}
+	if _, ok := ProfileSplitLevel("# A\n"); ok {
+		t.Errorf("Expected no level without front matter")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	items := SplitList(` cursor, "claude" ,, 'windsurf'`)
+	if len(items) != 3 || items[0] != "cursor" || items[1] != "claude" || items[2] != "windsurf" {
+		t.Errorf("Unexpected items: %q", items)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// plansFile in the config dir holds the plans, by working dir
+const plansFile = "plans.json"
+
+const planHelp = `
+Usage:
+  whats_next plan set FILE [--dir DIR]
+  whats_next plan show
+  whats_next plan clear
+
+Drives a long plan step by step: each reply sent to an agent in DIR (default:
+the current directory) or below starts with the next unchecked step of the
+plan, followed by what was typed.
+
+The steps are the top-level list items of FILE, e.g.:
+
+  1. Add the migration
+  2. Backfill the column
+     in batches of 1000
+  - [x] Already done, skipped
+
+set    stores the plan of FILE, replacing the one of DIR
+show   prints the plan of the current directory with its progress
+clear  removes the plan of the current directory
+`
+
+// plan is an ordered list of steps fed to the agent one reply at a time
+type plan struct {
+	Source string      `json:"source"`
+	Set    time.Time   `json:"set"`
+	Steps  []*planStep `json:"steps"`
+}
+
+type planStep struct {
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// planItemPattern matches a top-level list item, optionally a task list item
+var planItemPattern = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+(?:\[([ xX])\]\s+)?(.*)$`)
+
+func handlePlan(args []string) error {
+	var dir string
+	args, err := flags.String("--dir", &dir).
+		Help("-h,--help", planHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires: set, show or clear")
+	}
+	cmd, args := args[0], args[1:]
+	if dir == "" {
+		dir, err = os.Getwd()
+	} else {
+		dir, err = filepath.Abs(dir)
+	}
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "set":
+		if len(args) != 1 {
+			return fmt.Errorf("requires FILE")
+		}
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		steps := parsePlanSteps(string(data))
+		if len(steps) == 0 {
+			return fmt.Errorf("no list items found in %s", args[0])
+		}
+		source, _ := filepath.Abs(args[0])
+		p := &plan{Source: source, Set: time.Now(), Steps: steps}
+		if err := updatePlans(func(plans map[string]*plan) { plans[dir] = p }); err != nil {
+			return err
+		}
+		done, total := p.progress()
+		fmt.Printf("plan of %d step(s) set for %s, %d already done\n", total, dir, done)
+		return nil
+	case "show":
+		if len(args) > 0 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+		}
+		plans, err := readPlans()
+		if err != nil {
+			return err
+		}
+		planDir, p := findPlan(plans, dir)
+		if p == nil {
+			return fmt.Errorf("no plan for %s, set one with: %s plan set FILE", dir, GetProgramName())
+		}
+		printPlan(os.Stdout, planDir, p)
+		return nil
+	case "clear":
+		if len(args) > 0 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+		}
+		return updatePlans(func(plans map[string]*plan) { delete(plans, dir) })
+	}
+	return fmt.Errorf("unrecognized plan command: %s", cmd)
+}
+
+// parsePlanSteps returns the top-level list items of content,
+// with their indented continuation lines
+func parsePlanSteps(content string) []*planStep {
+	var steps []*planStep
+	var current *planStep
+	var inCodeBlock bool
+	for _, line := range strings.Split(sections.Normalize(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		fence := strings.HasPrefix(trimmed, "```")
+		indented := line != strings.TrimLeft(line, " \t")
+		if inCodeBlock {
+			// an indented code block belongs to the step, others are skipped
+			inCodeBlock = !fence
+		} else if fence {
+			inCodeBlock = true
+			if !indented {
+				current = nil
+			}
+		} else if m := planItemPattern.FindStringSubmatch(line); m != nil {
+			current = &planStep{Text: strings.TrimSpace(m[2]), Done: m[1] == "x" || m[1] == "X"}
+			steps = append(steps, current)
+			continue
+		} else if trimmed != "" && !indented {
+			// unindented text ends the list
+			current = nil
+		}
+		if current != nil {
+			current.Text += "\n" + strings.TrimRight(line, " \t")
+		}
+	}
+	for _, step := range steps {
+		step.Text = strings.TrimRight(step.Text, "\n")
+	}
+	return steps
+}
+
+func readPlans() (map[string]*plan, error) {
+	plans := make(map[string]*plan)
+	file, err := getConfigPath(false, plansFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return plans, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	return plans, nil
+}
+
+// updatePlans reads the plans, applies update and writes them back
+func updatePlans(update func(plans map[string]*plan)) error {
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	plans, err := readPlans()
+	if err != nil {
+		return err
+	}
+	update(plans)
+	file, err := getConfigPath(true, plansFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(plans, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// findPlan returns the plan of workingDir or of its closest parent having one
+func findPlan(plans map[string]*plan, workingDir string) (string, *plan) {
+	var found string
+	for dir := range plans {
+		if sections.IsUnderDir(workingDir, dir) && len(dir) > len(found) {
+			found = dir
+		}
+	}
+	if found == "" {
+		return "", nil
+	}
+	return found, plans[found]
+}
+
+func (p *plan) progress() (done int, total int) {
+	for _, step := range p.Steps {
+		if step.Done {
+			done++
+		}
+	}
+	return done, len(p.Steps)
+}
+
+func printPlan(w io.Writer, dir string, p *plan) {
+	done, total := p.progress()
+	fmt.Fprintf(w, "plan of %s from %s, %d/%d done\n", dir, p.Source, done, total)
+	for i, step := range p.Steps {
+		check := " "
+		if step.Done {
+			check = "x"
+		}
+		firstLine, _, _ := strings.Cut(step.Text, "\n")
+		fmt.Fprintf(w, "  [%s] %d. %s\n", check, i+1, firstLine)
+	}
+}
+
+// applyPlanStep puts the next unchecked step of the plan of workingDir before
+// the typed reply q and checks it, returning q unchanged if there is none
+func applyPlanStep(q string, workingDir string) string {
+	if workingDir == "" {
+		return q
+	}
+	var next string
+	err := updatePlans(func(plans map[string]*plan) {
+		_, p := findPlan(plans, workingDir)
+		if p == nil {
+			return
+		}
+		for i, step := range p.Steps {
+			if step.Done {
+				continue
+			}
+			step.Done = true
+			next = fmt.Sprintf("Step %d of %d of the plan:\n%s", i+1, len(p.Steps), step.Text)
+			return
+		}
+	})
+	if err != nil {
+		Errorf("plan: %v", err)
+		return q
+	}
+	if next == "" {
+		return q
+	}
+	if strings.TrimSpace(q) == "" {
+		return next
+	}
+	return next + "\n\n" + q
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePlanSteps(t *testing.T) {
+	content := "# Migration plan\n\n1. Add the column\n2. Backfill it\n   in batches of 1000\n- [x] Review the schema\n- [ ] Drop the old column\n\n```\n- not a step\n```\nNotes after the list.\n"
+	steps := parsePlanSteps(content)
+	expected := []planStep{
+		{Text: "Add the column"},
+		{Text: "Backfill it\n   in batches of 1000"},
+		{Text: "Review the schema", Done: true},
+		{Text: "Drop the old column"},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %d: %v", len(expected), len(steps), steps)
+	}
+	for i, step := range steps {
+		if *step != expected[i] {
+			t.Errorf("Step %d: expected %+v, got %+v", i+1, expected[i], *step)
+		}
+	}
+}
+
+func TestApplyPlanStep(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	file := filepath.Join(t.TempDir(), "plan.md")
+	if err := os.WriteFile(file, []byte("- [x] Done already\n- First\n- Second\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := handlePlan([]string{"set", file, "--dir", dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := applyPlanStep("careful with locks", filepath.Join(dir, "sub")); got != "Step 2 of 3 of the plan:\nFirst\n\ncareful with locks" {
+		t.Errorf("Unexpected first step: %q", got)
+	}
+	if got := applyPlanStep("", dir); got != "Step 3 of 3 of the plan:\nSecond" {
+		t.Errorf("Unexpected second step: %q", got)
+	}
+	if got := applyPlanStep("done?", dir); got != "done?" {
+		t.Errorf("Expected the reply unchanged after the plan, got %q", got)
+	}
+	if got := applyPlanStep("hi", t.TempDir()); got != "hi" {
+		t.Errorf("Expected no plan elsewhere, got %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+)
+
+// isProcessAlive reports whether a process with pid exists,
+// on windows FindProcess fails for exited processes
+func isProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
+
+// listServeProcesses is not supported on windows, whose process list has no arguments
+func listServeProcesses() ([]int, error) {
+	return nil, nil
+}
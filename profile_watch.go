@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// profileWatchDebounce is how long the server waits for more changes of
+// custom.md or the selected profile before telling, as editors may write
+// a file in several steps
+const profileWatchDebounce = 200 * time.Millisecond
+
+// fileStamp tells whether a file changed, zero if it does not exist
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFileStamp(file string) fileStamp {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// watchedProfileFiles returns custom.md and the file of the profile selected for the server
+func watchedProfileFiles() []string {
+	var files []string
+	if customFile, err := getCustomFile(false); err == nil {
+		files = append(files, customFile)
+	}
+	if name := readServerConfig().SelectedProfile; name != "" {
+		if groupDir, err := getGroupConfigPath(false); err == nil {
+			files = append(files, filepath.Join(groupDir, addMDSuffix(name)))
+		}
+	}
+	return files
+}
+
+// watchProfiles watches the profile files until ctx is done, calling
+// onChange with a file that changed, or a profile newly selected, once no
+// more changes come within debounce.
+// The profiles are read again for every answer, so this only tells the user.
+// The dirs are watched rather than the files, so that editors saving by
+// replacing the file, and profiles created or selected later, are seen.
+func watchProfiles(ctx context.Context, debounce time.Duration, onChange func(file string)) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		Errorf("watch profiles: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]bool)
+	watchDirs := func(files []string) {
+		var dirs []string
+		// config.json selects the profile
+		if configDir, err := getConfigDir(false); err == nil {
+			dirs = append(dirs, configDir)
+		}
+		for _, file := range files {
+			dirs = append(dirs, filepath.Dir(file))
+		}
+		for _, dir := range dirs {
+			if watched[dir] {
+				continue
+			}
+			// a dir not created yet is added on a later change
+			if err := watcher.Add(dir); err == nil {
+				watched[dir] = true
+			}
+		}
+	}
+
+	files := watchedProfileFiles()
+	stamps := make(map[string]fileStamp)
+	for _, file := range files {
+		stamps[file] = statFileStamp(file)
+	}
+	watchDirs(files)
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			settled = time.After(debounce)
+			continue
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			Errorf("watch profiles: %v", err)
+			continue
+		case <-settled:
+			settled = nil
+		}
+		files := watchedProfileFiles()
+		watchDirs(files)
+		for _, file := range files {
+			stamp := statFileStamp(file)
+			old, seen := stamps[file]
+			stamps[file] = stamp
+			if !seen || stamp != old {
+				onChange(file)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchProfiles(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	customFile := filepath.Join(configDir, "custom.md")
+	if err := os.WriteFile(customFile, []byte("# Rules\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan string, 10)
+	go watchProfiles(ctx, 10*time.Millisecond, func(file string) {
+		changed <- file
+	})
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case file := <-changed:
+		t.Fatalf("Unexpected change before editing: %s", file)
+	default:
+	}
+
+	if err := os.WriteFile(customFile, []byte("# Rules\nrun the tests\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case file := <-changed:
+		if file != customFile {
+			t.Errorf("Expected %s changed, got %s", customFile, file)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the change of custom.md reported")
+	}
+
+	// editors may save by replacing the file
+	tmpFile := filepath.Join(configDir, ".custom.md.tmp")
+	if err := os.WriteFile(tmpFile, []byte("# Rules\nrun the linter\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpFile, customFile); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case file := <-changed:
+		if file != customFile {
+			t.Errorf("Expected %s changed, got %s", customFile, file)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the replacement of custom.md reported")
+	}
+}
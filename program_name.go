@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// replaceWhatsNextWithProgramName replaces `whats_next` with the name this
+// program was invoked as, unless disabled by Config.DisableProgramNameSubstitution
+func replaceWhatsNextWithProgramName(reply string) string {
+	wd, _ := os.Getwd()
+	config, err := readEffectiveConfig(wd)
+	if err == nil && config.DisableProgramNameSubstitution {
+		return reply
+	}
+	return substituteProgramName(reply, GetProgramName())
+}
+
+// substituteProgramName replaces `whats_next` with `programName`, except in
+// sections whose heading, or a parent heading, has the (keep-program-name) directive
+func substituteProgramName(content string, programName string) string {
+	replacement := "`" + programName + "`"
+	if programName == "whats_next" || !strings.Contains(content, "`whats_next`") {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	var inCodeBlock bool
+	var keepLevel int // level of the heading keeping the name, 0 if none
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") {
+			inCodeBlock = !inCodeBlock
+		}
+		if !inCodeBlock {
+			heading, level := line, 0
+			if trimmed, ok := sections.TrimHeadingIndent(line); ok {
+				heading, level = trimmed, sections.HeadingLevel(trimmed)
+			} else if trimmedLine != "" && i+1 < len(lines) {
+				level = sections.SetextHeadingLevel(strings.TrimSpace(lines[i+1]))
+			}
+			if level > 0 {
+				if keepLevel > 0 && level <= keepLevel {
+					keepLevel = 0
+				}
+				if keepLevel == 0 && sections.HasDirective(sections.ApplyCommentDirectives(heading), sections.KeepProgramName) {
+					keepLevel = level
+				}
+			}
+		}
+		if keepLevel == 0 {
+			lines[i] = strings.ReplaceAll(line, "`whats_next`", replacement)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestSubstituteProgramName(t *testing.T) {
+	content := "# Loop\n" +
+		"Run `whats_next` when done.\n" +
+		"# Setup (keep-program-name)\n" +
+		"Install `whats_next` with go install.\n" +
+		"## Details\n" +
+		"`whats_next` is the canonical name.\n" +
+		"# Other <!-- keep-program-name -->\n" +
+		"Still `whats_next`.\n" +
+		"Back\n" +
+		"====\n" +
+		"Run `whats_next` again.\n" +
+		"```\n" +
+		"# not a heading (keep-program-name)\n" +
+		"```\n" +
+		"Then `whats_next`."
+
+	expected := "# Loop\n" +
+		"Run `wn` when done.\n" +
+		"# Setup (keep-program-name)\n" +
+		"Install `whats_next` with go install.\n" +
+		"## Details\n" +
+		"`whats_next` is the canonical name.\n" +
+		"# Other <!-- keep-program-name -->\n" +
+		"Still `whats_next`.\n" +
+		"Back\n" +
+		"====\n" +
+		"Run `wn` again.\n" +
+		"```\n" +
+		"# not a heading (keep-program-name)\n" +
+		"```\n" +
+		"Then `wn`."
+
+	if result := substituteProgramName(content, "wn"); result != expected {
+		t.Errorf("Expected:\n%s\n\nGot:\n%s", expected, result)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/xhd2015/whats_next/pkg/config"
+)
+
+// findProjectConfig walks up from dir to find .whats_next/config.json
+func findProjectConfig(dir string) (string, bool) {
+	return config.FindProject(dir)
+}
+
+// readEffectiveConfig reads the global config, applies the overrides
+// of the current host, and merges the project config found from
+// workingDir over it, if any. Only config.ProjectFields are taken from
+// the project config, the others are ignored with a warning.
+// The result must not be written back with writeConfig,
+// otherwise project settings would leak into the global config.
+func readEffectiveConfig(workingDir string) (*Config, error) {
+	reader, err := getConfigReader()
+	if err != nil {
+		return nil, err
+	}
+	return reader.Effective(workingDir)
+}
+
+// readHostConfig reads the global config with the overrides of the current host
+// applied, for settings a project config must not influence
+func readHostConfig() (*Config, error) {
+	reader, err := getConfigReader()
+	if err != nil {
+		return nil, err
+	}
+	return reader.Host()
+}
+
+// readConfigOrDefault is like readEffectiveConfig,
+// falling back to the defaults if the config cannot be read
+func readConfigOrDefault(workingDir string) *Config {
+	config, err := readEffectiveConfig(workingDir)
+	if err != nil {
+		return &Config{}
+	}
+	return config
+}
+
+// warnConfigFile reports the problems of a config file read, see config.Reader.OnRead
+func warnConfigFile(configFile string, data []byte, ignored []string, err error) {
+	if err != nil {
+		Errorf("%v", err)
+		return
+	}
+	if _, warned := warnedConfigFiles.Load(configFile); !warned && len(ignored) > 0 {
+		problem := fmt.Sprintf("ignored fields not allowed in a project config: %s", strings.Join(ignored, ", "))
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", configFile, problem)
+		Errorf("%s: %s", configFile, problem)
+	}
+	warnConfigProblems(configFile, data)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"golang.org/x/term"
+)
+
+// promptsDir is the dir under the config dir holding the saved prompts, one NAME.md each
+const promptsDir = "prompts"
+
+// promptCommandPrefix starts a line of input replaced by the saved prompt named after it
+const promptCommandPrefix = "/prompt "
+
+const promptHelp = `
+Usage:
+  whats_next prompt save NAME [TEXT]
+  whats_next prompt list
+  whats_next prompt use NAME [--send] [--copy] [--port PORT]
+  whats_next prompt rm NAME
+
+Keeps reusable prompt snippets, separate from profiles, in <config dir>/prompts.
+
+save  saves TEXT as NAME, or stdin if piped, or what is written in the editor
+list  lists the saved prompts with their first line
+use   prints the prompt, or queues it for the waiting agent with --send
+rm    removes the prompt
+
+While typing a reply, a line "/prompt NAME" is replaced by the prompt on Enter.
+
+Options:
+  --send           Queue the prompt on the running server
+  --copy           Also copy the prompt to the clipboard
+  --port PORT      The server port for --send (default: serverPort or 7654)
+  --editor EDITOR  The editor for save without TEXT
+`
+
+func handlePrompt(args []string) error {
+	var send bool
+	var copy bool
+	var port int
+	var editor string
+	args, err := flags.Bool("--send", &send).
+		Bool("--copy", &copy).
+		Int("--port", &port).
+		String("--editor", &editor).
+		Help("-h,--help", promptHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires: save, list, use or rm")
+	}
+	cmd, args := args[0], args[1:]
+	if cmd == "list" {
+		if len(args) > 0 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+		}
+		return listPrompts(os.Stdout)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires NAME")
+	}
+	name := args[0]
+	file, err := getPromptFile(name)
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "save":
+		if len(args) > 2 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args[2:], " "))
+		}
+		return savePrompt(file, args[1:], editor)
+	case "use":
+		if len(args) > 1 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args[1:], " "))
+		}
+		prompt, err := readPrompt(name)
+		if err != nil {
+			return err
+		}
+		if copy {
+			copyReply(prompt)
+		}
+		if send {
+			config := readServerConfig()
+			addr := getServerAddr(config.ServerBind, resolveServerPort(port, config))
+			wd, _ := os.Getwd()
+			if err := postReply(addr, prompt, wd); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Queued prompt %s on server %s\n", name, addr)
+			return nil
+		}
+		fmt.Println(prompt)
+		return nil
+	case "rm", "remove":
+		unlock, err := lockConfigDir()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		if err := os.Remove(file); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("no prompt named %s", name)
+			}
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("unrecognized prompt command: %s", cmd)
+}
+
+// getPromptFile returns the file of the prompt name
+func getPromptFile(name string) (string, error) {
+	name = strings.TrimSuffix(name, ".md")
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid prompt name: %q", name)
+	}
+	dir, err := getConfigPath(false, promptsDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".md"), nil
+}
+
+func savePrompt(file string, text []string, editor string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	var content string
+	switch {
+	case len(text) > 0:
+		content = text[0]
+	case !term.IsTerminal(int(os.Stdin.Fd())):
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		content = string(data)
+	default:
+		if err := openInEditor(editor, file); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			os.Remove(file)
+			return fmt.Errorf("empty prompt, not saved")
+		}
+		return nil
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return fmt.Errorf("empty prompt, not saved")
+	}
+	// the editor case is not locked, the lock would be held while editing
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return os.WriteFile(file, []byte(content+"\n"), 0644)
+}
+
+// readPrompt returns the content of the prompt name
+func readPrompt(name string) (string, error) {
+	file, err := getPromptFile(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no prompt named %s, see `%s prompt list`", name, GetProgramName())
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// getPromptNames returns the names of the saved prompts, sorted
+func getPromptNames() ([]string, error) {
+	dir, err := getConfigPath(false, promptsDir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".md"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func listPrompts(w io.Writer) error {
+	names, err := getPromptNames()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Fprintf(w, "no saved prompts, save one with: %s prompt save NAME TEXT\n", GetProgramName())
+		return nil
+	}
+	for _, name := range names {
+		prompt, err := readPrompt(name)
+		if err != nil {
+			return err
+		}
+		firstLine, _, _ := strings.Cut(prompt, "\n")
+		fmt.Fprintf(w, "%-16s %s\n", name, firstLine)
+	}
+	return nil
+}
+
+// expandPromptCommand returns the saved prompt if line is "/prompt NAME"
+func expandPromptCommand(line string) (string, bool, error) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(line), promptCommandPrefix)
+	if !ok {
+		return "", false, nil
+	}
+	prompt, err := readPrompt(strings.TrimSpace(name))
+	if err != nil {
+		return "", true, err
+	}
+	return prompt, true, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptLibrary(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+
+	if err := handlePrompt([]string{"save", "review", "  review the diff\nthen list the risks\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := handlePrompt([]string{"save", "../escape", "x"}); err == nil {
+		t.Errorf("Expected an invalid name to be rejected")
+	}
+	var b strings.Builder
+	if err := listPrompts(&b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != "review           review the diff\n" {
+		t.Errorf("listPrompts() = %q", got)
+	}
+
+	prompt, ok, err := expandPromptCommand(" /prompt review ")
+	if err != nil || !ok || prompt != "review the diff\nthen list the risks" {
+		t.Errorf("expandPromptCommand() = %q, %v, %v", prompt, ok, err)
+	}
+	if _, ok, _ := expandPromptCommand("please /prompt review"); ok {
+		t.Errorf("Expected /prompt only at the start of a line")
+	}
+	if _, ok, err := expandPromptCommand("/prompt missing"); !ok || err == nil {
+		t.Errorf("Expected an error for a missing prompt")
+	}
+
+	if err := handlePrompt([]string{"rm", "review"}); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := getPromptNames(); len(names) != 0 {
+		t.Errorf("Expected no prompts after rm, got %v", names)
+	}
+}
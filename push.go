@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"golang.org/x/term"
+)
+
+const pushHelp = `
+Usage:
+  whats_next push TEXT... [--port PORT]
+  whats_next push - [--port PORT]
+
+Queues TEXT on the running server from any terminal: the waiting agent gets it
+right away, otherwise the next agent executing whats_next. With - or when
+piped, the text is read from stdin.
+
+Options:
+  --port PORT  The server port (default: serverPort or 7654)
+`
+
+func handlePush(args []string) error {
+	var port int
+	args, err := flags.Int("--port", &port).
+		Help("-h,--help", pushHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	var text string
+	if (len(args) == 1 && args[0] == "-") || (len(args) == 0 && !term.IsTerminal(int(os.Stdin.Fd()))) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	} else {
+		text = strings.Join(args, " ")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("requires TEXT")
+	}
+
+	config := readServerConfig()
+	addr := getServerAddr(config.ServerBind, resolveServerPort(port, config))
+	// checked before, the waiting agent leaves once it gets the text
+	status, statusErr := fetchServerStatus(addr)
+	// the push comes from another terminal, it is not tied to its dir
+	if err := postReply(addr, text, ""); err != nil {
+		return err
+	}
+	if statusErr == nil && len(status.Waiting) > 0 {
+		fmt.Fprintf(os.Stderr, "Pushed to the agent waiting on server %s\n", addr)
+	} else {
+		fmt.Fprintf(os.Stderr, "Queued on server %s for the next agent executing %s\n", addr, GetProgramName())
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPush(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	h := &serveHandler{inputChan: make(chan InputMessage, 1)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reply", func(w http.ResponseWriter, r *http.Request) {
+		handleReplyRequest(h, w, r)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		handleStatusRequest(h, w, r)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+	if err := handlePush([]string{"stop,", "the requirements changed", "--port", port}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-h.inputChan; msg.Content != "stop, the requirements changed" || msg.WorkingDir != "" {
+		t.Errorf("Unexpected pushed message: %+v", msg)
+	}
+	if err := handlePush([]string{"--port", port}); err == nil {
+		t.Errorf("Expected push without text to fail")
+	}
+}
+
+func TestReplyServerMode(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	h := &serveHandler{inputChan: make(chan InputMessage, 1)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleReplyRequest(h, w, r)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err := writeConfig(&Config{Mode: ModeServer}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handleReply([]string{"LGTM,", "continue", "--port", port}); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if msg := <-h.inputChan; msg.Content != "LGTM, continue" || msg.WorkingDir != wd {
+		t.Errorf("Unexpected reply: %+v", msg)
+	}
+	if err := handleReply(nil); err == nil {
+		t.Errorf("Expected reply without text to fail")
+	}
+}
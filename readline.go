@@ -2,15 +2,13 @@ package main
 
 import (
 	"bufio"
-	"context"
 	"fmt"
 	"os"
 	"strings"
 	"sync/atomic"
 	"time"
 
-	"github.com/charmbracelet/bubbles/textarea"
-	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xhd2015/whats_next/pkg/protocol"
 )
 
 // readInputFromTerminal reads multiline input from terminal with rich editing capabilities.
@@ -21,106 +19,34 @@ import (
 // - Support special commands: END (submit), CLEAR (reset), exit (quit)
 // - Must work inline in terminal, not as vim-like overlay
 
+// inputProgram is the running terminal input of the server
+type inputProgram interface {
+	// enableTimer shows the timeout countdown, while a client is connected
+	enableTimer()
+	disableTimer()
+	// notice shows text below the input until the next key
+	notice(text string)
+	kill()
+}
+
 type readTerminalOptions struct {
 	showTimer     func() bool
 	getUserPrompt func(hasInput bool) string
 
 	noWrapWithGuidelines bool
+	// copyToClipboard also copies the wrapped question to the clipboard
+	copyToClipboard bool
 
-	onCreatedProgram  func(program *tea.Program)
-	onProgramFinished func(program *tea.Program)
+	onCreatedProgram  func(program inputProgram)
+	onProgramFinished func(program inputProgram)
 	onInputExit       func()
 	onInputUpdate     func(hasInput bool)
-}
-
-func readInputFromTerminal(ctx context.Context, hasInput *int32, timeout time.Duration, onInputUpdate func(hasInput bool), opts readTerminalOptions) ([]string, error) {
-	showTimer := opts.showTimer
-	userPrompt := opts.getUserPrompt
-	onCreatedProgram := opts.onCreatedProgram
-	onProgramFinished := opts.onProgramFinished
-	onInputExit := opts.onInputExit
-
-	ta := textarea.New()
-	ta.Placeholder = "Type your message here... (multi-line supported)"
-	ta.Focus()
-	ta.CharLimit = 0
-	ta.SetWidth(80)
-	ta.SetHeight(4)
-	ta.ShowLineNumbers = false
-
-	model := multiLineEditorModel{
-		textarea:         ta,
-		hasInput:         hasInput,
-		timeoutBeginTime: time.Now(),
-		timeout:          timeout,
-		showTimer:        showTimer,
-		getUserPrompt:    userPrompt,
-		onInputExit:      onInputExit,
-		onInputUpdate:    onInputUpdate,
-	}
-
-	// Use WITHOUT AltScreen to work inline in terminal
-	program := tea.NewProgram(model, tea.WithContext(ctx))
-	if onCreatedProgram != nil {
-		onCreatedProgram(program)
-	}
-	finalModel, err := program.Run()
-	if onProgramFinished != nil {
-		// clear
-		onProgramFinished(nil)
-	}
-	Logf("readInputFromTerminal program returned: err: %v", err)
-	if err != nil {
-		Logf("readInputFromTerminal error: %v", err)
-		// Check if it was cancelled due to timeout
-		if ctx.Err() != nil {
-			return nil, fmt.Errorf("timeout")
-		}
-		return nil, err
-	}
-
-	m := finalModel.(multiLineEditorModel)
-	if m.cancelled {
-		Logf("readInputFromTerminal cancelled")
-		return nil, fmt.Errorf("exit")
-	}
-
-	content := m.content
-	if strings.TrimSpace(content) == "" {
-		Logf("readInputFromTerminal empty content")
-		return []string{}, nil
-	}
-
-	// Split content into logical lines for the existing logic
-	lines := strings.Split(content, "\n")
-	var result []string
-	var currentBuffer strings.Builder
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" && currentBuffer.Len() > 0 {
-			// Empty line ends current buffer
-			result = append(result, currentBuffer.String())
-			currentBuffer.Reset()
-		} else if trimmed != "" {
-			if currentBuffer.Len() > 0 {
-				currentBuffer.WriteString("\n")
-			}
-			currentBuffer.WriteString(line)
-		}
-	}
 
-	// Add any remaining content
-	if currentBuffer.Len() > 0 {
-		result = append(result, currentBuffer.String())
-	}
-
-	if len(result) == 0 && content != "" {
-		result = []string{content}
-	}
-	Logf("readInputFromTerminal result: %v", result)
+	// todoDir scopes the items added with "/todo", empty for all agents
+	todoDir string
 
-	return result, nil
+	// onTogglePause pauses or resumes the server on Ctrl+P, returning a notice
+	onTogglePause func() string
 }
 
 func readInputFromNonTerminal(hasInput *int32) ([]string, error) {
@@ -146,7 +72,14 @@ func readInputFromNonTerminal(hasInput *int32) ([]string, error) {
 			continue
 		}
 		if in == "exit" && len(lines) == 0 {
-			return nil, fmt.Errorf("exit")
+			return nil, protocol.ErrUserExit
+		}
+		if prompt, ok, err := expandPromptCommand(in); ok {
+			if err != nil {
+				fmt.Fprintln(os.Stdout, err)
+				continue
+			}
+			in = prompt
 		}
 		if !USE_BACKSLAHS {
 			// must see an end
@@ -181,3 +114,30 @@ func readInputFromNonTerminal(hasInput *int32) ([]string, error) {
 	}
 	return lines, nil
 }
+
+func renderUserPrompt(showTimer bool, showClient bool, remaining time.Duration, waitingClient int) string {
+	var timer string
+	if showTimer {
+		if remaining > 0 {
+			minutes := int(remaining.Minutes())
+			seconds := int(remaining.Seconds()) % 60
+
+			timer = fmt.Sprintf(" (%dm %02ds)", minutes, seconds)
+		} else {
+			timer = " (0m0s)"
+		}
+	}
+
+	var client string
+	if showClient {
+		if waitingClient == 0 {
+			client = " (staging)"
+		} else if waitingClient == 1 {
+			client = " (client connected)"
+		} else {
+			client = fmt.Sprintf(" (%d clients connected)", waitingClient)
+		}
+	}
+
+	return "user" + timer + ">" + client
+}
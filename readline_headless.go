@@ -0,0 +1,38 @@
+//go:build headless
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// readInputFromTerminal reads the input line by line in a headless build,
+// like a non-terminal input
+func readInputFromTerminal(ctx context.Context, hasInput *int32, timeout time.Duration, onInputUpdate func(hasInput bool), opts readTerminalOptions) ([]string, error) {
+	if opts.getUserPrompt != nil {
+		fmt.Fprintln(os.Stdout, opts.getUserPrompt(false))
+	}
+	return readInputFromNonTerminal(hasInput)
+}
+
+// composeInTerminal reads the text to add from stdin until EOF in a headless build
+func composeInTerminal(initial string) (string, error) {
+	fmt.Fprintln(os.Stderr, "Type the content, then Ctrl+D:")
+	if initial != "" {
+		fmt.Fprint(os.Stderr, initial)
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	return initial + string(data), nil
+}
+
+// editFileInTerminal is not available in a headless build
+func editFileInTerminal(file string, line int) (bool, error) {
+	return false, fmt.Errorf("no terminal editor in this headless build, choose an installed editor with --editor, `%s config set editor EDITOR` or $EDITOR", GetProgramName())
+}
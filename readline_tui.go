@@ -0,0 +1,177 @@
+//go:build !headless
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xhd2015/whats_next/pkg/protocol"
+)
+
+// teaInputProgram is the inputProgram of the bubbletea input
+type teaInputProgram struct {
+	program *tea.Program
+}
+
+func (p teaInputProgram) enableTimer()       { go p.program.Send(enableTimerMsg{}) }
+func (p teaInputProgram) disableTimer()      { go p.program.Send(disableTimerMsg{}) }
+func (p teaInputProgram) notice(text string) { go p.program.Send(noticeMsg(text)) }
+func (p teaInputProgram) kill()              { p.program.Kill() }
+
+func readInputFromTerminal(ctx context.Context, hasInput *int32, timeout time.Duration, onInputUpdate func(hasInput bool), opts readTerminalOptions) ([]string, error) {
+	showTimer := opts.showTimer
+	userPrompt := opts.getUserPrompt
+	onCreatedProgram := opts.onCreatedProgram
+	onProgramFinished := opts.onProgramFinished
+	onInputExit := opts.onInputExit
+
+	ta := textarea.New()
+	ta.Placeholder = "Type your message here... (multi-line supported)"
+	ta.Focus()
+	ta.CharLimit = 0
+	ta.SetWidth(80)
+	ta.SetHeight(4)
+	ta.ShowLineNumbers = false
+
+	model := multiLineEditorModel{
+		textarea:         ta,
+		hasInput:         hasInput,
+		timeoutBeginTime: time.Now(),
+		timeout:          timeout,
+		showTimer:        showTimer,
+		getUserPrompt:    userPrompt,
+		onInputExit:      onInputExit,
+		onInputUpdate:    onInputUpdate,
+		todoDir:          opts.todoDir,
+		onTogglePause:    opts.onTogglePause,
+	}
+
+	// Use WITHOUT AltScreen to work inline in terminal
+	program := tea.NewProgram(model, tea.WithContext(ctx))
+	if onCreatedProgram != nil {
+		onCreatedProgram(teaInputProgram{program: program})
+	}
+	finalModel, err := program.Run()
+	if onProgramFinished != nil {
+		// clear
+		onProgramFinished(nil)
+	}
+	Logf("readInputFromTerminal program returned: err: %v", err)
+	if err != nil {
+		Logf("readInputFromTerminal error: %v", err)
+		// Check if it was cancelled due to timeout
+		if ctx.Err() != nil {
+			return nil, protocol.ErrTimeout
+		}
+		return nil, err
+	}
+
+	m := finalModel.(multiLineEditorModel)
+	if m.cancelled {
+		Logf("readInputFromTerminal cancelled")
+		return nil, protocol.ErrUserExit
+	}
+
+	content := m.content
+	if strings.TrimSpace(content) == "" {
+		Logf("readInputFromTerminal empty content")
+		return []string{}, nil
+	}
+
+	// Split content into logical lines for the existing logic
+	lines := strings.Split(content, "\n")
+	var result []string
+	var currentBuffer strings.Builder
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" && currentBuffer.Len() > 0 {
+			// Empty line ends current buffer
+			result = append(result, currentBuffer.String())
+			currentBuffer.Reset()
+		} else if trimmed != "" {
+			if currentBuffer.Len() > 0 {
+				currentBuffer.WriteString("\n")
+			}
+			currentBuffer.WriteString(line)
+		}
+	}
+
+	// Add any remaining content
+	if currentBuffer.Len() > 0 {
+		result = append(result, currentBuffer.String())
+	}
+
+	if len(result) == 0 && content != "" {
+		result = []string{content}
+	}
+	Logf("readInputFromTerminal result: %v", result)
+
+	return result, nil
+}
+
+// composeInTerminal lets the user write a text starting with initial in the
+// inline editor, returning it whole, blank lines included
+func composeInTerminal(initial string) (string, error) {
+	ta := textarea.New()
+	ta.Placeholder = "Type here... (multi-line supported)"
+	ta.Focus()
+	ta.CharLimit = 0
+	ta.SetWidth(80)
+	ta.SetHeight(8)
+	ta.ShowLineNumbers = false
+	ta.SetValue(initial)
+
+	finalModel, err := tea.NewProgram(multiLineEditorModel{textarea: ta}).Run()
+	if err != nil {
+		return "", err
+	}
+	m := finalModel.(multiLineEditorModel)
+	if m.cancelled || !m.finished {
+		return "", fmt.Errorf("aborted")
+	}
+	return m.content, nil
+}
+
+// editFileInTerminal edits file full screen in the inline editor, with the
+// cursor on line if it is positive. It returns whether the file was saved.
+func editFileInTerminal(file string, line int) (bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	ta := textarea.New()
+	ta.Focus()
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+	ta.ShowLineNumbers = true
+	ta.SetValue(string(data))
+	// SetValue leaves the cursor at the end
+	for target := max(line-1, 0); ta.Line() > target; {
+		ta.CursorUp()
+	}
+	ta.CursorStart()
+
+	finalModel, err := tea.NewProgram(multiLineEditorModel{textarea: ta, editingFile: file}, tea.WithAltScreen()).Run()
+	if err != nil {
+		return false, err
+	}
+	m := finalModel.(multiLineEditorModel)
+	if !m.finished || m.content == string(data) {
+		return false, nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+	if err := writeFileAtomic(file, []byte(m.content), info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
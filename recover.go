@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+const recoverHelp = `
+Usage:
+  whats_next recover [--id ID | --last] [--send] [--copy] [--editor EDITOR] [--port PORT]
+
+Prints the recover guideline with the interrupted prompt filled in, to resume
+the work of an agent stopped in the middle.
+
+The prompt is written in the editor, which starts with the last reply sent to
+an agent in the current directory, or is taken from the history with --id or
+--last.
+
+Options:
+  --id ID          The reply of history entry ID, see ` + "`whats_next history`" + `
+  --last           The last reply sent to an agent in the current directory
+  --send           Queue the recover block on the running server for the waiting agent
+  --copy           Also copy the recover block to the clipboard
+  --editor EDITOR  The editor to write the prompt in
+  --port PORT      The server port for --send (default: serverPort or 7654)
+`
+
+const (
+	recoverPromptOpen  = "<previous_prompt>"
+	recoverPromptClose = "</previous_prompt>"
+)
+
+func handleRecover(args []string) error {
+	var id string
+	var last bool
+	var send bool
+	var copy bool
+	var editor string
+	var port int
+	args, err := flags.String("--id", &id).
+		Bool("--last", &last).
+		Bool("--send", &send).
+		Bool("--copy", &copy).
+		String("--editor", &editor).
+		Int("--port", &port).
+		Help("-h,--help", recoverHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	if id != "" && last {
+		return fmt.Errorf("--id and --last cannot be used together")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	var prompt string
+	if id != "" {
+		entry, err := findTranscriptEntry(id)
+		if err != nil {
+			return err
+		}
+		if entry.Event != WebhookEventInput {
+			return fmt.Errorf("history %s is not a reply", id)
+		}
+		prompt = entry.Content
+	} else {
+		prompt, err = getLastPrompt(wd)
+		if err != nil {
+			return err
+		}
+		if last && prompt == "" {
+			return fmt.Errorf("no reply recorded in %s, see `%s history`", wd, GetProgramName())
+		}
+		if !last {
+			prompt, err = editRecoverPrompt(editor, prompt)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return fmt.Errorf("empty prompt, nothing to recover")
+	}
+
+	block := fillRecoverPrompt(getBuiltinText("recover.md"), prompt)
+	if copy {
+		copyReply(block)
+	}
+	if send {
+		config := readServerConfig()
+		addr := getServerAddr(config.ServerBind, resolveServerPort(port, config))
+		if err := postReply(addr, block, wd); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Queued the recover block on server %s\n", addr)
+		return nil
+	}
+	fmt.Print(block)
+	return nil
+}
+
+// getLastPrompt returns the last reply sent to an agent in dir or below, empty if none
+func getLastPrompt(dir string) (string, error) {
+	entries, err := readTranscript(time.Time{}, time.Time{})
+	if err != nil {
+		return "", err
+	}
+	entries = filterTranscriptByDir(entries, dir)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Event == WebhookEventInput && strings.TrimSpace(entries[i].Content) != "" {
+			return entries[i].Content, nil
+		}
+	}
+	return "", nil
+}
+
+// editRecoverPrompt opens the editor on initial and returns what was saved
+func editRecoverPrompt(editor string, initial string) (string, error) {
+	f, err := os.CreateTemp("", "whats_next-recover-*.md")
+	if err != nil {
+		return "", err
+	}
+	file := f.Name()
+	defer os.Remove(file)
+	_, err = f.WriteString(initial)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := openInEditor(editor, file); err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// fillRecoverPrompt puts prompt between the previous_prompt tags of text,
+// or appends it in them if a customized recover.md has no tags
+func fillRecoverPrompt(text string, prompt string) string {
+	open := strings.Index(text, recoverPromptOpen)
+	if open >= 0 {
+		if end := strings.Index(text[open:], recoverPromptClose); end >= 0 {
+			return text[:open+len(recoverPromptOpen)] + "\n" + prompt + "\n" + text[open+end:]
+		}
+	}
+	return strings.TrimRight(text, "\n") + "\n\n" + recoverPromptOpen + "\n" + prompt + "\n" + recoverPromptClose + "\n"
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFillRecoverPrompt(t *testing.T) {
+	block := fillRecoverPrompt(recover, "add the login page")
+	if !strings.Contains(block, "<previous_prompt>\nadd the login page\n</previous_prompt>\n") {
+		t.Errorf("Expected the prompt in the tags, got:\n%s", block)
+	}
+	if strings.Count(block, "<previous_prompt>") != 1 {
+		t.Errorf("Expected a single tag, got:\n%s", block)
+	}
+
+	block = fillRecoverPrompt("\n# Resume\n", "fix it")
+	if block != "\n# Resume\n\n<previous_prompt>\nfix it\n</previous_prompt>\n" {
+		t.Errorf("Expected the prompt appended to a text without tags, got %q", block)
+	}
+}
+
+func TestGetLastPrompt(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+
+	recordTranscript(ModeNative, WebhookEventInput, "/src/api", time.Now(), "first", "first")
+	recordTranscript(ModeNative, WebhookEventInput, "/src/api/cmd", time.Now(), "second", "second")
+	recordTranscript(ModeNative, WebhookEventIdle, "/src/api", time.Now(), "", "idle")
+	recordTranscript(ModeNative, WebhookEventInput, "/src/web", time.Now(), "other", "other")
+
+	prompt, err := getLastPrompt("/src/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt != "second" {
+		t.Errorf("getLastPrompt() = %q, want %q", prompt, "second")
+	}
+	if prompt, _ := getLastPrompt("/src/none"); prompt != "" {
+		t.Errorf("getLastPrompt() of a dir without history = %q, want empty", prompt)
+	}
+}
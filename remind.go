@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// remindersFile in the config dir holds the pending reminders
+const remindersFile = "reminders.json"
+
+const remindHelp = `
+Usage:
+  whats_next remind DURATION TEXT [--dir DIR]
+  whats_next remind list
+  whats_next remind clear
+
+Adds TEXT to the first answer sent to an agent in DIR (default: the current
+directory) or below once DURATION (e.g. 20m, 1h30m) has elapsed, whether the
+answer comes from the server or from the next native invocation.
+
+list   lists the pending reminders
+clear  removes the pending reminders
+`
+
+// reminder is a text due to be added to an answer
+type reminder struct {
+	Due        time.Time `json:"due"`
+	Created    time.Time `json:"created"`
+	WorkingDir string    `json:"workingDir"`
+	Text       string    `json:"text"`
+}
+
+func handleRemind(args []string) error {
+	var dir string
+	args, err := flags.String("--dir", &dir).
+		Help("-h,--help", remindHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires DURATION TEXT, list or clear")
+	}
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args[1:], " "))
+		}
+		return listReminders(os.Stdout, time.Now())
+	case "clear":
+		if len(args) > 1 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args[1:], " "))
+		}
+		unlock, err := lockConfigDir()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return writeReminders(nil)
+	}
+	duration, err := time.ParseDuration(args[0])
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("invalid duration: %s, expect e.g. 20m or 1h30m", args[0])
+	}
+	text := strings.TrimSpace(strings.Join(args[1:], " "))
+	if text == "" {
+		return fmt.Errorf("requires TEXT")
+	}
+	if dir == "" {
+		dir, err = os.Getwd()
+	} else {
+		dir, err = filepath.Abs(dir)
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := addReminder(&reminder{Due: now.Add(duration), Created: now, WorkingDir: dir, Text: text}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "will remind the agent in %s after %s\n", dir, formatWaitTime(duration))
+	return nil
+}
+
+func readReminders() ([]*reminder, error) {
+	file, err := getConfigPath(false, remindersFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var reminders []*reminder
+	if err := json.Unmarshal(data, &reminders); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	return reminders, nil
+}
+
+// writeReminders writes reminders, removing the file if there are none
+func writeReminders(reminders []*reminder) error {
+	file, err := getConfigPath(true, remindersFile)
+	if err != nil {
+		return err
+	}
+	if len(reminders) == 0 {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(reminders, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+func addReminder(r *reminder) error {
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	reminders, err := readReminders()
+	if err != nil {
+		return err
+	}
+	return writeReminders(append(reminders, r))
+}
+
+func listReminders(w io.Writer, now time.Time) error {
+	reminders, err := readReminders()
+	if err != nil {
+		return err
+	}
+	if len(reminders) == 0 {
+		fmt.Fprintln(w, "No pending reminders")
+		return nil
+	}
+	for _, r := range reminders {
+		due := "due"
+		if r.Due.After(now) {
+			due = "in " + formatWaitTime(r.Due.Sub(now))
+		}
+		fmt.Fprintf(w, "%-10s %s  %s\n", due, r.WorkingDir, r.Text)
+	}
+	return nil
+}
+
+// takeDueReminders removes and returns the reminders due at now for workingDir
+func takeDueReminders(workingDir string, now time.Time) ([]*reminder, error) {
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	reminders, err := readReminders()
+	if err != nil || len(reminders) == 0 {
+		return nil, err
+	}
+	var due, pending []*reminder
+	for _, r := range reminders {
+		if !r.Due.After(now) && sections.IsUnderDir(workingDir, r.WorkingDir) {
+			due = append(due, r)
+		} else {
+			pending = append(pending, r)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	if err := writeReminders(pending); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// appendReminders appends the reminders due for workingDir to answer
+func appendReminders(answer string, workingDir string) string {
+	if workingDir == "" {
+		return answer
+	}
+	now := time.Now()
+	due, err := takeDueReminders(workingDir, now)
+	if err != nil {
+		Errorf("read reminders: %v", err)
+		return answer
+	}
+	for _, r := range due {
+		answer = strings.TrimRight(answer, "\n") + "\n\n(reminder set " + formatWaitTime(now.Sub(r.Created)) + " ago: " + r.Text + ")"
+	}
+	return answer
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+const replyHelp = `
+Usage:
+  whats_next reply TEXT... [--copy] [--port PORT]
+
+Answers without the editor, for short answers like "LGTM, continue".
+
+In server mode, TEXT is queued on the running server and taken by the next
+agent waiting, whatever its directory. The current directory is only the
+working dir of the answer for an agent that sent none. In native mode, the
+wrapped answer is printed right away, as if TEXT had been typed.
+
+Options:
+  --copy       Also copy the answer to the clipboard
+  --port PORT  The server port (default: serverPort or 7654)
+`
+
+func handleReply(args []string) error {
+	var copy bool
+	var port int
+	args, err := flags.Bool("--copy", &copy).
+		Int("--port", &port).
+		Help("-h,--help", replyHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	text := strings.TrimSpace(strings.Join(args, " "))
+	if text == "" {
+		return fmt.Errorf("requires TEXT")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	config, err := readEffectiveConfig(wd)
+	if err != nil {
+		return err
+	}
+	if config.Mode == ModeServer {
+		addr := getServerAddr(config.ServerBind, resolveServerPort(port, config))
+		if err := postReply(addr, text, wd); err != nil {
+			return err
+		}
+		if copy {
+			copyReply(text)
+		}
+		fmt.Fprintf(os.Stderr, "Queued on server %s\n", addr)
+		return nil
+	}
+
+	q, answer := buildAnswer(text, wd, os.Getenv(toolCountSessionEnv), 0)
+	fmt.Println(answer)
+	if copy {
+		copyReply(answer)
+	}
+	recordTranscript(ModeNative, WebhookEventInput, wd, time.Now(), q, answer)
+	fireWebhooks(WebhookEventInput, wd, q)
+	return nil
+}
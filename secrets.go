@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"golang.org/x/term"
+)
+
+// secretService is the service name secrets are stored under in the OS keychain
+const secretService = "whats_next"
+
+// secretRefPrefix marks a config value as a reference to a keychain secret,
+// e.g. "keychain:slack-token"
+const secretRefPrefix = "keychain:"
+
+var errSecretNotFound = errors.New("secret not found")
+
+// secretStore stores named secrets outside of config.json
+type secretStore interface {
+	Set(name string, value string) error
+	Get(name string) (string, error)
+	Delete(name string) error
+}
+
+// secrets is the OS keychain: macOS Keychain, secret-service or Windows Credential Manager
+var secrets secretStore = newOSSecretStore()
+
+// resolveSecret returns the secret referenced by a "keychain:NAME" value,
+// other values are returned as is
+func resolveSecret(value string) (string, error) {
+	name, ok := strings.CutPrefix(value, secretRefPrefix)
+	if !ok {
+		return value, nil
+	}
+	secret, err := secrets.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+const configSecretHelp = `
+Usage:
+  whats_next config secret set NAME
+  whats_next config secret rm NAME
+
+Stores secrets like tokens in the OS keychain instead of config.json.
+The value is read from the terminal without echo, or from stdin.
+Reference a secret in config.json as "keychain:NAME".
+`
+
+func handleConfigSecret(args []string) error {
+	args, err := flags.Help("-h,--help", configSecretHelp).Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: %s config secret set|rm NAME", GetProgramName())
+	}
+	subCmd, name := args[0], args[1]
+	switch subCmd {
+	case "set":
+		value, err := readSecretValue(os.Stdin, name)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			return fmt.Errorf("empty secret")
+		}
+		if err := secrets.Set(name, value); err != nil {
+			return err
+		}
+		fmt.Printf("Stored secret %s, reference it as %q\n", name, secretRefPrefix+name)
+		return nil
+	case "rm", "remove":
+		return secrets.Delete(name)
+	default:
+		return fmt.Errorf("unrecognized config secret command: %s", subCmd)
+	}
+}
+
+// readSecretValue prompts for the secret without echo on a terminal,
+// otherwise reads the first line of r
+func readSecretValue(r *os.File, name string) (string, error) {
+	if term.IsTerminal(int(r.Fd())) {
+		fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+		value, err := term.ReadPassword(int(r.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(value)), nil
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainStore stores secrets in the macOS Keychain through the security tool
+type keychainStore struct{}
+
+func newOSSecretStore() secretStore {
+	return keychainStore{}
+}
+
+func (keychainStore) Set(name string, value string) error {
+	// feed the command through stdin in interactive mode so that
+	// the secret does not show up in the process list
+	command := fmt.Sprintf("add-generic-password -U -s %q -a %q -X %s\n", secretService, name, hex.EncodeToString([]byte(value)))
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(command)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security add-generic-password: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (keychainStore) Get(name string) (string, error) {
+	output, err := exec.Command("security", "find-generic-password", "-s", secretService, "-a", name, "-w").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+			return "", errSecretNotFound
+		}
+		return "", fmt.Errorf("security find-generic-password: %v", err)
+	}
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+func (keychainStore) Delete(name string) error {
+	output, err := exec.Command("security", "delete-generic-password", "-s", secretService, "-a", name).CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 44 {
+			return errSecretNotFound
+		}
+		return fmt.Errorf("security delete-generic-password: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
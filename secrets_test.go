@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+type memorySecretStore map[string]string
+
+func (s memorySecretStore) Set(name string, value string) error {
+	s[name] = value
+	return nil
+}
+
+func (s memorySecretStore) Get(name string) (string, error) {
+	value, ok := s[name]
+	if !ok {
+		return "", errSecretNotFound
+	}
+	return value, nil
+}
+
+func (s memorySecretStore) Delete(name string) error {
+	delete(s, name)
+	return nil
+}
+
+func TestResolveSecret(t *testing.T) {
+	old := secrets
+	defer func() { secrets = old }()
+	secrets = memorySecretStore{"slack-token": "xoxb-123"}
+
+	value, err := resolveSecret("keychain:slack-token")
+	if err != nil || value != "xoxb-123" {
+		t.Errorf("Expected xoxb-123, got %q, err: %v", value, err)
+	}
+
+	value, err = resolveSecret("plain-value")
+	if err != nil || value != "plain-value" {
+		t.Errorf("Expected plain values to be kept, got %q, err: %v", value, err)
+	}
+
+	if _, err := resolveSecret("keychain:missing"); err == nil {
+		t.Errorf("Expected error for missing secret")
+	}
+}
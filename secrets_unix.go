@@ -0,0 +1,62 @@
+//go:build !darwin && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretToolStore stores secrets with the freedesktop secret-service
+// (GNOME Keyring, KWallet) through libsecret's secret-tool
+type secretToolStore struct{}
+
+func newOSSecretStore() secretStore {
+	return secretToolStore{}
+}
+
+func (secretToolStore) check() error {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return fmt.Errorf("secret-tool not found, install libsecret-tools to store secrets")
+	}
+	return nil
+}
+
+func (s secretToolStore) Set(name string, value string) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	cmd := exec.Command("secret-tool", "store", "--label="+secretService+" "+name, "service", secretService, "name", name)
+	cmd.Stdin = strings.NewReader(value)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool store: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (s secretToolStore) Get(name string) (string, error) {
+	if err := s.check(); err != nil {
+		return "", err
+	}
+	output, err := exec.Command("secret-tool", "lookup", "service", secretService, "name", name).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(output) == 0 {
+			return "", errSecretNotFound
+		}
+		return "", fmt.Errorf("secret-tool lookup: %v", err)
+	}
+	return string(output), nil
+}
+
+func (s secretToolStore) Delete(name string) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	if output, err := exec.Command("secret-tool", "clear", "service", secretService, "name", name).CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool clear: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -6,35 +6,35 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/protocol"
 )
 
-const (
-	SERVER_PORT = 7654
-
-	// TODO: let's propose a dialog when this is completed
-	//
-	//   osascript -e 'display dialog "Task completed!" buttons {"OK"} default button "OK" with title "Task"'
-
-	// tested: 2m works fine,
-	// tested: 3m works fine
-	// let's try 3m
-	TIMEOUT = 3 * time.Minute
-	// TIMEOUT = 1 * time.Second
-	// TIMEOUT = 5 * time.Second // for testing
-)
+// DEFAULT_SERVER_PORT is used when neither --port nor serverPort is set
+const DEFAULT_SERVER_PORT = protocol.DefaultPort
 
 func handleServer(args []string) error {
 	var logFlag bool
+	var logDir string
 	var kill bool
-	var port int = SERVER_PORT
+	var port int
+	var bind string
+	var slack bool
+	var tmux bool
+	serveArgs := withoutArg(args, "--tmux")
 	args, err := flags.
 		Bool("--log", &logFlag).
+		String("--log-dir", &logDir).
+		Bool("--slack", &slack).
+		Bool("--tmux", &tmux).
 		Bool("--kill", &kill).
 		Int("--port", &port).
+		String("--bind", &bind).
 		Parse(args)
 	if err != nil {
 		return err
@@ -44,16 +44,33 @@ func handleServer(args []string) error {
 		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
 	}
 
-	if logFlag {
-		if err := initLoggers(); err != nil {
+	if tmux && !kill && os.Getenv(tmuxPaneEnv) == "" {
+		if configDirFlag != "" {
+			serveArgs = append(serveArgs, "--config-dir", configDirFlag)
+		}
+		if logLevelFlag == "debug" {
+			serveArgs = append(serveArgs, "--debug")
+		} else if logLevelFlag != "" {
+			serveArgs = append(serveArgs, "--verbose")
+		}
+		return openTmuxPane(serveArgs)
+	}
+
+	if logFlag || logDir != "" {
+		if err := initLoggers(logDir); err != nil {
 			return err
 		}
 		defer closeLoggers()
 	}
-	serverAddr := getServerAddrWithPort(port)
+	config := readServerConfig()
+	port = resolveServerPort(port, config)
+	if bind == "" {
+		bind = config.ServerBind
+	}
+	serverAddr := getServerAddr(bind, port)
 	if kill {
 		// get to /kill and send a POST request
-		resp, err := http.Get(fmt.Sprintf("http://%s/kill", serverAddr))
+		resp, err := newServerClient(config, 0).Get(fmt.Sprintf("http://%s/kill", serverAddr))
 		if err != nil {
 			if !isAddrReachable(serverAddr) {
 				fmt.Fprintf(os.Stderr, "Server is not running\n")
@@ -74,19 +91,93 @@ func handleServer(args []string) error {
 		return nil
 	}
 
+	token, err := getServerToken(config)
+	if err != nil {
+		return err
+	}
+	if err := checkServerBind(bind, token); err != nil {
+		return err
+	}
+
 	mux := http.NewServeMux()
-	server := &http.Server{Addr: serverAddr, Handler: mux}
+	server := &http.Server{Addr: getListenAddr(bind, port), Handler: debugHTTPHandler(versionHTTPHandler(tokenHTTPHandler(token, mux)))}
 
+	replyToken := token
+	if replyToken == "" {
+		replyToken, err = generateServerToken()
+		if err != nil {
+			return err
+		}
+	}
 	h := &serveHandler{
 		httpServer: server,
+		replyToken: replyToken,
+	}
+
+	if slack {
+		h.slack, err = newSlackBridge(config.Slack)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Start the background input loop
 	h.startBackgroundInputLoop()
+	enableFilterCache()
+	go watchProfiles(h.inputCtx, profileWatchDebounce, func(file string) {
+		Logf("profile changed: %s", file)
+		invalidateFilterCache()
+		h.sendNotice("profile reloaded: " + filepath.Base(file))
+	})
+	if h.slack != nil {
+		go h.slack.run(h.inputCtx, h.enqueueInput)
+	}
 
 	// Ensure cleanup on exit
 	defer h.shutdown(context.Background())
 
+	h.registerHandlers(mux)
+
+	if err := writeServerState(port, replyToken); err != nil {
+		Errorf("write server state: %v", err)
+	}
+	defer removeServerState(port)
+
+	fmt.Printf("Starting server on %s...", server.Addr)
+	serverErr := server.ListenAndServe()
+	fireWebhooks(WebhookEventShutdown, "", "")
+	if h.isShutdownRequested() {
+		return nil
+	}
+	return serverErr
+}
+
+// registerHandlers registers the endpoints of the server on mux
+func (h *serveHandler) registerHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, pingResponse)
+	})
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		handleStatusRequest(h, w, r)
+	})
+
+	mux.HandleFunc(apiPrefix, func(w http.ResponseWriter, r *http.Request) {
+		handleAPIRequest(h, w, r)
+	})
+
+	mux.HandleFunc("/reply", func(w http.ResponseWriter, r *http.Request) {
+		handleReplyRequest(h, w, r)
+	})
+
+	mux.HandleFunc("/pause", func(w http.ResponseWriter, r *http.Request) {
+		handlePauseRequest(h, w, r, true)
+	})
+
+	mux.HandleFunc("/resume", func(w http.ResponseWriter, r *http.Request) {
+		handlePauseRequest(h, w, r, false)
+	})
+
 	mux.HandleFunc("/kill", func(w http.ResponseWriter, r *http.Request) {
 		h.requestShutdown()
 		ctx := context.Background()
@@ -98,43 +189,62 @@ func handleServer(args []string) error {
 	})
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if h.isShutdownRequested() {
+		workingDir := r.URL.Query().Get("workingDir")
+		sessionID, ok := h.acceptClient(workingDir, r.URL.Query().Get(askParam))
+		if !ok {
 			http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
 			return
 		}
-		h.notifyRequestAccepted()
-		defer h.notifyRequestFinished()
-
+		defer func() {
+			if h.finishClient(sessionID) {
+				Logf("Client request finished, shutting down server")
+				go h.shutdown(context.Background())
+			}
+		}()
 		Logf("Client connected")
-
-		idleDeadline := time.Now().Add(TIMEOUT)
+		config := readConfigOrDefault(workingDir)
+		notifyWaiting(config, workingDir)
+		playSound(config, SoundEventConnect)
+		fireWebhooks(WebhookEventConnected, workingDir, "")
+		thread := h.slack.postWaiting(workingDir)
+		defer h.slack.closeThread(thread)
+
+		idleTimeout, hardTimeout := getTimeouts(workingDir, ModeServer)
+		idleDeadline := time.Now().Add(idleTimeout)
 		h.setClientWaitDeadline(idleDeadline)
 
 		w.Header().Set("Content-Type", "text/plain")
 
-		deadline := time.Now().Add(10 * time.Minute)
+		deadline := time.Now().Add(hardTimeout)
 
 		handleRequest(h, w, r, idleDeadline, deadline)
-
-		if h.isShutdownRequested() {
-			Logf("Client request finished, shutting down server")
-			go h.shutdown(context.Background())
-		}
 	})
+}
 
-	fmt.Printf("Starting server on port %d...", port)
-	serverErr := server.ListenAndServe()
-	if h.isShutdownRequested() {
-		return nil
-	}
-	return serverErr
+// respondAway answers the agent of workingDir that the user is away until until
+func respondAway(w http.ResponseWriter, workingDir string, waitStart time.Time, until time.Time) {
+	response := getAwayResponse(readConfigOrDefault(workingDir), until)
+	Logf("paused, answer away to %s", workingDir)
+	fmt.Fprintln(w, response)
+	recordTranscript(ModeServer, WebhookEventIdle, workingDir, waitStart, "", response)
 }
 
 func handleRequest(h *serveHandler, w http.ResponseWriter, r *http.Request, idleDeadline time.Time, hardDeadline time.Time) {
 	workingDir := r.URL.Query().Get("workingDir")
+	waitStart := time.Now()
+	if clientVersion := r.URL.Query().Get(versionParam); isVersionMismatch(clientVersion) {
+		Logf("client in %s runs version %s, server runs %s", workingDir, clientVersion, getVersion())
+	}
 
 	finalWorkingDir := workingDir
 
+	// answer right away while the user is away
+	pauseSignal := h.getPauseSignal()
+	if paused, until := h.getPause(); paused {
+		respondAway(w, workingDir, waitStart, until)
+		return
+	}
+
 	// Wait for input from the background goroutine
 
 	// for the first message, wait forever
@@ -153,18 +263,37 @@ func handleRequest(h *serveHandler, w http.ResponseWriter, r *http.Request, idle
 				return
 			}
 			if msg.Exit {
-				fmt.Fprintln(w, "exit")
+				fmt.Fprintln(w, protocol.ExitResponse)
 				return
 			}
 			msgs = append(msgs, msg)
+		case <-pauseSignal:
+			_, until := h.getPause()
+			respondAway(w, workingDir, waitStart, until)
+			return
+		case <-r.Context().Done():
+			// the agent was interrupted, leave the input to the next one
+			Logf("Client disconnected while waiting for input")
+			return
 		case <-time.After(time.Until(hardDeadline)): // Timeout for client requests
-			http.Error(w, "Timeout waiting for input", http.StatusRequestTimeout)
+			http.Error(w, protocol.ErrTimeout.Error(), http.StatusRequestTimeout)
 			Logf("Client request timed out")
 			return
 		case <-time.After(time.Until(idleDeadline)):
 			if !h.hasInputContent() {
-				Logf("input idle for %v, send thinking", TIMEOUT)
-				fmt.Fprintln(w, isThinking())
+				config := readConfigOrDefault(workingDir)
+				action := getIdleAction(config, ModeServer)
+				Logf("input idle until %v, idle action: %s", idleDeadline.Format(time.TimeOnly), action)
+				response, ok := getIdleResponse(config, action)
+				if !ok {
+					// keep waiting until the hard deadline
+					idleDeadline = hardDeadline.Add(time.Minute)
+					waitForFirstMsg = true
+					continue
+				}
+				fmt.Fprintln(w, response)
+				fireWebhooks(WebhookEventIdle, workingDir, "")
+				recordTranscript(ModeServer, WebhookEventIdle, workingDir, waitStart, "", response)
 				return
 			} else {
 				waitForFirstMsg = true
@@ -183,13 +312,16 @@ func handleRequest(h *serveHandler, w http.ResponseWriter, r *http.Request, idle
 		}
 	}
 
+	h.refillInput()
+
 	Logf("Client request received %d messages", len(msgs))
+	Debugf("queue: dequeued %d messages for %q, %d left", len(msgs), workingDir, len(h.inputChan))
 
 	var contents []string
 	var errors []string
 	for _, msg := range msgs {
 		if msg.Exit {
-			fmt.Fprintln(w, "exit")
+			fmt.Fprintln(w, protocol.ExitResponse)
 			return
 		}
 		// Use the working directory from the client request if provided,
@@ -211,9 +343,21 @@ func handleRequest(h *serveHandler, w http.ResponseWriter, r *http.Request, idle
 	content := strings.Join(contents, "\n")
 	Logf("Client request content: %s", content)
 
-	if content != "" {
-		resp := wrapQuestionWithGuidelines(content, finalWorkingDir)
+	if question := r.URL.Query().Get(askParam); question != "" {
+		resp := formatAskAnswer(question, content)
+		config := readConfigOrDefault(finalWorkingDir)
+		fmt.Fprintln(w, resp)
+		playSound(config, SoundEventReply)
+		fireWebhooks(WebhookEventInput, finalWorkingDir, content)
+		recordTranscript(ModeServer, WebhookEventInput, finalWorkingDir, waitStart, content, resp)
+	} else if content != "" {
+		var resp string
+		content, resp = buildAnswer(content, finalWorkingDir, r.URL.Query().Get(toolCountSessionParam), time.Since(waitStart))
+		config := readConfigOrDefault(finalWorkingDir)
 		fmt.Fprintln(w, resp)
+		playSound(config, SoundEventReply)
+		fireWebhooks(WebhookEventInput, finalWorkingDir, content)
+		recordTranscript(ModeServer, WebhookEventInput, finalWorkingDir, waitStart, content, resp)
 	} else {
 		fmt.Fprintln(w, isThinking())
 	}
@@ -221,8 +365,43 @@ func handleRequest(h *serveHandler, w http.ResponseWriter, r *http.Request, idle
 	Logf("Client request finished")
 }
 
-func getServerAddrWithPort(port int) string {
-	return fmt.Sprintf("localhost:%d", port)
+// readServerConfig reads the config deciding the server port and bind address,
+// falling back to the defaults if it cannot be read
+func readServerConfig() *Config {
+	wd, _ := os.Getwd()
+	config, err := readEffectiveConfig(wd)
+	if err != nil {
+		return &Config{}
+	}
+	return config
+}
+
+// resolveServerPort returns port if set by a flag, then Config.ServerPort, then the default
+func resolveServerPort(port int, config *Config) int {
+	if port != 0 {
+		return port
+	}
+	if config.ServerPort != 0 {
+		return config.ServerPort
+	}
+	return DEFAULT_SERVER_PORT
+}
+
+// getListenAddr returns the address the server listens on, localhost unless bind is set
+func getListenAddr(bind string, port int) string {
+	if bind == "" {
+		bind = "localhost"
+	}
+	return net.JoinHostPort(bind, strconv.Itoa(port))
+}
+
+// getServerAddr returns the address clients connect to for a server bound to bind,
+// wildcard binds like 0.0.0.0 are reached through localhost
+func getServerAddr(bind string, port int) string {
+	if ip := net.ParseIP(bind); bind == "" || (ip != nil && ip.IsUnspecified()) {
+		bind = "localhost"
+	}
+	return net.JoinHostPort(bind, strconv.Itoa(port))
 }
 
 func isAddrReachable(addr string) bool {
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests interleave clients with the server shutting down, timing out
+// and receiving input, run them with -race.
+
+// fakeInputProgram records the calls of the server to its input
+type fakeInputProgram struct {
+	mu      sync.Mutex
+	timer   bool
+	notices []string
+	killed  bool
+}
+
+func (p *fakeInputProgram) enableTimer()  { p.mu.Lock(); p.timer = true; p.mu.Unlock() }
+func (p *fakeInputProgram) disableTimer() { p.mu.Lock(); p.timer = false; p.mu.Unlock() }
+func (p *fakeInputProgram) kill()         { p.mu.Lock(); p.killed = true; p.mu.Unlock() }
+func (p *fakeInputProgram) notice(text string) {
+	p.mu.Lock()
+	p.notices = append(p.notices, text)
+	p.mu.Unlock()
+}
+
+// newRaceTestServer starts the endpoints of the server with config.json as config,
+// the input loop is replaced by closing the input once the server shuts down
+func newRaceTestServer(t *testing.T, config string) (*serveHandler, *httptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	server := httptest.NewUnstartedServer(mux)
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &serveHandler{
+		inputChan:     make(chan InputMessage, 4),
+		inputOverflow: inputQueueDropOldest,
+		inputCtx:      ctx,
+		inputCancel:   cancel,
+		httpServer:    server.Config,
+	}
+	h.registerHandlers(mux)
+	go func() {
+		<-ctx.Done()
+		h.closeInput()
+	}()
+	server.Start()
+	t.Cleanup(func() {
+		cancel()
+		server.Close()
+	})
+	return h, server
+}
+
+// getAll runs n clients waiting for input at once, returning their status codes and bodies
+func getAll(server *httptest.Server, n int, workingDir string) (<-chan int, <-chan string) {
+	codes := make(chan int, n)
+	bodies := make(chan string, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			resp, err := http.Get(server.URL + "/?workingDir=" + url.QueryEscape(workingDir))
+			if err != nil {
+				// the server shut down before accepting the client
+				codes <- 0
+				bodies <- ""
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			codes <- resp.StatusCode
+			bodies <- string(body)
+		}()
+	}
+	return codes, bodies
+}
+
+func waitClients(t *testing.T, codes <-chan int, n int) []int {
+	t.Helper()
+	var got []int
+	timeout := time.After(10 * time.Second)
+	for len(got) < n {
+		select {
+		case code := <-codes:
+			got = append(got, code)
+		case <-timeout:
+			t.Fatalf("only %d of %d clients returned", len(got), n)
+		}
+	}
+	return got
+}
+
+func TestServeConnectShutdownInterleaving(t *testing.T) {
+	h, server := newRaceTestServer(t, `{}`)
+	program := &fakeInputProgram{}
+	h.setProgram(program)
+
+	const clients = 20
+	codes, _ := getAll(server, clients, t.TempDir())
+
+	// poke the state from every side while clients connect
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				h.getWaitingSessions()
+				h.getUserPrompt()
+				h.setInputContent(j%2 == 0)
+				h.sendNotice(fmt.Sprintf("notice %d", j))
+				if resp, err := http.Get(server.URL + "/status"); err == nil {
+					resp.Body.Close()
+				}
+			}
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	resp, err := http.Get(server.URL + "/kill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	wg.Wait()
+
+	for _, code := range waitClients(t, codes, clients) {
+		switch code {
+		case 0, http.StatusOK, http.StatusServiceUnavailable, http.StatusInternalServerError:
+		default:
+			t.Errorf("unexpected status %d of a client during shutdown", code)
+		}
+	}
+	if h.hasProcessingClient() {
+		t.Errorf("expected no client left after shutdown")
+	}
+	if sessions := h.getWaitingSessions(); len(sessions) != 0 {
+		t.Errorf("expected no waiting session after shutdown, got %d", len(sessions))
+	}
+	if _, ok := h.acceptClient("/src/api", ""); ok {
+		t.Errorf("expected a client to be refused after shutdown")
+	}
+	program.mu.Lock()
+	defer program.mu.Unlock()
+	if !program.killed {
+		t.Errorf("expected the input to be killed on shutdown")
+	}
+}
+
+func TestServeTimeoutInputInterleaving(t *testing.T) {
+	h, server := newRaceTestServer(t, `{"hardTimeout": "200ms"}`)
+
+	const clients = 20
+	const answers = 10
+	codes, bodies := getAll(server, clients, t.TempDir())
+
+	// answers arrive while clients time out
+	go func() {
+		for i := 0; i < answers; i++ {
+			h.enqueueInput(InputMessage{Content: fmt.Sprintf("answer-%d.", i)})
+			time.Sleep(15 * time.Millisecond)
+		}
+	}()
+
+	got := waitClients(t, codes, clients)
+	delivered := make(map[string]int)
+	for i, code := range got {
+		body := <-bodies
+		switch code {
+		case http.StatusOK, http.StatusRequestTimeout:
+		default:
+			t.Errorf("client %d: unexpected status %d: %s", i, code, body)
+		}
+		for j := 0; j < answers; j++ {
+			if answer := fmt.Sprintf("answer-%d.", j); strings.Contains(body, answer) {
+				delivered[answer]++
+			}
+		}
+	}
+	for answer, n := range delivered {
+		if n > 1 {
+			t.Errorf("%s delivered to %d clients", answer, n)
+		}
+	}
+	if h.hasProcessingClient() {
+		t.Errorf("expected no client left after the timeouts")
+	}
+}
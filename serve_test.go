@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerAddr(t *testing.T) {
+	tests := []struct {
+		bind       string
+		listenAddr string
+		serverAddr string
+	}{
+		{"", "localhost:7654", "localhost:7654"},
+		{"0.0.0.0", "0.0.0.0:7654", "localhost:7654"},
+		{"::", "[::]:7654", "localhost:7654"},
+		{"192.168.1.2", "192.168.1.2:7654", "192.168.1.2:7654"},
+	}
+	for _, tt := range tests {
+		if addr := getListenAddr(tt.bind, 7654); addr != tt.listenAddr {
+			t.Errorf("getListenAddr(%q): expected %s, got %s", tt.bind, tt.listenAddr, addr)
+		}
+		if addr := getServerAddr(tt.bind, 7654); addr != tt.serverAddr {
+			t.Errorf("getServerAddr(%q): expected %s, got %s", tt.bind, tt.serverAddr, addr)
+		}
+	}
+}
+
+func TestResolveServerPort(t *testing.T) {
+	if port := resolveServerPort(0, &Config{}); port != DEFAULT_SERVER_PORT {
+		t.Errorf("Expected default port, got %d", port)
+	}
+	if port := resolveServerPort(0, &Config{ServerPort: 8000}); port != 8000 {
+		t.Errorf("Expected config port 8000, got %d", port)
+	}
+	if port := resolveServerPort(9000, &Config{ServerPort: 8000}); port != 9000 {
+		t.Errorf("Expected flag port 9000, got %d", port)
+	}
+}
+
+func TestPauseReleasesWaitingAgents(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	h := &serveHandler{inputChan: make(chan InputMessage)}
+	deadline := time.Now().Add(time.Minute)
+
+	done := make(chan string)
+	go func() {
+		w := httptest.NewRecorder()
+		handleRequest(h, w, httptest.NewRequest("GET", "/?workingDir=/src/api", nil), deadline, deadline)
+		done <- w.Body.String()
+	}()
+	// let the request start waiting
+	time.Sleep(50 * time.Millisecond)
+	h.pause(time.Now().Add(30 * time.Minute))
+	select {
+	case body := <-done:
+		if !strings.Contains(body, "The user is away until") {
+			t.Errorf("Unexpected away answer: %q", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the waiting agent released on pause")
+	}
+
+	// new agents are answered right away
+	w := httptest.NewRecorder()
+	handleRequest(h, w, httptest.NewRequest("GET", "/", nil), deadline, deadline)
+	if !strings.Contains(w.Body.String(), "away") {
+		t.Errorf("Expected an away answer while paused, got %q", w.Body.String())
+	}
+
+	h.resume()
+	if paused, _ := h.getPause(); paused {
+		t.Errorf("Expected resumed")
+	}
+	h.pause(time.Now().Add(-time.Second))
+	if paused, _ := h.getPause(); paused {
+		t.Errorf("Expected the pause to end at its time")
+	}
+}
+
+func TestClientDisconnectStopsWaiting(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	h := &serveHandler{inputChan: make(chan InputMessage, 1)}
+	deadline := time.Now().Add(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan string)
+	go func() {
+		w := httptest.NewRecorder()
+		handleRequest(h, w, httptest.NewRequest("GET", "/?workingDir=/src/api", nil).WithContext(ctx), deadline, deadline)
+		done <- w.Body.String()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case body := <-done:
+		if body != "" {
+			t.Errorf("Expected no answer to a disconnected agent, got %q", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the wait to stop when the agent disconnects")
+	}
+}
+
+func TestParsePauseUntil(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		arg  string
+		want time.Time
+	}{
+		{"45m", now.Add(45 * time.Minute)},
+		{"13:30", time.Date(2026, 10, 16, 13, 30, 0, 0, time.Local)},
+		{"09:00", time.Date(2026, 10, 17, 9, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := parsePauseUntil(tt.arg, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parsePauseUntil(%q) = %v, %v, want %v", tt.arg, got, err, tt.want)
+		}
+	}
+	if _, err := parsePauseUntil("lunch", now); err == nil {
+		t.Errorf("Expected an invalid pause end to fail")
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/whats_next/pkg/protocol"
+)
+
+// pingResponse is returned by the /ping endpoint of the server,
+// used to tell our server apart from other processes on the port
+const pingResponse = protocol.PingResponse
+
+// serverState is written to servers/<port>.json while a server is running
+type serverState struct {
+	PID       int       `json:"pid"`
+	Port      int       `json:"port"`
+	StartedAt time.Time `json:"startedAt"`
+
+	// Executable is the binary the server runs, with its modification time
+	// at start, to tell servers left running after the binary was updated
+	Executable        string    `json:"executable,omitempty"`
+	ExecutableModTime time.Time `json:"executableModTime"`
+
+	// Token is required by /reply, the configured serverToken or
+	// one generated at start, see handleReplyRequest
+	Token string `json:"token,omitempty"`
+
+	// File is the path of the state file, not persisted
+	File string `json:"-"`
+}
+
+func getServerStateFile(createDir bool, port int) (string, error) {
+	serversDir, err := getConfigPath(false, "servers")
+	if err != nil {
+		return "", err
+	}
+	if createDir {
+		if err := os.MkdirAll(serversDir, 0755); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(serversDir, fmt.Sprintf("%d.json", port)), nil
+}
+
+// writeServerState records the pid and the token of the server listening on port,
+// readable only by the user since the token authorizes replies
+func writeServerState(port int, token string) error {
+	file, err := getServerStateFile(true, port)
+	if err != nil {
+		return err
+	}
+	state := serverState{
+		PID:       os.Getpid(),
+		Port:      port,
+		StartedAt: time.Now(),
+		Token:     token,
+	}
+	state.Executable, state.ExecutableModTime = getExecutableModTime()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+// readServerStateToken returns the token recorded by the server listening on port
+func readServerStateToken(port int) string {
+	file, err := getServerStateFile(false, port)
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	var state serverState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return ""
+	}
+	return state.Token
+}
+
+// removeServerState removes the state file of port if it belongs to this process
+func removeServerState(port int) {
+	file, err := getServerStateFile(false, port)
+	if err != nil {
+		return
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	var state serverState
+	if json.Unmarshal(data, &state) == nil && state.PID != os.Getpid() {
+		return
+	}
+	os.Remove(file)
+}
+
+// getExecutableModTime returns the path of the running binary and its modification time
+func getExecutableModTime() (string, time.Time) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", time.Time{}
+	}
+	stat, err := os.Stat(exe)
+	if err != nil {
+		return exe, time.Time{}
+	}
+	return exe, stat.ModTime()
+}
+
+// listServerStates returns the recorded servers, including stale ones
+func listServerStates() ([]serverState, error) {
+	serversDir, err := getConfigPath(false, "servers")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(serversDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var states []serverState
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		file := filepath.Join(serversDir, entry.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var state serverState
+		if err := json.Unmarshal(data, &state); err != nil {
+			state = serverState{}
+		}
+		state.File = file
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// isServeCommand tells whether args run a long-lived server of this program
+func isServeCommand(args []string) bool {
+	name := strings.TrimSuffix(filepath.Base(args[0]), ".exe")
+	if name != "whats_next" && name != GetProgramName() {
+		return false
+	}
+	// skip global flags before the command
+	i := 1
+	for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		if args[i] == "--config-dir" {
+			i++
+		}
+	}
+	if i >= len(args) || args[i] != "serve" {
+		return false
+	}
+	for _, arg := range args[i+1:] {
+		if arg == "--kill" || arg == "--tmux" || arg == "-h" || arg == "--help" {
+			return false
+		}
+	}
+	return true
+}
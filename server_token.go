@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/whats_next/pkg/protocol"
+)
+
+const (
+	tokenHeader = protocol.TokenHeader
+	tokenParam  = protocol.ParamToken
+)
+
+// getServerToken returns Config.ServerToken with secret references resolved
+func getServerToken(config *Config) (string, error) {
+	if config.ServerToken == "" {
+		return "", nil
+	}
+	return resolveSecret(config.ServerToken)
+}
+
+// generateServerToken returns a random token for a server without serverToken
+func generateServerToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isLoopbackBind tells whether a server bound to bind is only reachable from this machine
+func isLoopbackBind(bind string) bool {
+	if bind == "" || bind == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(bind)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkServerBind refuses to listen on a non-loopback address without a token,
+// which would let anyone on the network answer agents or kill the server
+func checkServerBind(bind string, token string) error {
+	if token != "" || isLoopbackBind(bind) {
+		return nil
+	}
+	return fmt.Errorf("refusing to bind %s without a server token, set one with: %s config set serverToken keychain:NAME", bind, GetProgramName())
+}
+
+// hasServerToken tells whether r carries token in tokenHeader or the token parameter
+func hasServerToken(r *http.Request, token string) bool {
+	got := r.Header.Get(tokenHeader)
+	if got == "" {
+		got = r.URL.Query().Get(tokenParam)
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// tokenHTTPHandler rejects the requests without token, if not empty
+func tokenHTTPHandler(token string, handler http.Handler) http.Handler {
+	if token == "" {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// CORS preflights of the API carry no custom headers
+		preflight := r.Method == http.MethodOptions && strings.HasPrefix(r.URL.Path, apiPrefix)
+		if preflight || hasServerToken(r, token) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "invalid or missing server token", http.StatusUnauthorized)
+	})
+}
+
+// tokenTransport sets the server token on every request
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(tokenHeader, t.token)
+	return t.base.RoundTrip(req)
+}
+
+// newServerClient returns a client of the server sending the configured
+// server token, timeout 0 meaning no timeout
+func newServerClient(config *Config, timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	token, err := getServerToken(config)
+	if err != nil {
+		Errorf("server token: %v", err)
+		return client
+	}
+	if token != "" {
+		client.Transport = &tokenTransport{token: token, base: http.DefaultTransport}
+	}
+	return client
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckServerBind(t *testing.T) {
+	tests := []struct {
+		bind    string
+		token   string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"localhost", "", false},
+		{"127.0.0.1", "", false},
+		{"::1", "", false},
+		{"0.0.0.0", "", true},
+		{"192.168.1.2", "", true},
+		{"0.0.0.0", "secret", false},
+	}
+	for _, tt := range tests {
+		err := checkServerBind(tt.bind, tt.token)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkServerBind(%q, %q) = %v, want error %v", tt.bind, tt.token, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTokenHTTPHandler(t *testing.T) {
+	handler := tokenHTTPHandler("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		method string
+		target string
+		header string
+		want   int
+	}{
+		{http.MethodGet, "/status", "", http.StatusUnauthorized},
+		{http.MethodGet, "/status", "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/status", "secret", http.StatusOK},
+		{http.MethodPost, "/reply?token=secret", "", http.StatusOK},
+		{http.MethodOptions, apiPrefix + "sessions", "", http.StatusOK},
+		{http.MethodOptions, "/kill", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		if tt.header != "" {
+			req.Header.Set(tokenHeader, tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with %q: got %d, want %d", tt.method, tt.target, tt.header, rec.Code, tt.want)
+		}
+	}
+}
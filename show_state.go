@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// showSessionGap is the idle time after which whats_next calls are
+// considered to belong to a new session for (show: first N),
+// unless Config.ShowSessionGap sets another
+const showSessionGap = 1 * time.Hour
+
+// maxShowStateEntries caps show_state.json, the sections seen least
+// recently are dropped first
+const maxShowStateEntries = 500
+
+// getShowSessionGap returns Config.ShowSessionGap, or showSessionGap if unset
+func getShowSessionGap(config *Config) time.Duration {
+	if config == nil {
+		return showSessionGap
+	}
+	return parseTimeout("showSessionGap", config.ShowSessionGap, showSessionGap)
+}
+
+// showState tracks how often sections with (show: ...) directives
+// were emitted, stored in show_state.json in the config dir
+type showState struct {
+	Sections map[string]*showSectionState `json:"sections"`
+}
+
+type showSectionState struct {
+	// Count is the number of times the section was shown in the current session
+	Count int `json:"count"`
+	// LastShown is the last time the section was shown
+	LastShown time.Time `json:"lastShown"`
+	// LastSeen is the last time the section was evaluated
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// readShowState reads show_state.json, returning an empty state if it does not exist
+func readShowState() (*showState, error) {
+	stateFile, err := getConfigPath(false, "show_state.json")
+	if err != nil {
+		return nil, err
+	}
+	state := &showState{}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Sections == nil {
+		state.Sections = make(map[string]*showSectionState)
+	}
+	return state, nil
+}
+
+// writeShowState writes the state to show_state.json
+func writeShowState(state *showState) error {
+	stateFile, err := getConfigPath(true, "show_state.json")
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(stateFile, data, 0644)
+}
+
+// prune drops the sections that would be shown as if never seen:
+// their session ended and they were not shown today.
+// Then it keeps the maxShowStateEntries sections seen last.
+func (s *showState) prune(now time.Time, gap time.Duration) {
+	for key, section := range s.Sections {
+		if now.Sub(section.LastSeen) > gap && !isSameDay(section.LastShown, now) {
+			delete(s.Sections, key)
+		}
+	}
+	if len(s.Sections) <= maxShowStateEntries {
+		return
+	}
+	keys := make([]string, 0, len(s.Sections))
+	for key := range s.Sections {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return s.Sections[keys[i]].LastSeen.After(s.Sections[keys[j]].LastSeen)
+	})
+	for _, key := range keys[maxShowStateEntries:] {
+		delete(s.Sections, key)
+	}
+}
+
+// allow decides whether a section with a (show: ...) directive is shown
+// for the working dir, and records the decision.
+// Supported values are "first N" and "daily", unknown values always show.
+// A new session starts once the section was not evaluated for gap.
+func (s *showState) allow(workingDir string, heading string, show string, now time.Time, gap time.Duration) bool {
+	key := workingDir + "\n" + heading
+	section := s.Sections[key]
+	if section == nil {
+		section = &showSectionState{}
+		s.Sections[key] = section
+	}
+	if now.Sub(section.LastSeen) > gap {
+		section.Count = 0
+	}
+	section.LastSeen = now
+
+	var allowed bool
+	show = strings.TrimSpace(show)
+	if n, ok := strings.CutPrefix(show, "first"); ok {
+		limit, err := strconv.Atoi(strings.TrimSpace(n))
+		allowed = err != nil || section.Count < limit
+	} else if show == "daily" {
+		allowed = !isSameDay(section.LastShown, now)
+	} else {
+		allowed = true
+	}
+
+	if allowed {
+		section.Count++
+		section.LastShown = now
+	}
+	return allowed
+}
+
+func isSameDay(a, b time.Time) bool {
+	y1, m1, d1 := a.Date()
+	y2, m2, d2 := b.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+// filterContentWithShowState filters content for the working dir, applying
+// (show: ...) directives against the persisted state, which is updated if record is set
+func filterContentWithShowState(config *Config, content string, workingDir string, isCursor bool, record bool) string {
+	cfg := matchConfig(workingDir, isCursor)
+	if !strings.Contains(content, "show:") {
+		return sections.Filter(content, workingDir, cfg)
+	}
+	if record {
+		// the state is read, updated and written back by concurrent agents
+		unlock, err := lockConfigDir()
+		if err != nil {
+			Errorf("lock show state: %v", err)
+			return sections.Filter(content, workingDir, cfg)
+		}
+		defer unlock()
+	}
+	state, err := readShowState()
+	if err != nil {
+		Errorf("read show state: %v", err)
+		return sections.Filter(content, workingDir, cfg)
+	}
+	now := time.Now()
+	gap := getShowSessionGap(config)
+	cfg.AllowShow = func(heading string, show string) bool {
+		return state.allow(workingDir, heading, show, now, gap)
+	}
+	result := sections.Filter(content, workingDir, cfg)
+	if !record {
+		return result
+	}
+	state.prune(now, gap)
+	if err := writeShowState(state); err != nil {
+		Errorf("write show state: %v", err)
+	}
+	return result
+}
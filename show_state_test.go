@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestShowStateAllow(t *testing.T) {
+	state := &showState{Sections: make(map[string]*showSectionState)}
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local)
+
+	// first 2: shown twice, then hidden within the session
+	for i, expected := range []bool{true, true, false, false} {
+		now := start.Add(time.Duration(i) * time.Minute)
+		if got := state.allow("/work", "# Reminder", "first 2", now, showSessionGap); got != expected {
+			t.Errorf("first 2 call %d: expected %v, got %v", i, expected, got)
+		}
+	}
+	// a new session starts after an idle gap
+	if !state.allow("/work", "# Reminder", "first 2", start.Add(showSessionGap+time.Hour), showSessionGap) {
+		t.Errorf("Expected section to be shown again in a new session")
+	}
+	// different dirs are tracked separately
+	if !state.allow("/other", "# Reminder", "first 2", start, showSessionGap) {
+		t.Errorf("Expected section to be shown for another dir")
+	}
+
+	// daily: once per calendar day
+	if !state.allow("/work", "# Daily", "daily", start, showSessionGap) {
+		t.Errorf("Expected daily section to be shown first time")
+	}
+	if state.allow("/work", "# Daily", "daily", start.Add(2*time.Hour), showSessionGap) {
+		t.Errorf("Expected daily section to be hidden the same day")
+	}
+	if !state.allow("/work", "# Daily", "daily", start.Add(24*time.Hour), showSessionGap) {
+		t.Errorf("Expected daily section to be shown the next day")
+	}
+}
+
+func TestShowStatePrune(t *testing.T) {
+	now := time.Date(2024, 1, 2, 9, 0, 0, 0, time.Local)
+	state := &showState{Sections: map[string]*showSectionState{
+		"/work\n# In session":      {Count: 1, LastShown: now.Add(-2 * time.Hour), LastSeen: now.Add(-time.Minute)},
+		"/work\n# Shown today":     {Count: 1, LastShown: now.Add(-2 * time.Hour), LastSeen: now.Add(-2 * time.Hour)},
+		"/work\n# Stale":           {Count: 2, LastShown: now.Add(-48 * time.Hour), LastSeen: now.Add(-48 * time.Hour)},
+		"/work\n# Seen, not shown": {Count: 0, LastSeen: now.Add(-3 * time.Hour)},
+	}}
+	state.prune(now, showSessionGap)
+	for _, key := range []string{"/work\n# In session", "/work\n# Shown today"} {
+		if state.Sections[key] == nil {
+			t.Errorf("Expected %q to be kept", key)
+		}
+	}
+	if len(state.Sections) != 2 {
+		t.Errorf("Expected 2 sections left, got %d", len(state.Sections))
+	}
+
+	for i := 0; i < maxShowStateEntries+10; i++ {
+		state.Sections[fmt.Sprintf("/dir%d\n# Reminder", i)] = &showSectionState{Count: 1, LastShown: now, LastSeen: now.Add(time.Duration(i) * time.Second)}
+	}
+	state.prune(now, showSessionGap)
+	if len(state.Sections) != maxShowStateEntries {
+		t.Errorf("Expected the state capped at %d sections, got %d", maxShowStateEntries, len(state.Sections))
+	}
+	if state.Sections["/dir0\n# Reminder"] != nil {
+		t.Errorf("Expected the section seen least recently to be dropped")
+	}
+}
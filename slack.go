@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// slackAPIURL is the base of the Slack Web API, replaced in tests
+var slackAPIURL = "https://slack.com/api"
+
+// defaultSlackPollInterval is how often waiting threads are checked for replies
+const defaultSlackPollInterval = 5 * time.Second
+
+
+// slackBridge posts to a channel when an agent is waiting, and enqueues
+// replies in the thread of that post as input
+type slackBridge struct {
+	token        string
+	channel      string
+	pollInterval time.Duration
+	client       *http.Client
+
+	mutex sync.Mutex
+	// threads are the posts of waiting agents, by ts
+	threads map[string]*slackThread
+}
+
+type slackThread struct {
+	workingDir string
+	// lastSeen is the ts of the last reply already enqueued
+	lastSeen string
+}
+
+func newSlackBridge(config *SlackConfig) (*slackBridge, error) {
+	if config == nil || config.Token == "" || config.Channel == "" {
+		return nil, fmt.Errorf("slack bridge requires slack.token and slack.channel, run `%s config` to set them", GetProgramName())
+	}
+	token, err := resolveSecret(config.Token)
+	if err != nil {
+		return nil, err
+	}
+	pollInterval := defaultSlackPollInterval
+	if config.PollInterval != "" {
+		pollInterval, err = time.ParseDuration(config.PollInterval)
+		if err != nil || pollInterval <= 0 {
+			return nil, fmt.Errorf("invalid slack.pollInterval, expect a positive duration like 5s: %s", config.PollInterval)
+		}
+	}
+	return &slackBridge{
+		token:        token,
+		channel:      config.Channel,
+		pollInterval: pollInterval,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		threads:      make(map[string]*slackThread),
+	}, nil
+}
+
+// postWaiting posts that the agent of workingDir is waiting, returning the thread ts.
+// It is a no-op on a nil bridge.
+func (b *slackBridge) postWaiting(workingDir string) string {
+	if b == nil {
+		return ""
+	}
+	project := filepath.Base(workingDir)
+	if workingDir == "" {
+		project = "agent"
+	}
+	ts, err := b.postMessage(fmt.Sprintf("%s is waiting for your input, reply in thread", project), "")
+	if err != nil {
+		Errorf("slack: %v", err)
+		return ""
+	}
+	b.mutex.Lock()
+	b.threads[ts] = &slackThread{workingDir: workingDir, lastSeen: ts}
+	b.mutex.Unlock()
+	return ts
+}
+
+// closeThread stops watching the thread once its request is finished
+func (b *slackBridge) closeThread(ts string) {
+	if b == nil || ts == "" {
+		return
+	}
+	b.mutex.Lock()
+	delete(b.threads, ts)
+	b.mutex.Unlock()
+}
+
+// run polls the open threads until ctx is done, passing replies to enqueue
+func (b *slackBridge) run(ctx context.Context, enqueue func(msg InputMessage) bool) {
+	ticker := time.NewTicker(b.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, msg := range b.pollReplies() {
+			if !enqueue(msg) {
+				Errorf("slack: input queue closed or full, reply dropped")
+				continue
+			}
+			Logf("slack reply captured and ready for clients")
+		}
+	}
+}
+
+// pollReplies returns the new replies of all open threads
+func (b *slackBridge) pollReplies() []InputMessage {
+	b.mutex.Lock()
+	threads := make(map[string]slackThread, len(b.threads))
+	for ts, thread := range b.threads {
+		threads[ts] = *thread
+	}
+	b.mutex.Unlock()
+
+	var msgs []InputMessage
+	for ts, thread := range threads {
+		replies, err := b.getReplies(ts, thread.lastSeen)
+		if err != nil {
+			Errorf("slack: %v", err)
+			continue
+		}
+		if len(replies) == 0 {
+			continue
+		}
+		for _, reply := range replies {
+			msgs = append(msgs, InputMessage{Content: reply.Text, WorkingDir: thread.workingDir})
+		}
+		last := replies[len(replies)-1].TS
+		b.mutex.Lock()
+		if t, ok := b.threads[ts]; ok {
+			t.lastSeen = last
+		}
+		b.mutex.Unlock()
+		if _, err := b.postMessage("delivered to the agent", ts); err != nil {
+			Errorf("slack: %v", err)
+		}
+	}
+	return msgs
+}
+
+type slackMessage struct {
+	TS    string `json:"ts"`
+	Text  string `json:"text"`
+	User  string `json:"user"`
+	BotID string `json:"bot_id"`
+}
+
+type slackResponse struct {
+	OK       bool           `json:"ok"`
+	Error    string         `json:"error"`
+	TS       string         `json:"ts"`
+	Messages []slackMessage `json:"messages"`
+}
+
+// getReplies returns the user replies of thread ts posted after oldest, oldest first
+func (b *slackBridge) getReplies(ts string, oldest string) ([]slackMessage, error) {
+	query := url.Values{"channel": {b.channel}, "ts": {ts}, "oldest": {oldest}}
+	req, err := http.NewRequest(http.MethodGet, slackAPIURL+"/conversations.replies?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.call(req)
+	if err != nil {
+		return nil, err
+	}
+	var replies []slackMessage
+	for _, msg := range resp.Messages {
+		// skip the post itself and our own messages
+		if msg.TS == ts || msg.TS <= oldest || msg.BotID != "" || strings.TrimSpace(msg.Text) == "" {
+			continue
+		}
+		replies = append(replies, msg)
+	}
+	return replies, nil
+}
+
+// postMessage posts text to the channel, in thread threadTS if not empty
+func (b *slackBridge) postMessage(text string, threadTS string) (string, error) {
+	body, err := json.Marshal(struct {
+		Channel  string `json:"channel"`
+		Text     string `json:"text"`
+		ThreadTS string `json:"thread_ts,omitempty"`
+	}{b.channel, text, threadTS})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, slackAPIURL+"/chat.postMessage", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := b.call(req)
+	if err != nil {
+		return "", err
+	}
+	return resp.TS, nil
+}
+
+func (b *slackBridge) call(req *http.Request) (*slackResponse, error) {
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	httpResp, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	var resp slackResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("%s: %s", req.URL.Path, httpResp.Status)
+	}
+	if !resp.OK {
+		return nil, fmt.Errorf("%s: %s", req.URL.Path, resp.Error)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlackBridgeReplies(t *testing.T) {
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			json.NewEncoder(w).Encode(slackResponse{Error: "invalid_auth"})
+			return
+		}
+		switch r.URL.Path {
+		case "/chat.postMessage":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			posted = append(posted, body["thread_ts"]+":"+body["text"])
+			json.NewEncoder(w).Encode(slackResponse{OK: true, TS: "100.000001"})
+		case "/conversations.replies":
+			json.NewEncoder(w).Encode(slackResponse{OK: true, Messages: []slackMessage{
+				{TS: "100.000001", Text: "api is waiting", BotID: "B1"},
+				{TS: "100.000002", Text: "run the tests", User: "U1"},
+				{TS: "100.000003", Text: "delivered to the agent", BotID: "B1"},
+			}})
+		}
+	}))
+	defer server.Close()
+	defer func(old string) { slackAPIURL = old }(slackAPIURL)
+	slackAPIURL = server.URL
+
+	bridge, err := newSlackBridge(&SlackConfig{Token: "xoxb-test", Channel: "C1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	thread := bridge.postWaiting("/work/api")
+	if thread != "100.000001" {
+		t.Fatalf("Expected thread 100.000001, got %q", thread)
+	}
+
+	msgs := bridge.pollReplies()
+	if len(msgs) != 1 || msgs[0].Content != "run the tests" || msgs[0].WorkingDir != "/work/api" {
+		t.Fatalf("Expected the reply of the thread, got %+v", msgs)
+	}
+	if msgs := bridge.pollReplies(); len(msgs) != 0 {
+		t.Errorf("Expected replies to be delivered once, got %+v", msgs)
+	}
+	expected := []string{":api is waiting for your input, reply in thread", "100.000001:delivered to the agent"}
+	if len(posted) != 2 || posted[0] != expected[0] || posted[1] != expected[1] {
+		t.Errorf("Expected posts %q, got %q", expected, posted)
+	}
+
+	bridge.closeThread(thread)
+	if msgs := bridge.pollReplies(); len(msgs) != 0 {
+		t.Errorf("Expected no replies of a closed thread, got %+v", msgs)
+	}
+}
+
+func TestNewSlackBridgeRequiresChannel(t *testing.T) {
+	if _, err := newSlackBridge(&SlackConfig{Token: "xoxb-test"}); err == nil {
+		t.Errorf("Expected error without a channel")
+	}
+}
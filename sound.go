@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Sound events
+const (
+	// SoundEventConnect plays when an agent starts waiting for input
+	SoundEventConnect = "connect"
+	// SoundEventReply plays when the user's reply is delivered to the agent
+	SoundEventReply = "reply"
+)
+
+var soundEvents = []string{SoundEventConnect, SoundEventReply}
+
+// soundBell rings the terminal bell instead of playing a file
+const soundBell = "bell"
+
+// playSound plays Config.Sound for event if enabled by Config.SoundEvents.
+// It does not block, failures are only logged.
+func playSound(config *Config, event string) {
+	if config == nil || config.Sound == "" {
+		return
+	}
+	if config.SoundEvents != nil && !containsFold(*config.SoundEvents, event) {
+		return
+	}
+	if config.Sound == soundBell {
+		// stdout may be read by the agent
+		fmt.Fprint(os.Stderr, "\a")
+		return
+	}
+	go func() {
+		if err := playAudioFile(config.Sound); err != nil {
+			Errorf("play sound %s: %v", config.Sound, err)
+		}
+	}()
+}
+
+// playAudioFile plays file with afplay on macOS, paplay or aplay on Linux
+// and the .NET SoundPlayer on Windows
+func playAudioFile(file string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("afplay", file)
+	case "windows":
+		script := "(New-Object Media.SoundPlayer '" + strings.ReplaceAll(file, "'", "''") + "').PlaySync()"
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	default:
+		player := "paplay"
+		if _, err := exec.LookPath(player); err != nil {
+			player = "aplay"
+		}
+		cmd = exec.Command(player, file)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v %s", cmd.Path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
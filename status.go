@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+// statusTimeout bounds the query of status, which runs on every prompt redraw
+const statusTimeout = 300 * time.Millisecond
+
+const statusHelp = `
+Usage:
+  whats_next status [--short] [--port PORT]
+
+Shows whether agents are waiting for input on the running server.
+
+--short prints one line for prompts, nothing if the server is not running:
+  ● agent waiting in api
+  ● 2 agents waiting: api, web
+  idle
+
+For tmux:
+  set -g status-right '#(whats_next status --short)'
+For starship:
+  [custom.whats_next]
+  command = "whats_next status --short"
+  when = true
+
+Options:
+  --short      Print a single line
+  --port PORT  The server port (default: serverPort or 7654)
+`
+
+// serverStatus is returned by the /status endpoint of the server
+type serverStatus struct {
+	// Waiting are the working dirs of the agents waiting for input
+	Waiting []string `json:"waiting"`
+	// Typing tells the user has started typing an answer
+	Typing bool `json:"typing"`
+	// Version is the version of the server, see getVersion
+	Version string `json:"version,omitempty"`
+	// Paused tells agents are answered that the user is away, see pause
+	Paused bool `json:"paused,omitempty"`
+	// PausedUntil is when the pause ends, zero for until resume
+	PausedUntil time.Time `json:"pausedUntil,omitempty"`
+}
+
+func handleStatus(args []string) error {
+	var short bool
+	var port int
+	args, err := flags.Bool("--short", &short).
+		Int("--port", &port).
+		Help("-h,--help", statusHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	config := readServerConfig()
+	addr := getServerAddr(config.ServerBind, resolveServerPort(port, config))
+	status, err := fetchServerStatus(addr)
+	if short {
+		// prompts show nothing rather than an error
+		if err == nil {
+			fmt.Println(formatShortStatus(status))
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("server %s is not running: %v", addr, err)
+	}
+	fmt.Printf("Server %s is running\n", addr)
+	warnServerVersion(status.Version)
+	if status.Paused {
+		if status.PausedUntil.IsZero() {
+			fmt.Println("Paused until resume")
+		} else {
+			fmt.Printf("Paused until %s\n", status.PausedUntil.Local().Format("15:04"))
+		}
+	}
+	if len(status.Waiting) == 0 {
+		fmt.Println("No agent waiting")
+		return nil
+	}
+	fmt.Printf("%d agent(s) waiting:\n", len(status.Waiting))
+	for _, dir := range status.Waiting {
+		if dir == "" {
+			dir = "(unknown dir)"
+		}
+		fmt.Printf("  %s\n", dir)
+	}
+	if status.Typing {
+		fmt.Println("An answer is being typed")
+	}
+	return nil
+}
+
+func fetchServerStatus(addr string) (*serverStatus, error) {
+	client := newServerClient(readServerConfig(), statusTimeout)
+	resp, err := client.Get(fmt.Sprintf("http://%s/status", addr))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status: %s", resp.Status)
+	}
+	var status serverStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// formatShortStatus renders status as the single line of status --short
+func formatShortStatus(status *serverStatus) string {
+	if status.Paused {
+		return "paused"
+	}
+	if len(status.Waiting) == 0 {
+		return "idle"
+	}
+	var projects []string
+	for _, dir := range status.Waiting {
+		if dir == "" {
+			continue
+		}
+		projects = append(projects, filepath.Base(dir))
+	}
+	if len(status.Waiting) == 1 {
+		if len(projects) == 0 {
+			return "● agent waiting"
+		}
+		return "● agent waiting in " + projects[0]
+	}
+	line := fmt.Sprintf("● %d agents waiting", len(status.Waiting))
+	if len(projects) > 0 {
+		line += ": " + strings.Join(projects, ", ")
+	}
+	return line
+}
+
+// handleStatusRequest serves GET /status
+func handleStatusRequest(h *serveHandler, w http.ResponseWriter, r *http.Request) {
+	status := &serverStatus{
+		Waiting: h.getWaitingDirs(),
+		Typing:  h.hasInputContent(),
+		Version: getVersion(),
+	}
+	status.Paused, status.PausedUntil = h.getPause()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
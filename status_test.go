@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFormatShortStatus(t *testing.T) {
+	tests := []struct {
+		waiting []string
+		want    string
+	}{
+		{nil, "idle"},
+		{[]string{"/src/api"}, "● agent waiting in api"},
+		{[]string{""}, "● agent waiting"},
+		{[]string{"/src/api", "/src/web"}, "● 2 agents waiting: api, web"},
+	}
+	for _, tt := range tests {
+		if got := formatShortStatus(&serverStatus{Waiting: tt.waiting}); got != tt.want {
+			t.Errorf("formatShortStatus(%q) = %q, want %q", tt.waiting, got, tt.want)
+		}
+	}
+}
+
+func TestServerStatusEndpoint(t *testing.T) {
+	h := &serveHandler{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleStatusRequest(h, w, r)
+	}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	status, err := fetchServerStatus(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Waiting) != 0 {
+		t.Errorf("expect no waiting agent, got %q", status.Waiting)
+	}
+
+	web := h.addWaiting("/src/web")
+	h.addWaiting("/src/api")
+	h.addWaiting("/src/api")
+	h.removeWaiting(web)
+	status, err = fetchServerStatus(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := formatShortStatus(status); got != "● 2 agents waiting: api, api" {
+		t.Errorf("unexpected status: %q", got)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// syncConflictBackupDir is the directory under the config dir keeping
+// the files replaced when merging sync conflicts
+const syncConflictBackupDir = "conflicts"
+
+// syncConflictStampFormat prefixes the backups with the time of the merge
+const syncConflictStampFormat = "20060102-150405"
+
+// syncConflictPatterns match the copies cloud sync tools create on concurrent
+// writes, the submatches are the name and extension of the original file
+var syncConflictPatterns = []*regexp.Regexp{
+	// Dropbox: "config (John's conflicted copy 2024-01-02).json"
+	regexp.MustCompile(`^(.+) \([^()]*conflicted copy[^()]*\)(\.[^.]+)$`),
+	// Syncthing: "config.sync-conflict-20240102-150405-ABCDEFG.json"
+	regexp.MustCompile(`^(.+)\.sync-conflict-\d{8}-\d{6}(?:-[A-Z0-9]+)?(\.[^.]+)$`),
+	// iCloud: "config 2.json", only for config.json since "notes 2.md" may be a profile
+	regexp.MustCompile(`^(config) \d+(\.json)$`),
+}
+
+// syncConflictOriginal returns the name of the file a conflicted copy was made of
+func syncConflictOriginal(name string) (string, bool) {
+	for _, pattern := range syncConflictPatterns {
+		if m := pattern.FindStringSubmatch(name); m != nil {
+			if m[2] != ".json" && m[2] != ".md" {
+				return "", false
+			}
+			return m[1] + m[2], true
+		}
+	}
+	return "", false
+}
+
+// findSyncConflicts returns the conflicted copies in dir keyed by their original file
+func findSyncConflicts(dir string) (map[string][]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var conflicts map[string][]string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		original, ok := syncConflictOriginal(entry.Name())
+		if !ok {
+			continue
+		}
+		if conflicts == nil {
+			conflicts = make(map[string][]string)
+		}
+		file := filepath.Join(dir, original)
+		conflicts[file] = append(conflicts[file], filepath.Join(dir, entry.Name()))
+	}
+	return conflicts, nil
+}
+
+// syncConflictDirs are the directories holding files that may be synced:
+// config.json and custom.md in the config dir, and the profiles
+func syncConflictDirs() ([]string, error) {
+	configDir, err := getConfigDir(false)
+	if err != nil {
+		return nil, err
+	}
+	return []string{configDir, filepath.Join(configDir, "group")}, nil
+}
+
+// resolveSyncConflicts merges the conflicted copies of config.json and profiles
+// left by Dropbox, iCloud or Syncthing: the most recently modified copy wins,
+// the others are moved to the conflicts dir. It returns what was done.
+func resolveSyncConflicts() ([]string, error) {
+	dirs, err := syncConflictDirs()
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, dir := range dirs {
+		conflicts, err := findSyncConflicts(dir)
+		if err != nil {
+			return messages, err
+		}
+		originals := make([]string, 0, len(conflicts))
+		for original := range conflicts {
+			originals = append(originals, original)
+		}
+		sort.Strings(originals)
+		for _, original := range originals {
+			message, err := mergeSyncConflict(original, conflicts[original])
+			if err != nil {
+				return messages, err
+			}
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// mergeSyncConflict keeps the newest of original and its conflicted copies
+// as original, backing up the others
+func mergeSyncConflict(original string, copies []string) (string, error) {
+	winner := original
+	var winnerTime time.Time
+	if stat, err := os.Stat(original); err == nil {
+		winnerTime = stat.ModTime()
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	for _, file := range copies {
+		stat, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		if stat.ModTime().After(winnerTime) {
+			winner, winnerTime = file, stat.ModTime()
+		}
+	}
+
+	backupDir, err := getConfigPath(false, syncConflictBackupDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", err
+	}
+	stamp := time.Now().Format(syncConflictStampFormat)
+	backup := func(file string) error {
+		return os.Rename(file, filepath.Join(backupDir, stamp+"-"+filepath.Base(file)))
+	}
+
+	if winner != original {
+		if _, err := os.Stat(original); err == nil {
+			if err := backup(original); err != nil {
+				return "", err
+			}
+		}
+		if err := os.Rename(winner, original); err != nil {
+			return "", err
+		}
+	}
+	for _, file := range copies {
+		if file == winner {
+			continue
+		}
+		if err := backup(file); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("merged %d conflicted cop%s of %s, kept %s, others moved to %s",
+		len(copies), pluralY(len(copies)), original, filepath.Base(winner), backupDir), nil
+}
+
+func pluralY(n int) string {
+	if n == 1 {
+		return "y"
+	}
+	return "ies"
+}
+
+// syncConflictCommands are the commands resolving sync conflicts before they run:
+// doctor and those writing config.json or profiles. The others, including
+// the agent path, leave the conflicts to them.
+var syncConflictCommands = map[string]bool{
+	"doctor":    true,
+	"use":       true,
+	"edit":      true,
+	"add":       true,
+	"rm":        true,
+	"group":     true,
+	"config":    true,
+	"init":      true,
+	"backup":    true,
+	"restore":   true,
+	"telemetry": true,
+}
+
+// hasSyncConflicts tells whether any conflicted copy is waiting to be merged
+func hasSyncConflicts() bool {
+	dirs, err := syncConflictDirs()
+	if err != nil {
+		return false
+	}
+	for _, dir := range dirs {
+		if conflicts, _ := findSyncConflicts(dir); len(conflicts) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeSyncConflictsOnStart resolves sync conflicts under the config lock
+// before running one of syncConflictCommands,
+// reporting on stderr since stdout may be read by the agent
+func mergeSyncConflictsOnStart(args []string) {
+	if len(args) == 0 || !syncConflictCommands[args[0]] || !hasSyncConflicts() {
+		return
+	}
+	unlock, err := lockConfigDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: merge sync conflicts: %v\n", err)
+		Errorf("merge sync conflicts: %v", err)
+		return
+	}
+	defer unlock()
+	messages, err := resolveSyncConflicts()
+	for _, message := range messages {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", message)
+		Logf("%s", message)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: merge sync conflicts: %v\n", err)
+		Errorf("merge sync conflicts: %v", err)
+	}
+}
+
+// checkSyncConflicts reports conflicted copies that could not be merged,
+// and the backups of recent merges
+func checkSyncConflicts() doctorCheck {
+	dirs, err := syncConflictDirs()
+	if err != nil {
+		return doctorCheck{Name: "sync conflicts", Status: doctorFail, Message: err.Error()}
+	}
+	var unresolved []string
+	for _, dir := range dirs {
+		conflicts, err := findSyncConflicts(dir)
+		if err != nil {
+			return doctorCheck{Name: "sync conflicts", Status: doctorFail, Message: err.Error()}
+		}
+		for _, copies := range conflicts {
+			unresolved = append(unresolved, copies...)
+		}
+	}
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return doctorCheck{
+			Name:    "sync conflicts",
+			Status:  doctorWarn,
+			Message: "conflicted copies: " + strings.Join(unresolved, ", "),
+			Fix:     "keep the right version and remove the others",
+		}
+	}
+
+	backupDir, err := getConfigPath(false, syncConflictBackupDir)
+	if err != nil {
+		return doctorCheck{Name: "sync conflicts", Status: doctorFail, Message: err.Error()}
+	}
+	entries, _ := os.ReadDir(backupDir)
+	var recent int
+	for _, entry := range entries {
+		// backups are named after the time of the merge, their mtime is the one of the copy
+		if len(entry.Name()) < len(syncConflictStampFormat) {
+			continue
+		}
+		mergedAt, err := time.ParseInLocation(syncConflictStampFormat, entry.Name()[:len(syncConflictStampFormat)], time.Local)
+		if err == nil && time.Since(mergedAt) < 7*24*time.Hour {
+			recent++
+		}
+	}
+	if recent > 0 {
+		return doctorCheck{
+			Name:    "sync conflicts",
+			Status:  doctorWarn,
+			Message: fmt.Sprintf("%d conflicted file(s) merged in the last 7 days, the config dir may be written by several machines at once", recent),
+			Fix:     "check the replaced versions in " + backupDir,
+		}
+	}
+	return doctorCheck{Name: "sync conflicts", Status: doctorOK, Message: "none"}
+}
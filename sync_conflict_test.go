@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncConflictOriginal(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		ok       bool
+	}{
+		{"config (John's conflicted copy 2024-01-02).json", "config.json", true},
+		{"work (MacBook's conflicted copy 2024-01-02 (1)).md", "", false},
+		{"work (MacBook's conflicted copy 2024-01-02).md", "work.md", true},
+		{"config.sync-conflict-20240102-150405-ABCDEFG.json", "config.json", true},
+		{"custom.sync-conflict-20240102-150405.md", "custom.md", true},
+		{"config 2.json", "config.json", true},
+		{"notes 2.md", "", false},
+		{"config.json", "", false},
+		{"log (conflicted copy).txt", "", false},
+	}
+	for _, tt := range tests {
+		original, ok := syncConflictOriginal(tt.name)
+		if original != tt.original || ok != tt.ok {
+			t.Errorf("syncConflictOriginal(%q) = %q, %v, want %q, %v", tt.name, original, ok, tt.original, tt.ok)
+		}
+	}
+}
+
+func TestResolveSyncConflicts(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	groupDir := filepath.Join(dir, "group")
+	if err := os.MkdirAll(groupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	write := func(file string, content string, age time.Duration) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the copy is newer than config.json, the original is newer than the profile copy
+	write(filepath.Join(dir, "config.json"), `{"editor":"vi"}`, time.Hour)
+	write(filepath.Join(dir, "config (laptop's conflicted copy 2024-01-02).json"), `{"editor":"code"}`, time.Minute)
+	write(filepath.Join(groupDir, "work.md"), "new", time.Minute)
+	write(filepath.Join(groupDir, "work.sync-conflict-20240102-150405-ABC.md"), "old", time.Hour)
+
+	check := checkSyncConflicts()
+	if check.Status != doctorWarn {
+		t.Fatalf("expect doctor warning before merge, got %+v", check)
+	}
+
+	mergeSyncConflictsOnStart([]string{"show"})
+	if !hasSyncConflicts() {
+		t.Fatalf("expect show to leave the conflicts to the mutating commands")
+	}
+
+	messages, err := resolveSyncConflicts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expect 2 messages, got %q", messages)
+	}
+	for file, want := range map[string]string{
+		filepath.Join(dir, "config.json"):  `{"editor":"code"}`,
+		filepath.Join(groupDir, "work.md"): "new",
+	} {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != want {
+			t.Errorf("%s: expect %q, got %q", file, want, content)
+		}
+	}
+	backups, err := os.ReadDir(filepath.Join(dir, syncConflictBackupDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Errorf("expect the replaced config.json and the profile copy backed up, got %d files", len(backups))
+	}
+	if conflicts, _ := findSyncConflicts(groupDir); len(conflicts) != 0 {
+		t.Errorf("expect no conflict left, got %v", conflicts)
+	}
+
+	check = checkSyncConflicts()
+	if check.Status != doctorWarn || check.Fix == "" {
+		t.Errorf("expect doctor to report the recent merge, got %+v", check)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+// telemetryFile is the file under the config dir holding the usage counts.
+// It is created by `telemetry on` and removed by `telemetry off`, so that
+// commands only read the config to count features while telemetry is on.
+const telemetryFile = "telemetry.json"
+
+const telemetryHelp = `
+Usage:
+  whats_next telemetry on|off|status
+
+Telemetry is off unless turned on. When on, it counts which commands are run
+and which features are enabled in the config, never arguments, paths or content.
+
+The counts stay in telemetry.json under the config dir, nothing is sent over
+the network. status prints them, e.g. to share them in an issue.
+
+  on       Turn telemetry on with a new random id
+  off      Turn telemetry off and delete the counts
+  status   Show whether telemetry is on and the counts that would be sent
+`
+
+// telemetryState is the content of telemetryFile, and what is sent
+type telemetryState struct {
+	// ID is random, only telling reports of the same installation apart
+	ID       string         `json:"id"`
+	Since    time.Time      `json:"since"`
+	OS       string         `json:"os"`
+	Arch     string         `json:"arch"`
+	Commands map[string]int `json:"commands"`
+	Features map[string]int `json:"features"`
+}
+
+func handleTelemetry(args []string) error {
+	args, err := flags.Help("-h,--help", telemetryHelp).Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("requires: on, off or status")
+	}
+	switch args[0] {
+	case "on", "off":
+		unlock, err := lockConfigDir()
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		config, err := readConfig()
+		if err != nil {
+			return err
+		}
+		config.Telemetry = args[0] == "on"
+		if err := writeConfig(config); err != nil {
+			return err
+		}
+		file, err := getConfigPath(false, telemetryFile)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if config.Telemetry {
+			state, err := readTelemetryState()
+			if err != nil {
+				return err
+			}
+			if err := writeTelemetryState(state); err != nil {
+				return err
+			}
+			fmt.Println("Telemetry is on, thank you. Turn it off with: " + GetProgramName() + " telemetry off")
+		} else {
+			fmt.Println("Telemetry is off, collected counts deleted")
+		}
+		return nil
+	case "status":
+		config, err := readConfig()
+		if err != nil {
+			return err
+		}
+		if !config.Telemetry {
+			fmt.Println("Telemetry is off")
+			return nil
+		}
+		fmt.Println("Telemetry is on, counts are only kept locally")
+		state, err := readTelemetryState()
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	return fmt.Errorf("unrecognized telemetry command: %s", args[0])
+}
+
+// recordTelemetry counts the command of args and the enabled features
+// if telemetry is on. Failures are only logged.
+func recordTelemetry(args []string) {
+	file, err := getConfigPath(false, telemetryFile)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(file); err != nil {
+		return
+	}
+	config, err := readConfig()
+	if err != nil || !config.Telemetry {
+		return
+	}
+	state, err := readTelemetryState()
+	if err != nil {
+		Errorf("telemetry: %v", err)
+		return
+	}
+	state.Commands[telemetryCommand(args)]++
+	for _, feature := range telemetryFeatures(config) {
+		state.Features[feature]++
+	}
+	if err := writeTelemetryState(state); err != nil {
+		Errorf("telemetry: %v", err)
+	}
+}
+
+// telemetryCommand returns the command of args, with the sub command of
+// commands having them. Anything else is never recorded.
+func telemetryCommand(args []string) string {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "(default)"
+	}
+	for _, cmd := range completionCommands {
+		if cmd.name != args[0] {
+			continue
+		}
+		if len(args) > 1 {
+			for _, sub := range cmd.subCommands {
+				if sub.name == args[1] {
+					return cmd.name + " " + sub.name
+				}
+			}
+		}
+		return cmd.name
+	}
+	return "(other)"
+}
+
+// telemetryFeatures lists the features enabled in config, by name only
+func telemetryFeatures(config *Config) []string {
+	var features []string
+	add := func(enabled bool, name string) {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+	add(config.Mode == ModeServer, "mode:server")
+	add(config.SelectedProfile != "", "profile")
+	add(config.Notify, "notify")
+	add(config.Sound != "", "sound")
+	add(len(config.Webhooks) > 0, "webhooks")
+	add(config.Slack != nil, "slack")
+	add(config.Digest != nil, "digest")
+	add(config.GistID != "", "gist")
+	add(len(config.Hosts) > 0, "hosts")
+	add(config.ReplyLanguage != "", "replyLanguage")
+	add(config.AnswerTemplate != "", "answerTemplate")
+	add(config.WaitTimeMetadata, "waitTimeMetadata")
+	add(config.Editor != "", "editor")
+	add(len(config.CommandAliases) > 0, "commandAliases")
+	sort.Strings(features)
+	return features
+}
+
+func newTelemetryState(id string) *telemetryState {
+	return &telemetryState{
+		ID:       id,
+		Since:    time.Now(),
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Commands: make(map[string]int),
+		Features: make(map[string]int),
+	}
+}
+
+func readTelemetryState() (*telemetryState, error) {
+	file, err := getConfigPath(false, telemetryFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		return newTelemetryState(hex.EncodeToString(id)), nil
+	}
+	var state telemetryState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	if state.Commands == nil {
+		state.Commands = make(map[string]int)
+	}
+	if state.Features == nil {
+		state.Features = make(map[string]int)
+	}
+	return &state, nil
+}
+
+func writeTelemetryState(state *telemetryState) error {
+	file, err := getConfigPath(true, telemetryFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTelemetryCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "(default)"},
+		{[]string{"--copy"}, "(default)"},
+		{[]string{"history", "search", "secret plan"}, "history search"},
+		{[]string{"group", "show", "work"}, "group show"},
+		{[]string{"edit", "work"}, "edit"},
+		{[]string{"my-private-words"}, "(other)"},
+	}
+	for _, tt := range tests {
+		if got := telemetryCommand(tt.args); got != tt.want {
+			t.Errorf("telemetryCommand(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRecordTelemetry(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", dir)
+	file := filepath.Join(dir, telemetryFile)
+
+	// off by default
+	recordTelemetry([]string{"show"})
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("expect nothing recorded while telemetry is off")
+	}
+
+	if err := handleTelemetry([]string{"on"}); err != nil {
+		t.Fatal(err)
+	}
+	recordTelemetry([]string{"show"})
+	recordTelemetry([]string{"show"})
+	state, err := readTelemetryState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Commands["show"] != 2 || state.ID == "" {
+		t.Errorf("unexpected counts: %+v", state)
+	}
+
+	config, err := readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Notify = true
+	if err := writeConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	recordTelemetry([]string{"status", "--short"})
+	state, err = readTelemetryState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Commands["show"] != 2 || state.Commands["status"] != 1 || state.Features["notify"] != 1 {
+		t.Errorf("unexpected counts: %+v", state)
+	}
+
+	// turning telemetry off in config.json by hand stops the counting
+	config.Telemetry = false
+	if err := writeConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	recordTelemetry([]string{"show"})
+	state, err = readTelemetryState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Commands["show"] != 2 {
+		t.Errorf("expect nothing recorded while telemetry is off in the config: %+v", state)
+	}
+
+	if err := handleTelemetry([]string{"off"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expect counts deleted by telemetry off")
+	}
+}
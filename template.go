@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is the data available to section bodies, e.g.
+// "{{.Cwd}}", "{{.Branch}}", "{{.Date}}" and "{{.Profile}}"
+type templateData struct {
+	Cwd     string
+	Date    string
+	Profile string
+}
+
+func newTemplateData(workingDir string, profile string) *templateData {
+	return &templateData{
+		Cwd:     workingDir,
+		Date:    time.Now().Format("2006-01-02"),
+		Profile: profile,
+	}
+}
+
+// Branch returns the current git branch of the working dir,
+// it is only computed when a template references it
+func (d *templateData) Branch() string {
+	branch, err := getGitBranch(d.Cwd)
+	if err != nil {
+		return ""
+	}
+	return branch
+}
+
+// expandTemplates expands Go text/template expressions in content outside code blocks.
+// Each text segment between code blocks is expanded independently, and a segment
+// that fails to parse or execute is kept as is, so unrelated "{{" in a profile
+// does not break rendering.
+func expandTemplates(content string, data *templateData) string {
+	if !strings.Contains(content, "{{") {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	var result []string
+	var segment []string
+	var inCodeBlock bool
+
+	flush := func() {
+		if len(segment) == 0 {
+			return
+		}
+		result = append(result, expandTemplateSegment(strings.Join(segment, "\n"), data))
+		segment = nil
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if !inCodeBlock {
+				flush()
+			}
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+		if inCodeBlock {
+			result = append(result, line)
+			continue
+		}
+		segment = append(segment, line)
+	}
+	flush()
+	return strings.Join(result, "\n")
+}
+
+func expandTemplateSegment(segment string, data *templateData) string {
+	if !strings.Contains(segment, "{{") {
+		return segment
+	}
+	tmpl, err := template.New("section").Option("missingkey=error").Parse(segment)
+	if err != nil {
+		return segment
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return segment
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestExpandTemplates(t *testing.T) {
+	data := &templateData{Cwd: "/work/api", Date: "2024-01-02", Profile: "backend"}
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "no template",
+			content:  "# Title\nContent",
+			expected: "# Title\nContent",
+		},
+		{
+			name:     "fields",
+			content:  "# Title\nYou are in {{.Cwd}} on {{.Date}} using {{.Profile}}",
+			expected: "# Title\nYou are in /work/api on 2024-01-02 using backend",
+		},
+		{
+			name:     "code block untouched",
+			content:  "In {{.Cwd}}\n```yaml\nvalue: {{ .Values.name }}\n```\nDone {{.Profile}}",
+			expected: "In /work/api\n```yaml\nvalue: {{ .Values.name }}\n```\nDone backend",
+		},
+		{
+			name:     "invalid template kept",
+			content:  "Use {{ unknownFunc }} in {{.Cwd}}",
+			expected: "Use {{ unknownFunc }} in {{.Cwd}}",
+		},
+		{
+			name:     "unknown field kept",
+			content:  "Use {{.Unknown}}",
+			expected: "Use {{.Unknown}}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := expandTemplates(tt.content, data)
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\n\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// the default timeouts of both native and server mode, see getTimeouts
+const (
+	// tested: 2m works fine,
+	// tested: 3m works fine
+	// let's try 3m
+	TIMEOUT = 3 * time.Minute
+	// TIMEOUT = 1 * time.Second
+	// TIMEOUT = 5 * time.Second // for testing
+
+	// HARD_TIMEOUT is the longest a single client request waits for input
+	HARD_TIMEOUT = 10 * time.Minute
+)
+
+// getTimeouts returns the idle timeout of mode, after which the agent is told to keep
+// thinking, and the hard timeout of a single server request.
+// The idle timeout of the mode wins over the shared idleTimeout, and
+// WHATS_NEXT_TIMEOUT overrides both. Invalid or non-positive durations
+// fall back to the defaults.
+func getTimeouts(workingDir string, mode Mode) (idle time.Duration, hard time.Duration) {
+	idle, hard = TIMEOUT, HARD_TIMEOUT
+	config, err := readEffectiveConfig(workingDir)
+	if err != nil {
+		Errorf("read config: %v", err)
+		config = &Config{}
+	}
+	idle = parseTimeout("idleTimeout", config.IdleTimeout, idle)
+	switch mode {
+	case ModeServer:
+		idle = parseTimeout("serverIdleTimeout", config.ServerIdleTimeout, idle)
+	default:
+		idle = parseTimeout("nativeIdleTimeout", config.NativeIdleTimeout, idle)
+	}
+	hard = parseTimeout("hardTimeout", config.HardTimeout, hard)
+	idle = parseTimeout("WHATS_NEXT_TIMEOUT", os.Getenv("WHATS_NEXT_TIMEOUT"), idle)
+	return idle, hard
+}
+
+// parseTimeout parses value as a Go duration like "5m", returning def if it is empty or invalid
+func parseTimeout(name string, value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		Errorf("invalid %s %q, using %v", name, value, def)
+		return def
+	}
+	return d
+}
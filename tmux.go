@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// tmuxPaneEnv is set in the pane opened by `serve --tmux`, where the server runs
+const tmuxPaneEnv = "WHATS_NEXT_TMUX_PANE"
+
+// tmuxPaneTitle names the pane opened by `serve --tmux` to find it again
+const tmuxPaneTitle = "whats_next"
+
+// openTmuxPane runs `serve` with serveArgs in a dedicated pane below the current one,
+// leaving the launching pane free. When the server exits the pane closes and the
+// window layout from before the split is restored.
+// If the pane is already open, it is focused instead.
+func openTmuxPane(serveArgs []string) error {
+	if os.Getenv("TMUX") == "" {
+		return fmt.Errorf("--tmux requires running inside tmux")
+	}
+	if paneID, err := findTmuxPane(); err != nil {
+		return err
+	} else if paneID != "" {
+		if _, err := runTmux("select-window", "-t", paneID); err != nil {
+			return err
+		}
+		if _, err := runTmux("select-pane", "-t", paneID); err != nil {
+			return err
+		}
+		fmt.Printf("Server pane %s is already open\n", paneID)
+		return nil
+	}
+
+	window, err := runTmux("display-message", "-p", "#{window_id}\t#{window_layout}")
+	if err != nil {
+		return err
+	}
+	windowID, layout, _ := strings.Cut(window, "\t")
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	command := tmuxPaneEnv + "=1 " + shellJoin(append([]string{exe, "serve"}, serveArgs...))
+	if layout != "" {
+		// restore the layout once the pane is gone, from the tmux server
+		restore := "sleep 0.2; tmux select-layout -t " + shellQuote(windowID) + " " + shellQuote(layout)
+		command += "; tmux run-shell -b " + shellQuote(restore)
+	}
+	wd, _ := os.Getwd()
+	paneID, err := runTmux("split-window", "-v", "-l", "30%", "-c", wd, "-P", "-F", "#{pane_id}", command)
+	if err != nil {
+		return err
+	}
+	if _, err := runTmux("select-pane", "-t", paneID, "-T", tmuxPaneTitle); err != nil {
+		return err
+	}
+	return nil
+}
+
+// findTmuxPane returns the id of the pane opened by a previous `serve --tmux`, if any
+func findTmuxPane() (string, error) {
+	panes, err := runTmux("list-panes", "-a", "-F", "#{pane_id}\t#{pane_title}")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(panes, "\n") {
+		paneID, title, _ := strings.Cut(line, "\t")
+		if title == tmuxPaneTitle {
+			return paneID, nil
+		}
+	}
+	return "", nil
+}
+
+func runTmux(args ...string) (string, error) {
+	output, err := exec.Command("tmux", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("tmux %s: %v %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// withoutArg returns args without any occurrence of arg
+func withoutArg(args []string, arg string) []string {
+	var rest []string
+	for _, a := range args {
+		if a != arg {
+			rest = append(rest, a)
+		}
+	}
+	return rest
+}
+
+// shellJoin quotes args for a POSIX shell command line
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes s for a POSIX shell, leaving simple words as is
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:@%+,", r))
+	}) == -1 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestShellJoin(t *testing.T) {
+	got := shellJoin([]string{"/usr/bin/whats_next", "serve", "--config-dir", "/tmp/my dir", "it's", ""})
+	expected := `/usr/bin/whats_next serve --config-dir '/tmp/my dir' 'it'\''s' ''`
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestOpenTmuxPaneOutsideTmux(t *testing.T) {
+	t.Setenv("TMUX", "")
+	if err := openTmuxPane(nil); err == nil {
+		t.Errorf("Expected error outside tmux")
+	}
+}
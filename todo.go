@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/sections"
+)
+
+// todosFile in the config dir holds the todo items
+const todosFile = "todos.json"
+
+// todoCommandPrefix starts a line of input added as a todo item instead of being sent
+const todoCommandPrefix = "/todo "
+
+const todoHelp = `
+Usage:
+  whats_next todo add TEXT [--dir DIR | --global]
+  whats_next todo list [--all]
+  whats_next todo done ID...
+
+Tracks multi-step work: the open items of a directory are appended to every
+answer sent to an agent in it or below, under a "Pending tasks" heading.
+
+add   adds an item for DIR (default: the current directory), or for all agents with --global
+list  lists the open items of the current directory, or all items with --all
+done  marks the items done, the agent can run it too
+
+While typing a reply, a line "/todo TEXT" adds an item instead of being sent,
+for the directory of the agent in native mode, for all agents in server mode.
+`
+
+// todoItem is a task kept visible to the agent until done
+type todoItem struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+	// WorkingDir scopes the item to agents in it or below, empty for all agents
+	WorkingDir string    `json:"workingDir,omitempty"`
+	Created    time.Time `json:"created"`
+	Done       time.Time `json:"done,omitempty"`
+}
+
+func (t *todoItem) isDone() bool {
+	return !t.Done.IsZero()
+}
+
+// appliesTo tells whether the item is shown to an agent in workingDir
+func (t *todoItem) appliesTo(workingDir string) bool {
+	return t.WorkingDir == "" || sections.IsUnderDir(workingDir, t.WorkingDir)
+}
+
+func handleTodo(args []string) error {
+	var dir string
+	var global bool
+	var all bool
+	args, err := flags.String("--dir", &dir).
+		Bool("--global", &global).
+		Bool("--all", &all).
+		Help("-h,--help", todoHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires: add, list or done")
+	}
+	cmd, args := args[0], args[1:]
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "add":
+		text := strings.TrimSpace(strings.Join(args, " "))
+		if text == "" {
+			return fmt.Errorf("requires TEXT")
+		}
+		if dir != "" && global {
+			return fmt.Errorf("--dir and --global cannot be used together")
+		}
+		if global {
+			wd = ""
+		} else if dir != "" {
+			if wd, err = filepath.Abs(dir); err != nil {
+				return err
+			}
+		}
+		item, err := addTodo(wd, text)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("added todo #%d\n", item.ID)
+		return nil
+	case "list", "ls":
+		if len(args) > 0 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+		}
+		return listTodos(os.Stdout, wd, all)
+	case "done":
+		if len(args) == 0 {
+			return fmt.Errorf("requires ID, see `%s todo list`", GetProgramName())
+		}
+		var ids []int
+		for _, arg := range args {
+			id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+			if err != nil {
+				return fmt.Errorf("invalid todo ID: %s", arg)
+			}
+			ids = append(ids, id)
+		}
+		return markTodosDone(ids, time.Now())
+	}
+	return fmt.Errorf("unrecognized todo command: %s", cmd)
+}
+
+func readTodos() ([]*todoItem, error) {
+	file, err := getConfigPath(false, todosFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var todos []*todoItem
+	if err := json.Unmarshal(data, &todos); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	return todos, nil
+}
+
+func writeTodos(todos []*todoItem) error {
+	file, err := getConfigPath(true, todosFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(todos, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// addTodo adds an open item for workingDir, for all agents if empty
+func addTodo(workingDir string, text string) (*todoItem, error) {
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	todos, err := readTodos()
+	if err != nil {
+		return nil, err
+	}
+	item := &todoItem{ID: 1, Text: text, WorkingDir: workingDir, Created: time.Now()}
+	for _, t := range todos {
+		if t.ID >= item.ID {
+			item.ID = t.ID + 1
+		}
+	}
+	if err := writeTodos(append(todos, item)); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func markTodosDone(ids []int, now time.Time) error {
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	todos, err := readTodos()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		var found bool
+		for _, t := range todos {
+			if t.ID == id {
+				found = true
+				if !t.isDone() {
+					t.Done = now
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("no todo #%d, see `%s todo list`", id, GetProgramName())
+		}
+	}
+	return writeTodos(todos)
+}
+
+// getOpenTodos returns the open items shown to an agent in workingDir
+func getOpenTodos(workingDir string) ([]*todoItem, error) {
+	todos, err := readTodos()
+	if err != nil {
+		return nil, err
+	}
+	var open []*todoItem
+	for _, t := range todos {
+		if !t.isDone() && t.appliesTo(workingDir) {
+			open = append(open, t)
+		}
+	}
+	return open, nil
+}
+
+func listTodos(w io.Writer, workingDir string, all bool) error {
+	todos, err := readTodos()
+	if err != nil {
+		return err
+	}
+	var n int
+	for _, t := range todos {
+		if !all && (t.isDone() || !t.appliesTo(workingDir)) {
+			continue
+		}
+		n++
+		check := " "
+		if t.isDone() {
+			check = "x"
+		}
+		scope := t.WorkingDir
+		if scope == "" {
+			scope = "(global)"
+		}
+		fmt.Fprintf(w, "[%s] #%-3d %s  %s\n", check, t.ID, t.Text, scope)
+	}
+	if n == 0 {
+		fmt.Fprintf(w, "no open todos, add one with: %s todo add TEXT\n", GetProgramName())
+	}
+	return nil
+}
+
+// appendTodos appends the open items for workingDir to answer
+func appendTodos(answer string, workingDir string) string {
+	open, err := getOpenTodos(workingDir)
+	if err != nil {
+		Errorf("read todos: %v", err)
+		return answer
+	}
+	if len(open) == 0 {
+		return answer
+	}
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(answer, "\n"))
+	b.WriteString("\n\n## Pending tasks\n")
+	for _, t := range open {
+		fmt.Fprintf(&b, "- [ ] #%d %s\n", t.ID, t.Text)
+	}
+	fmt.Fprintf(&b, "Mark a task done with `%s todo done ID` once finished.", GetProgramName())
+	return b.String()
+}
+
+// expandTodoCommand adds a todo item for workingDir if line is "/todo TEXT"
+func expandTodoCommand(line string, workingDir string) (*todoItem, bool, error) {
+	text, ok := strings.CutPrefix(strings.TrimSpace(line), todoCommandPrefix)
+	if !ok {
+		return nil, false, nil
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, true, fmt.Errorf("requires TEXT after /todo")
+	}
+	item, err := addTodo(workingDir, text)
+	return item, true, err
+}
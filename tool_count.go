@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/protocol"
+)
+
+// toolCountFile in the config dir holds the tool-call counters of the agent sessions
+const toolCountFile = "tool_counts.json"
+
+// toolCountSessionEnv names the session of the agent, for agents running
+// several sessions in the same directory
+const toolCountSessionEnv = "WHATS_NEXT_SESSION"
+
+// toolCountSessionParam passes the session of the client to the server
+const toolCountSessionParam = protocol.ParamSession
+
+// toolCountExpiry is how long an unused counter is kept
+const toolCountExpiry = 24 * time.Hour
+
+const countHelp = `
+Usage:
+  whats_next count [--inc N | --reset | --show] [--session ID]
+
+Counts the tool calls of the agent session in the current directory, so that
+the count reported by the agent is authoritative. Answers include the count
+once it is used.
+
+Without options, adds 1 and prints the new count. A counter unused for
+showSessionGap, an hour by default, starts a new session from 0.
+
+Options:
+  --inc N        Add N and print the new count
+  --reset        Start the count of the session from 0
+  --show         Print the count without changing it
+  --session ID   The session, default $WHATS_NEXT_SESSION
+`
+
+// toolCounts are the counters by toolCountKey
+type toolCounts map[string]*toolCount
+
+type toolCount struct {
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+func handleCount(args []string) error {
+	var inc int
+	var reset bool
+	var show bool
+	var session string
+	args, err := flags.Int("--inc", &inc).
+		Bool("--reset", &reset).
+		Bool("--show", &show).
+		String("--session", &session).
+		Help("-h,--help", countHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	options := 0
+	for _, set := range []bool{inc != 0, reset, show} {
+		if set {
+			options++
+		}
+	}
+	if options > 1 {
+		return fmt.Errorf("--inc, --reset and --show cannot be used together")
+	}
+	if inc < 0 {
+		return fmt.Errorf("--inc must be positive: %d", inc)
+	}
+	if session == "" {
+		session = os.Getenv(toolCountSessionEnv)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if show {
+		count, err := readToolCount(wd, session)
+		if err != nil {
+			return err
+		}
+		fmt.Println(count)
+		return nil
+	}
+	if !reset && inc == 0 {
+		inc = 1
+	}
+	count, err := updateToolCount(wd, session, inc, reset, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Println(count)
+	return nil
+}
+
+func toolCountKey(workingDir string, session string) string {
+	return workingDir + "\n" + session
+}
+
+func readToolCounts() (toolCounts, error) {
+	file, err := getConfigPath(false, toolCountFile)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(toolCounts)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return counts, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	return counts, nil
+}
+
+// writeToolCounts replaces toolCountFile, the caller holds the config dir lock
+func writeToolCounts(counts toolCounts) error {
+	file, err := getConfigPath(true, toolCountFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(counts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, data, 0644)
+}
+
+// current returns the count of the session, 0 if it ended, idle for more than gap
+func (c *toolCount) current(now time.Time, gap time.Duration) int {
+	if c == nil || now.Sub(c.LastSeen) > gap {
+		return 0
+	}
+	return c.Count
+}
+
+// readToolCount returns the count of the session in workingDir, 0 if there is none
+func readToolCount(workingDir string, session string) (int, error) {
+	counts, err := readToolCounts()
+	if err != nil {
+		return 0, err
+	}
+	gap := getShowSessionGap(readConfigOrDefault(workingDir))
+	return counts[toolCountKey(workingDir, session)].current(time.Now(), gap), nil
+}
+
+// updateToolCount adds inc to the count of the session, from 0 if reset,
+// returning the new count. Expired counters are dropped.
+func updateToolCount(workingDir string, session string, inc int, reset bool, now time.Time) (int, error) {
+	unlock, err := lockConfigDir()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	counts, err := readToolCounts()
+	if err != nil {
+		return 0, err
+	}
+	key := toolCountKey(workingDir, session)
+	count := counts[key].current(now, getShowSessionGap(readConfigOrDefault(workingDir)))
+	if reset {
+		count = 0
+	}
+	count += inc
+	for k, c := range counts {
+		if now.Sub(c.LastSeen) > toolCountExpiry {
+			delete(counts, k)
+		}
+	}
+	counts[key] = &toolCount{Count: count, LastSeen: now}
+	if err := writeToolCounts(counts); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// transcriptDir is the directory under the config dir recording each exchange
+// with an agent, in one YYYY-MM-DD.jsonl file per day with one JSON entry per line
+const transcriptDir = "transcripts"
+
+// transcriptEntry is an exchange with an agent: the user's reply with the
+// guidelines sent along, or the idle response sent in its place
+type transcriptEntry struct {
+	Time       time.Time `json:"time"`
+	Mode       Mode      `json:"mode"`
+	Event      string    `json:"event"`
+	WorkingDir string    `json:"workingDir,omitempty"`
+	Project    string    `json:"project,omitempty"`
+	// WaitStart is when the agent started waiting
+	WaitStart time.Time `json:"waitStart"`
+	// Wait is how long the agent waited for the answer, in seconds
+	Wait float64 `json:"wait"`
+	// Content is the user's reply, empty for idle responses
+	Content string `json:"content,omitempty"`
+	// Sent is what the agent actually received
+	Sent string `json:"sent,omitempty"`
+
+	// ID is the day and line of the entry like "2024-01-02.3", not persisted
+	ID string `json:"-"`
+}
+
+// recordTranscript appends an exchange that started at waitStart to the transcript,
+// failures are only logged
+func recordTranscript(mode Mode, event string, workingDir string, waitStart time.Time, content string, sent string) {
+	now := time.Now()
+	entry := &transcriptEntry{
+		Time:       now,
+		Mode:       mode,
+		Event:      event,
+		WorkingDir: workingDir,
+		WaitStart:  waitStart,
+		Wait:       now.Sub(waitStart).Round(time.Millisecond).Seconds(),
+		Content:    content,
+		Sent:       sent,
+	}
+	if workingDir != "" {
+		entry.Project = filepath.Base(workingDir)
+	}
+	if err := appendTranscript(entry); err != nil {
+		Errorf("record transcript: %v", err)
+	}
+}
+
+func getTranscriptFile(createDir bool, day time.Time) (string, error) {
+	dir, err := getConfigPath(false, transcriptDir)
+	if err != nil {
+		return "", err
+	}
+	if createDir {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, day.Local().Format(time.DateOnly)+".jsonl"), nil
+}
+
+func appendTranscript(entry *transcriptEntry) error {
+	file, err := getTranscriptFile(true, entry.Time)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readTranscript returns the transcript entries in [from, to) in order,
+// skipping malformed lines. A zero from or to leaves that side open.
+func readTranscript(from time.Time, to time.Time) ([]*transcriptEntry, error) {
+	dir, err := getConfigPath(false, transcriptDir)
+	if err != nil {
+		return nil, err
+	}
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var days []string
+	for _, dirEntry := range dirEntries {
+		day, ok := strings.CutSuffix(dirEntry.Name(), ".jsonl")
+		if !ok || dirEntry.IsDir() {
+			continue
+		}
+		date, err := time.ParseInLocation(time.DateOnly, day, time.Local)
+		if err != nil {
+			continue
+		}
+		// files hold local days, skip the ones entirely out of range
+		if (!to.IsZero() && !date.Before(to)) || (!from.IsZero() && !date.AddDate(0, 0, 1).After(from)) {
+			continue
+		}
+		days = append(days, dirEntry.Name())
+	}
+	sort.Strings(days)
+
+	var entries []*transcriptEntry
+	for _, day := range days {
+		dayEntries, err := readTranscriptFile(filepath.Join(dir, day))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range dayEntries {
+			if (!from.IsZero() && entry.Time.Before(from)) || (!to.IsZero() && !entry.Time.Before(to)) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func readTranscriptFile(file string) ([]*transcriptEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	day := strings.TrimSuffix(filepath.Base(file), ".jsonl")
+	var entries []*transcriptEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var line int
+	for scanner.Scan() {
+		line++
+		var entry transcriptEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entry.ID = day + "." + strconv.Itoa(line)
+		entries = append(entries, &entry)
+	}
+	return entries, scanner.Err()
+}
+
+// findTranscriptEntry returns the entry of an id like "2024-01-02.3"
+func findTranscriptEntry(id string) (*transcriptEntry, error) {
+	day, _, ok := strings.Cut(id, ".")
+	if _, err := time.Parse(time.DateOnly, day); !ok || err != nil {
+		return nil, fmt.Errorf("invalid history id %q, expect one like 2024-01-02.3 from `%s history`", id, GetProgramName())
+	}
+	dir, err := getConfigPath(false, transcriptDir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readTranscriptFile(filepath.Join(dir, day+".jsonl"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return nil, fmt.Errorf("history %s not found", id)
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"golang.org/x/term"
+)
+
+const uninstallHelp = `
+Usage:
+  whats_next uninstall [--yes] [--backup FILE]
+
+Removes what whats_next created on this machine, for a clean removal or to
+reset a broken setup:
+  - stops the servers recorded in the config dir
+  - unregisters the whats_next:// URL handler
+  - deletes the keychain secrets referenced by config.json
+  - removes the global rules written by install
+  - deletes the client log ~/.whats_next.log
+  - removes the config dir: config, profiles, history and server logs
+
+The rules installed into projects are left, remove the block between the
+whats_next markers by hand. The binary itself is not removed either.
+
+Options:
+  --yes          Do not ask for confirmation
+  --backup FILE  First save the config dir to FILE as a .tar.gz
+`
+
+// secretRefPattern finds the keychain references in config.json
+var secretRefPattern = regexp.MustCompile(`"` + secretRefPrefix + `([^"]+)"`)
+
+func handleUninstall(args []string) error {
+	var yes bool
+	var backup string
+	args, err := flags.Bool("--yes", &yes).
+		String("--backup", &backup).
+		Help("-h,--help", uninstallHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	if !yes && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("requires --yes when not run in a terminal")
+	}
+	return runUninstall(os.Stdin, os.Stdout, yes, backup)
+}
+
+// runUninstall confirms on w, reading the answer from r unless yes,
+// then removes everything, going on after failures to remove as much as possible
+func runUninstall(r io.Reader, w io.Writer, yes bool, backup string) error {
+	configDir, err := getConfigDir(false)
+	if err != nil {
+		return err
+	}
+	clientLog, err := getClientLogPath()
+	if err != nil {
+		return err
+	}
+	globalRules := getInstalledGlobalRules()
+	if backup != "" {
+		if err := checkBackupPath(configDir, backup); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, "This removes:")
+	fmt.Fprintf(w, "  %s\n", configDir)
+	fmt.Fprintf(w, "  %s\n", clientLog)
+	for _, file := range globalRules {
+		fmt.Fprintf(w, "  the whats_next block of %s\n", file)
+	}
+	fmt.Fprintln(w, "and stops the servers, the URL handler and the keychain secrets.")
+	if !yes {
+		fmt.Fprint(w, "Continue? [y/N] ")
+		answer, _ := bufio.NewReader(r).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return fmt.Errorf("aborted")
+		}
+	}
+
+	if backup != "" {
+		if err := backupConfigDir(configDir, backup); err != nil {
+			return fmt.Errorf("backup: %w, nothing removed", err)
+		}
+		fmt.Fprintf(w, "backed up %s to %s\n", configDir, backup)
+	}
+
+	var failed int
+	step := func(name string, err error) {
+		if err != nil {
+			fmt.Fprintf(w, "failed to %s: %v\n", name, err)
+			failed++
+			return
+		}
+		fmt.Fprintln(w, name)
+	}
+
+	states, _ := listServerStates()
+	for _, state := range states {
+		if !isProcessAlive(state.PID) {
+			continue
+		}
+		addr := getServerAddr(readServerConfig().ServerBind, state.Port)
+		err := killServerAt(addr)
+		if err != nil {
+			err = killProcess(state.PID)
+		}
+		step(fmt.Sprintf("stop server :%d", state.Port), err)
+	}
+	if isURLSchemeRegistered() {
+		step("unregister the URL handler", unregisterURLScheme())
+	}
+	for _, name := range getConfigSecretRefs(configDir) {
+		if err := secrets.Delete(name); !errors.Is(err, errSecretNotFound) {
+			step("delete secret "+name, err)
+		}
+	}
+	for _, file := range globalRules {
+		step("remove the block of "+file, removeInstallBlockFromFile(file))
+	}
+	for _, file := range append([]string{clientLog}, globLogFiles(clientLog)...) {
+		if _, err := os.Stat(file); err == nil {
+			step("remove "+file, os.Remove(file))
+		}
+	}
+	// the server logs go with the config dir, unless elsewhere by --log-dir
+	if _, err := os.Stat(configDir); err == nil {
+		step("remove "+configDir, os.RemoveAll(configDir))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d step(s) failed", failed)
+	}
+	return nil
+}
+
+// globLogFiles returns the rotated files of the log file
+func globLogFiles(file string) []string {
+	matches, _ := filepath.Glob(file + ".*")
+	return matches
+}
+
+// getInstalledGlobalRules returns the global rules files containing a block written by install
+func getInstalledGlobalRules() []string {
+	var files []string
+	for _, target := range installTargets {
+		if target.globalFile == "" {
+			continue
+		}
+		file, err := target.resolveFile(true, "")
+		if err != nil {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err == nil && strings.Contains(string(content), installMarkerBegin) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// getConfigSecretRefs returns the names of the keychain secrets referenced by config.json
+func getConfigSecretRefs(configDir string) []string {
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range secretRefPattern.FindAllStringSubmatch(string(data), -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// isURLSchemeRegistered tells whether url register was run
+func isURLSchemeRegistered() bool {
+	var file string
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		file, err = urlSchemeAppPath()
+	case "windows":
+		return runCommand("reg", "query", `HKCU\Software\Classes\`+urlScheme) == nil
+	default:
+		file, err = urlSchemeDesktopFile()
+	}
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(file)
+	return err == nil
+}
+
+// checkBackupPath rejects a backup file inside configDir,
+// which would be archived into itself and then removed with the dir
+func checkBackupPath(configDir string, file string) error {
+	dir, err := resolvePathSymlinks(configDir)
+	if err != nil {
+		return err
+	}
+	backup, err := resolvePathSymlinks(file)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(dir, backup)
+	if err != nil {
+		return nil
+	}
+	if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+		return fmt.Errorf("backup %s is inside %s, which is removed, choose a file outside of it", file, configDir)
+	}
+	return nil
+}
+
+// resolvePathSymlinks returns the absolute, cleaned path of file with the
+// symlinks of its longest existing parent resolved
+func resolvePathSymlinks(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	dir := abs
+	for {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return abs, nil
+		}
+		rest = append([]string{filepath.Base(dir)}, rest...)
+		dir = parent
+	}
+}
+
+// backupConfigDir writes the files of configDir to a gzipped tarball at file,
+// then reads it back to check that it is complete
+func backupConfigDir(configDir string, file string) error {
+	entries, err := writeConfigDirArchive(configDir, file)
+	if err != nil {
+		return err
+	}
+	return verifyArchive(file, entries)
+}
+
+// writeConfigDirArchive writes the tarball of backupConfigDir,
+// returning the number of entries written
+func writeConfigDirArchive(configDir string, file string) (entries int, err error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	base := filepath.Base(configDir)
+	err = filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			// sockets and the like cannot be archived
+			return nil
+		}
+		rel, err := filepath.Rel(configDir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		entries++
+		if info.IsDir() {
+			return nil
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := tw.Close(); err != nil {
+		return 0, err
+	}
+	return entries, gz.Close()
+}
+
+// verifyArchive reads the gzipped tarball file to the end,
+// failing if it is corrupt or does not have entries entries
+func verifyArchive(file string, entries int) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", file, err)
+	}
+	tr := tar.NewReader(gz)
+	var n int
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("verify %s: %w", file, err)
+		}
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return fmt.Errorf("verify %s: %w", file, err)
+		}
+		n++
+	}
+	if n != entries {
+		return fmt.Errorf("verify %s: %d entries, expect %d", file, n, entries)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestUninstall(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	configDir := filepath.Join(t.TempDir(), "whats_next")
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	old := secrets
+	defer func() { secrets = old }()
+	store := memorySecretStore{"slack-token": "xoxb-123", "other": "x"}
+	secrets = store
+
+	if err := os.MkdirAll(filepath.Join(configDir, "group"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "group", "work.md"), []byte("# Work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"slack": {"botToken": "keychain:slack-token"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	clientLog := filepath.Join(home, ".whats_next.log")
+	if err := os.WriteFile(clientLog, []byte("log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	claudeFile := filepath.Join(home, ".claude", "CLAUDE.md")
+	if err := os.MkdirAll(filepath.Dir(claudeFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(claudeFile, []byte("# Mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := handleInstall([]string{"claude", "--global"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := runUninstall(strings.NewReader("n\n"), &out, false, ""); err == nil {
+		t.Fatalf("Expected abort without confirmation")
+	}
+	if _, err := os.Stat(configDir); err != nil {
+		t.Fatalf("Expected config dir kept after abort: %v", err)
+	}
+
+	if err := runUninstall(strings.NewReader(""), &out, true, filepath.Join(configDir, "group", "..", "backup.tar.gz")); err == nil {
+		t.Fatalf("Expected a backup inside the config dir to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "config.json")); err != nil {
+		t.Fatalf("Expected config dir kept after a rejected backup: %v", err)
+	}
+
+	backup := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := runUninstall(strings.NewReader("y\n"), &out, false, backup); err != nil {
+		t.Fatalf("uninstall: %v\n%s", err, out.String())
+	}
+	for _, file := range []string{configDir, clientLog} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("Expected %s removed, got %v", file, err)
+		}
+	}
+	if content, err := os.ReadFile(claudeFile); err != nil || string(content) != "# Mine\n" {
+		t.Errorf("Expected only the installed block removed, got %q, %v", content, err)
+	}
+	if _, ok := store["slack-token"]; ok || store["other"] == "" {
+		t.Errorf("Expected only the referenced secret deleted, got %v", store)
+	}
+
+	names := readTarNames(t, backup)
+	want := []string{"whats_next", "whats_next/config.json", "whats_next/group", "whats_next/group/work.md"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("backup contains %q, want %q", names, want)
+	}
+}
+
+func readTarNames(t *testing.T, file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, strings.TrimSuffix(header.Name, "/"))
+	}
+	sort.Strings(names)
+	return names
+}
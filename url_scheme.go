@@ -0,0 +1,358 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xhd2015/less-gen/flags"
+)
+
+// urlScheme is the scheme of URLs like whats-next://reply?text=...
+const urlScheme = "whats-next"
+
+const urlHelp = `
+Usage:
+  whats_next url register
+  whats_next url unregister
+  whats_next url open URL
+  whats_next url endpoint
+
+Handles whats-next:// URLs, so automations like Apple Shortcuts can answer
+a waiting agent:
+  whats-next://reply?text=run%20the%20tests&token=TOKEN
+
+register makes this program the handler of whats-next:// URLs for the current
+user, open is what the handler runs. The reply is enqueued on the running server
+(see serve), optional parameters are port and workingDir.
+The token is required, copy it from the URL printed by endpoint: the handler
+never fills it in, as any web page or document can open such a URL.
+
+Shortcuts may also POST the text directly with "Get Contents of URL"
+to the URL printed by endpoint, which carries the token of the server:
+  POST http://localhost:7654/reply?token=TOKEN  text=run the tests
+The token changes on every start unless serverToken is configured.
+`
+
+func handleURL(args []string) error {
+	args, err := flags.Help("-h,--help", urlHelp).Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires: register, unregister or open URL")
+	}
+	switch args[0] {
+	case "register", "unregister":
+		if len(args) != 1 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args[1:], " "))
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		if args[0] == "register" {
+			return registerURLScheme(exe)
+		}
+		return unregisterURLScheme()
+	case "open":
+		if len(args) != 2 {
+			return fmt.Errorf("requires URL")
+		}
+		return openURL(args[1])
+	case "endpoint":
+		if len(args) != 1 {
+			return fmt.Errorf("unrecognized extra args: %s", strings.Join(args[1:], " "))
+		}
+		config := readServerConfig()
+		addr := getServerAddr(config.ServerBind, resolveServerPort(0, config))
+		if !isAddrReachable(addr) {
+			return fmt.Errorf("server %s is not running, start it with: %s serve", addr, GetProgramName())
+		}
+		fmt.Println(replyURL(addr))
+		return nil
+	}
+	return fmt.Errorf("unrecognized url command: %s", args[0])
+}
+
+// openURL performs the action of a whats-next:// URL
+func openURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != urlScheme {
+		return fmt.Errorf("expect a %s:// URL, got: %s", urlScheme, rawURL)
+	}
+	// whats-next://reply parses "reply" as the host, whats-next:reply as opaque
+	action := u.Host
+	if action == "" {
+		action = strings.TrimPrefix(u.Opaque, "//")
+	}
+	action = strings.Trim(action+u.Path, "/")
+
+	query := u.Query()
+	switch action {
+	case "reply":
+		text := query.Get("text")
+		if strings.TrimSpace(text) == "" {
+			return fmt.Errorf("reply requires text")
+		}
+		config := readServerConfig()
+		port := config.ServerPort
+		if p := query.Get("port"); p != "" {
+			if _, err := fmt.Sscanf(p, "%d", &port); err != nil {
+				return fmt.Errorf("invalid port: %s", p)
+			}
+		}
+		token := query.Get(tokenParam)
+		if token == "" {
+			return fmt.Errorf("reply requires the token of the server, copy it from the URL printed by: %s url endpoint", GetProgramName())
+		}
+		addr := getServerAddr(config.ServerBind, resolveServerPort(port, config))
+		// only the token of the URL, not the one of the user, is sent
+		postURL := fmt.Sprintf("http://%s/reply?%s", addr, url.Values{tokenParam: {token}}.Encode())
+		return postReplyForm(&http.Client{Timeout: 10 * time.Second}, postURL, addr, text, query.Get("workingDir"))
+	}
+	return fmt.Errorf("unsupported action: %s, expect reply", action)
+}
+
+// postReply enqueues text as input on the server at addr, with the token of the server
+func postReply(addr string, text string, workingDir string) error {
+	return postReplyForm(newServerClient(readServerConfig(), 10*time.Second), replyURL(addr), addr, text, workingDir)
+}
+
+// postReplyForm posts text to postURL, the /reply of the server at addr
+func postReplyForm(client *http.Client, postURL string, addr string, text string, workingDir string) error {
+	form := url.Values{"text": {text}}
+	if workingDir != "" {
+		form.Set("workingDir", workingDir)
+	}
+	resp, err := client.PostForm(postURL, form)
+	if err != nil {
+		if !isAddrReachable(addr) {
+			return fmt.Errorf("server %s is not running, start it with: %s serve", addr, GetProgramName())
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// replyURL returns the URL of /reply on the server at addr, with its token
+func replyURL(addr string) string {
+	return tokenServerURL(addr, "/reply")
+}
+
+// tokenServerURL returns the URL of path on the server at addr, with the
+// token the server recorded at start, or the configured one
+func tokenServerURL(addr string, path string) string {
+	serverURL := fmt.Sprintf("http://%s%s", addr, path)
+	token, err := getServerToken(readServerConfig())
+	if err != nil {
+		Errorf("server token: %v", err)
+	}
+	if token == "" {
+		if _, p, err := net.SplitHostPort(addr); err == nil {
+			if port, err := strconv.Atoi(p); err == nil {
+				token = readServerStateToken(port)
+			}
+		}
+	}
+	if token != "" {
+		serverURL += "?" + url.Values{tokenParam: {token}}.Encode()
+	}
+	return serverURL
+}
+
+// handleReplyRequest serves POST /reply, enqueuing the text form value
+// or the plain text body as input for the waiting agent.
+// Since browsers send such a POST cross-origin without a preflight, it
+// requires the token of the server and rejects the origins not allowed
+// by apiAllowOrigins.
+func handleReplyRequest(h *serveHandler, w http.ResponseWriter, r *http.Request) {
+	if !allowFormPost(h, w, r) {
+		return
+	}
+	var text string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/plain") {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		text = string(body)
+	} else {
+		text = r.FormValue("text")
+	}
+	if strings.TrimSpace(text) == "" {
+		http.Error(w, "requires text", http.StatusBadRequest)
+		return
+	}
+	if !h.enqueueInput(InputMessage{Content: text, WorkingDir: r.FormValue("workingDir")}) {
+		http.Error(w, "input queue is closed or full", http.StatusServiceUnavailable)
+		return
+	}
+	Logf("reply enqueued from %s", r.RemoteAddr)
+	fmt.Fprintln(w, "queued")
+}
+
+// allowFormPost checks a POST the server accepts as a form, like /reply
+// and /pause, writing the error if not allowed: browsers send such a
+// POST cross-origin without a preflight, so the origin must be allowed
+// by apiAllowOrigins and the request must carry the token of the server.
+func allowFormPost(h *serveHandler, w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "expect POST", http.StatusMethodNotAllowed)
+		return false
+	}
+	if !allowAPIOrigin(w, r, readServerConfig().APIAllowOrigins) {
+		http.Error(w, fmt.Sprintf("origin not allowed: %s, see apiAllowOrigins", r.Header.Get("Origin")), http.StatusForbidden)
+		return false
+	}
+	if !hasServerToken(r, h.replyToken) {
+		http.Error(w, "invalid or missing server token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// registerURLScheme makes exe the handler of whats-next:// URLs for the current user:
+// an app bundle on macOS, a .desktop file on Linux and a registry key on Windows
+func registerURLScheme(exe string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return registerURLSchemeDarwin(exe)
+	case "windows":
+		key := `HKCU\Software\Classes\` + urlScheme
+		command := fmt.Sprintf(`"%s" url open "%%1"`, exe)
+		for _, args := range [][]string{
+			{"add", key, "/ve", "/d", "URL:" + urlScheme, "/f"},
+			{"add", key, "/v", "URL Protocol", "/d", "", "/f"},
+			{"add", key + `\shell\open\command`, "/ve", "/d", command, "/f"},
+		} {
+			if err := runCommand("reg", args...); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Registered %s:// in %s\n", urlScheme, key)
+		return nil
+	}
+	file, err := urlSchemeDesktopFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=whats_next URL handler
+Exec=%s url open %%u
+NoDisplay=true
+MimeType=x-scheme-handler/%s;
+`, shellQuote(exe), urlScheme)
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		return err
+	}
+	if err := runCommand("xdg-mime", "default", filepath.Base(file), "x-scheme-handler/"+urlScheme); err != nil {
+		return err
+	}
+	fmt.Printf("Registered %s:// with %s\n", urlScheme, file)
+	return nil
+}
+
+func unregisterURLScheme() error {
+	switch runtime.GOOS {
+	case "darwin":
+		app, err := urlSchemeAppPath()
+		if err != nil {
+			return err
+		}
+		runCommand(lsregisterPath, "-u", app)
+		return os.RemoveAll(app)
+	case "windows":
+		return runCommand("reg", "delete", `HKCU\Software\Classes\`+urlScheme, "/f")
+	}
+	file, err := urlSchemeDesktopFile()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func urlSchemeDesktopFile() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "applications", "whats_next-url-handler.desktop"), nil
+}
+
+const lsregisterPath = "/System/Library/Frameworks/CoreServices.framework/Frameworks/LaunchServices.framework/Support/lsregister"
+
+func urlSchemeAppPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Applications", "whats_next URL Handler.app"), nil
+}
+
+// registerURLSchemeDarwin builds an AppleScript app receiving the URLs, since
+// macOS only dispatches URL schemes to app bundles declaring them
+func registerURLSchemeDarwin(exe string) error {
+	app, err := urlSchemeAppPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(app), 0755); err != nil {
+		return err
+	}
+	script := fmt.Sprintf("on open location theURL\n\tdo shell script %s & \" url open \" & quoted form of theURL\nend open location\n",
+		appleScriptString(shellQuote(exe)))
+	if err := runCommand("osacompile", "-o", app, "-e", script); err != nil {
+		return err
+	}
+	plist := filepath.Join(app, "Contents", "Info.plist")
+	urlTypes := fmt.Sprintf(`[{"CFBundleURLName":"%s","CFBundleURLSchemes":["%s"]}]`, urlScheme, urlScheme)
+	if err := runCommand("plutil", "-replace", "CFBundleURLTypes", "-json", urlTypes, plist); err != nil {
+		return err
+	}
+	if err := runCommand("plutil", "-replace", "LSBackgroundOnly", "-bool", "YES", plist); err != nil {
+		return err
+	}
+	if err := runCommand(lsregisterPath, "-f", app); err != nil {
+		return err
+	}
+	fmt.Printf("Registered %s:// with %s\n", urlScheme, app)
+	return nil
+}
+
+func runCommand(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %v %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
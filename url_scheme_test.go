@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestOpenURLReply(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	h := &serveHandler{inputChan: make(chan InputMessage, 1), replyToken: "secret"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/reply" {
+			http.NotFound(w, r)
+			return
+		}
+		handleReplyRequest(h, w, r)
+	}))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	// the token of the server is never filled in
+	if err := writeServerState(port, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"", "&token=guess"} {
+		if err := openURL(fmt.Sprintf("whats-next://reply?text=a&port=%d%s", port, token)); err == nil {
+			t.Errorf("Expected a reply with token %q to be rejected", token)
+		}
+	}
+
+	if err := openURL(fmt.Sprintf("whats-next://reply?text=run%%20the%%20tests&port=%d&workingDir=/work/api&token=secret", port)); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-h.inputChan
+	if msg.Content != "run the tests" || msg.WorkingDir != "/work/api" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+
+	// the queue is full
+	if err := openURL(fmt.Sprintf("whats-next://reply?text=a&port=%d&token=secret", port)); err != nil {
+		t.Fatal(err)
+	}
+	if err := openURL(fmt.Sprintf("whats-next://reply?text=b&port=%d&token=secret", port)); err == nil {
+		t.Errorf("Expected error when the queue is full")
+	}
+}
+
+func TestOpenURLInvalid(t *testing.T) {
+	for _, rawURL := range []string{"https://example.com/reply?text=a", "whats-next://reply", "whats-next://kill"} {
+		if err := openURL(rawURL); err == nil {
+			t.Errorf("Expected error for %s", rawURL)
+		}
+	}
+}
+
+func TestHandleReplyRequestPlainText(t *testing.T) {
+	h := &serveHandler{inputChan: make(chan InputMessage, 1)}
+	req := httptest.NewRequest(http.MethodPost, "/reply", strings.NewReader("looks good"))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	handleReplyRequest(h, rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if msg := <-h.inputChan; msg.Content != "looks good" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+
+	rec = httptest.NewRecorder()
+	handleReplyRequest(h, rec, httptest.NewRequest(http.MethodGet, "/reply?"+url.Values{"text": {"a"}}.Encode(), nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected GET to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestHandleReplyRequestToken(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	h := &serveHandler{inputChan: make(chan InputMessage, 1), replyToken: "secret"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleReplyRequest(h, w, r)
+	}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	if err := postReply(addr, "a", ""); err == nil {
+		t.Errorf("Expected reply without token to fail")
+	}
+	if err := writeServerState(port, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := postReply(addr, "a", ""); err != nil {
+		t.Fatal(err)
+	}
+	if msg := <-h.inputChan; msg.Content != "a" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+
+	// a web page posting with the token is still rejected by its origin
+	req := httptest.NewRequest(http.MethodPost, "/reply?token=secret", strings.NewReader("text=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://example.com")
+	rec := httptest.NewRecorder()
+	handleReplyRequest(h, rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a foreign origin to be rejected, got %d", rec.Code)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -26,10 +28,13 @@ func groupShow(use bool, args []string) error {
 
 	// Filter content based on project paths if using the profile
 	if use {
-		filteredContent, err := filterContentByProject(string(group))
+		reportStrictDiagnostics(os.Stderr, groupFile, string(group), filepath.Dir(groupDir))
+		filteredContent, err := filterContentByProject(expandIncludes(string(group), filepath.Dir(groupDir)))
 		if err != nil {
 			return err
 		}
+		wd, _ := os.Getwd()
+		filteredContent = expandTemplates(filteredContent, newTemplateData(wd, strings.TrimSuffix(name, ".md")))
 		printlnContent(os.Stdout, replaceWhatsNextWithProgramName(filteredContent))
 	} else {
 		printlnContent(os.Stdout, string(group))
@@ -37,6 +42,11 @@ func groupShow(use bool, args []string) error {
 
 	if use {
 		// Save selected profile to config
+		unlock, err := lockConfigDir()
+		if err != nil {
+			return err
+		}
+		defer unlock()
 		config, err := readConfig()
 		if err != nil {
 			return err
@@ -50,3 +60,84 @@ func groupShow(use bool, args []string) error {
 	}
 	return nil
 }
+
+const useHelp = `
+Usage:
+  whats_next use [NAME] [--dry-run]
+
+Selects the profile NAME, printing it as filtered for the current directory.
+
+Options:
+  --dry-run  Print the sections that would apply to the current directory and
+             the config change, without writing config.json
+`
+
+// useDryRun writes what selecting the profile in args would do in the current directory
+func useDryRun(w io.Writer, args []string) error {
+	groupDir, err := getGroupConfigPath(false)
+	if err != nil {
+		return err
+	}
+	name, err := selectGroupName(groupDir, args)
+	if err != nil {
+		return err
+	}
+	name = strings.TrimSuffix(name, ".md")
+	content, err := os.ReadFile(filepath.Join(groupDir, addMDSuffix(name)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no profile named %s", name)
+		}
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	var applied, skipped []sectionExplanation
+	for _, e := range explainSections(expandIncludes(string(content), filepath.Dir(groupDir)), wd, isCursor()) {
+		if e.Section.Title == "" {
+			continue
+		}
+		if e.Included {
+			applied = append(applied, e)
+		} else {
+			skipped = append(skipped, e)
+		}
+	}
+	fmt.Fprintf(w, "Sections of %s applying to %s:\n", name, wd)
+	if len(applied) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for _, e := range applied {
+		fmt.Fprintf(w, "  + %s\n", e.Section.Title)
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintln(w, "Sections not applying:")
+		for _, e := range skipped {
+			fmt.Fprintf(w, "  - %s (%s)\n", e.Section.Title, e.describe())
+		}
+	}
+
+	config, err := readConfig()
+	if err != nil {
+		return err
+	}
+	configFile, err := getConfigPath(false, "config.json")
+	if err != nil {
+		return err
+	}
+	if config.SelectedProfile == name {
+		fmt.Fprintf(w, "%s: selectedProfile is already %q, unchanged\n", configFile, name)
+	} else {
+		fmt.Fprintf(w, "%s: selectedProfile %q -> %q\n", configFile, config.SelectedProfile, name)
+	}
+	if projectFile, ok := findProjectConfig(wd); ok {
+		if effective, err := readEffectiveConfig(wd); err == nil && effective.SelectedProfile != config.SelectedProfile {
+			fmt.Fprintf(w, "note: %s selects %q, which takes precedence in %s\n", projectFile, effective.SelectedProfile, wd)
+		}
+	}
+	fmt.Fprintln(w, "(dry run, nothing written)")
+	return nil
+}
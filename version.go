@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/protocol"
+)
+
+// Build metadata, set by release builds:
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Builds without them, e.g. go install, fall back to the embedded build info.
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// versionHeader carries the version of the server in its responses
+const versionHeader = protocol.VersionHeader
+
+// versionParam carries the version of the client in its requests
+const versionParam = protocol.ParamVersion
+
+const versionHelp = `
+Usage:
+  whats_next version [--json]
+
+Prints the version, commit and build date of this binary.
+
+Options:
+  --json   Print as JSON
+`
+
+// versionInfo describes the build of the binary
+type versionInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	// BuildDate falls back to the commit time
+	BuildDate string `json:"buildDate,omitempty"`
+	// Modified tells the binary was built from a tree with uncommitted changes
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+func handleVersion(args []string) error {
+	var jsonOutput bool
+	args, err := flags.Bool("--json", &jsonOutput).
+		Help("-h,--help", versionHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+	}
+	info := getVersionInfo()
+	if jsonOutput {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Printf("%s %s\n", GetProgramName(), info.Version)
+	if info.Commit != "" {
+		modified := ""
+		if info.Modified {
+			modified = " (modified)"
+		}
+		fmt.Printf("commit:  %s%s\n", info.Commit, modified)
+	}
+	if info.BuildDate != "" {
+		fmt.Printf("date:    %s\n", info.BuildDate)
+	}
+	fmt.Printf("go:      %s %s\n", info.GoVersion, info.Platform)
+	return nil
+}
+
+// getVersionInfo returns the ldflags metadata, completed from the build info
+func getVersionInfo() versionInfo {
+	info := versionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && buildInfo.Main.Version != "(devel)" {
+			info.Version = buildInfo.Main.Version
+		}
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "devel"
+	}
+	return info
+}
+
+// getVersion returns the version identifying this binary for the client and
+// server to compare: the version, with the short commit for devel builds
+func getVersion() string {
+	info := getVersionInfo()
+	if info.Version != "devel" || info.Commit == "" {
+		return info.Version
+	}
+	short := info.Commit
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	return "devel-" + short
+}
+
+// isVersionMismatch tells whether the peer's version differs from ours,
+// unknown versions of older binaries not counting
+func isVersionMismatch(peer string) bool {
+	return peer != "" && peer != getVersion()
+}
+
+// versionHTTPHandler tells the version of the server in every response
+func versionHTTPHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(versionHeader, getVersion())
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// warnServerVersion warns on stderr if the server runs another version
+func warnServerVersion(serverVersion string) {
+	if !isVersionMismatch(serverVersion) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "warning: the server runs %s %s but this is %s, restart it: %s serve --kill && %s serve\n",
+		GetProgramName(), serverVersion, getVersion(), GetProgramName(), GetProgramName())
+}
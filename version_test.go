@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetVersionFromLdflags(t *testing.T) {
+	defer func(v, c, d string) { version, commit, buildDate = v, c, d }(version, commit, buildDate)
+	version, commit, buildDate = "v1.2.3", "abcdef0123456789", "2024-05-01T10:00:00Z"
+
+	info := getVersionInfo()
+	if info.Version != "v1.2.3" || info.Commit != "abcdef0123456789" || info.BuildDate != "2024-05-01T10:00:00Z" {
+		t.Errorf("unexpected version info: %+v", info)
+	}
+	if got := getVersion(); got != "v1.2.3" {
+		t.Errorf("getVersion() = %q, want v1.2.3", got)
+	}
+
+	version = ""
+	if got := getVersion(); got != "devel-abcdef012345" {
+		t.Errorf("getVersion() of a devel build = %q, want devel-abcdef012345", got)
+	}
+}
+
+func TestVersionMismatch(t *testing.T) {
+	if isVersionMismatch("") {
+		t.Errorf("expect servers without a version not to mismatch")
+	}
+	if isVersionMismatch(getVersion()) {
+		t.Errorf("expect the same version not to mismatch")
+	}
+	if !isVersionMismatch("v0.0.1-old") {
+		t.Errorf("expect another version to mismatch")
+	}
+
+	handler := versionHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if got := rec.Header().Get(versionHeader); got != getVersion() {
+		t.Errorf("%s = %q, want %q", versionHeader, got, getVersion())
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/xhd2015/whats_next/pkg/config"
+)
+
+// Webhook events
+const (
+	// WebhookEventConnected fires when an agent starts waiting for input
+	WebhookEventConnected = "connected"
+	// WebhookEventInput fires when the user's input is delivered to the agent
+	WebhookEventInput = "input"
+	// WebhookEventIdle fires when the idle timeout response is sent to the agent
+	WebhookEventIdle = "idle"
+	// WebhookEventShutdown fires when `whats_next serve` stops
+	WebhookEventShutdown = "shutdown"
+)
+
+var webhookEvents = []string{WebhookEventConnected, WebhookEventInput, WebhookEventIdle, WebhookEventShutdown}
+
+// webhookTimeout bounds a single webhook request, and the wait for
+// pending webhooks before the process exits
+const webhookTimeout = 5 * time.Second
+
+// webhookData is the data available to webhook templates
+type webhookData struct {
+	Event      string `json:"event"`
+	Time       string `json:"time"`
+	Host       string `json:"host"`
+	WorkingDir string `json:"workingDir,omitempty"`
+	Project    string `json:"project,omitempty"`
+	// Content is the user's input of the input event
+	Content string `json:"content,omitempty"`
+}
+
+var pendingWebhooks sync.WaitGroup
+
+// fireWebhooks sends the webhooks subscribed to event in the background,
+// failures are only logged. waitWebhooks waits for them before exit.
+// Webhooks receive the user's input and secrets, so they are only taken
+// from the global config and its host overrides, never from a project.
+func fireWebhooks(event string, workingDir string, content string) {
+	config, err := readHostConfig()
+	if err != nil {
+		Errorf("webhook %s: %v", event, err)
+		return
+	}
+	startWebhooks(config.Webhooks, event, workingDir, content)
+}
+
+// startWebhooks sends hooks subscribed to event in the background
+func startWebhooks(hooks []Webhook, event string, workingDir string, content string) {
+	if len(hooks) == 0 {
+		return
+	}
+	data := &webhookData{
+		Event:      event,
+		Time:       time.Now().Format(time.RFC3339),
+		Host:       config.Hostname(),
+		WorkingDir: workingDir,
+		Content:    content,
+	}
+	if workingDir != "" {
+		data.Project = filepath.Base(workingDir)
+	}
+	for _, hook := range hooks {
+		if len(hook.Events) > 0 && !containsFold(hook.Events, event) {
+			continue
+		}
+		pendingWebhooks.Add(1)
+		go func(hook Webhook) {
+			defer pendingWebhooks.Done()
+			if err := sendWebhook(hook, data); err != nil {
+				Errorf("webhook %s: %v", event, err)
+			}
+		}(hook)
+	}
+}
+
+// waitWebhooks waits for pending webhooks, at most webhookTimeout
+func waitWebhooks() {
+	done := make(chan struct{})
+	go func() {
+		pendingWebhooks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(webhookTimeout):
+		fmt.Fprintf(os.Stderr, "warning: webhooks still pending after %v\n", webhookTimeout)
+	}
+}
+
+func sendWebhook(hook Webhook, data *webhookData) error {
+	url, err := resolveSecret(hook.URL)
+	if err != nil {
+		return err
+	}
+	if url == "" {
+		return fmt.Errorf("missing url")
+	}
+	body, err := renderWebhookBody(hook.Template, data)
+	if err != nil {
+		return err
+	}
+	method := strings.ToUpper(hook.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if hook.Template == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range hook.Headers {
+		value, err := resolveSecret(value)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(name, value)
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("%s %s: %s %s", method, req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// renderWebhookBody executes text with data, an empty text renders data as JSON.
+// The "json" function quotes a value for JSON templates, e.g. {"text": {{json .Content}}}
+func renderWebhookBody(text string, data *webhookData) (string, error) {
+	if text == "" {
+		body, err := json.Marshal(data)
+		return string(body), err
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
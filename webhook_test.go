@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFireWebhooks(t *testing.T) {
+	bodies := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- r.Header.Get("Title") + " " + string(body)
+	}))
+	defer server.Close()
+
+	hooks := []Webhook{
+		{URL: server.URL, Events: []string{"input"}, Headers: map[string]string{"Title": "agent"}, Template: `{"text": {{json .Content}}, "project": "{{.Project}}"}`},
+		{URL: server.URL, Events: []string{"shutdown"}},
+	}
+	startWebhooks(hooks, WebhookEventInput, "/work/api", `say "hi"`)
+	waitWebhooks()
+
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 webhook, got %d", len(bodies))
+	}
+	expected := `agent {"text": "say \"hi\"", "project": "api"}`
+	if got := <-bodies; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestValidateWebhooksConfig(t *testing.T) {
+	problems, err := validateConfigJSON([]byte(`{"webhooks": [{"url": "https://ntfy.sh/x", "events": ["ring"], "body": ""}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"unknown key: webhooks[0].body", "invalid webhooks[0].events[0]: ring, expect one of connected, input, idle, shutdown"}
+	if len(problems) != len(expected) || problems[0] != expected[0] || problems[1] != expected[1] {
+		t.Errorf("Expected %q, got %q", expected, problems)
+	}
+}
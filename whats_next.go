@@ -2,34 +2,51 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	tea "github.com/charmbracelet/bubbletea"
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/protocol"
+	"github.com/xhd2015/whats_next/pkg/sections"
 	"golang.org/x/term"
 )
 
 func handleWhatsNext(args []string) error {
 	// Check config for mode
-	config, err := readConfig()
+	wd, _ := os.Getwd()
+	config, err := readEffectiveConfig(wd)
 	if err != nil {
 		return err
 	}
 
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		hintInit()
+	}
+
 	// If mode is server, delegate to server mode handler
 	if config.Mode != ModeServer {
-		wd, _ := os.Getwd()
-		return createInput(os.Stdout, wd, readTerminalOptions{
+		var copy bool
+		// --port only matters in server mode
+		var port int
+		_, err := flags.Bool("--copy", &copy).Int("--port", &port).Parse(args)
+		if err != nil {
+			return err
+		}
+		return createInput(context.Background(), os.Stdout, wd, readTerminalOptions{
 			showTimer: func() bool {
 				return true
 			},
+			copyToClipboard: copy,
+			todoDir:         wd,
 		})
 	}
 	return handleClient(args)
@@ -43,144 +60,318 @@ type InputMessage struct {
 	Exit       bool
 }
 
+// serveHandler serves the agents waiting for the input of the user.
+//
+// Its mutable state is a serveState owned by a single goroutine: the other
+// goroutines, the HTTP handlers, the input loop and the slack bridge, pass it
+// functions with do instead of sharing it under locks.
 type serveHandler struct {
-	mutex sync.Mutex
-
+	// inputChan passes the answers of the user to the waiting clients,
+	// it is closed by closeInput once the input loop stops
 	inputChan chan InputMessage
+	// inputOverflow is the policy applied when inputChan is full, see inputQueueOverflows
+	inputOverflow string
 
 	inputCtx    context.Context
 	inputCancel context.CancelFunc
 
-	clientConn         int64
+	httpServer *http.Server
+
+	// slack is the bridge of `serve --slack`, nil if not enabled
+	slack *slackBridge
+
+	// replyToken is required by /reply, /pause and /resume, see allowFormPost
+	replyToken string
+
+	// actions are run on the state by the goroutine started by the first do
+	actionsOnce sync.Once
+	actions     chan func(s *serveState)
+}
+
+// serveState is the mutable state of the server, only accessed in the
+// functions passed to serveHandler.do
+type serveState struct {
+	// inputClosed is set when inputChan is closed
+	inputClosed bool
+	// spooled is the number of answers in inputSpoolFile
+	spooled int
+
+	clientConn         int
 	clientWaitDeadline time.Time
 	lastInputEmptyTime time.Time
-	program            *tea.Program
-
-	httpServer *http.Server
+	program            inputProgram
 
 	shutdownRequested bool
 
-	flagHasInputContent int32
+	hasInputContent bool
+
+	// waiting are the clients waiting for input by id
+	waiting       map[int64]*waitingSession
+	lastSessionID int64
+
+	// paused answers agents that the user is away until pausedUntil,
+	// zero for until resume
+	paused      bool
+	pausedUntil time.Time
+	// pauseSignal is closed on pause to release the waiting agents
+	pauseSignal chan struct{}
 }
 
-func (h *serveHandler) hasProcessingClient() bool {
-	return atomic.LoadInt64(&h.clientConn) > 0
+// waitingSession is a client waiting for input, reported by /status and /api/v1/sessions
+type waitingSession struct {
+	ID         int64
+	WorkingDir string
+	Since      time.Time
+	// Question is the yes/no question of `ask`, empty for other clients
+	Question string
 }
 
-func (h *serveHandler) getClientWaitDeadline() time.Time {
-	h.mutex.Lock()
-	t := h.clientWaitDeadline
-	h.mutex.Unlock()
-	return t
+// do runs action on the state in the goroutine owning it, and waits for it.
+// The goroutine lives as long as the process. action must not block nor call do.
+func (h *serveHandler) do(action func(s *serveState)) {
+	h.actionsOnce.Do(func() {
+		h.actions = make(chan func(s *serveState))
+		go runServeState(h.actions)
+	})
+	done := make(chan struct{})
+	h.actions <- func(s *serveState) {
+		defer close(done)
+		action(s)
+	}
+	<-done
 }
 
-func (h *serveHandler) setClientWaitDeadline(t time.Time) {
-	h.mutex.Lock()
-	h.clientWaitDeadline = t
-	h.mutex.Unlock()
+func runServeState(actions <-chan func(s *serveState)) {
+	s := &serveState{}
+	for action := range actions {
+		action(s)
+	}
 }
 
-func (h *serveHandler) getLastInputEmptyTime() time.Time {
-	h.mutex.Lock()
-	t := h.lastInputEmptyTime
-	h.mutex.Unlock()
-	return t
+func (h *serveHandler) hasProcessingClient() bool {
+	var has bool
+	h.do(func(s *serveState) { has = s.clientConn > 0 })
+	return has
 }
 
-func (h *serveHandler) setLastInputEmptyTime(t time.Time) {
-	h.mutex.Lock()
-	h.lastInputEmptyTime = t
-	h.mutex.Unlock()
+func (h *serveHandler) setClientWaitDeadline(t time.Time) {
+	h.do(func(s *serveState) { s.clientWaitDeadline = t })
 }
 
-func (h *serveHandler) setProgram(program *tea.Program) {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
-	h.program = program
+// getUserPrompt renders the prompt of the server input
+func (h *serveHandler) getUserPrompt() string {
+	var conn int
+	var remaining time.Duration
+	var questions []string
+	h.do(func(s *serveState) {
+		conn = s.clientConn
+		remaining = s.clientWaitDeadline.Sub(s.lastInputEmptyTime)
+		questions = s.waitingQuestions()
+	})
+	return renderUserPrompt(conn > 0, true, remaining, conn) + renderQueueDepth(len(h.inputChan), cap(h.inputChan)) + renderAskQuestions(questions)
+}
 
-	if program != nil {
-		if h.clientConn == 0 {
-			go program.Send(disableTimerMsg{})
+// setInputContent records whether an answer is being typed
+func (h *serveHandler) setInputContent(hasInput bool) {
+	h.do(func(s *serveState) {
+		if !hasInput {
+			s.lastInputEmptyTime = time.Now()
+		}
+		s.hasInputContent = hasInput
+	})
+}
+
+func (h *serveHandler) setProgram(program inputProgram) {
+	h.do(func(s *serveState) {
+		s.program = program
+		if program == nil {
+			return
+		}
+		if s.clientConn == 0 {
+			program.disableTimer()
 		} else {
-			go program.Send(enableTimerMsg{})
+			program.enableTimer()
 		}
-	}
+	})
 }
 
-func (h *serveHandler) notifyRequestAccepted() {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
+// acceptClient records a client waiting for input in workingDir, with the
+// question of `ask` if not empty. It returns the id for finishClient, or
+// false if the server is shutting down.
+func (h *serveHandler) acceptClient(workingDir string, question string) (int64, bool) {
+	var id int64
+	var accepted bool
+	h.do(func(s *serveState) {
+		if s.shutdownRequested {
+			return
+		}
+		accepted = true
+		s.clientConn++
+		if s.program != nil {
+			// enable the timer
+			s.program.enableTimer()
+		}
+		id = s.addWaiting(workingDir)
+		if question != "" {
+			s.waiting[id].Question = question
+		}
+	})
+	return id, accepted
+}
 
-	h.clientConn++
+// finishClient removes the client id, reporting whether the server is shutting down
+func (h *serveHandler) finishClient(id int64) bool {
+	var shutdownRequested bool
+	h.do(func(s *serveState) {
+		delete(s.waiting, id)
+		s.clientConn--
+		if s.program != nil && s.clientConn == 0 {
+			s.program.disableTimer()
+		}
+		shutdownRequested = s.shutdownRequested
+	})
+	return shutdownRequested
+}
 
-	if h.program == nil {
-		return
+// sendNotice shows text below the server input until the next key
+func (h *serveHandler) sendNotice(text string) {
+	h.do(func(s *serveState) {
+		if s.program != nil {
+			s.program.notice(text)
+		}
+	})
+}
+
+// addWaiting records a client waiting for input in workingDir, returning its id for removeWaiting
+func (h *serveHandler) addWaiting(workingDir string) int64 {
+	var id int64
+	h.do(func(s *serveState) { id = s.addWaiting(workingDir) })
+	return id
+}
+
+func (s *serveState) addWaiting(workingDir string) int64 {
+	if s.waiting == nil {
+		s.waiting = make(map[int64]*waitingSession)
 	}
-	// send message to enable timer
-	h.program.Send(enableTimerMsg{})
+	s.lastSessionID++
+	s.waiting[s.lastSessionID] = &waitingSession{ID: s.lastSessionID, WorkingDir: workingDir, Since: time.Now()}
+	return s.lastSessionID
 }
 
-func (h *serveHandler) notifyRequestFinished() {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
+func (h *serveHandler) removeWaiting(id int64) {
+	h.do(func(s *serveState) { delete(s.waiting, id) })
+}
 
-	h.clientConn--
+// setWaitingQuestion records that the client id waits for the answer to question
+func (h *serveHandler) setWaitingQuestion(id int64, question string) {
+	h.do(func(s *serveState) {
+		if session, ok := s.waiting[id]; ok {
+			session.Question = question
+		}
+	})
+}
 
-	if h.program == nil {
-		return
+// getWaitingQuestions returns the questions of the waiting ask clients, oldest first
+func (h *serveHandler) getWaitingQuestions() []string {
+	var questions []string
+	h.do(func(s *serveState) { questions = s.waitingQuestions() })
+	return questions
+}
+
+func (s *serveState) waitingQuestions() []string {
+	var questions []string
+	for _, session := range s.waitingSessions() {
+		if session.Question != "" {
+			questions = append(questions, session.Question)
+		}
 	}
-	if h.clientConn == 0 {
-		h.program.Send(disableTimerMsg{})
+	return questions
+}
+
+// getWaitingSessions returns the waiting clients, oldest first
+func (h *serveHandler) getWaitingSessions() []waitingSession {
+	var sessions []waitingSession
+	h.do(func(s *serveState) { sessions = s.waitingSessions() })
+	return sessions
+}
+
+func (s *serveState) waitingSessions() []waitingSession {
+	sessions := make([]waitingSession, 0, len(s.waiting))
+	for _, session := range s.waiting {
+		sessions = append(sessions, *session)
 	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+// getWaitingDirs returns the working dirs of the waiting clients, sorted
+func (h *serveHandler) getWaitingDirs() []string {
+	sessions := h.getWaitingSessions()
+	dirs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		dirs = append(dirs, session.WorkingDir)
+	}
+	sort.Strings(dirs)
+	return dirs
 }
 
 func (h *serveHandler) hasWaitingClient() bool {
-	return atomic.LoadInt64(&h.clientConn) > 0
+	return h.hasProcessingClient()
 }
 
+// shutdown stops the input and the HTTP server. The HTTP server is shut
+// down outside of the state goroutine, as it waits for the handlers using it.
 func (h *serveHandler) shutdown(ctx context.Context) {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
-
+	var program inputProgram
+	h.do(func(s *serveState) {
+		program = s.program
+		s.program = nil
+	})
 	if h.inputCancel != nil {
 		h.inputCancel()
-		h.inputCancel = nil
 	}
-	if h.program != nil {
-		h.program.Kill()
-		h.program = nil
+	if program != nil {
+		program.kill()
+	}
+	if h.httpServer != nil {
+		h.httpServer.Shutdown(ctx)
 	}
-	h.httpServer.Shutdown(ctx)
 }
 
 func (h *serveHandler) requestShutdown() {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
-	h.shutdownRequested = true
+	h.do(func(s *serveState) { s.shutdownRequested = true })
 }
 
 func (h *serveHandler) isShutdownRequested() bool {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
-	return h.shutdownRequested
+	var requested bool
+	h.do(func(s *serveState) { requested = s.shutdownRequested })
+	return requested
 }
 
 func (h *serveHandler) hasInputContent() bool {
-	return atomic.LoadInt32(&h.flagHasInputContent) != 0
+	var has bool
+	h.do(func(s *serveState) { has = s.hasInputContent })
+	return has
 }
 
-func createInput(w io.Writer, workingDir string, opts readTerminalOptions) error {
+// createInput waits for the user to type an answer and writes it to w.
+// Cancelling ctx stops the wait and returns its error.
+func createInput(ctx context.Context, w io.Writer, workingDir string, opts readTerminalOptions) error {
 	// Default to native mode (current logic)
 	// wait for user input
 	type Result struct {
 		Error error
 	}
 	done := make(chan Result)
+	waitStart := time.Now()
 
 	var hasInput int32
 
 	// Create context for timeout cancellation
-	ctx, cancel := context.WithCancel(context.Background())
+	parentCtx := ctx
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	go func() {
@@ -189,15 +380,48 @@ func createInput(w io.Writer, workingDir string, opts readTerminalOptions) error
 
 		var lines []string
 		var err error
+		var idleResponse string
+		var idled int32
 
 		if isTerminal {
-			lines, err = readInputFromTerminal(ctx, &hasInput, TIMEOUT, opts.onInputUpdate, opts)
+			idleTimeout, _ := getTimeouts(workingDir, ModeNative)
+			config := readConfigOrDefault(workingDir)
+			notifyWaiting(config, workingDir)
+			playSound(config, SoundEventConnect)
+			fireWebhooks(WebhookEventConnected, workingDir, "")
+			if response, ok := getIdleResponse(config, getIdleAction(config, ModeNative)); ok {
+				idleResponse = response
+				// stop reading if nothing was typed when the idle timeout passes
+				timer := time.AfterFunc(idleTimeout, func() {
+					if atomic.LoadInt32(&hasInput) == 0 {
+						atomic.StoreInt32(&idled, 1)
+						cancel()
+					}
+				})
+				defer timer.Stop()
+			}
+			lines, err = readInputFromTerminal(ctx, &hasInput, idleTimeout, opts.onInputUpdate, opts)
 		} else {
 			lines, err = readInputFromNonTerminal(&hasInput)
 		}
 
+		if err != nil && atomic.LoadInt32(&idled) != 0 {
+			Logf("input idle, send idle response")
+			fmt.Fprintln(w, idleResponse)
+			fireWebhooks(WebhookEventIdle, workingDir, "")
+			if !opts.noWrapWithGuidelines {
+				recordTranscript(ModeNative, WebhookEventIdle, workingDir, waitStart, "", idleResponse)
+			}
+			done <- Result{}
+			return
+		}
+		if err != nil && parentCtx.Err() != nil {
+			Logf("input cancelled: %v", parentCtx.Err())
+			done <- Result{Error: fmt.Errorf("%w: %w", protocol.ErrCancelled, parentCtx.Err())}
+			return
+		}
 		if err != nil {
-			if err.Error() == "exit" {
+			if errors.Is(err, protocol.ErrUserExit) {
 				Logf("exit")
 				done <- Result{}
 				return
@@ -209,8 +433,19 @@ func createInput(w io.Writer, workingDir string, opts readTerminalOptions) error
 		if opts.noWrapWithGuidelines {
 			fmt.Fprintln(w, q)
 		} else {
-			questionGuidelines := wrapQuestionWithGuidelines(q, workingDir)
+			var questionGuidelines string
+			q, questionGuidelines = buildAnswer(q, workingDir, os.Getenv(toolCountSessionEnv), time.Since(waitStart))
 			fmt.Fprintln(w, questionGuidelines)
+			if opts.copyToClipboard {
+				copyReply(questionGuidelines)
+			}
+			// the server records what it sends itself
+			recordTranscript(ModeNative, WebhookEventInput, workingDir, waitStart, q, questionGuidelines)
+		}
+		if isTerminal {
+			config := readConfigOrDefault(workingDir)
+			playSound(config, SoundEventReply)
+			fireWebhooks(WebhookEventInput, workingDir, q)
 		}
 		done <- Result{}
 	}()
@@ -223,54 +458,114 @@ func createInput(w io.Writer, workingDir string, opts readTerminalOptions) error
 	return nil
 }
 
+// buildAnswer returns the reply q with the next step of the plan of workingDir,
+// and the answer sent to the agent: that reply wrapped with the guidelines,
+// followed by the wait time, the due reminders and the pending tasks
+func buildAnswer(q string, workingDir string, session string, wait time.Duration) (string, string) {
+	q = applyPlanStep(q, workingDir)
+	answer := appendWaitTime(readConfigOrDefault(workingDir), wrapQuestionWithGuidelinesForSession(q, workingDir, session), wait)
+	answer = appendReminders(answer, workingDir)
+	answer = appendTodos(answer, workingDir)
+	return q, answer
+}
+
 func wrapQuestionWithGuidelines(q string, workingDir string) string {
-	var s strings.Builder
-	var w io.Writer = &s
-	fmt.Fprintf(w, "the user is asking: \n<question>\n%s\n</question>\nplease think step by step and give your answer\n", q)
+	return wrapQuestionWithGuidelinesForSession(q, workingDir, os.Getenv(toolCountSessionEnv))
+}
 
-	fmt.Fprintln(w, "----")
+// wrapQuestionWithGuidelinesForSession is wrapQuestionWithGuidelines for the agent
+// session, whose tool-call count is included
+func wrapQuestionWithGuidelinesForSession(q string, workingDir string, session string) string {
+	config, _ := readEffectiveConfig(workingDir)
+	var profileName string
+	if config != nil {
+		profileName = config.SelectedProfile
+	}
+	// profile holds the selected profile, or the built-in guidelines
+	var profile strings.Builder
+	profileContent := writeGuidelines(&profile, config, profileName, workingDir, isCursor(), true)
+
+	data := &answerTemplateData{
+		Question:      q,
+		Profile:       profile.String(),
+		WorkingDir:    workingDir,
+		ReplyLanguage: getReplyLanguage(config, string(profileContent)),
+	}
+	if workingDir != "" {
+		data.ToolCalls, _ = readToolCount(workingDir, session)
+	}
+	if profileContent != nil {
+		data.ProfileName = profileName
+	}
+	return renderAnswer(getAnswerTemplate(config, string(profileContent)), data)
+}
 
-	var printSelectedProfile bool
-	// Check for selected profile and print its content
-	config, err := readConfig()
-	if err == nil && config.SelectedProfile != "" {
+// writeGuidelines writes the profile profileName filtered for workingDir and the agent,
+// or the built-in guidelines if there is no such profile, returning the raw profile.
+// The (show: ...) state is only updated when record is set.
+func writeGuidelines(w io.Writer, config *Config, profileName string, workingDir string, isCursor bool, record bool) []byte {
+	var profileContent []byte
+	var groupFile string
+	if profileName != "" {
 		groupDir, err := getGroupConfigPath(false)
 		if err == nil {
-			profileName := addMDSuffix(config.SelectedProfile)
-			groupFile := filepath.Join(groupDir, profileName)
-			if profileContent, readErr := os.ReadFile(groupFile); readErr == nil {
-				printSelectedProfile = true
-				printContent := string(profileContent)
-				if workingDir != "" {
-					printContent = filterContentByDir(printContent, workingDir, isCursor())
-				}
-				fmt.Fprintln(w, printContent)
+			groupFile = filepath.Join(groupDir, addMDSuffix(profileName))
+			if content, readErr := os.ReadFile(groupFile); readErr == nil {
+				profileContent = content
 			}
 		}
 	}
-	if !printSelectedProfile {
-		fmt.Fprint(w, getGeneralGuideline())
-		fmt.Fprint(w, toolCallAwareness)
-		fmt.Fprint(w, runningCommand)
+	if profileContent == nil {
+		writeBuiltinGuidelines(w, config)
+		return nil
+	}
+
+	configDir := filepath.Dir(filepath.Dir(groupFile))
+	printContent := expandIncludes(string(profileContent), configDir)
+	reportStrictDiagnostics(os.Stderr, groupFile, string(profileContent), configDir)
+	if workingDir != "" {
+		printContent = filterContentWithShowState(config, printContent, workingDir, isCursor, record)
 	}
-	return s.String()
+	printContent = expandTemplates(printContent, newTemplateData(workingDir, profileName))
+	fmt.Fprintln(w, printContent)
+	return profileContent
+}
+
+// getReplyLanguage returns the language answers should be written in:
+// the replyLanguage front matter of the profile, then Config.ReplyLanguage
+func getReplyLanguage(config *Config, profileContent string) string {
+	if language := sections.ProfileFrontMatter(profileContent)["replylanguage"]; language != "" {
+		return language
+	}
+	if config == nil {
+		return ""
+	}
+	return config.ReplyLanguage
 }
 
 func isThinking() string {
+	var general string
+	config, _ := readConfig()
+	if isBuiltinGuidelineEnabled(config, "general") {
+		general = getGeneralGuideline()
+	}
 	return strings.Join([]string{
 		"The user is thinking, please execute `" + GetProgramName() + "` again.",
 		"",
-		getGeneralGuideline(),
+		general,
 	}, "\n")
 }
 
 // startBackgroundInputLoop starts a background goroutine that continuously reads user input
 func (h *serveHandler) startBackgroundInputLoop() {
-	h.inputChan = make(chan InputMessage, 100) // up to 100 messages can be buffered
+	config := readServerConfig()
+	h.inputChan = make(chan InputMessage, getInputQueueSize(config))
+	h.inputOverflow = getInputQueueOverflow(config)
 	h.inputCtx, h.inputCancel = context.WithCancel(context.Background())
+	h.loadSpooledInput()
 
 	go func() {
-		defer close(h.inputChan)
+		defer h.closeInput()
 
 		for {
 			if h.isShutdownRequested() {
@@ -288,33 +583,27 @@ func (h *serveHandler) startBackgroundInputLoop() {
 				// Read input using the existing acceptInput logic
 				var content strings.Builder
 				var isExit bool
-				err := createInput(&content, wd, readTerminalOptions{
+				err := createInput(h.inputCtx, &content, wd, readTerminalOptions{
 					showTimer:            h.hasProcessingClient,
 					noWrapWithGuidelines: true,
 					getUserPrompt: func(hasInput bool) string {
-						conn := atomic.LoadInt64(&h.clientConn)
-						remaining := h.getClientWaitDeadline().Sub(h.getLastInputEmptyTime())
-						return renderUserPrompt(conn > 0, true, remaining, int(conn))
+						return h.getUserPrompt()
 					},
-					onCreatedProgram: func(program *tea.Program) {
+					onCreatedProgram: func(program inputProgram) {
 						Logf("program created")
 						h.setProgram(program)
 					},
-					onProgramFinished: func(program *tea.Program) {
+					onProgramFinished: func(program inputProgram) {
 						Logf("program finished")
 						h.setProgram(nil)
 					},
+					onTogglePause: h.togglePause,
 					onInputExit: func() {
 						Logf("input exit")
 						isExit = true
 						h.requestShutdown()
 					},
-					onInputUpdate: func(hasInput bool) {
-						if !hasInput {
-							h.setLastInputEmptyTime(time.Now())
-						}
-						atomic.StoreInt32(&h.flagHasInputContent, toBoolInt32(hasInput))
-					},
+					onInputUpdate: h.setInputContent,
 				})
 
 				contentStr := content.String()
@@ -345,10 +634,15 @@ func (h *serveHandler) startBackgroundInputLoop() {
 					return
 				}
 
-				// Send the input to the channel (non-blocking)
+				// if spooling fails, wait for room rather than dropping the input
+				if h.inputOverflow != inputQueueBlock && h.enqueueInput(msg) {
+					continue
+				}
+				// Send the input to the channel, waiting for room
 				select {
 				case h.inputChan <- msg:
 					Logf("Input captured and ready for clients")
+					Debugf("queue: enqueued terminal input of %d bytes, %d queued", len(contentStr), len(h.inputChan))
 				case <-h.inputCtx.Done():
 					return
 				}
@@ -357,9 +651,123 @@ func (h *serveHandler) startBackgroundInputLoop() {
 	}()
 }
 
-func toBoolInt32(b bool) int32 {
-	if b {
-		return 1
+// enqueueInput passes input from sources other than the terminal, like the slack bridge,
+// to clients. When the buffer is full, the oldest input is dropped with the
+// drop-oldest policy, the input is spooled with the spool policy, otherwise
+// it reports false, as it does if the input loop is closed or spooling fails.
+func (h *serveHandler) enqueueInput(msg InputMessage) bool {
+	var enqueued bool
+	h.do(func(s *serveState) {
+		if s.inputClosed {
+			return
+		}
+		enqueued = h.tryEnqueueInput(s, msg)
+	})
+	return enqueued
+}
+
+// tryEnqueueInput sends msg without blocking, in the state goroutine so
+// that inputChan is not closed meanwhile
+func (h *serveHandler) tryEnqueueInput(s *serveState, msg InputMessage) bool {
+	if s.spooled > 0 {
+		h.moveSpooledInput(s)
+	}
+	// spool after the answers already spooled, keeping their order
+	spoolFirst := h.inputOverflow == inputQueueSpool && s.spooled > 0
+	for !spoolFirst {
+		select {
+		case h.inputChan <- msg:
+			Debugf("queue: enqueued input of %d bytes for %q, %d queued", len(msg.Content), msg.WorkingDir, len(h.inputChan))
+			return true
+		default:
+		}
+		if h.inputOverflow != inputQueueDropOldest {
+			break
+		}
+		select {
+		case dropped := <-h.inputChan:
+			Errorf("input queue full, dropped the oldest input of %d bytes for %q", len(dropped.Content), dropped.WorkingDir)
+		default:
+		}
+	}
+	if h.inputOverflow != inputQueueSpool {
+		Debugf("queue: refused input of %d bytes, queue full", len(msg.Content))
+		return false
 	}
-	return 0
+	if err := spoolInput(msg); err != nil {
+		Errorf("input queue full, spool input: %v", err)
+		return false
+	}
+	s.spooled++
+	Logf("input queue full, spooled input of %d bytes, %d spooled", len(msg.Content), s.spooled)
+	return true
+}
+
+// loadSpooledInput queues the answers left spooled by the last server
+func (h *serveHandler) loadSpooledInput() {
+	h.do(func(s *serveState) {
+		msgs, err := readSpooledInput()
+		if err != nil {
+			Errorf("spooled input: %v", err)
+			return
+		}
+		s.spooled = len(msgs)
+		if s.spooled > 0 {
+			Logf("found %d spooled answers of the last server", s.spooled)
+			h.moveSpooledInput(s)
+		}
+	})
+}
+
+// refillInput moves spooled answers to inputChan as far as it has room,
+// called once agents took answers
+func (h *serveHandler) refillInput() {
+	h.do(func(s *serveState) {
+		if s.inputClosed || s.spooled == 0 {
+			return
+		}
+		h.moveSpooledInput(s)
+	})
+}
+
+// moveSpooledInput moves the oldest spooled answers to inputChan without blocking
+func (h *serveHandler) moveSpooledInput(s *serveState) {
+	msgs, err := readSpooledInput()
+	if err != nil {
+		Errorf("spooled input: %v", err)
+		return
+	}
+	moved := 0
+move:
+	for moved < len(msgs) {
+		select {
+		case h.inputChan <- msgs[moved]:
+			moved++
+		default:
+			break move
+		}
+	}
+	if moved == 0 {
+		return
+	}
+	if err := writeSpooledInput(msgs[moved:]); err != nil {
+		// the moved answers may be queued again by the next server
+		Errorf("spooled input: %v", err)
+	}
+	s.spooled = len(msgs) - moved
+	Debugf("queue: moved %d spooled answers, %d queued, %d spooled", moved, len(h.inputChan), s.spooled)
+}
+
+func (h *serveHandler) closeInput() {
+	h.do(func(s *serveState) {
+		s.inputClosed = true
+		if queued := len(h.inputChan); queued > 0 {
+			Logf("input closed with %d answers no agent took", queued)
+		}
+		if s.spooled > 0 {
+			Logf("input closed with %d answers spooled, queued on the next start", s.spooled)
+		}
+		close(h.inputChan)
+		Debugf("queue: closed")
+	})
 }
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrapQuestionReplyLanguage(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	if err := writeConfig(&Config{ReplyLanguage: "French"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result := wrapQuestionWithGuidelines("what next?", "")
+	if !strings.Contains(result, "</question>\nplease think step by step and give your answer\nplease answer in French\n----\n") {
+		t.Errorf("Expected global reply language, got:\n%s", result)
+	}
+
+	groupDir := filepath.Join(configDir, "group")
+	if err := os.MkdirAll(groupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	profile := "---\nreplyLanguage: Chinese\n---\n# Rules\nBe brief.\n"
+	if err := os.WriteFile(filepath.Join(groupDir, "zh.md"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfig(&Config{ReplyLanguage: "French", SelectedProfile: "zh"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result = wrapQuestionWithGuidelines("what next?", "")
+	if !strings.Contains(result, "please answer in Chinese\n") || strings.Contains(result, "French") {
+		t.Errorf("Expected profile reply language, got:\n%s", result)
+	}
+	if !strings.Contains(result, "Be brief.") {
+		t.Errorf("Expected profile content, got:\n%s", result)
+	}
+}
+
+func TestWrapQuestionAnswerTemplate(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", configDir)
+	if err := writeConfig(&Config{BuiltinGuidelines: &[]string{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "the user is asking: \n<question>\nwhat next?\n</question>\nplease think step by step and give your answer\n----\n"
+	if result := wrapQuestionWithGuidelines("what next?", ""); result != expected {
+		t.Errorf("Expected default wrapper %q, got %q", expected, result)
+	}
+
+	if err := writeConfig(&Config{AnswerTemplate: "Q: {{.Question}} in {{.WorkingDir}}", BuiltinGuidelines: &[]string{}}); err != nil {
+		t.Fatal(err)
+	}
+	if result := wrapQuestionWithGuidelines("what next?", "/tmp/x"); result != "Q: what next? in /tmp/x" {
+		t.Errorf("Expected config template, got %q", result)
+	}
+
+	if err := writeConfig(&Config{AnswerTemplate: "Q: {{.Missing}}", BuiltinGuidelines: &[]string{}}); err != nil {
+		t.Fatal(err)
+	}
+	if result := wrapQuestionWithGuidelines("what next?", ""); result != expected {
+		t.Errorf("Expected fallback to the default wrapper for a broken template, got %q", result)
+	}
+
+	groupDir := filepath.Join(configDir, "group")
+	if err := os.MkdirAll(groupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, "short.tmpl"), []byte("[{{.ProfileName}}] {{.Question}}\n{{.Profile}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	profile := "---\nanswerTemplateFile: short.tmpl\n---\n# Rules\nBe brief.\n"
+	if err := os.WriteFile(filepath.Join(groupDir, "short.md"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfig(&Config{AnswerTemplate: "ignored", SelectedProfile: "short"}); err != nil {
+		t.Fatal(err)
+	}
+	result := wrapQuestionWithGuidelines("what next?", "")
+	if !strings.HasPrefix(result, "[short] what next?\n") || !strings.Contains(result, "Be brief.") {
+		t.Errorf("Expected profile template, got %q", result)
+	}
+}
+
+func TestAppendWaitTime(t *testing.T) {
+	wait := 4*time.Minute + 12*time.Second
+	if result := appendWaitTime(&Config{}, "answer\n", wait); result != "answer\n" {
+		t.Errorf("Expected no wait time unless configured, got %q", result)
+	}
+	if result := appendWaitTime(&Config{WaitTimeMetadata: true}, "answer\n", wait); result != "answer\n\n(user replied after 4m 12s)" {
+		t.Errorf("Expected wait time appended, got %q", result)
+	}
+
+	tests := map[time.Duration]string{
+		12 * time.Second:               "12s",
+		time.Minute:                    "1m 0s",
+		time.Hour + 4*time.Minute + 59: "1h 4m",
+	}
+	for d, want := range tests {
+		if got := formatWaitTime(d); got != want {
+			t.Errorf("formatWaitTime(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestToolCount(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	now := time.Now()
+
+	if count, err := updateToolCount(dir, "", 3, false, now); err != nil || count != 3 {
+		t.Fatalf("Expected 3, got %d, err: %v", count, err)
+	}
+	if count, _ := updateToolCount(dir, "", 1, false, now); count != 4 {
+		t.Errorf("Expected 4, got %d", count)
+	}
+	if count, _ := updateToolCount(dir, "other", 1, false, now); count != 1 {
+		t.Errorf("Expected sessions counted apart, got %d", count)
+	}
+	if count, _ := updateToolCount(dir, "", 1, false, now.Add(2*time.Hour)); count != 1 {
+		t.Errorf("Expected a new session after the gap, got %d", count)
+	}
+	if count, _ := updateToolCount(dir, "", 0, true, now.Add(2*time.Hour)); count != 0 {
+		t.Errorf("Expected reset to 0, got %d", count)
+	}
+
+	if _, err := updateToolCount(dir, "s1", 7, false, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	result := wrapQuestionWithGuidelinesForSession("what next?", dir, "s1")
+	if !strings.Contains(result, "tool calls used so far in this session: 7\n") {
+		t.Errorf("Expected the tool-call count in the answer, got:\n%s", result)
+	}
+}
+
+func TestAppendReminders(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+	now := time.Now()
+	reminders := []*reminder{
+		{Due: now.Add(-time.Minute), Created: now.Add(-20 * time.Minute), WorkingDir: dir, Text: "check the migration"},
+		{Due: now.Add(time.Hour), Created: now, WorkingDir: dir, Text: "later"},
+		{Due: now.Add(-time.Minute), Created: now, WorkingDir: t.TempDir(), Text: "elsewhere"},
+	}
+	if err := writeReminders(reminders); err != nil {
+		t.Fatal(err)
+	}
+
+	answer := appendReminders("answer\n", filepath.Join(dir, "sub"))
+	if answer != "answer\n\n(reminder set 20m 0s ago: check the migration)" {
+		t.Errorf("Unexpected answer: %q", answer)
+	}
+	if answer := appendReminders("answer", dir); answer != "answer" {
+		t.Errorf("Expected the reminder given once, got %q", answer)
+	}
+	left, err := readReminders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 2 {
+		t.Errorf("Expected 2 pending reminders, got %d", len(left))
+	}
+}
+
+func TestAppendTodos(t *testing.T) {
+	t.Setenv("WHATS_NEXT_CONFIG_DIR", t.TempDir())
+	dir := t.TempDir()
+
+	if answer := appendTodos("answer", dir); answer != "answer" {
+		t.Errorf("Expected no tasks appended, got %q", answer)
+	}
+	first, err := addTodo(dir, "write the migration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := addTodo("", "keep the changelog"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := addTodo(t.TempDir(), "elsewhere"); err != nil {
+		t.Fatal(err)
+	}
+	if item, ok, err := expandTodoCommand("/todo run the backfill", dir); !ok || err != nil || item.ID != 4 {
+		t.Fatalf("Expected /todo to add #4, got %v %v %v", item, ok, err)
+	}
+
+	answer := appendTodos("answer\n", dir)
+	expected := "answer\n\n## Pending tasks\n- [ ] #1 write the migration\n- [ ] #2 keep the changelog\n- [ ] #4 run the backfill\n"
+	if !strings.HasPrefix(answer, expected) {
+		t.Errorf("Expected prefix %q, got %q", expected, answer)
+	}
+
+	if err := markTodosDone([]int{first.ID}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if answer := appendTodos("answer", dir); strings.Contains(answer, "migration") {
+		t.Errorf("Expected done task hidden, got %q", answer)
+	}
+	if err := markTodosDone([]int{42}, time.Now()); err == nil {
+		t.Errorf("Expected unknown ID to fail")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/xhd2015/less-gen/flags"
+	"github.com/xhd2015/whats_next/pkg/config"
+)
+
+const whereHelp = `
+Usage:
+  whats_next where [TARGET [NAME]] [--open]
+
+Prints the path of the config dir, or of TARGET in it, for scripting:
+
+  config          config.json
+  custom          custom.md
+  group [NAME]    the profiles dir, or the profile NAME
+  prompt [NAME]   the prompts dir, or the prompt NAME
+  builtins        the dir overriding the built-in texts
+  logs            the server logs dir
+  history         the transcripts dir
+  project         the config.json of the project of the current directory
+
+The path is printed even if it does not exist yet, except for project.
+
+Options:
+  --open  Open the path in Finder, Explorer or with xdg-open
+`
+
+func where(args []string) error {
+	var open bool
+	args, err := flags.Bool("--open", &open).
+		Help("-h,--help", whereHelp).
+		Parse(args)
+	if err != nil {
+		return err
+	}
+	path, err := resolveWherePath(args)
+	if err != nil {
+		return err
+	}
+	if open {
+		return openPath(path)
+	}
+	fmt.Println(path)
+	return nil
+}
+
+// resolveWherePath returns the path of the target named by args
+func resolveWherePath(args []string) (string, error) {
+	if len(args) == 0 {
+		return getConfigDir(false)
+	}
+	target, args := args[0], args[1:]
+	var name string
+	switch target {
+	case "group", "prompt":
+		if len(args) > 1 {
+			return "", fmt.Errorf("unrecognized extra args: %s", strings.Join(args[1:], " "))
+		}
+		if len(args) == 1 {
+			name = args[0]
+		}
+	default:
+		if len(args) > 0 {
+			return "", fmt.Errorf("unrecognized extra args: %s", strings.Join(args, " "))
+		}
+	}
+	switch target {
+	case "config":
+		return getConfigPath(false, "config.json")
+	case "custom":
+		return getCustomFile(false)
+	case "group":
+		groupDir, err := getGroupConfigPath(false)
+		if err != nil || name == "" {
+			return groupDir, err
+		}
+		return filepath.Join(groupDir, addMDSuffix(name)), nil
+	case "prompt":
+		if name == "" {
+			return getConfigPath(false, promptsDir)
+		}
+		return getPromptFile(name)
+	case "builtins":
+		return getConfigPath(false, "builtins")
+	case "logs":
+		return getLogsDir()
+	case "history":
+		return getConfigPath(false, transcriptDir)
+	case "project":
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		file, ok := findProjectConfig(wd)
+		if !ok {
+			return "", fmt.Errorf("no %s/%s found from %s", config.ProjectDir, config.FileName, wd)
+		}
+		return file, nil
+	}
+	return "", fmt.Errorf("unrecognized target: %s, see `%s where --help`", target, GetProgramName())
+}
+
+// openPath opens path with the default application of the OS,
+// a dir in the file manager
+func openPath(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", path)
+	case "windows":
+		cmd = exec.Command("explorer", path)
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
+	// not waited for, explorer exits with 1 even on success
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	return nil
+}